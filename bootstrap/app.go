@@ -3,7 +3,9 @@ package bootstrap
 import (
 	"fmt"
 
+	"panorama/lib/audit"
 	"panorama/lib/logger"
+	"panorama/lib/storage"
 	"panorama/lib/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -27,6 +29,10 @@ type App struct {
 	Log        logger.Contract
 	Redis      *redis.Client
 	RedisCache *redis.Client
+	Storage    storage.Storage
+
+	// TrustedProxies load balancers of app.trusted_proxies, the forwarded client ip is used only from them
+	TrustedProxies audit.Proxies
 }
 
 // Validator set validator instance
@@ -65,9 +71,29 @@ func SetupValidator(config utils.Config) *Validator {
 func SetupLogger(config utils.Config) logger.Contract {
 	def := config.GetString("log.default")
 	source := fmt.Sprintf("log.%s.source", def)
-	return logger.New(
-		def, config.GetString(source),
-	)
+
+	level := config.GetString("log.level")
+	if len(level) == 0 && config.GetBool("app.debug") {
+		level = "debug"
+	}
+
+	return logger.New(logger.Options{
+		Default:    def,
+		Source:     config.GetString(source),
+		Level:      level,
+		MaxSize:    configInt(config, "log.file.max_size", 100),
+		MaxBackups: configInt(config, "log.file.max_backups", 7),
+		MaxAge:     configInt(config, "log.file.max_age", 30),
+	})
+}
+
+// configInt int of the key, def when it is not set
+func configInt(config utils.Config, key string, def int) int {
+	if v := config.GetInt(key); v > 0 {
+		return v
+	}
+
+	return def
 }
 
 // SetupRedis ...
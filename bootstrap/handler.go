@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"panorama/lib/logger"
 	"panorama/lib/utils"
 
 	validator "github.com/go-playground/validator/v10"
@@ -23,6 +24,15 @@ const (
 	// XPlayer is a token that we get from OneSignal Push notification
 	XPlayer = "X-PLAYER"
 
+	// XAppVersion version of the mobile app, e.g. 1.4.2
+	XAppVersion = "X-App-Version"
+
+	// XPlatform platform of the mobile app, android or ios
+	XPlatform = "X-Platform"
+
+	// XAppUpdate response header that tell the app a newer version is recommended
+	XAppUpdate = "X-App-Update"
+
 	// MsgSuccess ...
 	MsgSuccess = "APP:SUCCESS"
 
@@ -46,6 +56,18 @@ const (
 
 	MsgAuthorizedErr = "ERR:AUTHORIZED"
 
+	// MsgForceUpdate the app version is below the minimum version
+	MsgForceUpdate = "ERR:FORCE_UPDATE"
+
+	// MsgMaintenance the platform is under maintenance
+	MsgMaintenance = "ERR:MAINTENANCE"
+
+	// MsgNotReady a dependency of the api (database, redis, rabbitmq) is unreachable
+	MsgNotReady = "ERR:NOT_READY"
+
+	// MsgConflict the request conflicts with the current state, e.g. the concurrent request won
+	MsgConflict = "ERR:CONFLICT"
+
 	// XChannelHeader custom header for determine what the channel is
 	XChannelHeader = "X-Channel"
 
@@ -119,6 +141,11 @@ func (h *App) SendNotfound(w http.ResponseWriter, message string) {
 	h.RespondWithJSON(w, 404, MsgNotfound, message, h.EmptyJSONArr(), h.EmptyJSONArr())
 }
 
+// SendConflict send conflict into response with 409 http code.
+func (h *App) SendConflict(w http.ResponseWriter, message string) {
+	h.RespondWithJSON(w, 409, MsgConflict, message, h.EmptyJSONArr(), h.EmptyJSONArr())
+}
+
 // SendAuthError send bad request into response with 400 http code.
 func (h *App) SendAuthError(w http.ResponseWriter, message string) {
 	h.RespondWithJSON(w, 401, MsgAuthErr, message, h.EmptyJSONArr(), h.EmptyJSONArr())
@@ -221,6 +248,12 @@ func (h *App) GetBoolParam(r *http.Request, name string) (bool, error) {
 	return result, nil
 }
 
+// Context of the model calls of the request, carries the request id & the user for the logs
+// but is not canceled when the client is gone, so the transaction of the request is finished
+func (h *App) Context(r *http.Request) context.Context {
+	return logger.Detach(r.Context())
+}
+
 func (h *App) PingAction(w http.ResponseWriter, r *http.Request) {
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
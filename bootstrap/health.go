@@ -0,0 +1,99 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"panorama/lib/rabbit"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// readyTimeout timeout of every dependency check of the readiness
+const readyTimeout = 2 * time.Second
+
+const (
+	checkOK       = "ok"
+	checkFail     = "fail"
+	checkDisabled = "disabled"
+)
+
+// errDisabled the dependency is not configured
+var errDisabled = errors.New(checkDisabled)
+
+// HealthzAction liveness of the process, does not check the dependencies
+func (h *App) HealthzAction(w http.ResponseWriter, r *http.Request) {
+	h.SendSuccess(w, map[string]string{"status": checkOK}, nil)
+}
+
+// ReadyzAction readiness, the database, both redis clients & rabbitmq must be reachable.
+// The dependency that is not configured is reported as disabled, the error of the failed check
+// is only logged because the probe is served on the public host
+func (h *App) ReadyzAction(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	checks := map[string]func(ctx context.Context) error{
+		"postgres": func(ctx context.Context) error {
+			if h.DB == nil {
+				return errDisabled
+			}
+			return h.DB.Ping(ctx)
+		},
+		"redis":       pingRedis(h.Redis),
+		"redis_cache": pingRedis(h.RedisCache),
+		"rabbitmq": func(ctx context.Context) error {
+			host := h.Config.GetString("queue.rabbitmq.host")
+			if len(host) == 0 {
+				return errDisabled
+			}
+			return rabbit.Ping(host, readyTimeout)
+		},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := map[string]string{}
+	ready := true
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+
+			status := checkOK
+			if err := check(ctx); err == errDisabled {
+				status = checkDisabled
+			} else if err != nil {
+				status = checkFail
+				h.Log.FromContext(r.Context()).WithError(err).WithField("check", name).Error("Readiness check failed")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result[name] = status
+			if status == checkFail {
+				ready = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if !ready {
+		h.RespondWithJSON(w, http.StatusServiceUnavailable, MsgNotReady, "not ready", result, h.EmptyJSONArr())
+		return
+	}
+
+	h.SendSuccess(w, result, nil)
+}
+
+// pingRedis check of the redis client, nil client is not configured
+func pingRedis(rd *redis.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if rd == nil {
+			return errDisabled
+		}
+		return rd.Ping(ctx).Err()
+	}
+}
@@ -2,10 +2,21 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"panorama/lib/audit"
+	"panorama/lib/logger"
+	"panorama/lib/metrics"
+	"panorama/lib/settings"
 	"panorama/lib/utils"
+	"regexp"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/go-chi/chi/middleware"
@@ -63,21 +74,96 @@ func (app *App) NotfoundMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// MetricsMiddleware count the requests & the latency by the route pattern, the unmatched routes are grouped
+func (app *App) MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if len(route) == 0 {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.ObserveRequest(r.Method, route, status, time.Since(start))
+	})
+}
+
+// RequestLogger id of the request (kept from X-Request-ID when it is valid) & the json access log of the request.
+// The id & the user of the request are attached to every log of the request by Log.FromContext
+func (app *App) RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(logger.RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(logger.RequestIDHeader, id)
+
+		fields := logrus.Fields{logger.RequestIDField: id}
+		if channel := r.Header.Get(XChannelHeader); len(channel) > 0 {
+			fields["channel"] = channel
+		}
+		ctx := logger.NewContext(r.Context(), fields)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		entry := app.Log.FromContext(ctx).WithFields(logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"route":       chi.RouteContext(r.Context()).RoutePattern(),
+			"status":      status,
+			"bytes":       ww.BytesWritten(),
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		})
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request")
+		case status >= http.StatusBadRequest:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+	})
+}
+
+// requestIDPattern the incoming request id that is kept, the other is replaced
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// newRequestID random 128 bits hex id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
 // Recoverer ...
 func (app *App) Recoverer(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rvr := recover(); rvr != nil {
-				logEntry := middleware.GetLogEntry(r)
-				if logEntry != nil {
-					logEntry.Panic(rvr, debug.Stack())
-				} else {
-					debug.PrintStack()
-				}
-
-				app.Log.FromDefault().WithFields(logrus.Fields{
-					"Panic": rvr,
-				}).Errorf("Panic: %v \n %v", rvr, string(debug.Stack()))
+				app.Log.FromContext(r.Context()).WithFields(logrus.Fields{
+					"panic": fmt.Sprint(rvr),
+					"stack": string(debug.Stack()),
+				}).Error("Panic")
 
 				app.SendBadRequest(w, "Something error with our system. Please contact our administrator")
 				return
@@ -93,17 +179,7 @@ func (app *App) Recoverer(next http.Handler) http.Handler {
 // VerifyJwtToken ...
 func (app *App) VerifyJwtToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims := &CustomClaims{}
-		tokenAuth := r.Header.Get("Authorization")
-		_, err := jwt.ParseWithClaims(tokenAuth, claims, func(token *jwt.Token) (interface{}, error) {
-			if jwt.SigningMethodHS256 != token.Method {
-				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-			}
-
-			secret := app.Config.GetString("app.key")
-			return []byte(secret), nil
-		})
-
+		claims, err := app.parseJwtToken(r)
 		if err != nil {
 			msg := "token is invalid"
 			if mErr, ok := err.(*jwt.ValidationError); ok {
@@ -124,6 +200,20 @@ func (app *App) VerifyJwtToken(next http.Handler) http.Handler {
 
 		// TODO: should check to redis/db is token expired or not
 
+		if req := audit.FromContext(r.Context()); req != nil {
+			req.ActorCode = claims.MemberCode
+			req.ActorRole = claims.Role
+			if len(req.Channel) == 0 {
+				req.Channel = claims.Channel
+			}
+		}
+
+		logger.AddFields(r.Context(), logrus.Fields{
+			"user_code": claims.MemberCode,
+			"role":      claims.Role,
+			"channel":   claims.Channel,
+		})
+
 		ctx := userContext(r.Context(), "identifier", map[string]string{
 			"mcode": claims.MemberCode,
 			"role":  claims.Role,
@@ -133,6 +223,80 @@ func (app *App) VerifyJwtToken(next http.Handler) http.Handler {
 	})
 }
 
+// parseJwtToken claims of the token in the Authorization header
+func (app *App) parseJwtToken(r *http.Request) (*CustomClaims, error) {
+	claims := &CustomClaims{}
+	_, err := jwt.ParseWithClaims(r.Header.Get("Authorization"), claims, func(token *jwt.Token) (interface{}, error) {
+		if jwt.SigningMethodHS256 != token.Method {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+
+		secret := app.Config.GetString("app.key")
+		return []byte(secret), nil
+	})
+
+	return claims, err
+}
+
+// AuditMiddleware audit trail of the data-changing requests (POST, PUT, PATCH, DELETE).
+// The handlers record the entity changes, the other successful requests of the logged in actor
+// are recorded per route
+func (app *App) AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := auditAction(r.Method)
+		if len(action) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		req := audit.NewRequest(r, r.Header.Get(XChannelHeader), app.TrustedProxies)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(audit.WithRequest(r.Context(), req)))
+
+		if ww.Status() >= http.StatusBadRequest || req.Recorded() || len(req.ActorCode) == 0 {
+			return
+		}
+
+		var entityID string
+		rctx := chi.RouteContext(r.Context())
+		if n := len(rctx.URLParams.Values); n > 0 {
+			entityID = rctx.URLParams.Values[n-1]
+		}
+
+		err := audit.Write(context.Background(), app.DB, req.Entry(auditEntity(rctx.RoutePattern()), entityID, action, nil, nil))
+		if err != nil {
+			app.Log.FromDefault().WithFields(logrus.Fields{
+				"Process": "[Audit] Write route entry",
+			}).Errorf("Audit %s %s: %s", r.Method, r.URL.Path, err.Error())
+		}
+	})
+}
+
+// auditAction create, update or delete of the method, empty when the method does not change the data
+func auditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return audit.ActionCreate
+	case http.MethodPut, http.MethodPatch:
+		return audit.ActionUpdate
+	case http.MethodDelete:
+		return audit.ActionDelete
+	}
+
+	return ""
+}
+
+// auditEntity first segment of the route after the version, /v1/vouchers/{code} -> vouchers
+func auditEntity(pattern string) string {
+	for _, s := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if len(s) > 0 && s != "v1" {
+			return s
+		}
+	}
+
+	return pattern
+}
+
 // HeaderCheckerMiddleware check the necesarry headers
 func (app *App) HeaderCheckerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -147,6 +311,126 @@ func (app *App) HeaderCheckerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+const (
+	appSettingGroup       = "app"
+	defaultMaintenanceMsg = "Panorama is under maintenance, please try again later."
+)
+
+// AppControlMiddleware maintenance mode & forced update of the mobile apps (cust_mobile_app, tc_mobile_app)
+// from the app settings, the ping and the other channels (cms, webhooks) are always served
+func (app *App) AppControlMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		channel := app.requestChannel(r)
+		if isPingRequest(r) || (channel != ChannelCustApp && channel != ChannelTCApp) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		values, err := settings.Group(r.Context(), app.Log, app.DB, app.RedisCache, settings.TTL(app.Config.GetInt("settings.cache_ttl")), appSettingGroup)
+		if err != nil {
+			// the settings can not be read, serve the app as usual
+			app.Log.FromDefault().WithFields(logrus.Fields{
+				"Process": "[App-Control] Get app settings",
+			}).Errorf("App settings: %s", err.Error())
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if maintenance, _ := strconv.ParseBool(values["maintenance"]); maintenance {
+			msg := values["maintenance_message"]
+			if len(msg) == 0 {
+				msg = defaultMaintenanceMsg
+			}
+
+			app.RespondWithJSON(w, http.StatusServiceUnavailable, MsgMaintenance, msg, app.EmptyJSONArr(), app.EmptyJSONArr())
+			return
+		}
+
+		// the old app without version header can not be told to update
+		version := r.Header.Get(XAppVersion)
+		platform := strings.ToLower(r.Header.Get(XPlatform))
+		if len(version) == 0 || len(platform) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if min := appVersion(values["min_version"], channel, platform); len(min) > 0 && compareVersion(version, min) < 0 {
+			app.RespondWithJSON(w, http.StatusUpgradeRequired, MsgForceUpdate, "Please update the app to the latest version.", map[string]string{
+				"current_version": version,
+				"min_version":     min,
+			}, app.EmptyJSONArr())
+			return
+		}
+
+		if recommended := appVersion(values["recommended_version"], channel, platform); len(recommended) > 0 && compareVersion(version, recommended) < 0 {
+			w.Header().Set(XAppUpdate, recommended)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestChannel channel of the logged in user from the token, so the app can not skip the maintenance
+// by another X-Channel header. The header is only used before login (e.g. the auth routes), the token
+// of the login is then issued for that channel
+func (app *App) requestChannel(r *http.Request) string {
+	if len(r.Header.Get("Authorization")) > 0 {
+		if claims, err := app.parseJwtToken(r); err == nil {
+			return claims.Channel
+		}
+	}
+
+	return r.Header.Get(XChannelHeader)
+}
+
+// appVersion version of the channel & platform from the setting value,
+// e.g. {"cust_mobile_app": {"android": "1.2.0", "ios": "1.2.1"}}
+func appVersion(value, channel, platform string) string {
+	versions := map[string]map[string]string{}
+	if err := json.Unmarshal([]byte(value), &versions); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(versions[channel][platform])
+}
+
+// compareVersion compare the dotted versions (1.10.0 > 1.9.2), -1 when a is older than b, 1 when newer, 0 when equal
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(strings.TrimSpace(a), "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(strings.TrimSpace(b), "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = versionNumber(as[i])
+		}
+		if i < len(bs) {
+			y = versionNumber(bs[i])
+		}
+
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// versionNumber leading number of the version part, 3 of 3-beta
+func versionNumber(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
 // CMSonly check that route only for cms/admin user
 func (app *App) CMSonly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
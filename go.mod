@@ -1,6 +1,6 @@
 module panorama
 
-go 1.15
+go 1.16
 
 require (
 	github.com/AgoraIO/Tools/DynamicKey/AgoraDynamicKey/go/src v0.0.0-20210720085833-2603e2055b1f
@@ -13,7 +13,6 @@ require (
 	github.com/evalphobia/logrus_sentry v0.8.2
 	github.com/georgysavva/scany v0.2.8
 	github.com/getsentry/raven-go v0.2.0 // indirect
-	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8
 	github.com/go-chi/chi v1.5.4
 	github.com/go-chi/chi/v5 v5.0.3
 	github.com/go-chi/cors v1.2.0
@@ -22,10 +21,10 @@ require (
 	github.com/go-playground/universal-translator v0.17.0
 	github.com/go-playground/validator/v10 v10.6.1
 	github.com/go-redis/redis/v8 v8.9.0
-	github.com/golang/protobuf v1.4.3 // indirect
 	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/lib/pq v1.8.0 // indirect
+	github.com/prometheus/client_golang v1.12.2
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/viper v1.7.1
 	github.com/streadway/amqp v1.0.0
@@ -33,7 +32,6 @@ require (
 	github.com/veritrans/go-midtrans v0.0.0-20210616100512-16326c5eeb00
 	github.com/xhit/go-simple-mail/v2 v2.9.1
 	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
-	golang.org/x/sys v0.0.0-20210309074719-68d13333faf2 // indirect
-	google.golang.org/protobuf v1.25.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
@@ -6,19 +6,29 @@ import (
 	rtctokenbuilder "github.com/AgoraIO/Tools/DynamicKey/AgoraDynamicKey/go/src/RtcTokenBuilder"
 )
 
+// DefaultTokenTTL number of seconds after which the token expires when TokenTTL is not set
+const DefaultTokenTTL = 3600
+
 type Contract struct {
-	AppID   string
-	AppCert string
+	AppID    string
+	AppCert  string
+	TokenTTL uint32
 }
 
 // Use RtcTokenBuilder to generate an RTC token.
 func (c Contract) GenerateRtcToken(initUID uint32, channelName string, role rtctokenbuilder.Role) (string, error) {
-	appID := c.AppID
-	appCertificate := c.AppCert
+	token, _, err := c.GenerateRtcTokenWithExpiry(initUID, channelName, role)
+	return token, err
+}
 
+// GenerateRtcTokenWithExpiry generate an RTC token and return the time when the token expires,
+// so the client knows when it has to renew the token.
+func (c Contract) GenerateRtcTokenWithExpiry(initUID uint32, channelName string, role rtctokenbuilder.Role) (string, time.Time, error) {
 	// Number of seconds after which the token expires.
-	// For demonstration purposes the expiry time is set to 40 seconds. This shows you the automatic token renew actions of the client.
-	expireTimeInSeconds := uint32(40)
+	expireTimeInSeconds := c.TokenTTL
+	if expireTimeInSeconds == 0 {
+		expireTimeInSeconds = DefaultTokenTTL
+	}
 
 	// Get current timestamp.
 	currentTimestamp := uint32(time.Now().UTC().Unix())
@@ -26,13 +36,7 @@ func (c Contract) GenerateRtcToken(initUID uint32, channelName string, role rtct
 	// Timestamp when the token expires.
 	expireTimestamp := currentTimestamp + expireTimeInSeconds
 
-	return rtctokenbuilder.BuildTokenWithUID(appID, appCertificate, channelName, initUID, role, expireTimestamp)
-	// if err != nil {
-	// 	fmt.Println(err)
-	// } else {
-	// 	fmt.Printf("Token with uid: %s\n", result)
-	// 	fmt.Printf("uid is %d\n", initUID)
-	// 	fmt.Printf("ChannelName is %s\n", channelName)
-	// 	fmt.Printf("Role is %d\n", role)
-	// }
+	token, err := rtctokenbuilder.BuildTokenWithUID(c.AppID, c.AppCert, channelName, initUID, role, expireTimestamp)
+
+	return token, time.Unix(int64(expireTimestamp), 0).In(time.UTC), err
 }
@@ -0,0 +1,324 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// masked value of the sensitive fields (password, token, pin, secret)
+const masked = "***"
+
+var sensitiveKeys = []string{"password", "token", "secret"}
+
+type ctxKey struct{}
+
+// Execer database pool, connection or transaction
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// Request actor & client of the data-changing request, set by the audit middleware
+type Request struct {
+	ActorCode string
+	ActorRole string
+	Channel   string
+	IP        string
+	UserAgent string
+	Method    string
+	Path      string
+
+	recorded int32
+}
+
+// Entry a change of the entity
+type Entry struct {
+	ActorCode  string
+	ActorRole  string
+	Channel    string
+	EntityType string
+	EntityID   string
+	Action     string
+	Before     interface{}
+	After      interface{}
+	IP         string
+	UserAgent  string
+	Method     string
+	Path       string
+}
+
+// NewRequest request of the http request, the actor is set after the token is verified
+func NewRequest(r *http.Request, channel string, proxies Proxies) *Request {
+	return &Request{
+		Channel:   channel,
+		IP:        proxies.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}
+}
+
+// WithRequest ...
+func WithRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, ctxKey{}, req)
+}
+
+// FromContext request of the context, nil when the request is not audited
+func FromContext(ctx context.Context) *Request {
+	req, _ := ctx.Value(ctxKey{}).(*Request)
+	return req
+}
+
+// Entry change of the entity by the request actor
+func (r *Request) Entry(entityType, entityID, action string, before, after interface{}) Entry {
+	return Entry{
+		ActorCode:  r.ActorCode,
+		ActorRole:  r.ActorRole,
+		Channel:    r.Channel,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Before:     before,
+		After:      after,
+		IP:         r.IP,
+		UserAgent:  r.UserAgent,
+		Method:     r.Method,
+		Path:       r.Path,
+	}
+}
+
+// Record mark the request as audited by the handler, the middleware skip the route entry
+func (r *Request) Record() {
+	atomic.StoreInt32(&r.recorded, 1)
+}
+
+// Recorded ...
+func (r *Request) Recorded() bool {
+	return atomic.LoadInt32(&r.recorded) == 1
+}
+
+// Proxies trusted proxies (load balancer) in front of the api, only they can set the forwarded client address
+type Proxies []*net.IPNet
+
+// ParseProxies comma separated ip or cidr of the trusted proxies (e.g. 10.0.0.0/8, 127.0.0.1)
+func ParseProxies(list string) (Proxies, error) {
+	var proxies Proxies
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %s is invalid", s)
+		}
+		proxies = append(proxies, ipNet)
+	}
+
+	return proxies, nil
+}
+
+func (p Proxies) trusts(ip net.IP) bool {
+	for _, ipNet := range p {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP ip of the client. The X-Forwarded-For (the nearest address that is not a trusted proxy) or
+// X-Real-IP is used only when the request comes from a trusted proxy, the remote address otherwise
+func (p Proxies) ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(remote)
+	if ip == nil || !p.trusts(ip) {
+		return remote
+	}
+
+	if fwd := r.Header.Get("X-Forwarded-For"); len(fwd) > 0 {
+		hops := strings.Split(fwd, ",")
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			client = hop.String()
+			if !p.trusts(hop) {
+				break
+			}
+		}
+
+		return client
+	}
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+		return real.String()
+	}
+
+	return remote
+}
+
+// Snapshot json object of the entity, the sql.Null* values are flattened & the sensitive fields are masked
+func Snapshot(v interface{}) map[string]interface{} {
+	return mask(snapshot(v))
+}
+
+func snapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil
+	}
+
+	for k, val := range data {
+		data[k] = flatten(val)
+	}
+
+	return data
+}
+
+// Diff changed fields of the snapshots, {"field": {"from": .., "to": ..}}
+func Diff(before, after map[string]interface{}) map[string]interface{} {
+	diff := map[string]interface{}{}
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			diff[k] = map[string]interface{}{"from": before[k], "to": v}
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			diff[k] = map[string]interface{}{"from": v, "to": nil}
+		}
+	}
+
+	return diff
+}
+
+// Write insert the entry into the audit logs
+func Write(ctx context.Context, db Execer, e Entry) error {
+	// the diff is taken before masking, a changed password is still listed as changed
+	before := snapshot(e.Before)
+	after := snapshot(e.After)
+	diff := Diff(before, after)
+
+	_, err := db.Exec(ctx, `insert into audit_logs(actor_code, actor_role, channel, entity_type, entity_id, action,
+		before_data, after_data, diff, ip, user_agent, method, path, created_date)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		nullString(e.ActorCode), nullString(e.ActorRole), nullString(e.Channel), e.EntityType, nullString(e.EntityID), e.Action,
+		jsonValue(mask(before)), jsonValue(mask(after)), jsonValue(mask(diff)),
+		nullString(e.IP), nullString(e.UserAgent), nullString(e.Method), nullString(e.Path), time.Now().In(time.UTC),
+	)
+
+	return err
+}
+
+// flatten {"String": "x", "Valid": true} of the sql.Null* into "x" or nil
+func flatten(v interface{}) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	if valid, ok := obj["Valid"].(bool); ok && len(obj) == 2 {
+		if !valid {
+			return nil
+		}
+		for k, val := range obj {
+			if k != "Valid" {
+				return val
+			}
+		}
+	}
+
+	for k, val := range obj {
+		obj[k] = flatten(val)
+	}
+
+	return obj
+}
+
+// mask replace the values of the sensitive fields
+func mask(data map[string]interface{}) map[string]interface{} {
+	for k, v := range data {
+		if isSensitive(k) {
+			data[k] = masked
+			continue
+		}
+		if obj, ok := v.(map[string]interface{}); ok {
+			data[k] = mask(obj)
+		}
+	}
+
+	return data
+}
+
+func isSensitive(key string) bool {
+	k := strings.ToLower(strings.ReplaceAll(key, "_", ""))
+	if k == "pin" || k == "pass" {
+		return true
+	}
+
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func jsonValue(v map[string]interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func nullString(s string) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return s
+}
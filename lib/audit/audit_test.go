@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		fwd     string
+		realIP  string
+		proxies Proxies
+		want    string
+	}{
+		{"no proxy is trusted", "203.0.113.7:4000", "1.2.3.4", "", nil, "203.0.113.7"},
+		{"spoofed by the client", "203.0.113.7:4000", "1.2.3.4", "", proxies, "203.0.113.7"},
+		{"forwarded by the proxy", "10.0.0.5:4000", "198.51.100.9", "", proxies, "198.51.100.9"},
+		{"spoofed through the proxy", "10.0.0.5:4000", "1.2.3.4, 198.51.100.9", "", proxies, "198.51.100.9"},
+		{"chain of proxies", "127.0.0.1:4000", "198.51.100.9, 10.0.0.8", "", proxies, "198.51.100.9"},
+		{"invalid forwarded address", "10.0.0.5:4000", "not-an-ip", "", proxies, "10.0.0.5"},
+		{"real ip of the proxy", "10.0.0.5:4000", "", "198.51.100.9", proxies, "198.51.100.9"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/v1/orders", nil)
+		r.RemoteAddr = tt.remote
+		if len(tt.fwd) > 0 {
+			r.Header.Set("X-Forwarded-For", tt.fwd)
+		}
+		if len(tt.realIP) > 0 {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+
+		if got := tt.proxies.ClientIP(r); got != tt.want {
+			t.Errorf("%s: ClientIP = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ParseProxies("10.0.0.0/33"); err == nil {
+		t.Error("invalid cidr is parsed")
+	}
+}
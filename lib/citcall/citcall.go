@@ -1,11 +1,15 @@
 package citcall
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"panorama/bootstrap"
+	"panorama/lib/metrics"
 	"panorama/lib/utils"
+	"strings"
+	"time"
 )
 
 const (
@@ -26,15 +30,33 @@ type ResponseStatus struct {
 	Info string
 }
 
+// err the sms is not sent when rc is not ok
+func (r ResponseStatus) err() error {
+	if r.RC != STATUS_OK {
+		return fmt.Errorf("citcall: rc %d %s", r.RC, r.Info)
+	}
+
+	return nil
+}
+
 func New(app *bootstrap.App) *service {
 	return &service{app}
 }
 
-func getURLByType(typePrefix string) string {
-	url := fmt.Sprintf("%s/%s/%s", URL_HOST, VERSION, typePrefix)
+func (s *service) getURLByType(typePrefix string) string {
+	url := fmt.Sprintf("%s/%s/%s", s.getHost(), VERSION, typePrefix)
 	return url
 }
 
+// getHost citcall.api_url is set to use a local fake of citcall
+func (s *service) getHost() string {
+	if u := s.app.Config.GetString("citcall.api_url"); len(u) > 0 {
+		return strings.TrimRight(u, "/")
+	}
+
+	return URL_HOST
+}
+
 func getOTPMessage(token string, expiredTime int) string {
 	return fmt.Sprintf("Your One Time Password (OTP) is %s and valid for %d minutes. If you did not initiate this, call our Call Center at (021) 2556 5000", token, expiredTime)
 }
@@ -43,11 +65,11 @@ func (s *service) getApiKey() string {
 	return s.app.Config.GetString("citcall.api_key")
 }
 
-func (s *service) SendOTP(phone, token string, expiredTime int) (ResponseStatus, error) {
+func (s *service) SendOTP(ctx context.Context, phone, token string, expiredTime int) (ResponseStatus, error) {
 	responseStatus := ResponseStatus{}
 	textMessage := getOTPMessage(token, expiredTime)
 	// url := getURLByType(s.app.Config.GetString("citcall.sms_type"))
-	url := getURLByType(METHOD_SMSOTP)
+	url := s.getURLByType(METHOD_SMSOTP)
 
 	values := map[string]interface{}{
 		"msisdn":   phone,
@@ -55,7 +77,7 @@ func (s *service) SendOTP(phone, token string, expiredTime int) (ResponseStatus,
 		"text":     textMessage,
 	}
 
-	request, err := utils.RequestHandler(values, url, http.MethodPost)
+	request, err := utils.RequestHandler(ctx, values, url, http.MethodPost)
 	if err != nil {
 		return responseStatus, err
 	}
@@ -63,12 +85,15 @@ func (s *service) SendOTP(phone, token string, expiredTime int) (ResponseStatus,
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Authorization", "Apikey "+s.getApiKey())
 
-	response, err := utils.ResponseAsyncHandler(request)
+	start := time.Now()
+	response, err := utils.ResponseAsyncHandler(s.app.Log, request)
 	if err != nil {
+		metrics.ObserveExternal(metrics.Citcall, METHOD_SMSOTP, start, err)
 		return responseStatus, err
 	}
 
 	json.Unmarshal([]byte(response[0]), &responseStatus)
+	metrics.ObserveExternal(metrics.Citcall, METHOD_SMSOTP, start, responseStatus.err())
 
 	return responseStatus, err
 }
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSV(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// Write ...
+func (c *csvWriter) Write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case string:
+			record[i] = escapeFormula(val)
+		case float64:
+			record[i] = strconv.FormatFloat(val, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprintf("%v", val)
+		}
+	}
+
+	return c.w.Write(record)
+}
+
+// Close ...
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula text that starts like a formula is quoted, so the spreadsheet does not run it
+func escapeFormula(s string) string {
+	if len(s) > 0 && (s[0] == '=' || s[0] == '+' || s[0] == '-' || s[0] == '@') {
+		return "'" + s
+	}
+
+	return s
+}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatCSV ...
+	FormatCSV = "csv"
+	// FormatXLSX ...
+	FormatXLSX = "xlsx"
+)
+
+// writer write the cells of a row into the file
+type writer interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// column field of the row struct, named by the json tag
+type column struct {
+	Name  string
+	Index []int
+}
+
+// Encoder write the rows (response structs) into csv / xlsx without keeping them in memory,
+// the columns of the file are the json fields of the row
+type Encoder struct {
+	w       writer
+	columns []column
+}
+
+// IsFormat ...
+func IsFormat(format string) bool {
+	return format == FormatCSV || format == FormatXLSX
+}
+
+// ContentType mime of the format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv"
+}
+
+// Columns json names of the row fields
+func Columns(row interface{}) []string {
+	var names []string
+	for _, c := range columns(reflect.TypeOf(row)) {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
+// NewEncoder write the header of the selected columns, all columns when none selected
+func NewEncoder(w io.Writer, format string, row interface{}, selected []string) (*Encoder, error) {
+	all := columns(reflect.TypeOf(row))
+
+	e := &Encoder{columns: all}
+	if len(selected) > 0 {
+		e.columns = []column{}
+		for _, s := range selected {
+			found := false
+			for _, c := range all {
+				if c.Name == strings.TrimSpace(s) {
+					e.columns = append(e.columns, c)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("Column %s is not available, the columns are %s.", s, strings.Join(Columns(row), ", "))
+			}
+		}
+	}
+
+	switch format {
+	case FormatCSV:
+		e.w = newCSV(w)
+	case FormatXLSX:
+		e.w = newXLSX(w)
+	default:
+		return nil, fmt.Errorf("Format %s is not supported.", format)
+	}
+
+	var header []interface{}
+	for _, c := range e.columns {
+		header = append(header, c.Name)
+	}
+
+	return e, e.w.Write(header)
+}
+
+// Encode write the row
+func (e *Encoder) Encode(row interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(row))
+
+	var values []interface{}
+	for _, c := range e.columns {
+		values = append(values, value(v.FieldByIndex(c.Index)))
+	}
+
+	return e.w.Write(values)
+}
+
+// Close flush the rest of the file
+func (e *Encoder) Close() error {
+	return e.w.Close()
+}
+
+// columns exported fields of the struct, the embedded struct fields are flattened like encoding/json
+func columns(t reflect.Type) []column {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var list []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && len(name) == 0 && f.Type.Kind() == reflect.Struct {
+			for _, c := range columns(f.Type) {
+				list = append(list, column{Name: c.Name, Index: append([]int{i}, c.Index...)})
+			}
+			continue
+		}
+
+		if len(name) == 0 {
+			name = f.Name
+		}
+		list = append(list, column{Name: name, Index: []int{i}})
+	}
+
+	return list
+}
+
+// value of the cell, number & bool are kept, the rest written as text
+func value(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Map, reflect.Slice, reflect.Struct, reflect.Array:
+		if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+			return ""
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	return fmt.Sprintf("%v", v.Interface())
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// static parts of the workbook with a single sheet, the sheet itself is streamed
+var xlsxParts = []struct {
+	Name    string
+	Content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+type xlsxWriter struct {
+	z     *zip.Writer
+	sheet *bufio.Writer
+	row   int
+	err   error
+}
+
+func newXLSX(w io.Writer) *xlsxWriter {
+	x := &xlsxWriter{z: zip.NewWriter(w)}
+
+	for _, p := range xlsxParts {
+		f, err := x.z.Create(p.Name)
+		if err != nil {
+			x.err = err
+			return x
+		}
+		if _, err = io.WriteString(f, p.Content); err != nil {
+			x.err = err
+			return x
+		}
+	}
+
+	f, err := x.z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		x.err = err
+		return x
+	}
+
+	x.sheet = bufio.NewWriter(f)
+	_, x.err = x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return x
+}
+
+// Write ...
+func (x *xlsxWriter) Write(values []interface{}) error {
+	if x.err != nil {
+		return x.err
+	}
+
+	x.row++
+	row := strconv.Itoa(x.row)
+
+	x.sheet.WriteString(`<row r="` + row + `">`)
+	for i, v := range values {
+		ref := cellColumn(i) + row
+		switch val := v.(type) {
+		case int64:
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatInt(val, 10) + `</v></c>`)
+		case float64:
+			x.sheet.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(val, 'f', -1, 64) + `</v></c>`)
+		case bool:
+			b := "0"
+			if val {
+				b = "1"
+			}
+			x.sheet.WriteString(`<c r="` + ref + `" t="b"><v>` + b + `</v></c>`)
+		default:
+			s, _ := val.(string)
+			x.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(x.sheet, []byte(s))
+			x.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, x.err = x.sheet.WriteString(`</row>`)
+
+	return x.err
+}
+
+// Close ...
+func (x *xlsxWriter) Close() error {
+	if x.err != nil {
+		return x.err
+	}
+
+	if _, err := x.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return x.z.Close()
+}
+
+// cellColumn letters of the column, 0 -> A, 26 -> AA
+func cellColumn(i int) string {
+	var s string
+	for i++; i > 0; i = (i - 1) / 26 {
+		s = string(rune('A'+(i-1)%26)) + s
+	}
+
+	return s
+}
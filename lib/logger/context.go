@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDHeader id of the request, kept from the client or the proxy when it is set
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDField ...
+	RequestIDField = "request_id"
+)
+
+type ctxKey int
+
+const fieldsKey ctxKey = iota
+
+// fields of the request, shared by the middlewares & the handlers of the request
+type fields struct {
+	mu     sync.RWMutex
+	values logrus.Fields
+}
+
+// NewContext ctx with the log fields, the fields added later by AddFields are seen by every log of the request
+func NewContext(ctx context.Context, f logrus.Fields) context.Context {
+	values := Fields(ctx)
+	for k, v := range f {
+		values[k] = v
+	}
+
+	return context.WithValue(ctx, fieldsKey, &fields{values: values})
+}
+
+// AddFields add the fields to the log fields of the ctx, e.g. the user once the token is verified.
+// Nothing is added when ctx has no log fields
+func AddFields(ctx context.Context, f logrus.Fields) {
+	holder, ok := ctx.Value(fieldsKey).(*fields)
+	if !ok {
+		return
+	}
+
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+
+	for k, v := range f {
+		holder.values[k] = v
+	}
+}
+
+// Fields copy of the log fields of the ctx
+func Fields(ctx context.Context) logrus.Fields {
+	values := logrus.Fields{}
+	if ctx == nil {
+		return values
+	}
+
+	holder, ok := ctx.Value(fieldsKey).(*fields)
+	if !ok {
+		return values
+	}
+
+	holder.mu.RLock()
+	defer holder.mu.RUnlock()
+
+	for k, v := range holder.values {
+		values[k] = v
+	}
+
+	return values
+}
+
+// RequestID id of the request of the ctx, empty when ctx is not of a request
+func RequestID(ctx context.Context) string {
+	id, _ := Fields(ctx)[RequestIDField].(string)
+
+	return id
+}
+
+// Detach ctx with the values of parent that is not canceled with parent, for the work that must finish
+// even when the client of the request is gone (transactions, background jobs)
+func Detach(parent context.Context) context.Context {
+	return detached{parent}
+}
+
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
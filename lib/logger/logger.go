@@ -1,98 +1,109 @@
 package logger
 
 import (
+	"context"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/evalphobia/logrus_sentry"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Contract ...
 type Contract interface {
-	Sentry() *logrus.Logger
-	File() *logrus.Logger
 	FromDefault() *logrus.Logger
+	FromContext(ctx context.Context) *logrus.Entry
 }
 
+// Options of the logger
+type Options struct {
+	// Default file or sentry, stdout otherwise
+	Default string
+	// Source path of the log file (file) or dsn (sentry)
+	Source string
+	// Level debug, info, warn or error
+	Level string
+
+	// MaxSize megabytes of the log file before it is rotated
+	MaxSize int
+	// MaxBackups rotated files that are kept
+	MaxBackups int
+	// MaxAge days of the rotated files that are kept
+	MaxAge int
+}
+
+// defaultFileName of the log file when the source is a dir
+const defaultFileName = "api.log"
+
 // logs ...
 type logs struct {
-	Logrus      *logrus.Logger
-	DefaultType string
-	Source      string
+	Logrus *logrus.Logger
 }
 
-// New instantiate the logger package
-func New(tp, source string) Contract {
-	return &logs{
-		Logrus:      logrus.New(),
-		DefaultType: tp,
-		Source:      source,
+// New instantiate the logger package, the json logs are written to the rotated file, sentry or stdout
+func New(opt Options) Contract {
+	l := logrus.New()
+	l.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	l.SetOutput(os.Stdout)
+
+	level, err := logrus.ParseLevel(opt.Level)
+	if err != nil {
+		level = logrus.InfoLevel
 	}
-}
+	l.SetLevel(level)
 
-func (th logs) FromDefault() *logrus.Logger {
-	var log *logrus.Logger
-	switch def := th.DefaultType; def {
+	switch opt.Default {
 	case "file":
-		log = th.File()
+		l.SetOutput(fileWriter(opt))
 	case "sentry":
-		log = th.Sentry()
+		hook, err := logrus_sentry.NewAsyncSentryHook(opt.Source, []logrus.Level{
+			logrus.PanicLevel,
+			logrus.FatalLevel,
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+		})
+		if err != nil {
+			l.WithError(err).Error("Failed to log to sentry, using stdout")
+			break
+		}
+		hook.Timeout = 10 * time.Second
+		l.AddHook(hook)
 	}
 
-	// log.SetFormatter(&logrus.JSONFormatter{})
-	return log
+	// the lines of the standard log (libs & the old code) are also written as the json logs
+	log.SetFlags(0)
+	log.SetOutput(l.Writer())
+
+	return &logs{Logrus: l}
 }
 
-// File is a function to set logrus with file
-func (th logs) File() *logrus.Logger {
-	var (
-		err  error
-		file *os.File
-	)
-	path := th.Source
-	// if file exist then open and append/write into log file.
-	if _, err = os.Stat(path); err == nil {
-		file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-		if err == nil {
-			th.Logrus.Out = file
-
-			return th.Logrus
-		}
+// fileWriter rotated log file, the file is in the source dir when the source is a dir
+func fileWriter(opt Options) io.Writer {
+	path := opt.Source
+	if info, err := os.Stat(path); (err == nil && info.IsDir()) || strings.HasSuffix(path, "/") {
+		path = filepath.Join(path, defaultFileName)
 	}
 
-	file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0755)
-	if err == nil {
-		th.Logrus.Out = file
-	} else {
-		th.Logrus.Info("Failed to log to file, using default stderr")
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    opt.MaxSize,
+		MaxBackups: opt.MaxBackups,
+		MaxAge:     opt.MaxAge,
+		Compress:   true,
 	}
+}
 
-	defer file.Close()
-
+// FromDefault ...
+func (th logs) FromDefault() *logrus.Logger {
 	return th.Logrus
 }
 
-// Sentry is a function for setting Sentry.io logger
-func (th logs) Sentry() *logrus.Logger {
-	var (
-		err  error
-		hook *logrus_sentry.SentryHook
-	)
-	hook, err = logrus_sentry.NewAsyncSentryHook(th.Source, []logrus.Level{
-		logrus.PanicLevel,
-		logrus.FatalLevel,
-		logrus.ErrorLevel,
-		logrus.DebugLevel,
-		logrus.InfoLevel,
-		logrus.WarnLevel,
-	})
-
-	hook.Timeout = 10 * time.Second
-
-	if err == nil {
-		th.Logrus.Hooks.Add(hook)
-	}
-
-	return th.Logrus
+// FromContext entry with the fields of the ctx (request id, user), use it whenever ctx is of a request
+func (th logs) FromContext(ctx context.Context) *logrus.Entry {
+	return th.Logrus.WithFields(Fields(ctx))
 }
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "panorama"
+
+// external services
+const (
+	OneSignal = "onesignal"
+	Citcall   = "citcall"
+	SendGrid  = "sendgrid"
+	Midtrans  = "midtrans"
+)
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requests of the api by the route pattern & the response status.",
+	}, []string{"method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the api by the route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	externalRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_requests_total",
+		Help:      "Calls to the external services, result is ok or error.",
+	}, []string{"service", "operation", "result"})
+
+	externalDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "external_request_duration_seconds",
+		Help:      "Latency of the calls to the external services.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"service", "operation"})
+
+	ordersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders created by the order type.",
+	}, []string{"order_type"})
+
+	paymentsSettled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_settled_total",
+		Help:      "Payments settled (paid) by the midtrans payment type.",
+	}, []string{"payment_type"})
+
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_messages_sent_total",
+		Help:      "Chat messages sent by the role of the sender.",
+	}, []string{"role"})
+)
+
+// Handler exposition of the metrics for the prometheus scraper
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest record the request of the api, route is the chi route pattern
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	requestDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveExternal record the latency & the result of the call to the external service started at start
+func ObserveExternal(service, operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	externalRequests.WithLabelValues(service, operation, result).Inc()
+	externalDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+}
+
+// OrderCreated ...
+func OrderCreated(orderType string) {
+	ordersCreated.WithLabelValues(orderType).Inc()
+}
+
+// PaymentSettled ...
+func PaymentSettled(paymentType string) {
+	paymentsSettled.WithLabelValues(paymentType).Inc()
+}
+
+// MessageSent ...
+func MessageSent(role string) {
+	messagesSent.WithLabelValues(role).Inc()
+}
+
+// RegisterDBPool export the stats of the pool, registered once per process
+func RegisterDBPool(db *pgxpool.Pool) error {
+	err := prometheus.Register(poolCollector{db: db})
+
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+
+	return err
+}
+
+var (
+	poolAcquiredConns = poolDesc("acquired_conns", "Connections of the pool in use.")
+	poolIdleConns     = poolDesc("idle_conns", "Idle connections of the pool.")
+	poolTotalConns    = poolDesc("total_conns", "Connections of the pool, in use, idle & constructing.")
+	poolMaxConns      = poolDesc("max_conns", "Max size of the pool.")
+	poolAcquires      = poolDesc("acquires_total", "Acquires of connection from the pool.")
+	poolAcquireTime   = poolDesc("acquire_duration_seconds_total", "Time spent to acquire connection from the pool.")
+	poolEmptyAcquires = poolDesc("empty_acquires_total", "Acquires that waited for a connection because the pool was empty.")
+	poolCanceled      = poolDesc("canceled_acquires_total", "Acquires canceled by the context.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+// poolCollector stats of the pgx pool at the scrape time
+type poolCollector struct {
+	db *pgxpool.Pool
+}
+
+// Describe ...
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns,
+		poolAcquires, poolAcquireTime, poolEmptyAcquires, poolCanceled,
+	} {
+		ch <- d
+	}
+}
+
+// Collect ...
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireTime, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
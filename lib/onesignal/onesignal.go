@@ -1,11 +1,15 @@
 package onesignal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"panorama/bootstrap"
+	"panorama/lib/metrics"
 	"panorama/lib/utils"
+	"strings"
+	"time"
 )
 
 // device_type list reference https://documentation.onesignal.com/reference/add-a-device
@@ -30,11 +34,29 @@ func New(app *bootstrap.App) *service {
 	return &service{app}
 }
 
-func getURLByPrefix(prefix string) string {
-	url := fmt.Sprintf("%s/%s/%s", URL_HOST, VERSION, prefix)
+func (s *service) getURLByPrefix(prefix string) string {
+	url := fmt.Sprintf("%s/%s/%s", s.getHost(), VERSION, prefix)
 	return url
 }
 
+// getHost onesignal.api_url is set to use a local fake of onesignal
+func (s *service) getHost() string {
+	if u := s.app.Config.GetString("onesignal.api_url"); len(u) > 0 {
+		return strings.TrimRight(u, "/")
+	}
+
+	return URL_HOST
+}
+
+// responseErr errors of the onesignal response
+func responseErr(response map[string]interface{}) error {
+	if errs, ok := response["errors"]; ok && errs != nil {
+		return fmt.Errorf("onesignal: %v", errs)
+	}
+
+	return nil
+}
+
 func (s *service) getApiKey() string {
 	return s.app.Config.GetString("onesignal.api_key")
 }
@@ -65,34 +87,41 @@ type Player struct {
 	BadgeCount        int               `json:"badge_count"`
 }
 
-func (s *service) AddDevice(deviceType int) (string, error) {
+func (s *service) AddDevice(ctx context.Context, deviceType int) (string, error) {
 	player := Player{}
-	url := getURLByPrefix(API_PREFIX_DEVICE)
+	url := s.getURLByPrefix(API_PREFIX_DEVICE)
 
 	values := map[string]interface{}{
 		"app_id":      s.getAppID(),
 		"device_type": deviceType,
 	}
 
-	request, err := utils.RequestHandler(values, url, http.MethodPost)
+	request, err := utils.RequestHandler(ctx, values, url, http.MethodPost)
 	if err != nil {
 		return "", err
 	}
 	request.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	response, err := utils.ResponseAsyncHandler(request)
+	start := time.Now()
+	response, err := utils.ResponseAsyncHandler(s.app.Log, request)
 	if err != nil {
+		metrics.ObserveExternal(metrics.OneSignal, "add_device", start, err)
 		return "", err
 	}
 
 	json.Unmarshal([]byte(response[0]), &player)
+	if len(player.ID) == 0 {
+		metrics.ObserveExternal(metrics.OneSignal, "add_device", start, fmt.Errorf("onesignal: %s", response[0]))
+	} else {
+		metrics.ObserveExternal(metrics.OneSignal, "add_device", start, nil)
+	}
 
 	return player.ID, err
 }
 
-func (s *service) PushNotification(header, content string, playerID []string) (map[string]interface{}, error) {
+func (s *service) PushNotification(ctx context.Context, header, content string, playerID []string) (map[string]interface{}, error) {
 	var callback map[string]interface{}
-	url := getURLByPrefix(API_PREFIX_NOTIFICATION)
+	url := s.getURLByPrefix(API_PREFIX_NOTIFICATION)
 
 	values := map[string]interface{}{
 		"app_id":             s.getAppID(),
@@ -101,40 +130,46 @@ func (s *service) PushNotification(header, content string, playerID []string) (m
 		"include_player_ids": playerID,
 	}
 
-	request, err := utils.RequestHandler(values, url, http.MethodPost)
+	request, err := utils.RequestHandler(ctx, values, url, http.MethodPost)
 	if err != nil {
 		return callback, err
 	}
 	request.Header.Set("Authorization", "Basic "+s.getApiKey())
 	request.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	response, err := utils.ResponseAsyncHandler(request)
+	start := time.Now()
+	response, err := utils.ResponseAsyncHandler(s.app.Log, request)
 	if err != nil {
+		metrics.ObserveExternal(metrics.OneSignal, "push_notification", start, err)
 		return callback, err
 	}
 
 	json.Unmarshal([]byte(response[0]), &callback)
+	metrics.ObserveExternal(metrics.OneSignal, "push_notification", start, responseErr(callback))
 
-	utils.ResponseHandler(request)
+	utils.ResponseHandler(s.app.Log, request)
 
 	return callback, err
 }
 
-func (s *service) GetPlayerDevice(playerID string) (Player, error) {
+func (s *service) GetPlayerDevice(ctx context.Context, playerID string) (Player, error) {
 	player := Player{}
-	url := fmt.Sprintf("%s/%s", getURLByPrefix(API_PREFIX_DEVICE), playerID)
+	url := fmt.Sprintf("%s/%s", s.getURLByPrefix(API_PREFIX_DEVICE), playerID)
 
-	request, err := utils.RequestHandler(nil, url, http.MethodGet)
+	request, err := utils.RequestHandler(ctx, nil, url, http.MethodGet)
 	if err != nil {
 		return player, err
 	}
 	request.Header.Set("Authorization", "Basic "+s.getApiKey())
 	request.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	response, err := utils.ResponseHandler(request)
+	start := time.Now()
+	response, err := utils.ResponseHandler(s.app.Log, request)
 	if err != nil {
+		metrics.ObserveExternal(metrics.OneSignal, "get_player", start, err)
 		return player, err
 	}
+	metrics.ObserveExternal(metrics.OneSignal, "get_player", start, responseErr(response))
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		return player, err
@@ -1,9 +1,22 @@
 package payment
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"panorama/bootstrap"
+	"panorama/lib/logger"
+	"panorama/lib/metrics"
 	"panorama/services/api/model"
+	"strings"
+	"time"
 
 	midtrans "github.com/veritrans/go-midtrans"
 )
@@ -21,6 +34,19 @@ const (
 	DURATION_EXPIRED                       = 2 // in minutes
 )
 
+// TransactionStatus status of midtrans transaction with the mapped order and payment status
+type TransactionStatus struct {
+	OrderID           string
+	TransactionID     string
+	PaymentType       string
+	TransactionStatus string
+	FraudStatus       string
+	TransactionTime   string
+	GrossAmount       int64
+	OrderStatus       string
+	PaymentStatus     string
+}
+
 type service struct {
 	app *bootstrap.App
 }
@@ -43,10 +69,10 @@ func (s *service) GetMidtransStatus(paymentType string, transactionStatus string
 	paymentStatus := model.PAYMENT_STATUS_PROCESS
 
 	if paymentType == MIDTRANS_PAYMENT_TYPE_CREDIT_CARD && transactionStatus == MIDTRANS_TRANSACTION_STATUS_CAPTURE && fraudStatus == MIDTRANS_FRAUD_STATUS_ACCEPT {
-		orderStatus = model.ORDER_STATUS_COMPLETED
+		orderStatus = model.ORDER_STATUS_PAID
 		paymentStatus = model.PAYMENT_STATUS_PAID
 	} else if transactionStatus == MIDTRANS_TRANSACTION_STATUS_SETTLEMENT {
-		orderStatus = model.ORDER_STATUS_COMPLETED
+		orderStatus = model.ORDER_STATUS_PAID
 		paymentStatus = model.PAYMENT_STATUS_PAID
 	} else if transactionStatus == MIDTRANS_TRANSACTION_STATUS_DENY || transactionStatus == MIDTRANS_TRANSACTION_STATUS_EXPIRE || transactionStatus == MIDTRANS_TRANSACTION_STATUS_CANCEL {
 		orderStatus = model.ORDER_STATUS_CANCEL
@@ -61,11 +87,24 @@ func (s *service) GetMidtransStatus(paymentType string, transactionStatus string
 	return result
 }
 
-func (s *service) GetMidtransPaymentURL(r map[string]interface{}) (map[string]string, error) {
-	if r["user_email"] == nil || r["user_name"] == nil || r["order_code"] == nil || r["order_amount"] == nil {
-		return nil, fmt.Errorf("invalid param %v", r)
+// MidtransSignature signature key of midtrans notification, sha512 of the order id, status code, gross amount and server key
+func (s *service) MidtransSignature(orderID, statusCode, grossAmount string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + s.app.Config.GetString("midtrans.server_key")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyMidtransSignature whether the notification is signed by midtrans with the server key
+func (s *service) VerifyMidtransSignature(orderID, statusCode, grossAmount, signatureKey string) bool {
+	if len(s.app.Config.GetString("midtrans.server_key")) == 0 || len(signatureKey) == 0 {
+		return false
 	}
+	expected := s.MidtransSignature(orderID, statusCode, grossAmount)
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(signatureKey))) == 1
+}
 
+func (s *service) midtransClient() midtrans.Client {
 	midtransClient := midtrans.NewClient()
 	midtransClient.ServerKey = s.app.Config.GetString("midtrans.server_key")
 	midtransClient.ClientKey = s.app.Config.GetString("midtrans.client_key")
@@ -76,8 +115,85 @@ func (s *service) GetMidtransPaymentURL(r map[string]interface{}) (map[string]st
 		midtransClient.APIEnvType = midtrans.Production
 	}
 
-	snapGateway := midtrans.SnapGateway{
-		Client: midtransClient,
+	return midtransClient
+}
+
+// apiURL base url of midtrans core api, midtrans.api_url is set to use a local fake of midtrans
+func (s *service) apiURL() string {
+	if u := s.app.Config.GetString("midtrans.api_url"); len(u) > 0 {
+		return strings.TrimRight(u, "/")
+	}
+
+	return s.midtransClient().APIEnvType.String()
+}
+
+// snapURL base url of midtrans snap, the local fake of midtrans (midtrans.api_url) also serve snap
+func (s *service) snapURL() string {
+	if u := s.app.Config.GetString("midtrans.api_url"); len(u) > 0 {
+		return strings.TrimRight(u, "/")
+	}
+
+	return s.midtransClient().APIEnvType.SnapURL()
+}
+
+// call midtrans with the ctx, the id of the request of ctx is forwarded to midtrans
+func (s *service) call(ctx context.Context, method, path string, body io.Reader, v interface{}) error {
+	midtransClient := s.midtransClient()
+	req, err := midtransClient.NewRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if id := logger.RequestID(ctx); len(id) > 0 {
+		req.Header.Set(logger.RequestIDHeader, id)
+	}
+
+	return midtransClient.ExecuteRequest(req, v)
+}
+
+// GetMidtransTransactionStatus query the status of transaction by the midtrans order id
+func (s *service) GetMidtransTransactionStatus(ctx context.Context, orderID string) (TransactionStatus, error) {
+	var result TransactionStatus
+
+	resp := midtrans.Response{}
+	start := time.Now()
+	err := s.call(ctx, "GET", fmt.Sprintf("%s/v2/%s/status", s.apiURL(), url.PathEscape(orderID)), nil, &resp)
+	metrics.ObserveExternal(metrics.Midtrans, "transaction_status", start, err)
+	if err != nil {
+		return result, err
+	}
+	if resp.StatusCode == "404" {
+		return result, fmt.Errorf("Midtrans transaction %s not found.", orderID)
+	}
+	if len(resp.TransactionStatus) == 0 {
+		return result, fmt.Errorf("Midtrans status of %s failed: %s %s", orderID, resp.StatusCode, resp.StatusMessage)
+	}
+
+	result = TransactionStatus{
+		OrderID:           resp.OrderID,
+		TransactionID:     resp.TransactionID,
+		PaymentType:       resp.PaymentType,
+		TransactionStatus: resp.TransactionStatus,
+		FraudStatus:       resp.FraudStatus,
+		TransactionTime:   resp.TransactionTime,
+	}
+	if len(resp.GrossAmount) > 0 {
+		result.GrossAmount, err = model.ParseMidtransAmount(resp.GrossAmount)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	midtransStatus := s.GetMidtransStatus(resp.PaymentType, resp.TransactionStatus, resp.FraudStatus)
+	result.OrderStatus = midtransStatus["order_status"]
+	result.PaymentStatus = midtransStatus["payment_status"]
+
+	return result, nil
+}
+
+func (s *service) GetMidtransPaymentURL(ctx context.Context, r map[string]interface{}) (map[string]string, error) {
+	if r["user_email"] == nil || r["user_name"] == nil || r["order_code"] == nil || r["order_amount"] == nil {
+		return nil, fmt.Errorf("invalid param %v", r)
 	}
 
 	snapRequest := &midtrans.SnapReq{
@@ -91,7 +207,14 @@ func (s *service) GetMidtransPaymentURL(r map[string]interface{}) (map[string]st
 		},
 	}
 
-	snapResponse, err := snapGateway.GetToken(snapRequest)
+	snapResponse := midtrans.SnapResponse{}
+	jsonReq, _ := json.Marshal(snapRequest)
+	start := time.Now()
+	err := s.call(ctx, "POST", s.snapURL()+"/snap/v1/transactions", bytes.NewBuffer(jsonReq), &snapResponse)
+	if err == nil && len(snapResponse.ErrorMessages) > 0 {
+		err = errors.New(strings.Join(snapResponse.ErrorMessages, ", "))
+	}
+	metrics.ObserveExternal(metrics.Midtrans, "snap_transaction", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,151 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// A4 page size in point
+	PageWidth  = 595
+	PageHeight = 842
+
+	margin     = 40
+	fontSize   = 9
+	lineHeight = 12
+
+	// BoldPrefix line that start with the prefix is written in bold font
+	BoldPrefix = "**"
+)
+
+// Document plain text document with monospace font, the layout of the text is
+// prepared by the caller (e.g. from text/template) so the column is kept aligned
+type Document struct {
+	pages [][]string
+	title string
+}
+
+// New empty document
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// LinesPerPage total line that fit into one page
+func LinesPerPage() int {
+	return (PageHeight - 2*margin) / lineHeight
+}
+
+// Write add text into document, new page is added when the current page is full
+// and form feed (\f) in the text force a new page
+func (d *Document) Write(text string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	for i, page := range strings.Split(text, "\f") {
+		if i > 0 || len(d.pages) == 0 {
+			d.pages = append(d.pages, []string{})
+		}
+
+		for _, line := range strings.Split(strings.TrimRight(page, "\n"), "\n") {
+			current := len(d.pages) - 1
+			if len(d.pages[current]) >= LinesPerPage() {
+				d.pages = append(d.pages, []string{})
+				current++
+			}
+			d.pages[current] = append(d.pages[current], line)
+		}
+	}
+}
+
+// Bytes render the document into pdf
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.pages = [][]string{{}}
+	}
+
+	var objects []string
+
+	// 1: catalog, 2: pages, 3: regular font, 4: bold font, 5: info, then page and content of each page
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+i*2))
+	}
+
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (panorama) >>", escape(d.title)),
+	)
+
+	for i, lines := range d.pages {
+		content := d.content(lines)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", PageWidth, PageHeight, 7+i*2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// content text operator of the page
+func (d *Document) content(lines []string) string {
+	var b strings.Builder
+
+	b.WriteString("BT\n")
+	fmt.Fprintf(&b, "%d TL\n%d %d Td\n", lineHeight, margin, PageHeight-margin-fontSize)
+	for _, line := range lines {
+		font := "F1"
+		if strings.HasPrefix(line, BoldPrefix) {
+			font = "F2"
+			line = strings.TrimPrefix(line, BoldPrefix)
+		}
+		fmt.Fprintf(&b, "/%s %d Tf\n(%s) Tj T*\n", font, fontSize, escape(line))
+	}
+	b.WriteString("ET")
+
+	return b.String()
+}
+
+// escape string of pdf, character outside latin-1 is replaced by question mark
+func escape(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 32:
+			continue
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,296 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// table version of the schema, same as golang-migrate so the databases migrated by the tool keep their version
+const table = "schema_migrations"
+
+// lockID key of the advisory lock, only one runner migrate the database at a time
+const lockID = 7419020410
+
+// fileName <version>_<name>.<up|down>.sql, e.g. 000001_create_users_table.up.sql
+var fileName = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrDirty the last migration failed halfway by the old migrate tool, the schema must be fixed by hand
+var ErrDirty = errors.New("migrate: database is dirty")
+
+// Migration sql of a version, the versions are not contiguous
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status of a migration in the database
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Load the migrations of the dir sorted by the version, every version must have the up & down file (may be empty)
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	hasUp, hasDown := map[uint]bool{}, map[uint]bool{}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: invalid file name %s", e.Name())
+		}
+
+		v, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("migrate: invalid version of %s", e.Name())
+		}
+
+		m, ok := byVersion[uint(v)]
+		if !ok {
+			m = &Migration{Version: uint(v), Name: match[2]}
+			byVersion[uint(v)] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by %s and %s", v, m.Name, match[2])
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up = string(b)
+			hasUp[m.Version] = true
+		} else {
+			m.Down = string(b)
+			hasDown[m.Version] = true
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !hasUp[m.Version] || !hasDown[m.Version] {
+			return nil, fmt.Errorf("migrate: %06d_%s must have both up & down file", m.Version, m.Name)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+// Migrator apply the migrations to the database
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// New ...
+func New(db *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Latest version of the migrations
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version current version of the database, 0 when nothing is applied yet
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Release()
+
+	return version(ctx, conn)
+}
+
+// Status of every migration
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	current, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		list = append(list, Status{Migration: mg, Applied: mg.Version <= current})
+	}
+
+	return list, nil
+}
+
+// Pending migrations that are not applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+
+	return m.after(current), nil
+}
+
+// Check returns error when the database is dirty or behind the latest migration
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		current, _, _ := m.Version(ctx)
+		return fmt.Errorf("migrate: database schema is behind, version %d of %d, %d migrations pending", current, m.Latest(), len(pending))
+	}
+
+	return nil
+}
+
+// Up apply the next n pending migrations, all of them when n <= 0
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn, current uint) error {
+		pending := m.after(current)
+		if n > 0 && n < len(pending) {
+			pending = pending[:n]
+		}
+
+		for _, mg := range pending {
+			if err := apply(ctx, conn, mg.Up, mg.Version); err != nil {
+				return fmt.Errorf("migrate: %06d_%s up: %w", mg.Version, mg.Name, err)
+			}
+			applied = append(applied, mg)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down revert the last n applied migrations, all of them when n <= 0
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn, current uint) error {
+		i := len(m.migrations) - len(m.after(current)) - 1
+		if current > 0 && (i < 0 || m.migrations[i].Version != current) {
+			return fmt.Errorf("migrate: version %d of the database is not in the migrations", current)
+		}
+
+		for ; i >= 0 && (n <= 0 || len(reverted) < n); i-- {
+			mg := m.migrations[i]
+
+			var prev uint
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+			if err := apply(ctx, conn, mg.Down, prev); err != nil {
+				return fmt.Errorf("migrate: %06d_%s down: %w", mg.Version, mg.Name, err)
+			}
+			reverted = append(reverted, mg)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// after migrations newer than the version
+func (m *Migrator) after(v uint) []Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version > v })
+
+	return m.migrations[i:]
+}
+
+// locked run fn with the advisory lock held, the database must not be dirty
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, current uint) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "select pg_advisory_lock($1)", lockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "select pg_advisory_unlock($1)", lockID)
+
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+
+	return fn(conn, current)
+}
+
+// version of the database, the table is created when it does not exist yet
+func version(ctx context.Context, conn *pgxpool.Conn) (uint, bool, error) {
+	_, err := conn.Exec(ctx, "create table if not exists "+table+" (version bigint not null primary key, dirty boolean not null)")
+	if err != nil {
+		return 0, false, err
+	}
+
+	var v int64
+	var dirty bool
+	err = conn.QueryRow(ctx, "select version, dirty from "+table+" limit 1").Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return uint(v), dirty, err
+}
+
+// apply run the sql and set the version in one transaction, version 0 clear the table
+func apply(ctx context.Context, conn *pgxpool.Conn, sql string, v uint) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "delete from "+table); err != nil {
+		return err
+	}
+	if v > 0 {
+		if _, err = tx.Exec(ctx, "insert into "+table+" (version, dirty) values ($1, false)", int64(v)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,82 @@
+package migrate_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"panorama/lib/psql/migrate"
+	"panorama/lib/psql/psqltest"
+	"panorama/resources"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	list, err := migrate.Load(resources.Migrations, resources.MigrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no migrations")
+	}
+
+	for i := 1; i < len(list); i++ {
+		if list[i].Version <= list[i-1].Version {
+			t.Fatalf("migrations are not sorted: %d after %d", list[i].Version, list[i-1].Version)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"m/000001_a.up.sql": {Data: []byte("select 1")},
+		},
+		"invalid name": {
+			"m/000001_a.up.up.sql":   {Data: []byte("select 1")},
+			"m/000001_a.up.down.sql": {Data: []byte("")},
+		},
+		"duplicate version": {
+			"m/000001_a.up.sql":   {Data: []byte("select 1")},
+			"m/000001_a.down.sql": {Data: []byte("")},
+			"m/000001_b.up.sql":   {Data: []byte("select 1")},
+			"m/000001_b.down.sql": {Data: []byte("")},
+		},
+	}
+
+	for name, fsys := range cases {
+		if _, err := migrate.Load(fsys, "m"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db := psqltest.Connect(t)
+	ctx := context.Background()
+
+	list, err := migrate.Load(resources.Migrations, resources.MigrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrate.New(db, list)
+
+	if err = m.Check(ctx); err != nil {
+		t.Fatalf("check after connect: %v", err)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != m.Latest() {
+		t.Fatalf("down 1 = %v, %v", reverted, err)
+	}
+	if err = m.Check(ctx); err == nil {
+		t.Fatal("check must fail when the schema is behind")
+	}
+
+	applied, err := m.Up(ctx, 0)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("up = %v, %v", applied, err)
+	}
+	if v, dirty, err := m.Version(ctx); err != nil || dirty || v != m.Latest() {
+		t.Fatalf("version = %d %v %v, want %d", v, dirty, err, m.Latest())
+	}
+}
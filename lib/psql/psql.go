@@ -22,6 +22,13 @@ func Connect(dsn string) (*pgxpool.Pool, error) {
 	return conn, nil
 }
 
+// IsUniqueViolation the error is the violation of the unique constraint (or index) name
+func IsUniqueViolation(err error, name string) bool {
+	pqe, ok := err.(*pgconn.PgError)
+
+	return ok && pqe.Code == "23505" && pqe.ConstraintName == name
+}
+
 // Parsing Error
 func ParseErr(err error) string {
 	switch pqe := err.(type) {
@@ -0,0 +1,25 @@
+package psql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	err := &pgconn.PgError{Code: "23505", ConstraintName: "call_sessions_active_chat_group_id_idx"}
+
+	if !IsUniqueViolation(err, "call_sessions_active_chat_group_id_idx") {
+		t.Fatal("unique violation of the index is not detected")
+	}
+	if IsUniqueViolation(err, "call_sessions_call_code_key") {
+		t.Fatal("unique violation of the other constraint is detected")
+	}
+	if IsUniqueViolation(&pgconn.PgError{Code: "23503", ConstraintName: "call_sessions_active_chat_group_id_idx"}, "call_sessions_active_chat_group_id_idx") {
+		t.Fatal("foreign key violation is detected as unique violation")
+	}
+	if IsUniqueViolation(errors.New("duplicate"), "call_sessions_active_chat_group_id_idx") {
+		t.Fatal("plain error is detected as unique violation")
+	}
+}
@@ -0,0 +1,125 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Query conditions & arguments of the list query, the request values are always bound as $n
+// and never spliced into the sql
+type Query struct {
+	Args  []interface{}
+	where []string
+}
+
+// Copier connection or transaction that support COPY
+type Copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Arg bind the value, returns the $n placeholder
+func (q *Query) Arg(v interface{}) string {
+	q.Args = append(q.Args, v)
+	return "$" + strconv.Itoa(len(q.Args))
+}
+
+// Where add the condition joined by AND, each ? of the condition is bound to the next value
+func (q *Query) Where(cond string, args ...interface{}) {
+	if strings.Count(cond, "?") != len(args) {
+		panic(fmt.Sprintf("psql: %d values for the condition %q", len(args), cond))
+	}
+
+	var b strings.Builder
+	i := 0
+	for _, r := range cond {
+		if r == '?' {
+			b.WriteString(q.Arg(args[i]))
+			i++
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	q.where = append(q.where, b.String())
+}
+
+// Like keyword of any of the columns (case insensitive), the % and _ of the keyword are matched literally
+func (q *Query) Like(keyword string, columns ...string) {
+	arg := q.Arg("%" + likeEscaper.Replace(strings.ToLower(keyword)) + "%")
+
+	var or []string
+	for _, c := range columns {
+		or = append(or, fmt.Sprintf("lower(%s) like %s", c, arg))
+	}
+
+	q.where = append(q.where, "("+strings.Join(or, " OR ")+")")
+}
+
+// WhereSQL " WHERE ..." of the conditions, empty without condition
+func (q *Query) WhereSQL() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// Page " offset $n limit $n" of the page, empty when the limit is -1 (without pagination)
+func (q *Query) Page(offset, limit int) string {
+	if limit == -1 {
+		return ""
+	}
+
+	return fmt.Sprintf(" offset %s limit %s", q.Arg(offset), q.Arg(limit))
+}
+
+// Direction ASC or DESC of the sort, DESC by default
+func Direction(sort string) string {
+	if strings.ToLower(sort) == "asc" {
+		return "ASC"
+	}
+
+	return "DESC"
+}
+
+// OrderBy "column direction" of the allowed columns, def is used when the column is not allowed
+func OrderBy(column, sort string, allowed []string, def string) string {
+	for _, a := range allowed {
+		if a == column {
+			return column + " " + Direction(sort)
+		}
+	}
+
+	return def + " " + Direction(sort)
+}
+
+// Allowed the column / table name is one of the allowed names, the name is spliced into the sql
+func Allowed(name string, allowed ...string) error {
+	for _, a := range allowed {
+		if a == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("psql: %q is not allowed", name)
+}
+
+// Ident quoted identifier, Ident("public", "members") -> "public"."members"
+func Ident(name ...string) string {
+	return pgx.Identifier(name).Sanitize()
+}
+
+// InsertBatch insert the rows with COPY, the values are sent as typed parameters
+func InsertBatch(ctx context.Context, db Copier, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	return db.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+}
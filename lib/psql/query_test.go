@@ -0,0 +1,183 @@
+package psql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var hostileInputs = []string{
+	`' OR 1=1 --`,
+	`'; DROP TABLE members; --`,
+	`%' OR '1'='1`,
+	`1) OR (1=1`,
+	`\'; select pg_sleep(10); --`,
+}
+
+func TestWhere(t *testing.T) {
+	var q Query
+	q.Where("a.code = ?", "x")
+	q.Where("a.status = 'active'")
+	q.Where("(a.id = ? OR a.parent_id = ?)", 1, 2)
+
+	if got, want := q.WhereSQL(), " WHERE a.code = $1 AND a.status = 'active' AND (a.id = $2 OR a.parent_id = $3)"; got != want {
+		t.Fatalf("WhereSQL() = %q, want %q", got, want)
+	}
+	if want := []interface{}{"x", 1, 2}; !reflect.DeepEqual(q.Args, want) {
+		t.Fatalf("Args = %v, want %v", q.Args, want)
+	}
+}
+
+func TestWhereMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Where() with missing value should panic")
+		}
+	}()
+
+	var q Query
+	q.Where("a = ? AND b = ?", 1)
+}
+
+func TestWhereSQLEmpty(t *testing.T) {
+	var q Query
+	if got := q.WhereSQL(); got != "" {
+		t.Fatalf("WhereSQL() = %q, want empty", got)
+	}
+}
+
+func TestLike(t *testing.T) {
+	var q Query
+	q.Where("role = ?", "tc")
+	q.Like(`Bali_100%\`, "name", "m.email")
+
+	if got, want := q.WhereSQL(), " WHERE role = $1 AND (lower(name) like $2 OR lower(m.email) like $2)"; got != want {
+		t.Fatalf("WhereSQL() = %q, want %q", got, want)
+	}
+	if got, want := q.Args[1], `%bali\_100\%\\%`; got != want {
+		t.Fatalf("like arg = %q, want %q", got, want)
+	}
+}
+
+func TestHostileValuesAreBound(t *testing.T) {
+	for _, in := range hostileInputs {
+		var q Query
+		q.Where("member_code = ?", in)
+		q.Like(in, "name", "username")
+		sql := q.WhereSQL() + q.Page(0, 10)
+
+		if strings.Contains(sql, in) || strings.Contains(sql, "'1'") || strings.Contains(sql, "DROP") {
+			t.Errorf("value %q is spliced into %q", in, sql)
+		}
+		if len(q.Args) != 4 || q.Args[0] != in {
+			t.Errorf("value %q is not bound, args %v", in, q.Args)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	var q Query
+	q.Where("a = ?", 1)
+
+	if got, want := q.Page(20, 10), " offset $2 limit $3"; got != want {
+		t.Fatalf("Page() = %q, want %q", got, want)
+	}
+	if got := q.Page(0, -1); got != "" {
+		t.Fatalf("Page() without pagination = %q, want empty", got)
+	}
+	if want := []interface{}{1, 20, 10}; !reflect.DeepEqual(q.Args, want) {
+		t.Fatalf("Args = %v, want %v", q.Args, want)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	allowed := []string{"o.id", "o.created_date"}
+	tests := []struct {
+		column, sort, want string
+	}{
+		{"o.created_date", "asc", "o.created_date ASC"},
+		{"o.id", "DESC", "o.id DESC"},
+		{"", "", "o.id DESC"},
+		{"o.id; drop table orders", "asc", "o.id ASC"},
+		{"o.id", "asc; drop table orders", "o.id DESC"},
+	}
+
+	for _, tt := range tests {
+		if got := OrderBy(tt.column, tt.sort, allowed, "o.id"); got != tt.want {
+			t.Errorf("OrderBy(%q, %q) = %q, want %q", tt.column, tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	if err := Allowed("email", "id", "email"); err != nil {
+		t.Fatalf("Allowed(email) = %v", err)
+	}
+	for _, in := range append(hostileInputs, "", "password") {
+		if err := Allowed(in, "id", "email"); err == nil {
+			t.Errorf("Allowed(%q) should fail", in)
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	if got, want := Ident("public", "members"), `"public"."members"`; got != want {
+		t.Fatalf("Ident() = %s, want %s", got, want)
+	}
+	if got, want := Ident(`a"; drop table members; --`), `"a""; drop table members; --"`; got != want {
+		t.Fatalf("Ident() = %s, want %s", got, want)
+	}
+}
+
+type copier struct {
+	table   pgx.Identifier
+	columns []string
+	rows    [][]interface{}
+	calls   int
+}
+
+func (c *copier) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	c.calls++
+	c.table = table
+	c.columns = columns
+	for src.Next() {
+		row, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.rows = append(c.rows, row)
+	}
+
+	return int64(len(c.rows)), src.Err()
+}
+
+func TestInsertBatch(t *testing.T) {
+	db := &copier{}
+	rows := [][]interface{}{
+		{hostileInputs[1], int32(1)},
+		{"a@example.com", int32(2)},
+	}
+
+	n, err := InsertBatch(context.Background(), db, "chat_member_temporaries", []string{"email", "chat_group_id"}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || !reflect.DeepEqual(db.rows, rows) {
+		t.Fatalf("InsertBatch() copied %d rows %v, want %v", n, db.rows, rows)
+	}
+	if !reflect.DeepEqual(db.table, pgx.Identifier{"chat_member_temporaries"}) || !reflect.DeepEqual(db.columns, []string{"email", "chat_group_id"}) {
+		t.Fatalf("InsertBatch() copied into %v %v", db.table, db.columns)
+	}
+}
+
+func TestInsertBatchEmpty(t *testing.T) {
+	db := &copier{}
+
+	n, err := InsertBatch(context.Background(), db, "chat_group_relations", []string{"member_id"}, nil)
+	if err != nil || n != 0 || db.calls != 0 {
+		t.Fatalf("InsertBatch() without rows = %d, %v, %d calls", n, err, db.calls)
+	}
+}
@@ -2,6 +2,7 @@ package rabbit
 
 import (
 	"log"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -28,3 +29,13 @@ func Connect(host string) (*amqp.Connection, *amqp.Channel, error) {
 
 	return conn, amqpChannel, err
 }
+
+// Ping dial the broker and close the connection, returns error when the broker is unreachable
+func Ping(host string, timeout time.Duration) error {
+	conn, err := amqp.DialConfig(host, amqp.Config{Dial: amqp.DefaultDial(timeout)})
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
@@ -1,11 +1,15 @@
 package sendgrid
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"panorama/bootstrap"
+	"panorama/lib/metrics"
 	"panorama/lib/utils"
 )
 
@@ -22,11 +26,20 @@ func New(app *bootstrap.App) *service {
 	return &service{app}
 }
 
-func getURLByURI(URI string) string {
-	url := fmt.Sprintf("%s/%s/%s", URL_HOST, VERSION, URI)
+func (s *service) getURLByURI(URI string) string {
+	url := fmt.Sprintf("%s/%s/%s", s.getHost(), VERSION, strings.TrimLeft(URI, "/"))
 	return url
 }
 
+// getHost sendgrid.api_url is set to use a local fake of sendgrid
+func (s *service) getHost() string {
+	if u := s.app.Config.GetString("sendgrid.api_url"); len(u) > 0 {
+		return strings.TrimRight(u, "/")
+	}
+
+	return URL_HOST
+}
+
 func (s *service) getApiKey() string {
 	return s.app.Config.GetString("sendgrid.api_key")
 }
@@ -57,27 +70,35 @@ func (s *service) SetMailSenderContent(subject, to, htmlTemplate string) MailPer
 	}
 }
 
-func (s *service) MailSender(subject, to, htmlTemplate string) (map[string]interface{}, error) {
+func (s *service) MailSender(ctx context.Context, subject, to, htmlTemplate string) (map[string]interface{}, error) {
 	var callback map[string]interface{}
 
-	url := getURLByURI("/mail/send")
+	url := s.getURLByURI("/mail/send")
 	values := s.SetMailSenderContent(subject, to, htmlTemplate)
 
-	request, err := utils.RequestHandlerEntity(values, url, http.MethodPost)
+	request, err := utils.RequestHandlerEntity(ctx, values, url, http.MethodPost)
 	if err != nil {
 		return callback, err
 	}
 	request.Header.Add("Authorization", "Bearer "+s.getApiKey())
 	request.Header.Add("Content-Type", "application/json")
 
-	response, err := utils.ResponseAsyncHandler(request)
+	start := time.Now()
+	response, err := utils.ResponseAsyncHandler(s.app.Log, request)
 	if err != nil {
+		metrics.ObserveExternal(metrics.SendGrid, "mail_send", start, err)
 		return callback, err
 	}
 
+	// the accepted mail has no body, the rejected one has the errors
 	json.Unmarshal([]byte(response[0]), &callback)
+	if errs, ok := callback["errors"]; ok && errs != nil {
+		metrics.ObserveExternal(metrics.SendGrid, "mail_send", start, fmt.Errorf("sendgrid: %v", errs))
+	} else {
+		metrics.ObserveExternal(metrics.SendGrid, "mail_send", start, nil)
+	}
 
-	utils.ResponseHandler(request)
+	utils.ResponseHandler(s.app.Log, request)
 
 	return callback, err
 }
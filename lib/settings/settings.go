@@ -0,0 +1,87 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"panorama/lib/logger"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4"
+)
+
+const cachePrefix = "settings:"
+
+// Querier database pool or connection
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// Group active content values of the settings group by key, cached in redis (when cache is not nil)
+// until the ttl or until the group is forgotten. The cache failure is logged, the values are read from the database
+func Group(ctx context.Context, log logger.Contract, db Querier, cache *redis.Client, ttl time.Duration, group string) (map[string]string, error) {
+	values := map[string]string{}
+	key := cachePrefix + group
+
+	if cache != nil {
+		cached, err := cache.Get(ctx, key).Result()
+		if err == nil && json.Unmarshal([]byte(cached), &values) == nil {
+			return values, nil
+		}
+		if err != nil && err != redis.Nil {
+			log.FromContext(ctx).WithField("group", group).Warnf("Get cached settings: %s", err.Error())
+		}
+	}
+
+	rows, err := db.Query(ctx, `select set_key, content_value from settings where set_group = $1 and is_active = true`, group)
+	if err != nil {
+		return values, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var k, v string
+		if err = rows.Scan(&k, &v); err != nil {
+			return values, err
+		}
+		values[k] = strings.TrimSpace(v)
+	}
+	if err = rows.Err(); err != nil {
+		return values, err
+	}
+
+	if cache != nil {
+		b, _ := json.Marshal(values)
+		if err := cache.Set(ctx, key, b, ttl).Err(); err != nil {
+			log.FromContext(ctx).WithField("group", group).Warnf("Cache settings: %s", err.Error())
+		}
+	}
+
+	return values, nil
+}
+
+// Forget remove the cached groups, the next read is loaded from the database
+func Forget(ctx context.Context, log logger.Contract, cache *redis.Client, groups ...string) {
+	if cache == nil || len(groups) == 0 {
+		return
+	}
+
+	var keys []string
+	for _, g := range groups {
+		keys = append(keys, cachePrefix+g)
+	}
+
+	if err := cache.Del(ctx, keys...).Err(); err != nil {
+		log.FromContext(ctx).WithField("groups", groups).Warnf("Forget cached settings: %s", err.Error())
+	}
+}
+
+// TTL cache ttl of the settings.cache_ttl seconds, 5 minutes by default
+func TTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 5 * time.Minute
+	}
+
+	return time.Duration(seconds) * time.Second
+}
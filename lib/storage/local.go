@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local storage of local disk for development and tests, the files are served by the file route of api.
+// Private object is written without the read permission of others and only served with the signed url
+type Local struct {
+	Root    string
+	BaseURL string
+	Secret  string
+	// URLTTL lifetime of the signed url returned by URL, a day by default
+	URLTTL time.Duration
+}
+
+// NewLocal ...
+func NewLocal(root, url, secret string) (*Local, error) {
+	if len(root) == 0 {
+		return nil, fmt.Errorf("%s", "upload_path is required for local storage")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Local{Root: root, BaseURL: strings.TrimSuffix(url, "/"), Secret: secret, URLTTL: 24 * time.Hour}, nil
+}
+
+// path of the key in the root, key outside of the root is rejected
+func (l *Local) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid key %s", key)
+	}
+
+	return filepath.Join(l.Root, filepath.FromSlash(clean)), nil
+}
+
+// Put ...
+func (l *Local) Put(key string, data []byte, mime string, private bool) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	var perm os.FileMode = 0644
+	if private {
+		perm = 0600
+	}
+
+	// the permission of existing file is not changed by WriteFile
+	os.Remove(p)
+
+	return ioutil.WriteFile(p, data, perm)
+}
+
+// PutReader ...
+func (l *Local) PutReader(key string, r io.Reader, mime string, private bool) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	var perm os.FileMode = 0644
+	if private {
+		perm = 0600
+	}
+
+	os.Remove(p)
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(p)
+	}
+
+	return err
+}
+
+// Get ...
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	f, _, err := l.Open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Open file of the key with the info
+func (l *Local) Open(key string) (*os.File, os.FileInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, nil, os.ErrNotExist
+	}
+
+	return f, info, nil
+}
+
+// IsPrivate ...
+func (l *Local) IsPrivate(info os.FileInfo) bool {
+	return info.Mode().Perm()&0004 == 0
+}
+
+// Delete ...
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Presign ...
+func (l *Local) Presign(key string, expire time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expire).Unix(), 10)
+	u := &url.URL{Path: "/" + strings.TrimPrefix(key, "/")}
+
+	return fmt.Sprintf("%s%s?expires=%s&signature=%s", l.BaseURL, u.EscapedPath(), expires, l.sign(key, expires)), nil
+}
+
+// URL signed url of the file route, the invalid key has no url
+func (l *Local) URL(key string) string {
+	u, err := l.Presign(key, l.URLTTL)
+	if err != nil {
+		return ""
+	}
+
+	return u
+}
+
+// Key key of the signed url of the file route, the signature is not checked
+func (l *Local) Key(u string) (string, bool) {
+	if len(l.BaseURL) == 0 || !strings.HasPrefix(u, l.BaseURL+"/") {
+		return "", false
+	}
+
+	key, err := url.PathUnescape(strings.SplitN(strings.TrimPrefix(u, l.BaseURL), "?", 2)[0])
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimPrefix(key, "/"), true
+}
+
+// Verify signature of the presigned url
+func (l *Local) Verify(key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(l.sign(key, expires)))
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(l.Secret))
+	mac.Write([]byte(strings.TrimPrefix(key, "/") + "|" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// List ...
+func (l *Local) List(prefix string) ([]Object, error) {
+	list := []Object{}
+
+	err := filepath.Walk(l.Root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(l.Root, p)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, strings.TrimPrefix(prefix, "/")) {
+			list = append(list, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+
+		return nil
+	})
+
+	return list, err
+}
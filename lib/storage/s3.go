@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3 storage of aws s3 bucket, the public object is served from the public url of bucket (or its cdn)
+type S3 struct {
+	Bucket    string
+	PublicURL string
+	client    *s3.S3
+}
+
+// NewS3 ...
+func NewS3(key, secret, region, bucket, publicURL string) (*S3, error) {
+	session, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewStaticCredentials(key, secret, ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{Bucket: bucket, PublicURL: publicURL, client: s3.New(session)}, nil
+}
+
+// Put ...
+func (s *S3) Put(key string, data []byte, mime string, private bool) error {
+	acl := "public-read"
+	if private {
+		acl = "private"
+	}
+
+	// config settings: this is where you choose the bucket,
+	// filename, content-type and storage class of the file
+	// you're uploading
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:               aws.String(s.Bucket),
+		Key:                  aws.String(key),
+		ACL:                  aws.String(acl),
+		Body:                 bytes.NewReader(data),
+		ContentLength:        aws.Int64(int64(len(data))),
+		ContentType:          aws.String(mime),
+		ContentDisposition:   aws.String("attachment"),
+		ServerSideEncryption: aws.String("AES256"),
+		StorageClass:         aws.String("INTELLIGENT_TIERING"),
+	})
+
+	return err
+}
+
+// PutReader upload the data in parts (multipart upload), the upload is aborted when the reader fails
+func (s *S3) PutReader(key string, r io.Reader, mime string, private bool) error {
+	acl := "public-read"
+	if private {
+		acl = "private"
+	}
+
+	_, err := s3manager.NewUploaderWithClient(s.client).Upload(&s3manager.UploadInput{
+		Bucket:               aws.String(s.Bucket),
+		Key:                  aws.String(key),
+		ACL:                  aws.String(acl),
+		Body:                 r,
+		ContentType:          aws.String(mime),
+		ContentDisposition:   aws.String("attachment"),
+		ServerSideEncryption: aws.String("AES256"),
+		StorageClass:         aws.String("INTELLIGENT_TIERING"),
+	})
+
+	return err
+}
+
+// Get ...
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+// Delete ...
+func (s *S3) Delete(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+// Presign ...
+func (s *S3) Presign(key string, expire time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+
+	return req.Presign(expire)
+}
+
+// URL ...
+func (s *S3) URL(key string) string {
+	return s.PublicURL + key
+}
+
+// Key ...
+func (s *S3) Key(u string) (string, bool) {
+	if len(s.PublicURL) == 0 || !strings.HasPrefix(u, s.PublicURL) {
+		return "", false
+	}
+
+	return strings.SplitN(strings.TrimPrefix(u, s.PublicURL), "?", 2)[0], true
+}
+
+// List ...
+func (s *S3) List(prefix string) ([]Object, error) {
+	list := []Object{}
+
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, o := range page.Contents {
+			list = append(list, Object{
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				LastModified: aws.TimeValue(o.LastModified),
+			})
+		}
+		return true
+	})
+
+	return list, err
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"panorama/lib/utils"
+)
+
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+// Object stored file
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Storage backend of the uploaded files, the key is the path that saved in the database
+type Storage interface {
+	// Put store the data, private object is only accessed by the presigned url
+	Put(key string, data []byte, mime string, private bool) error
+	// PutReader store the data read until EOF without keeping it in memory, nothing is kept
+	// when the reader fails
+	PutReader(key string, r io.Reader, mime string, private bool) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// Presign short-lived url to download the object
+	Presign(key string, expire time.Duration) (string, error)
+	// URL url of the public object that is returned to the clients
+	URL(key string) string
+	// Key key of the url that built by URL, false when the url is not served by the storage
+	Key(url string) (string, bool)
+	// List objects under the prefix
+	List(prefix string) ([]Object, error)
+}
+
+// New storage by the storage.driver config (s3 by default)
+func New(config utils.Config) (Storage, error) {
+	driver := config.GetString("storage.driver")
+	if len(driver) == 0 {
+		driver = DriverS3
+	}
+
+	switch driver {
+	case DriverS3:
+		return NewS3(
+			config.GetString("aws.s3.key"),
+			config.GetString("aws.s3.secret"),
+			config.GetString("aws.s3.region"),
+			config.GetString("aws.s3.bucket"),
+			config.GetString("aws.s3.public_url"),
+		)
+	case DriverLocal:
+		local, err := NewLocal(
+			config.GetString("upload_path"),
+			config.GetString("storage.local.url"),
+			config.GetString("storage.local.secret"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if ttl := config.GetInt("storage.local.url_ttl"); ttl > 0 {
+			local.URLTTL = time.Duration(ttl) * time.Hour
+		}
+
+		return local, nil
+	}
+
+	return nil, fmt.Errorf("storage driver %s is not supported", driver)
+}
+
+// defaultStorage storage of the app, used by URL for the transforms of the responses
+var defaultStorage Storage
+
+// SetDefault storage of the app that build the file url, set once on boot
+func SetDefault(s Storage) {
+	defaultStorage = s
+}
+
+// URL url of the public object of the default storage, empty when the storage is not set
+func URL(key string) string {
+	if defaultStorage == nil {
+		return ""
+	}
+
+	return defaultStorage.URL(key)
+}
@@ -34,18 +34,21 @@ type FileInfo struct {
 
 // MultipartHandler handle multipart form data file upload
 func (fu Info) MultipartHandler(w http.ResponseWriter, r *http.Request, key string, AllowedExt []string) (multipart.File, FileInfo, error) {
+	// Limit upload size, the body is limited before parsing and leave room for the other form fields
+	r.Body = http.MaxBytesReader(w, r.Body, fu.MaxSize*MB+MB)
+
 	if err := r.ParseMultipartForm(fu.MaxSize * MB); err != nil {
 		return nil, FileInfo{}, err
 	}
 
-	// Limit upload size
-	r.Body = http.MaxBytesReader(w, r.Body, fu.MaxSize*MB) // 1 Mb
-
 	// get the file informations
 	file, multipartFileHeader, err := r.FormFile(key)
 	if err != nil {
 		return nil, FileInfo{}, err
 	}
+	if multipartFileHeader.Size > fu.MaxSize*MB {
+		return nil, FileInfo{}, fmt.Errorf("File size exceeds %d MB.", fu.MaxSize)
+	}
 	contentTypeFileHeader := multipartFileHeader.Header.Get("Content-Type")
 
 	// Create a buffer to store the header of the file in
@@ -0,0 +1,255 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register decoder
+)
+
+const (
+	RenditionThumb    = "thumb"
+	RenditionMedium   = "medium"
+	RenditionOriginal = "original"
+
+	// ContentTypeJPEG every rendition is encoded as jpeg
+	ContentTypeJPEG = "image/jpeg"
+)
+
+// Rendition size of the processed image, zero MaxSize keep the image dimension
+type Rendition struct {
+	Name    string
+	MaxSize int
+	Quality int
+}
+
+// DefaultRenditions ...
+var DefaultRenditions = []Rendition{
+	{Name: RenditionThumb, MaxSize: 200, Quality: 80},
+	{Name: RenditionMedium, MaxSize: 800, Quality: 85},
+	{Name: RenditionOriginal, Quality: 90},
+}
+
+// ImageRendition encoded image of the rendition
+type ImageRendition struct {
+	Name     string
+	Width    int
+	Height   int
+	FileMime string
+	Data     []byte
+}
+
+// IsImage mime that processed into renditions
+func IsImage(mime string) bool {
+	switch mime {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+
+	return false
+}
+
+// ProcessImage decode the uploaded image and encode every rendition as jpeg,
+// the image is rotated by the exif orientation before the metadata (exif, gps) is dropped by re-encoding.
+// The dimension is read from the header first, the image above maxPixels (width * height) is rejected before it is decoded
+func ProcessImage(r io.Reader, renditions []Rendition, maxPixels int) ([]ImageRendition, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Image can not be decoded: %s", err.Error())
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, fmt.Errorf("Image dimension %dx%d exceeds the limit of %d pixels.", cfg.Width, cfg.Height, maxPixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Image can not be decoded: %s", err.Error())
+	}
+	src = orient(src, exifOrientation(data))
+
+	list := []ImageRendition{}
+	for _, rd := range renditions {
+		width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), rd.MaxSize)
+
+		// transparent area is flattened on white background
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: rd.Quality}); err != nil {
+			return nil, err
+		}
+
+		list = append(list, ImageRendition{
+			Name:     rd.Name,
+			Width:    width,
+			Height:   height,
+			FileMime: ContentTypeJPEG,
+			Data:     buf.Bytes(),
+		})
+	}
+
+	return list, nil
+}
+
+// RenditionPath path of the rendition, the base path is the path of the original rendition without extension
+func RenditionPath(base string, name string) string {
+	return fmt.Sprintf("%s_%s.jpg", base, name)
+}
+
+// RenditionPaths paths of default renditions from the stored path of original rendition,
+// nil when the file is not uploaded through the image pipeline
+func RenditionPaths(path string) map[string]string {
+	suffix := "_" + RenditionOriginal + ".jpg"
+	if !strings.HasSuffix(path, suffix) {
+		return nil
+	}
+
+	base := strings.TrimSuffix(path, suffix)
+	paths := map[string]string{}
+	for _, rd := range DefaultRenditions {
+		paths[rd.Name] = RenditionPath(base, rd.Name)
+	}
+
+	return paths
+}
+
+// fit dimension into the box of maxSize, the image is never enlarged
+func fit(width, height, maxSize int) (int, int) {
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return width, height
+	}
+
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+
+	return max(1, width*maxSize/height), maxSize
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// orient rotate/flip the image by exif orientation (1-8)
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var dst *image.RGBA
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+// exifOrientation orientation tag of the jpeg exif, 1 when it is not found
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		// start of scan, the metadata is always before the image data
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 1
+}
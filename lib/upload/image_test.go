@@ -0,0 +1,52 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestProcessImage(t *testing.T) {
+	list, err := ProcessImage(bytes.NewReader(testPNG(t, 400, 300)), DefaultRenditions, 400*300)
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if len(list) != len(DefaultRenditions) {
+		t.Fatalf("ProcessImage() renditions = %d, want %d", len(list), len(DefaultRenditions))
+	}
+	if list[0].Name != RenditionThumb || list[0].Width != 200 || list[0].Height != 150 {
+		t.Fatalf("thumb rendition = %s %dx%d, want thumb 200x150", list[0].Name, list[0].Width, list[0].Height)
+	}
+}
+
+func TestProcessImageMaxPixels(t *testing.T) {
+	if _, err := ProcessImage(bytes.NewReader(testPNG(t, 400, 300)), DefaultRenditions, 400*300-1); err == nil {
+		t.Fatal("image above the pixel limit is processed")
+	}
+
+	// the header claims 100000x100000 pixels while the data is of 1x1 image,
+	// it must be rejected by the header before the pixel buffer is allocated
+	data := testPNG(t, 1, 1)
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	_, err := ProcessImage(bytes.NewReader(data), DefaultRenditions, 16000000)
+	if err == nil || !strings.Contains(err.Error(), "100000x100000") {
+		t.Fatalf("ProcessImage() error = %v, want the dimension error", err)
+	}
+}
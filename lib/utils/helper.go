@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"mime/multipart"
-	"net/http"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -14,13 +12,6 @@ import (
 	"strings"
 	"testing"
 	"text/template"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/globalsign/mgo/bson"
-	"github.com/spf13/viper"
 )
 
 type (
@@ -56,78 +47,6 @@ func ParseTpl(fileName string, data interface{}) (string, error) {
 	return buffer.String(), nil
 }
 
-// UploadFileToS3 saves a file to aws bucket and returns the url to // the file and an error if there's any
-func UploadFileToS3(s *session.Session, typeImage string, file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
-	// get the file size and read
-	// the file content into a buffer
-	size := fileHeader.Size
-	buffer := make([]byte, size)
-	file.Read(buffer)
-
-	// create a unique file name for the file
-	tempFileName := typeImage + "/" + bson.NewObjectId().Hex() + filepath.Ext(fileHeader.Filename)
-
-	// config settings: this is where you choose the bucket,
-	// filename, content-type and storage class of the file
-	// you're uploading
-	_, err := s3.New(s).PutObject(&s3.PutObjectInput{
-		Bucket:               aws.String(viper.GetString("aws.S3_BUCKET")),
-		Key:                  aws.String(tempFileName),
-		ACL:                  aws.String("public-read"), // could be private if you want it to be access by only authorized users
-		Body:                 bytes.NewReader(buffer),
-		ContentLength:        aws.Int64(int64(size)),
-		ContentType:          aws.String(http.DetectContentType(buffer)),
-		ContentDisposition:   aws.String("attachment"),
-		ServerSideEncryption: aws.String("AES256"),
-		StorageClass:         aws.String("INTELLIGENT_TIERING"),
-	})
-	if err != nil {
-		return "", err
-	}
-
-	return tempFileName, err
-}
-
-func UploadFile(w http.ResponseWriter, r *http.Request, typeImage string) string {
-
-	maxSize := int64(1024000) // allow only 1MB of file size
-	err := r.ParseMultipartForm(maxSize)
-
-	if err != nil {
-		log.Println(err)
-		fmt.Fprintf(w, "Image too large. Max Size: %v", maxSize)
-		return ""
-	}
-	file, fileHeader, err := r.FormFile("file")
-
-	if err != nil {
-		log.Println(err)
-		fmt.Fprintf(w, "Could not get uploaded file")
-		return ""
-	}
-	defer file.Close()
-	// create an AWS session which can be
-	// reused if we're uploading many files
-	s, err := session.NewSession(&aws.Config{
-		Region: aws.String(viper.GetString("aws.S3_REGION")),
-		Credentials: credentials.NewStaticCredentials(
-			viper.GetString("aws.aws_key"),    // id
-			viper.GetString("aws.aws_secret"), // secret
-			""),                               // token can be left blank for now
-	})
-	if err != nil {
-		fmt.Fprintf(w, "Could not upload file")
-	}
-	fileName, err := UploadFileToS3(s, typeImage, file, fileHeader)
-	if err != nil {
-		fmt.Fprintf(w, "Could not upload file")
-	}
-	// fmt.Fprintf(w, "Image uploaded successfully: %v", fileName)
-
-	return fileName
-
-}
-
 func CompareMemberCode(mCodeJwt string, mCodeReq string) bool {
 
 	if mCodeJwt != mCodeReq {
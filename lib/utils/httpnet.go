@@ -2,29 +2,52 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"panorama/lib/logger"
 	"sync"
+
+	"github.com/sirupsen/logrus"
 )
 
-func RequestHandler(bodyRequest map[string]interface{}, url, method string) (*http.Request, error) {
+// setRequestID forward the id of the request of ctx to the external service
+func setRequestID(ctx context.Context, request *http.Request) {
+	if id := logger.RequestID(ctx); len(id) > 0 {
+		request.Header.Set(logger.RequestIDHeader, id)
+	}
+}
+
+func RequestHandler(ctx context.Context, bodyRequest map[string]interface{}, url, method string) (*http.Request, error) {
 	dataValues, err := json.Marshal(bodyRequest)
 	if err != nil {
 		return nil, err
 	}
 
 	reqBody := []byte(string(dataValues))
-	request, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return request, err
 	}
+	setRequestID(ctx, request)
 
 	return request, nil
 }
 
-func ResponseHandler(request *http.Request) (map[string]interface{}, error) {
+// logResponse status of the external service response, the body may contain the otp or the tokens
+// so it is only logged at debug level
+func logResponse(log logger.Contract, request *http.Request, response *http.Response, body []byte) {
+	entry := log.FromContext(request.Context()).WithFields(logrus.Fields{
+		"method": request.Method,
+		"url":    request.URL.Redacted(),
+		"status": response.StatusCode,
+	})
+	entry.Info("External service response")
+	entry.WithField("body", string(body)).Debug("External service response body")
+}
+
+func ResponseHandler(log logger.Contract, request *http.Request) (map[string]interface{}, error) {
 	var result map[string]interface{}
 
 	client := &http.Client{}
@@ -35,8 +58,7 @@ func ResponseHandler(request *http.Request) (map[string]interface{}, error) {
 	defer response.Body.Close()
 
 	body, _ := ioutil.ReadAll(response.Body)
-	log.Println("Response Status:", response.Status)
-	log.Println("Response Body:", string(body))
+	logResponse(log, request, response, body)
 
 	json.Unmarshal([]byte(string(body)), &result)
 	if err != nil {
@@ -46,35 +68,35 @@ func ResponseHandler(request *http.Request) (map[string]interface{}, error) {
 	return result, nil
 }
 
-func DoAsyncRequest(request *http.Request, ch chan<- string, wg *sync.WaitGroup) {
+func DoAsyncRequest(log logger.Contract, request *http.Request, ch chan<- string, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	client := &http.Client{}
 	response, err := client.Do(request)
 	if err != nil {
-		log.Println("err handle it")
+		log.FromContext(request.Context()).WithError(err).WithField("url", request.URL.Redacted()).Error("External service request failed")
+		return
 	}
 
 	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		log.Println("err handle it")
+		log.FromContext(request.Context()).WithError(err).WithField("url", request.URL.Redacted()).Error("External service response is not read")
 	}
-	log.Println("Response Status:", response.Status)
-	log.Println("Response Body:", string(body))
+	logResponse(log, request, response, body)
 
 	ch <- string(body)
 }
 
-func ResponseAsyncHandler(request *http.Request) ([]string, error) {
+func ResponseAsyncHandler(log logger.Contract, request *http.Request) ([]string, error) {
 	// make a channel
 	ch := make(chan string)
 	var wg sync.WaitGroup
 
 	// do async request with channel
 	wg.Add(1)
-	go DoAsyncRequest(request, ch, &wg)
+	go DoAsyncRequest(log, request, ch, &wg)
 
 	// close the channel in the background
 	go func() {
@@ -91,17 +113,18 @@ func ResponseAsyncHandler(request *http.Request) ([]string, error) {
 	return responses, nil
 }
 
-func RequestHandlerEntity(entity interface{}, url, method string) (*http.Request, error) {
+func RequestHandlerEntity(ctx context.Context, entity interface{}, url, method string) (*http.Request, error) {
 	dataValues, err := json.Marshal(entity)
 	if err != nil {
 		return nil, err
 	}
 
 	reqBody := []byte(string(dataValues))
-	request, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return request, err
 	}
+	setRequestID(ctx, request)
 
 	return request, nil
 }
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"panorama/bootstrap"
+	"panorama/lib/audit"
+	"panorama/lib/storage"
 	"panorama/lib/utils"
 	"panorama/services/api"
 
@@ -49,7 +51,7 @@ func setup() {
 		config.GetInt("db.redis.default_db"),
 	)
 	if err != nil {
-		fmt.Println(err.Error())
+		cLog.FromDefault().WithError(err).Error("Failed to connect to the redis")
 	}
 
 	// connect to redis cache
@@ -59,16 +61,31 @@ func setup() {
 		1,
 	)
 	if err != nil {
-		fmt.Println("[redis-cache] " + err.Error())
+		cLog.FromDefault().WithError(err).Error("Failed to connect to the redis cache")
+	}
+
+	// storage of uploaded files
+	fs, err := storage.New(config)
+	if err != nil {
+		cLog.FromDefault().WithError(err).Error("Failed to setup the storage")
+	}
+	storage.SetDefault(fs)
+
+	// load balancers that set the forwarded client ip
+	proxies, err := audit.ParseProxies(config.GetString("app.trusted_proxies"))
+	if err != nil {
+		cLog.FromDefault().WithError(err).WithField("trusted_proxies", config.GetString("app.trusted_proxies")).Error("Failed to parse the trusted proxies")
 	}
 
 	app = &bootstrap.App{
-		Debug:      debug,
-		Config:     config,
-		Validator:  validator,
-		Log:        cLog,
-		Redis:      rd,
-		RedisCache: rdCache,
+		Debug:          debug,
+		Config:         config,
+		Validator:      validator,
+		Log:            cLog,
+		Redis:          rd,
+		RedisCache:     rdCache,
+		Storage:        fs,
+		TrustedProxies: proxies,
 	}
 }
 
@@ -85,6 +102,70 @@ func main() {
 				Flags:  api.Flags,
 				Action: api.Boot{App: app}.Start,
 			},
+			{
+				Name:   "installment",
+				Usage:  "Remind due installments and cancel order of overdue installments, run from cron",
+				Action: api.Boot{App: app}.ProcessInstallments,
+			},
+			{
+				Name:   "reconcile",
+				Usage:  "Match midtrans settlement export with the paid payments",
+				Flags:  api.ReconcileFlags,
+				Action: api.Boot{App: app}.ReconcileSettlement,
+			},
+			{
+				Name:   "storage-cleanup",
+				Usage:  "List or delete the stored files that no longer referenced, run from cron",
+				Flags:  api.CleanupFlags,
+				Action: api.Boot{App: app}.CleanupStorage,
+			},
+			{
+				Name:   "analytics-refresh",
+				Usage:  "Refresh the daily rollups of analytics, run from cron",
+				Action: api.Boot{App: app}.RefreshAnalytics,
+			},
+			{
+				Name:   "audit-prune",
+				Usage:  "Delete the audit logs older than the retention days, run from cron",
+				Flags:  api.AuditPruneFlags,
+				Action: api.Boot{App: app}.PruneAuditLogs,
+			},
+			{
+				Name:  "migrate",
+				Usage: "Migrate the database schema with the migrations built into the binary",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "up",
+						Usage:  "Apply the pending migrations",
+						Flags:  api.MigrateUpFlags,
+						Action: api.Boot{App: app}.MigrateUp,
+					},
+					{
+						Name:   "down",
+						Usage:  "Revert the last applied migrations",
+						Flags:  api.MigrateDownFlags,
+						Action: api.Boot{App: app}.MigrateDown,
+					},
+					{
+						Name:   "status",
+						Usage:  "List the applied and pending migrations",
+						Action: api.Boot{App: app}.MigrateStatus,
+					},
+					{
+						Name:      "create",
+						Usage:     "Create the up and down file of the next migration in the source tree",
+						ArgsUsage: "<name>",
+						Flags:     api.MigrateCreateFlags,
+						Action:    api.Boot{App: app}.MigrateCreate,
+					},
+				},
+			},
+			{
+				Name:   "seed",
+				Usage:  "Seed the data sets for the local development (admin, tcs, tags, itineraries)",
+				Flags:  api.SeedFlags,
+				Action: api.Boot{App: app}.Seed,
+			},
 		},
 		Action: func(cli *cli.Context) error {
 			fmt.Printf("%s version:%s\n", cli.App.Name, "1.0")
@@ -0,0 +1 @@
+ALTER TABLE chat_member_temporaries DROP CONSTRAINT chat_member_temp_unique;
\ No newline at end of file
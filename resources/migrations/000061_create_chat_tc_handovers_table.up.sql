@@ -0,0 +1,13 @@
+-- handover notes written by the leaving tc for the next tc of the chat room
+CREATE TABLE chat_tc_handovers (
+	id SERIAL PRIMARY KEY,
+	chat_group_id INT NOT NULL REFERENCES chat_groups(id),
+	from_tc_id INT NULL, -- tc who left / got replaced
+	to_tc_id INT NULL, -- filled when the next tc is assigned
+	notes TEXT NULL,
+	changed_by VARCHAR(10) NOT NULL, -- tc, customer, admin
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	assigned_date TIMESTAMPTZ(0) NULL
+);
+
+CREATE INDEX chat_tc_handovers_chat_group_id_idx ON chat_tc_handovers (chat_group_id);
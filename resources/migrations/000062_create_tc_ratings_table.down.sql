@@ -0,0 +1 @@
+DROP TABLE IF EXISTS tc_ratings;
@@ -0,0 +1,12 @@
+CREATE TABLE tc_ratings (
+	id SERIAL PRIMARY KEY,
+	chat_group_id INT NOT NULL REFERENCES chat_groups(id),
+	tc_id INT NOT NULL REFERENCES users(id),
+	member_id INT NOT NULL REFERENCES members(id),
+	rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+	comment TEXT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	UNIQUE (chat_group_id, tc_id, member_id)
+);
+
+CREATE INDEX tc_ratings_tc_id_idx ON tc_ratings (tc_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS call_participants;
+DROP TABLE IF EXISTS call_sessions;
@@ -0,0 +1,29 @@
+CREATE TABLE call_sessions (
+	id SERIAL PRIMARY KEY,
+	call_code VARCHAR(30) UNIQUE NOT NULL,
+	chat_group_id INT NOT NULL REFERENCES chat_groups(id),
+	channel_name VARCHAR(64) NOT NULL,
+	call_type VARCHAR(10) NOT NULL, -- voice, video
+	status VARCHAR(10) NOT NULL, -- ringing, ongoing, ended, missed
+	started_by INT NOT NULL, -- id of members / users(tc)
+	started_role VARCHAR(10) NOT NULL,
+	answered_date TIMESTAMPTZ(0) NULL,
+	ended_date TIMESTAMPTZ(0) NULL,
+	duration INT NOT NULL DEFAULT 0, -- in seconds
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX call_sessions_chat_group_id_idx ON call_sessions (chat_group_id);
+
+-- participant id is used as agora uid of the channel
+CREATE TABLE call_participants (
+	id SERIAL PRIMARY KEY,
+	call_session_id INT NOT NULL REFERENCES call_sessions(id),
+	user_id INT NOT NULL, -- id of members / users(tc)
+	role VARCHAR(10) NOT NULL,
+	joined_date TIMESTAMPTZ(0) NULL,
+	left_date TIMESTAMPTZ(0) NULL,
+	token_expired_date TIMESTAMPTZ(0) NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	UNIQUE (call_session_id, user_id, role)
+);
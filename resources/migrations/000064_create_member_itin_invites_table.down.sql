@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS member_itin_invites;
+
+ALTER TABLE member_itin_relations DROP COLUMN IF EXISTS role;
@@ -0,0 +1,20 @@
+ALTER TABLE member_itin_relations ADD COLUMN role VARCHAR(10) NOT NULL DEFAULT 'viewer';
+
+CREATE TABLE member_itin_invites (
+	id SERIAL PRIMARY KEY,
+	invite_code VARCHAR(50) NOT NULL UNIQUE,
+	member_itin_id INT NOT NULL REFERENCES member_itins(id),
+	email VARCHAR(255) NOT NULL,
+	member_id INT NULL REFERENCES members(id),
+	role VARCHAR(10) NOT NULL DEFAULT 'viewer',
+	status VARCHAR(10) NOT NULL DEFAULT 'pending',
+	invited_by INT NOT NULL REFERENCES members(id),
+	resend_count INT NOT NULL DEFAULT 0,
+	expired_date TIMESTAMPTZ(0) NOT NULL,
+	responded_date TIMESTAMPTZ(0) NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	UNIQUE (member_itin_id, email)
+);
+
+CREATE INDEX member_itin_invites_email_idx ON member_itin_invites (email);
@@ -0,0 +1,21 @@
+CREATE TABLE order_payment_shares (
+	id SERIAL PRIMARY KEY,
+	share_code VARCHAR(50) NOT NULL UNIQUE,
+	order_id INT NOT NULL REFERENCES orders(id),
+	member_id INT NOT NULL REFERENCES members(id),
+	amount BIGINT NOT NULL DEFAULT 0,
+	payment_status VARCHAR(4) NOT NULL DEFAULT 'PROC',
+	payment_type VARCHAR(50) NULL,
+	payment_url TEXT NULL,
+	transaction_code VARCHAR(50) NULL,
+	covered_by INT NULL REFERENCES members(id),
+	payloads JSONB NULL,
+	expired_date TIMESTAMPTZ(0) NULL,
+	paid_date TIMESTAMPTZ(0) NULL,
+	reminded_date TIMESTAMPTZ(0) NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	UNIQUE (order_id, member_id)
+);
+
+CREATE INDEX order_payment_shares_transaction_code_idx ON order_payment_shares (transaction_code);
@@ -0,0 +1,12 @@
+DELETE FROM order_payments WHERE installment_no > 0;
+
+DROP INDEX IF EXISTS order_payments_payment_code_idx;
+DROP INDEX IF EXISTS order_payments_order_installment_idx;
+
+ALTER TABLE order_payments
+	DROP COLUMN IF EXISTS installment_no,
+	DROP COLUMN IF EXISTS title,
+	DROP COLUMN IF EXISTS payment_code,
+	DROP COLUMN IF EXISTS due_date,
+	DROP COLUMN IF EXISTS paid_date,
+	DROP COLUMN IF EXISTS reminded_date;
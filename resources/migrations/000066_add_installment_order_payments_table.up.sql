@@ -0,0 +1,10 @@
+ALTER TABLE order_payments
+	ADD COLUMN installment_no INT NOT NULL DEFAULT 0,
+	ADD COLUMN title VARCHAR(50) NULL,
+	ADD COLUMN payment_code VARCHAR(50) NULL,
+	ADD COLUMN due_date TIMESTAMPTZ(0) NULL,
+	ADD COLUMN paid_date TIMESTAMPTZ(0) NULL,
+	ADD COLUMN reminded_date TIMESTAMPTZ(0) NULL;
+
+CREATE UNIQUE INDEX order_payments_order_installment_idx ON order_payments (order_id, installment_no);
+CREATE INDEX order_payments_payment_code_idx ON order_payments (payment_code);
@@ -0,0 +1,8 @@
+DELETE FROM settings WHERE set_group = 'order' AND set_key IN ('ppn_rate', 'rounding_unit', 'rounding_mode');
+
+ALTER TABLE orders
+	DROP COLUMN IF EXISTS ppn_rate,
+	DROP COLUMN IF EXISTS ppn_amount,
+	DROP COLUMN IF EXISTS rounding_amount;
+
+DROP TABLE IF EXISTS order_items;
@@ -0,0 +1,61 @@
+CREATE TABLE order_items (
+	id SERIAL PRIMARY KEY,
+	order_id INT NOT NULL REFERENCES orders(id),
+	item_type VARCHAR(20) NOT NULL, -- itinerary, stuff, service_fee, discount
+	ref_code VARCHAR(50) NULL,
+	name VARCHAR(150) NOT NULL,
+	description TEXT NULL,
+	quantity INT NOT NULL DEFAULT 1,
+	unit_price BIGINT NOT NULL DEFAULT 0,
+	subtotal BIGINT NOT NULL DEFAULT 0,
+	is_taxable BOOLEAN NOT NULL DEFAULT TRUE,
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX order_items_order_id_idx ON order_items (order_id);
+
+ALTER TABLE orders
+	ADD COLUMN ppn_rate NUMERIC(5,2) NULL,
+	ADD COLUMN ppn_amount BIGINT NULL,
+	ADD COLUMN rounding_amount BIGINT NULL;
+
+INSERT INTO settings (set_group, set_key, set_label, content_type, content_value, is_active, created_date) VALUES
+	('order', 'ppn_rate', 'Order PPN Rate (%)', 'str', '11', TRUE, NOW()),
+	('order', 'rounding_unit', 'Order Total Rounding Unit', 'str', '1', TRUE, NOW()),
+	('order', 'rounding_mode', 'Order Total Rounding Mode (nearest, up, down)', 'str', 'nearest', TRUE, NOW())
+ON CONFLICT (set_label) DO NOTHING;
+
+-- migrate the legacy json details into line items, the invalid json is skipped
+DO $$
+DECLARE
+	o RECORD;
+BEGIN
+	FOR o IN SELECT id, title, details FROM orders WHERE details ~ '^\s*\[\s*\{' LOOP
+		BEGIN
+			INSERT INTO order_items (order_id, item_type, name, description, quantity, unit_price, subtotal, is_taxable, created_date)
+			SELECT
+				o.id,
+				'itinerary',
+				LEFT(COALESCE(NULLIF(e->>'name', ''), NULLIF(e->>'title', ''), NULLIF(o.title, ''), 'Item'), 150),
+				e->>'description',
+				q.quantity,
+				p.price,
+				q.quantity * p.price,
+				TRUE,
+				NOW()
+			FROM json_array_elements(o.details::JSON) e,
+			LATERAL (SELECT CASE WHEN COALESCE(e->>'qty', e->>'quantity') ~ '^\d+$' THEN GREATEST(COALESCE(e->>'qty', e->>'quantity')::INT, 1) ELSE 1 END quantity) q,
+			LATERAL (SELECT CASE WHEN COALESCE(e->>'price', e->>'amount') ~ '^\d+$' THEN COALESCE(e->>'price', e->>'amount')::BIGINT ELSE 0 END price) p;
+		EXCEPTION WHEN others THEN
+			RAISE NOTICE 'order % details is not migrated: %', o.id, SQLERRM;
+		END;
+	END LOOP;
+END $$;
+
+-- order without migrated details keep the total price as one itinerary item
+INSERT INTO order_items (order_id, item_type, name, quantity, unit_price, subtotal, is_taxable, created_date)
+SELECT o.id, 'itinerary', LEFT(COALESCE(NULLIF(o.title, ''), o.order_code), 150), 1, o.total_price, o.total_price, TRUE, NOW()
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id);
+
+UPDATE orders SET ppn_amount = COALESCE(total_price_ppn, total_price) - total_price WHERE ppn_amount IS NULL;
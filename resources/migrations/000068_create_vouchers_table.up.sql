@@ -0,0 +1,35 @@
+CREATE TABLE vouchers (
+	id SERIAL PRIMARY KEY,
+	voucher_code VARCHAR(50) NOT NULL UNIQUE,
+	title VARCHAR(150) NOT NULL,
+	description TEXT NULL,
+	discount_type VARCHAR(10) NOT NULL, -- percent, fixed
+	discount_value BIGINT NOT NULL CHECK (discount_value > 0),
+	max_discount BIGINT NULL,
+	min_spend BIGINT NOT NULL DEFAULT 0,
+	start_date TIMESTAMPTZ(0) NOT NULL,
+	end_date TIMESTAMPTZ(0) NOT NULL,
+	usage_limit INT NULL, -- global cap, null is unlimited
+	usage_limit_member INT NULL, -- cap per member, null is unlimited
+	used_count INT NOT NULL DEFAULT 0,
+	destinations TEXT[] NULL,
+	order_types VARCHAR(2)[] NULL,
+	is_active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_by INT NOT NULL REFERENCES users(id),
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	deleted_date TIMESTAMPTZ(0) NULL,
+	CHECK (usage_limit IS NULL OR used_count <= usage_limit)
+);
+
+CREATE TABLE voucher_redemptions (
+	id SERIAL PRIMARY KEY,
+	voucher_id INT NOT NULL REFERENCES vouchers(id),
+	order_id INT NOT NULL UNIQUE REFERENCES orders(id),
+	member_id INT NOT NULL REFERENCES members(id),
+	discount_amount BIGINT NOT NULL DEFAULT 0,
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX voucher_redemptions_voucher_id_member_id_idx ON voucher_redemptions (voucher_id, member_id);
+CREATE INDEX voucher_redemptions_created_date_idx ON voucher_redemptions (created_date);
@@ -0,0 +1,4 @@
+DELETE FROM settings WHERE set_group = 'company' AND set_key IN ('name', 'address', 'npwp', 'phone', 'email');
+
+DROP TABLE IF EXISTS order_invoices;
+DROP TABLE IF EXISTS invoice_sequences;
@@ -0,0 +1,33 @@
+CREATE TABLE invoice_sequences (
+	doc_type VARCHAR(10) NOT NULL,
+	period CHAR(6) NOT NULL, -- yyyymm
+	last_no INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (doc_type, period)
+);
+
+CREATE TABLE order_invoices (
+	id SERIAL PRIMARY KEY,
+	order_id INT NOT NULL REFERENCES orders(id),
+	doc_type VARCHAR(10) NOT NULL, -- invoice, receipt
+	doc_no VARCHAR(30) NOT NULL UNIQUE,
+	period CHAR(6) NOT NULL,
+	seq_no INT NOT NULL,
+	amount BIGINT NOT NULL DEFAULT 0,
+	ppn_amount BIGINT NOT NULL DEFAULT 0,
+	payment_type VARCHAR(50) NULL,
+	file_path TEXT NULL,
+	issued_date TIMESTAMPTZ(0) NOT NULL,
+	emailed_date TIMESTAMPTZ(0) NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	UNIQUE (order_id, doc_type),
+	UNIQUE (doc_type, period, seq_no)
+);
+
+INSERT INTO settings (set_group, set_key, set_label, content_type, content_value, is_active, created_date) VALUES
+	('company', 'name', 'Company Name', 'str', 'PT Panorama', TRUE, NOW()),
+	('company', 'address', 'Company Address', 'str', '', TRUE, NOW()),
+	('company', 'npwp', 'Company Tax ID (NPWP)', 'str', '', TRUE, NOW()),
+	('company', 'phone', 'Company Phone', 'str', '', TRUE, NOW()),
+	('company', 'email', 'Company Email', 'str', '', TRUE, NOW())
+ON CONFLICT (set_label) DO NOTHING;
@@ -0,0 +1,16 @@
+DROP FUNCTION IF EXISTS to_idr(BIGINT, CHAR(3), TIMESTAMPTZ);
+
+ALTER TABLE member_itins DROP COLUMN IF EXISTS est_price_currency;
+
+ALTER TABLE stuff DROP COLUMN IF EXISTS currency_code;
+
+ALTER TABLE order_items
+	DROP COLUMN IF EXISTS currency_code,
+	DROP COLUMN IF EXISTS source_unit_price,
+	DROP COLUMN IF EXISTS exchange_rate;
+
+DROP TABLE IF EXISTS order_exchange_rates;
+
+DROP TABLE IF EXISTS exchange_rates;
+
+DROP TABLE IF EXISTS currencies;
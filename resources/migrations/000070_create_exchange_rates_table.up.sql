@@ -0,0 +1,74 @@
+CREATE TABLE currencies (
+	currency_code CHAR(3) PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	symbol VARCHAR(5) NOT NULL,
+	minor_unit SMALLINT NOT NULL DEFAULT 2, -- decimal digits, amount is saved in the smallest unit
+	is_active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+INSERT INTO currencies (currency_code, name, symbol, minor_unit) VALUES
+	('IDR', 'Indonesian Rupiah', 'Rp', 0),
+	('USD', 'US Dollar', '$', 2),
+	('EUR', 'Euro', 'EUR', 2),
+	('GBP', 'Pound Sterling', 'GBP', 2),
+	('SGD', 'Singapore Dollar', 'S$', 2),
+	('MYR', 'Malaysian Ringgit', 'RM', 2),
+	('THB', 'Thai Baht', 'THB', 2),
+	('AUD', 'Australian Dollar', 'A$', 2),
+	('SAR', 'Saudi Riyal', 'SAR', 2),
+	('CNY', 'Chinese Yuan', 'CNY', 2),
+	('JPY', 'Japanese Yen', 'JPY', 0),
+	('KRW', 'South Korean Won', 'KRW', 0)
+ON CONFLICT (currency_code) DO NOTHING;
+
+-- rate is the rupiah value of one major unit of the currency
+CREATE TABLE exchange_rates (
+	id SERIAL PRIMARY KEY,
+	currency_code CHAR(3) NOT NULL REFERENCES currencies(currency_code),
+	rate NUMERIC(18,6) NOT NULL CHECK (rate > 0),
+	effective_date DATE NOT NULL,
+	source VARCHAR(20) NOT NULL DEFAULT 'manual', -- manual, import
+	created_by INT NULL REFERENCES users(id),
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	UNIQUE (currency_code, effective_date)
+);
+
+-- rate used by the order, locked when the currency is used the first time by the order
+CREATE TABLE order_exchange_rates (
+	order_id INT NOT NULL REFERENCES orders(id),
+	currency_code CHAR(3) NOT NULL REFERENCES currencies(currency_code),
+	rate NUMERIC(18,6) NOT NULL,
+	effective_date DATE NOT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	PRIMARY KEY (order_id, currency_code)
+);
+
+ALTER TABLE order_items
+	ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'IDR' REFERENCES currencies(currency_code),
+	ADD COLUMN source_unit_price BIGINT NULL,
+	ADD COLUMN exchange_rate NUMERIC(18,6) NULL;
+
+UPDATE order_items SET source_unit_price = unit_price WHERE source_unit_price IS NULL;
+
+ALTER TABLE stuff
+	ADD COLUMN currency_code CHAR(3) NOT NULL DEFAULT 'IDR' REFERENCES currencies(currency_code);
+
+ALTER TABLE member_itins
+	ADD COLUMN est_price_currency CHAR(3) NOT NULL DEFAULT 'IDR' REFERENCES currencies(currency_code);
+
+-- rupiah value of the amount in the smallest unit of currency with the latest rate at the time
+CREATE OR REPLACE FUNCTION to_idr(amount BIGINT, currency CHAR(3), at TIMESTAMPTZ) RETURNS BIGINT AS $$
+	SELECT CASE
+		WHEN amount IS NULL THEN NULL
+		WHEN currency = 'IDR' THEN amount
+		ELSE (
+			SELECT ROUND(amount * er.rate / POWER(10, c.minor_unit))::BIGINT
+			FROM exchange_rates er
+			JOIN currencies c ON c.currency_code = er.currency_code
+			WHERE er.currency_code = currency AND er.effective_date <= at::DATE
+			ORDER BY er.effective_date DESC
+			LIMIT 1
+		)
+	END
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,5 @@
+UPDATE orders SET order_status = 'C' WHERE order_status IN ('PD', 'IP');
+
+UPDATE orders SET order_status = 'P' WHERE order_status = 'D';
+
+DROP TABLE IF EXISTS order_status_history;
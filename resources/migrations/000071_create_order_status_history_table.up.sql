@@ -0,0 +1,23 @@
+CREATE TABLE order_status_history (
+	id SERIAL PRIMARY KEY,
+	order_id INT NOT NULL REFERENCES orders(id),
+	from_status VARCHAR(8) NULL, -- null when the order is created
+	to_status VARCHAR(8) NOT NULL,
+	actor_type VARCHAR(10) NOT NULL, -- user, member, system
+	actor_id INT NULL,
+	actor_name VARCHAR(100) NOT NULL DEFAULT '',
+	reason TEXT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id, created_date);
+
+-- completed status was used for the paid order, the trip that is not ended yet is moved into paid
+UPDATE orders o SET order_status = 'PD'
+FROM chat_groups cg
+JOIN member_itins mi ON mi.id = cg.member_itin_id
+WHERE o.chat_id = cg.id AND o.order_status = 'C' AND mi.end_date > NOW();
+
+INSERT INTO order_status_history (order_id, from_status, to_status, actor_type, actor_name, reason, created_date)
+SELECT id, NULL, order_status, 'system', 'migration', 'Status before the order history is recorded', created_date
+FROM orders WHERE order_status IS NOT NULL;
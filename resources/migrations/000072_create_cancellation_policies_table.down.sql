@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_refunds;
+DROP TABLE IF EXISTS cancellation_policy_tiers;
+DROP TABLE IF EXISTS cancellation_policies;
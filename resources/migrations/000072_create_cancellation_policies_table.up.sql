@@ -0,0 +1,47 @@
+CREATE TABLE cancellation_policies (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(150) NOT NULL,
+	order_type VARCHAR(2) NULL, -- null is all order type
+	member_itin_id INT NULL REFERENCES member_itins(id), -- policy of a single itinerary
+	free_until_days INT NOT NULL DEFAULT 0 CHECK (free_until_days >= 0), -- free cancellation until N days before the trip start
+	is_active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_by INT NULL REFERENCES users(id),
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	updated_date TIMESTAMPTZ(0) NULL,
+	deleted_date TIMESTAMPTZ(0) NULL
+);
+
+CREATE TABLE cancellation_policy_tiers (
+	id SERIAL PRIMARY KEY,
+	policy_id INT NOT NULL REFERENCES cancellation_policies(id) ON DELETE CASCADE,
+	min_days INT NOT NULL CHECK (min_days >= 0), -- the tier is used when cancelled at least N days before the trip start
+	fee_percent INT NOT NULL CHECK (fee_percent BETWEEN 0 AND 100),
+	UNIQUE (policy_id, min_days)
+);
+
+CREATE TABLE order_refunds (
+	id SERIAL PRIMARY KEY,
+	order_id INT NOT NULL UNIQUE REFERENCES orders(id),
+	policy_id INT NULL REFERENCES cancellation_policies(id),
+	days_before INT NULL, -- null when the order has no trip start date
+	fee_percent INT NOT NULL DEFAULT 0,
+	paid_amount BIGINT NOT NULL DEFAULT 0,
+	fee_amount BIGINT NOT NULL DEFAULT 0,
+	refund_amount BIGINT NOT NULL DEFAULT 0,
+	refund_status VARCHAR(10) NOT NULL, -- pending, processed, none
+	reason TEXT NULL,
+	created_by INT NULL REFERENCES members(id),
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	processed_date TIMESTAMPTZ(0) NULL
+);
+
+CREATE INDEX cancellation_policies_member_itin_id_idx ON cancellation_policies (member_itin_id);
+CREATE INDEX order_refunds_refund_status_idx ON order_refunds (refund_status);
+
+-- default policy: free until 30 days before the trip, 25% from 14 days, 50% from 7 days and full fee in the last week
+WITH p AS (
+	INSERT INTO cancellation_policies (name, free_until_days, created_date)
+	VALUES ('Default', 30, NOW()) RETURNING id
+)
+INSERT INTO cancellation_policy_tiers (policy_id, min_days, fee_percent)
+SELECT p.id, t.min_days, t.fee_percent FROM p, (VALUES (14, 25), (7, 50), (0, 100)) AS t(min_days, fee_percent);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_reconciliation_items;
+DROP TABLE IF EXISTS payment_reconciliations;
@@ -0,0 +1,32 @@
+CREATE TABLE payment_reconciliations (
+	id SERIAL PRIMARY KEY,
+	file_name VARCHAR(255) NOT NULL,
+	period_start TIMESTAMPTZ(0) NULL, -- earliest transaction time of the settlement file
+	period_end TIMESTAMPTZ(0) NULL,
+	total_rows INT NOT NULL DEFAULT 0,
+	matched_rows INT NOT NULL DEFAULT 0,
+	mismatched_rows INT NOT NULL DEFAULT 0,
+	settled_amount BIGINT NOT NULL DEFAULT 0,
+	created_by INT NULL REFERENCES users(id), -- null when imported from cli
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE TABLE payment_reconciliation_items (
+	id SERIAL PRIMARY KEY,
+	reconciliation_id INT NOT NULL REFERENCES payment_reconciliations(id) ON DELETE CASCADE,
+	midtrans_order_id VARCHAR(100) NOT NULL,
+	order_code VARCHAR(50) NULL,
+	payment_source VARCHAR(15) NULL, -- order, installment, share
+	transaction_id VARCHAR(100) NULL,
+	payment_type VARCHAR(30) NULL,
+	transaction_time TIMESTAMPTZ(0) NULL,
+	midtrans_status VARCHAR(20) NULL,
+	midtrans_amount BIGINT NULL,
+	local_status VARCHAR(10) NULL,
+	local_amount BIGINT NULL,
+	result VARCHAR(20) NOT NULL, -- matched, pending_local, amount_mismatch, unknown_order, missing, skipped
+	note TEXT NULL,
+	checked_date TIMESTAMPTZ(0) NULL -- last re-check against the midtrans status api
+);
+
+CREATE INDEX payment_reconciliation_items_reconciliation_id_idx ON payment_reconciliation_items (reconciliation_id, result);
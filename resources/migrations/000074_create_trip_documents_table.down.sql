@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trip_document_access_logs;
+DROP TABLE IF EXISTS trip_documents;
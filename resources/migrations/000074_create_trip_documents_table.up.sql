@@ -0,0 +1,33 @@
+CREATE TABLE trip_documents (
+	id SERIAL PRIMARY KEY,
+	document_code VARCHAR(50) NOT NULL UNIQUE,
+	member_itin_id INT NOT NULL REFERENCES member_itins(id),
+	member_id INT NULL REFERENCES members(id), -- traveller of the document
+	document_type VARCHAR(20) NOT NULL, -- passport, visa, ticket, insurance, other
+	title VARCHAR(150) NOT NULL,
+	document_number VARCHAR(50) NULL,
+	expiry_date DATE NULL,
+	file_path VARCHAR(255) NOT NULL, -- private s3 object
+	file_name VARCHAR(255) NOT NULL,
+	file_mime VARCHAR(50) NOT NULL,
+	file_size BIGINT NOT NULL DEFAULT 0,
+	uploaded_by_type VARCHAR(10) NOT NULL, -- member, user
+	uploaded_by INT NOT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL,
+	deleted_date TIMESTAMPTZ(0) NULL
+);
+
+CREATE TABLE trip_document_access_logs (
+	id SERIAL PRIMARY KEY,
+	document_id INT NOT NULL REFERENCES trip_documents(id),
+	actor_type VARCHAR(10) NOT NULL, -- member, user
+	actor_id INT NOT NULL,
+	actor_name VARCHAR(100) NOT NULL DEFAULT '',
+	action VARCHAR(20) NOT NULL, -- upload, download, delete
+	ip_address VARCHAR(50) NULL,
+	user_agent TEXT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX trip_documents_member_itin_id_idx ON trip_documents (member_itin_id) WHERE deleted_date IS NULL;
+CREATE INDEX trip_document_access_logs_document_id_idx ON trip_document_access_logs (document_id, created_date);
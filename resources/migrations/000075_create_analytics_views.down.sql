@@ -0,0 +1,8 @@
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_tc;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_destinations;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_suggestions;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_funnel;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_orders;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_revenue;
+DROP TABLE IF EXISTS analytics_refreshes;
+DROP TABLE IF EXISTS itin_suggestion_events;
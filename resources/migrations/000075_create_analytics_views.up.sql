@@ -0,0 +1,114 @@
+CREATE TABLE itin_suggestion_events (
+	id SERIAL PRIMARY KEY,
+	itin_sug_id INT NOT NULL REFERENCES itin_suggestions(id),
+	member_id INT NULL REFERENCES members(id),
+	member_itin_id INT NULL REFERENCES member_itins(id),
+	event_type VARCHAR(10) NOT NULL, -- view, clone
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX itin_suggestion_events_created_date_idx ON itin_suggestion_events (created_date, itin_sug_id);
+
+CREATE TABLE analytics_refreshes (
+	view_name VARCHAR(50) PRIMARY KEY,
+	refreshed_date TIMESTAMPTZ(0) NOT NULL
+);
+
+-- daily rollups in WIB, refreshed by the analytics-refresh command
+
+CREATE MATERIALIZED VIEW analytics_daily_revenue AS
+SELECT (COALESCE(op.paid_date, op.created_date) AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	COALESCE(o.order_type, '') AS order_type,
+	COUNT(op.id) AS total_payments,
+	SUM(op.amount) AS total_amount
+FROM order_payments op
+JOIN orders o ON o.id = op.order_id
+WHERE op.payment_status = 'PAID'
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_revenue_idx ON analytics_daily_revenue (day, order_type);
+
+CREATE MATERIALIZED VIEW analytics_daily_orders AS
+SELECT (o.created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	COALESCE(o.order_type, '') AS order_type,
+	COUNT(o.id) AS total_orders,
+	COUNT(o.id) FILTER (WHERE o.order_status IN ('PD', 'IP', 'C')) AS paid_orders,
+	COUNT(o.id) FILTER (WHERE o.order_status IN ('X', 'R')) AS cancelled_orders,
+	COALESCE(SUM(o.total_price), 0) AS total_price
+FROM orders o
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_orders_idx ON analytics_daily_orders (day, order_type);
+
+-- chat created -> itinerary created -> order created -> order paid
+CREATE MATERIALIZED VIEW analytics_daily_funnel AS
+WITH events AS (
+	SELECT created_date, 'chat' AS stage FROM chat_groups
+	UNION ALL
+	SELECT created_date, 'itinerary' FROM member_itins
+	UNION ALL
+	SELECT created_date, 'order' FROM orders
+	UNION ALL
+	SELECT MIN(created_date), 'paid' FROM order_status_history WHERE to_status IN ('PD', 'IP', 'C') GROUP BY order_id
+)
+SELECT (created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	COUNT(*) FILTER (WHERE stage = 'chat') AS chats,
+	COUNT(*) FILTER (WHERE stage = 'itinerary') AS itineraries,
+	COUNT(*) FILTER (WHERE stage = 'order') AS orders,
+	COUNT(*) FILTER (WHERE stage = 'paid') AS paid_orders
+FROM events
+GROUP BY 1;
+
+CREATE UNIQUE INDEX analytics_daily_funnel_idx ON analytics_daily_funnel (day);
+
+CREATE MATERIALIZED VIEW analytics_daily_suggestions AS
+SELECT (e.created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	e.itin_sug_id,
+	COUNT(e.id) FILTER (WHERE e.event_type = 'view') AS views,
+	COUNT(e.id) FILTER (WHERE e.event_type = 'clone') AS clones
+FROM itin_suggestion_events e
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_suggestions_idx ON analytics_daily_suggestions (day, itin_sug_id);
+
+CREATE MATERIALIZED VIEW analytics_daily_destinations AS
+SELECT (mi.created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	LOWER(TRIM(mi.destination)) AS destination,
+	COUNT(DISTINCT mi.id) AS itineraries,
+	COUNT(DISTINCT o.id) AS orders,
+	COUNT(DISTINCT o.id) FILTER (WHERE o.order_status IN ('PD', 'IP', 'C')) AS paid_orders
+FROM member_itins mi
+LEFT JOIN chat_groups cg ON cg.member_itin_id = mi.id
+LEFT JOIN orders o ON o.chat_id = cg.id
+WHERE mi.deleted_date IS NULL AND COALESCE(TRIM(mi.destination), '') != ''
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_destinations_idx ON analytics_daily_destinations (day, destination);
+
+CREATE MATERIALIZED VIEW analytics_daily_tc AS
+WITH events AS (
+	SELECT tc_id, created_date, 1 AS chats, 0 AS orders, 0::BIGINT AS revenue, 0 AS ratings, 0 AS rating_total
+	FROM chat_groups WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 1, 0, 0, 0
+	FROM orders WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT o.tc_id, COALESCE(op.paid_date, op.created_date), 0, 0, op.amount, 0, 0
+	FROM order_payments op
+	JOIN orders o ON o.id = op.order_id
+	WHERE op.payment_status = 'PAID' AND o.tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 0, 0, 1, rating
+	FROM tc_ratings
+)
+SELECT (created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	tc_id,
+	SUM(chats) AS chats,
+	SUM(orders) AS orders,
+	SUM(revenue) AS revenue,
+	SUM(ratings) AS ratings,
+	SUM(rating_total) AS rating_total
+FROM events
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_tc_idx ON analytics_daily_tc (day, tc_id);
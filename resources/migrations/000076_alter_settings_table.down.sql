@@ -0,0 +1,10 @@
+DELETE FROM settings WHERE (set_group, set_key) IN (('auth', 'otp_expiry_min'), ('app', 'min_version'), ('app', 'banners'), ('support', 'contacts'));
+
+UPDATE settings SET content_type = 'str' WHERE content_type IN ('int', 'float');
+
+DROP INDEX IF EXISTS settings_group_key_unique;
+
+-- content_type is kept as varchar(10), json_arr & json_obj do not fit the old length
+ALTER TABLE settings
+	DROP COLUMN IF EXISTS set_order,
+	DROP COLUMN IF EXISTS is_public;
@@ -0,0 +1,16 @@
+ALTER TABLE settings
+	ALTER COLUMN content_type TYPE varchar(10),
+	ADD COLUMN set_order int NOT NULL DEFAULT 0,
+	ADD COLUMN is_public boolean NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX settings_group_key_unique ON settings (set_group, set_key);
+
+UPDATE settings SET content_type = 'float' WHERE set_group = 'order' AND set_key = 'ppn_rate';
+UPDATE settings SET content_type = 'int' WHERE set_group = 'order' AND set_key = 'rounding_unit';
+
+INSERT INTO settings (set_group, set_key, set_label, set_order, content_type, content_value, is_active, is_public, created_date) VALUES
+	('auth', 'otp_expiry_min', 'OTP Expiry (minutes)', 1, 'int', '2', TRUE, FALSE, NOW()),
+	('app', 'min_version', 'Minimum App Version', 1, 'json_obj', '{"android": "1.0.0", "ios": "1.0.0"}', TRUE, TRUE, NOW()),
+	('app', 'banners', 'App Banners', 2, 'json_arr', '[]', TRUE, TRUE, NOW()),
+	('support', 'contacts', 'Support Contacts', 1, 'json_obj', '{"phone": "(021) 2556 5000", "email": "customer.care@panorama-group.com"}', TRUE, TRUE, NOW())
+ON CONFLICT (set_group, set_key) DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM settings WHERE set_group = 'app' AND set_key IN ('recommended_version', 'maintenance', 'maintenance_message');
+
+UPDATE settings SET content_value = '{"android": "1.0.0", "ios": "1.0.0"}'
+WHERE set_group = 'app' AND set_key = 'min_version';
@@ -0,0 +1,9 @@
+-- minimum & recommended app version per channel and platform, the app below the minimum is forced to update
+UPDATE settings SET content_value = '{"cust_mobile_app": {"android": "1.0.0", "ios": "1.0.0"}, "tc_mobile_app": {"android": "1.0.0", "ios": "1.0.0"}}'
+WHERE set_group = 'app' AND set_key = 'min_version';
+
+INSERT INTO settings (set_group, set_key, set_label, set_order, content_type, content_value, is_active, is_public, created_date) VALUES
+	('app', 'recommended_version', 'Recommended App Version', 3, 'json_obj', '{"cust_mobile_app": {"android": "1.0.0", "ios": "1.0.0"}, "tc_mobile_app": {"android": "1.0.0", "ios": "1.0.0"}}', TRUE, TRUE, NOW()),
+	('app', 'maintenance', 'Maintenance Mode', 4, 'bool', 'false', TRUE, TRUE, NOW()),
+	('app', 'maintenance_message', 'Maintenance Message', 5, 'str', 'Panorama is under maintenance, please try again later.', TRUE, TRUE, NOW())
+ON CONFLICT (set_group, set_key) DO NOTHING;
@@ -0,0 +1,35 @@
+CREATE TABLE audit_logs (
+	id BIGSERIAL PRIMARY KEY,
+	actor_code VARCHAR(50) NULL,
+	actor_role VARCHAR(20) NULL,
+	channel VARCHAR(20) NULL,
+	entity_type VARCHAR(50) NOT NULL,
+	entity_id VARCHAR(100) NULL,
+	action VARCHAR(10) NOT NULL, -- create, update, delete
+	before_data JSONB NULL,
+	after_data JSONB NULL,
+	diff JSONB NULL,
+	ip VARCHAR(64) NULL,
+	user_agent TEXT NULL,
+	method VARCHAR(10) NULL,
+	path TEXT NULL,
+	created_date TIMESTAMPTZ(0) NOT NULL
+);
+
+CREATE INDEX audit_logs_entity_idx ON audit_logs (entity_type, entity_id);
+CREATE INDEX audit_logs_actor_idx ON audit_logs (actor_code);
+CREATE INDEX audit_logs_created_date_idx ON audit_logs (created_date);
+
+-- append only, the rows can only be deleted by the audit-prune command (retention)
+CREATE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' AND current_setting('audit.prune', true) = 'on' THEN
+		RETURN OLD;
+	END IF;
+
+	RAISE EXCEPTION 'audit_logs is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW EXECUTE PROCEDURE audit_logs_append_only();
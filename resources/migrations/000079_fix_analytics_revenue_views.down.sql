@@ -0,0 +1,43 @@
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_tc;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_revenue;
+DROP VIEW IF EXISTS analytics_paid_payments;
+
+CREATE MATERIALIZED VIEW analytics_daily_revenue AS
+SELECT (COALESCE(op.paid_date, op.created_date) AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	COALESCE(o.order_type, '') AS order_type,
+	COUNT(op.id) AS total_payments,
+	SUM(op.amount) AS total_amount
+FROM order_payments op
+JOIN orders o ON o.id = op.order_id
+WHERE op.payment_status = 'PAID'
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_revenue_idx ON analytics_daily_revenue (day, order_type);
+
+CREATE MATERIALIZED VIEW analytics_daily_tc AS
+WITH events AS (
+	SELECT tc_id, created_date, 1 AS chats, 0 AS orders, 0::BIGINT AS revenue, 0 AS ratings, 0 AS rating_total
+	FROM chat_groups WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 1, 0, 0, 0
+	FROM orders WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT o.tc_id, COALESCE(op.paid_date, op.created_date), 0, 0, op.amount, 0, 0
+	FROM order_payments op
+	JOIN orders o ON o.id = op.order_id
+	WHERE op.payment_status = 'PAID' AND o.tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 0, 0, 1, rating
+	FROM tc_ratings
+)
+SELECT (created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	tc_id,
+	SUM(chats) AS chats,
+	SUM(orders) AS orders,
+	SUM(revenue) AS revenue,
+	SUM(ratings) AS ratings,
+	SUM(rating_total) AS rating_total
+FROM events
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_tc_idx ON analytics_daily_tc (day, tc_id);
@@ -0,0 +1,57 @@
+-- revenue is the money collected by midtrans: the installments, the order payment that is paid directly
+-- and the shares. The summary payment (installment_no 0) of the installment & split order is not counted again
+
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_tc;
+DROP MATERIALIZED VIEW IF EXISTS analytics_daily_revenue;
+
+CREATE VIEW analytics_paid_payments AS
+SELECT op.id, op.order_id, op.amount, COALESCE(op.paid_date, op.created_date) AS paid_date
+FROM order_payments op
+WHERE op.payment_status = 'PAID' AND op.installment_no > 0
+UNION ALL
+SELECT op.id, op.order_id, op.amount, COALESCE(op.paid_date, op.created_date)
+FROM order_payments op
+WHERE op.payment_status = 'PAID' AND op.installment_no = 0 AND COALESCE(op.payment_type, '') != ''
+UNION ALL
+SELECT ops.id, ops.order_id, ops.amount, COALESCE(ops.paid_date, ops.created_date)
+FROM order_payment_shares ops
+WHERE ops.payment_status = 'PAID';
+
+CREATE MATERIALIZED VIEW analytics_daily_revenue AS
+SELECT (p.paid_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	COALESCE(o.order_type, '') AS order_type,
+	COUNT(p.id) AS total_payments,
+	SUM(p.amount) AS total_amount
+FROM analytics_paid_payments p
+JOIN orders o ON o.id = p.order_id
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_revenue_idx ON analytics_daily_revenue (day, order_type);
+
+CREATE MATERIALIZED VIEW analytics_daily_tc AS
+WITH events AS (
+	SELECT tc_id, created_date, 1 AS chats, 0 AS orders, 0::BIGINT AS revenue, 0 AS ratings, 0 AS rating_total
+	FROM chat_groups WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 1, 0, 0, 0
+	FROM orders WHERE tc_id IS NOT NULL
+	UNION ALL
+	SELECT o.tc_id, p.paid_date, 0, 0, p.amount, 0, 0
+	FROM analytics_paid_payments p
+	JOIN orders o ON o.id = p.order_id
+	WHERE o.tc_id IS NOT NULL
+	UNION ALL
+	SELECT tc_id, created_date, 0, 0, 0, 1, rating
+	FROM tc_ratings
+)
+SELECT (created_date AT TIME ZONE 'Asia/Jakarta')::DATE AS day,
+	tc_id,
+	SUM(chats) AS chats,
+	SUM(orders) AS orders,
+	SUM(revenue) AS revenue,
+	SUM(ratings) AS ratings,
+	SUM(rating_total) AS rating_total
+FROM events
+GROUP BY 1, 2;
+
+CREATE UNIQUE INDEX analytics_daily_tc_idx ON analytics_daily_tc (day, tc_id);
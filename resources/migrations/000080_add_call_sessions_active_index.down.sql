@@ -0,0 +1 @@
+DROP INDEX IF EXISTS call_sessions_active_chat_group_id_idx;
@@ -0,0 +1,9 @@
+-- the older duplicates of the active call are closed before only one ringing or ongoing call is allowed per chat group
+UPDATE call_sessions cs SET status = 'missed', ended_date = NOW()
+WHERE cs.status IN ('ringing', 'ongoing')
+	AND EXISTS (
+		SELECT 1 FROM call_sessions n
+		WHERE n.chat_group_id = cs.chat_group_id AND n.status IN ('ringing', 'ongoing') AND n.id > cs.id
+	);
+
+CREATE UNIQUE INDEX call_sessions_active_chat_group_id_idx ON call_sessions (chat_group_id) WHERE status IN ('ringing', 'ongoing');
@@ -0,0 +1 @@
+COMMENT ON COLUMN order_items.item_type IS 'itinerary, stuff, service_fee, discount, voucher';
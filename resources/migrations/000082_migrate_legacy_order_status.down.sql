@@ -0,0 +1,8 @@
+UPDATE orders o SET order_status = h.from_status
+FROM order_status_history h
+WHERE h.order_id = o.id AND h.actor_type = 'system' AND h.actor_name = 'migration'
+	AND h.reason = 'Legacy order status is mapped into the order lifecycle';
+
+DELETE FROM order_status_history
+WHERE actor_type = 'system' AND actor_name = 'migration'
+	AND reason = 'Legacy order status is mapped into the order lifecycle';
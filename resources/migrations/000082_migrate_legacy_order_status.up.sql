@@ -0,0 +1,28 @@
+-- the order that has no status change since the lifecycle is added keeps the legacy status (completed, pending,
+-- cancel or the free text status), the status is mapped from the payment and the end date of the trip
+WITH legacy AS (
+	SELECT o.id, o.order_status AS from_status,
+		CASE
+			WHEN o.order_status IN ('C', 'PD') OR LOWER(o.order_status) IN ('completed', 'issued', 'paid')
+				OR EXISTS (SELECT 1 FROM order_payments op WHERE op.order_id = o.id AND op.payment_status = 'PAID') THEN
+				CASE WHEN mi.end_date <= NOW() THEN 'C' ELSE 'PD' END
+			WHEN o.order_status = 'X' OR LOWER(o.order_status) IN ('cancel', 'canceled', 'cancelled', 'expired')
+				OR EXISTS (SELECT 1 FROM order_payments op WHERE op.order_id = o.id AND op.payment_status = 'CANC') THEN 'X'
+			ELSE 'P'
+		END AS to_status
+	FROM orders o
+	LEFT JOIN chat_groups cg ON cg.id = o.chat_id
+	LEFT JOIN member_itins mi ON mi.id = cg.member_itin_id
+	WHERE NOT EXISTS (
+		SELECT 1 FROM order_status_history h
+		WHERE h.order_id = o.id AND NOT (h.actor_type = 'system' AND h.actor_name = 'migration')
+	)
+), moved AS (
+	UPDATE orders o SET order_status = l.to_status
+	FROM legacy l
+	WHERE o.id = l.id AND o.order_status IS DISTINCT FROM l.to_status
+	RETURNING o.id, l.from_status, l.to_status
+)
+INSERT INTO order_status_history (order_id, from_status, to_status, actor_type, actor_name, reason, created_date)
+SELECT id, from_status, to_status, 'system', 'migration', 'Legacy order status is mapped into the order lifecycle', NOW()
+FROM moved;
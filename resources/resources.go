@@ -0,0 +1,21 @@
+package resources
+
+import "embed"
+
+// Migrations sql migrations of the database, built into the binary
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// Seeds sql data sets for the local development
+//
+//go:embed seeds/*.sql
+var Seeds embed.FS
+
+const (
+	// MigrationsDir dir of the migrations in Migrations & in the source tree
+	MigrationsDir = "migrations"
+
+	// SeedsDir dir of the data sets in Seeds
+	SeedsDir = "seeds"
+)
@@ -0,0 +1,4 @@
+-- admin of the cms, login with admin@panorama.local / secret123
+insert into users(user_code, name, email, phone, password, role, is_active, created_date) values
+	('ADM-local01', 'Local Admin', 'admin@panorama.local', '+6281000000001', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'admin', true, now())
+on conflict (email) do nothing;
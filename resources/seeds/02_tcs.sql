@@ -0,0 +1,6 @@
+-- travel consultants, login on the cms channel with <name>@panorama.local / secret123
+insert into users(user_code, name, email, phone, password, role, is_active, created_date) values
+	('TC-local01', 'Rina Consultant', 'rina@panorama.local', '+6281000000011', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'tc', true, now()),
+	('TC-local02', 'Budi Consultant', 'budi@panorama.local', '+6281000000012', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'tc', true, now()),
+	('TC-local03', 'Sari Consultant', 'sari@panorama.local', '+6281000000013', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'tc', true, now())
+on conflict (email) do nothing;
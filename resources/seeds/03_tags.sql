@@ -0,0 +1,6 @@
+-- tags of the suggested itineraries
+insert into tags(tag_name)
+	select v.tag_name from (values
+		('Beach'), ('Mountain'), ('Culture'), ('Culinary'), ('Family'), ('Honeymoon'), ('Adventure'), ('Diving')
+	) v(tag_name)
+	where not exists (select 1 from tags t where t.tag_name = v.tag_name);
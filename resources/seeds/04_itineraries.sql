@@ -0,0 +1,32 @@
+-- suggested itineraries created by the first admin, tagged by the tags data set
+insert into itin_suggestions(itin_code, created_by, title, content, details, destination, view, created_date)
+	select v.itin_code, (select id from users where role = 'admin' order by id limit 1), v.title, v.content, v.details::json, v.destination, 0, now()
+	from (values
+		('SGIT-bali', 'Bali Beach Escape 4D3N', 'Beaches of the south coast, Ubud rice terraces and the sunset at Uluwatu.', 'Bali',
+			'[{"day": 1, "visit_list": [{"time": "10:00", "place": "Ngurah Rai Airport"}, {"time": "16:00", "place": "Kuta Beach"}]},
+			  {"day": 2, "visit_list": [{"time": "08:00", "place": "Tegallalang Rice Terrace"}, {"time": "13:00", "place": "Ubud Market"}]},
+			  {"day": 3, "visit_list": [{"time": "09:00", "place": "Nusa Dua"}, {"time": "17:00", "place": "Uluwatu Temple"}]},
+			  {"day": 4, "visit_list": [{"time": "10:00", "place": "Ngurah Rai Airport"}]}]'),
+		('SGIT-yogya', 'Yogyakarta Heritage 3D2N', 'Borobudur at sunrise, Prambanan and the culinary of Malioboro.', 'Yogyakarta',
+			'[{"day": 1, "visit_list": [{"time": "11:00", "place": "Malioboro"}, {"time": "19:00", "place": "Alun-alun Kidul"}]},
+			  {"day": 2, "visit_list": [{"time": "04:30", "place": "Borobudur"}, {"time": "15:00", "place": "Prambanan"}]},
+			  {"day": 3, "visit_list": [{"time": "09:00", "place": "Kraton"}]}]'),
+		('SGIT-rjampat', 'Raja Ampat Diving 5D4N', 'Liveaboard diving in the coral reefs of Raja Ampat.', 'Raja Ampat',
+			'[{"day": 1, "visit_list": [{"time": "12:00", "place": "Sorong"}]},
+			  {"day": 2, "visit_list": [{"time": "08:00", "place": "Cape Kri"}]},
+			  {"day": 3, "visit_list": [{"time": "08:00", "place": "Manta Sandy"}]},
+			  {"day": 4, "visit_list": [{"time": "06:00", "place": "Piaynemo"}]},
+			  {"day": 5, "visit_list": [{"time": "10:00", "place": "Sorong"}]}]')
+	) v(itin_code, title, content, destination, details)
+on conflict (itin_code) do nothing;
+
+insert into itin_suggestion_tags(itin_sug_id, tag_id)
+	select s.id, t.id
+	from (values
+		('SGIT-bali', 'Beach'), ('SGIT-bali', 'Honeymoon'), ('SGIT-bali', 'Culture'),
+		('SGIT-yogya', 'Culture'), ('SGIT-yogya', 'Culinary'), ('SGIT-yogya', 'Family'),
+		('SGIT-rjampat', 'Diving'), ('SGIT-rjampat', 'Adventure'), ('SGIT-rjampat', 'Beach')
+	) v(itin_code, tag_name)
+	join itin_suggestions s on s.itin_code = v.itin_code
+	join tags t on t.tag_name = v.tag_name
+	where not exists (select 1 from itin_suggestion_tags st where st.itin_sug_id = s.id and st.tag_id = t.id);
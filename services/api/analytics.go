@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"panorama/lib/psql"
+	"panorama/services/api/model"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// RefreshAnalytics refresh the daily rollups that read by the analytics endpoints, meant to be run periodically from cron
+func (app Boot) RefreshAnalytics(c *cli.Context) error {
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.App.DB = db
+
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m := model.Contract{App: app.App}
+
+	start := time.Now()
+	if err = m.RefreshAnalytics(conn, ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Analytics refreshed in %s\n", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"panorama/lib/psql"
+	"panorama/services/api/model"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// AuditPruneFlags ...
+	AuditPruneFlags = []cli.Flag{
+		&cli.IntFlag{
+			Name:  "days",
+			Usage: "Keep the audit logs of the last days, audit.retention_days (365) by default",
+		},
+	}
+)
+
+// PruneAuditLogs delete the audit logs older than the retention, run from cron
+func (app Boot) PruneAuditLogs(c *cli.Context) error {
+	days := c.Int("days")
+	if days <= 0 {
+		days = app.Config.GetInt("audit.retention_days")
+	}
+	if days <= 0 {
+		days = 365
+	}
+
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.App.DB = db
+
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m := model.Contract{App: app.App}
+	before := time.Now().In(time.UTC).AddDate(0, 0, -days)
+	deleted, err := m.PruneAuditLog(conn, ctx, before)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Prune audit logs -> %d deleted (before %s)\n", deleted, before.Format(time.RFC3339))
+
+	return nil
+}
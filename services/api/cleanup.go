@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"panorama/lib/psql"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// CleanupFlags ...
+	CleanupFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:  "prefix",
+			Usage: "Only clean the files under the prefix, the audited folders of aws.s3.filepath by default",
+		},
+		&cli.IntFlag{
+			Name:  "min-age",
+			Value: 24,
+			Usage: "Skip the files that uploaded within the hours",
+		},
+		&cli.BoolFlag{
+			Name:  "delete",
+			Usage: "Delete the orphan files, otherwise the files are only listed",
+		},
+	}
+)
+
+// CleanupStorage delete the stored files that no longer referenced by the data, run from cron
+func (app Boot) CleanupStorage(c *cli.Context) error {
+	if app.Storage == nil {
+		return fmt.Errorf("%s", "storage is not configured")
+	}
+
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.App.DB = db
+
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m := model.Contract{App: app.App}
+
+	prefixes := m.AuditedFilePrefixes()
+	if prefix := c.String("prefix"); len(prefix) > 0 {
+		// the references of the files outside the audited folders are not known, the file may still be used
+		if c.Bool("delete") && !m.IsAuditedFilePrefix(prefix) {
+			return fmt.Errorf("prefix %s is not audited, only %s can be deleted", prefix, strings.Join(prefixes, ", "))
+		}
+		prefixes = []string{prefix}
+	}
+
+	orphans, err := m.GetOrphanFiles(conn, ctx, prefixes, time.Duration(c.Int("min-age"))*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	var deleted int
+	var size int64
+	for _, o := range orphans {
+		size += o.Size
+		if !c.Bool("delete") {
+			log.Printf("Cleanup -> orphan %s (%d bytes)", o.Key, o.Size)
+			continue
+		}
+
+		if err = app.Storage.Delete(o.Key); err != nil {
+			log.Printf("Cleanup -> %s: %s", o.Key, err.Error())
+			continue
+		}
+		deleted++
+	}
+
+	fmt.Printf("Cleanup storage -> %d orphan files (%d bytes), %d deleted\n", len(orphans), size, deleted)
+
+	return nil
+}
@@ -0,0 +1,468 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"panorama/bootstrap"
+	"panorama/services/api/model"
+)
+
+var otpPattern = regexp.MustCompile(`\(OTP\) is (\d{4})`)
+
+// member registered & activated member with the client of the customer app
+type member struct {
+	Code   string
+	Email  string
+	Phone  string
+	Client client
+}
+
+// registerMember register → otp → login of the customer app
+func (s *testServer) registerMember(username, phone string) member {
+	s.t.Helper()
+
+	m := member{
+		Email:  username + "@e2e.test",
+		Phone:  phone,
+		Client: client{Channel: bootstrap.ChannelCustApp, Player: "player-" + username},
+	}
+
+	var registered struct {
+		MemberCode string `json:"member_code"`
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/auth/register", map[string]interface{}{
+		"username":        username,
+		"name":            "E2E " + username,
+		"gender":          "male",
+		"email":           m.Email,
+		"phone":           m.Phone,
+		"password":        testPassword,
+		"retype_password": testPassword,
+	}, &registered)
+	m.Code = registered.MemberCode
+
+	// the otp is only known from the sms of citcall
+	var otp string
+	for _, sms := range s.Citcall.Requests(http.MethodPost, "/v3/smsotp") {
+		if sms.Body["msisdn"] == m.Phone {
+			if match := otpPattern.FindStringSubmatch(fmt.Sprintf("%v", sms.Body["text"])); match != nil {
+				otp = match[1]
+			}
+		}
+	}
+	if len(otp) == 0 {
+		s.t.Fatalf("otp sms of %s is not sent", m.Phone)
+	}
+
+	// the login of the inactive member is not authorized
+	var login struct {
+		Token      string `json:"token"`
+		UserStatus bool   `json:"user_status"`
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/auth/login", map[string]interface{}{"username": m.Email, "password": testPassword}, &login)
+	if login.UserStatus || len(login.Token) > 0 {
+		s.t.Fatalf("login of inactive member %s = %+v, want no token", m.Email, login)
+	}
+
+	// the token of the resent otp is the latest one
+	for _, sms := range s.Citcall.Requests(http.MethodPost, "/v3/smsotp") {
+		if sms.Body["msisdn"] == m.Phone {
+			if match := otpPattern.FindStringSubmatch(fmt.Sprintf("%v", sms.Body["text"])); match != nil {
+				otp = match[1]
+			}
+		}
+	}
+
+	code, res := s.Do(m.Client, http.MethodPost, "/v1/auth/checktoken-phone", map[string]interface{}{"phone": m.Phone, "token": "0000x"})
+	if code == http.StatusOK {
+		s.t.Fatalf("checktoken-phone with invalid otp = %d %s", code, res.StatMsg)
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/auth/checktoken-phone", map[string]interface{}{"phone": m.Phone, "token": otp}, nil)
+
+	s.Must(m.Client, http.MethodPost, "/v1/auth/login", map[string]interface{}{"username": m.Email, "password": testPassword}, &login)
+	if !login.UserStatus || len(login.Token) == 0 {
+		s.t.Fatalf("login of %s = %+v, want active with token", m.Email, login)
+	}
+	m.Client.Token = login.Token
+
+	return m
+}
+
+// loginUser login of the seeded user (admin, tc) from cms
+func (s *testServer) loginUser(email string) client {
+	s.t.Helper()
+
+	c := client{Channel: bootstrap.ChannelCMS}
+	var login struct {
+		Token string `json:"token"`
+	}
+	s.Must(c, http.MethodPost, "/v1/auth/login", map[string]interface{}{"username": email, "password": testPassword}, &login)
+	c.Token = login.Token
+
+	return c
+}
+
+// createChatRoom chat room of the member, tcCode is empty to assign the tc later
+func (s *testServer) createChatRoom(m member, name, tcCode string) string {
+	s.t.Helper()
+
+	var room struct {
+		ChatGroupCode string `json:"chat_group_code"`
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/chats/room", map[string]interface{}{
+		"name":            name,
+		"chat_group_type": "itinerary",
+		"tc_code":         tcCode,
+	}, &room)
+
+	return room.ChatGroupCode
+}
+
+func TestRegisterOTPLogin(t *testing.T) {
+	s := newTestServer(t)
+
+	m := s.registerMember("budi", "+6281200000001")
+
+	var active, validPhone bool
+	s.QueryValue(&active, "select is_active from members where member_code = $1", m.Code)
+	s.QueryValue(&validPhone, "select is_valid_phone from members where member_code = $1", m.Code)
+	if !active || !validPhone {
+		t.Fatalf("member %s is_active = %v, is_valid_phone = %v", m.Code, active, validPhone)
+	}
+
+	for _, sms := range s.Citcall.Requests(http.MethodPost, "/v3/smsotp") {
+		if got := sms.Header.Get("Authorization"); got != "Apikey e2e-citcall" {
+			t.Errorf("citcall authorization = %q", got)
+		}
+	}
+
+	// the token of the member is accepted by the authorized routes
+	s.Must(m.Client, http.MethodGet, "/v1/chats", nil, nil)
+
+	// wrong password is not authorized
+	code, _ := s.Do(m.Client, http.MethodPost, "/v1/auth/login", map[string]interface{}{"username": m.Email, "password": "wrong"})
+	if code == http.StatusOK {
+		t.Fatalf("login with wrong password = %d", code)
+	}
+}
+
+func TestChatCreationAndTcAssignment(t *testing.T) {
+	s := newTestServer(t)
+
+	// the tc device is registered into onesignal by the login
+	s.loginUser("tc@e2e.test")
+	m := s.registerMember("sari", "+6281200000002")
+
+	var tcID int32
+	s.QueryValue(&tcID, "select id from users where user_code = 'u-e2e-tc'")
+
+	// the room with tc notify the tc
+	withTc := s.createChatRoom(m, "Bali trip", "u-e2e-tc")
+
+	var roomTcID int32
+	s.QueryValue(&roomTcID, "select tc_id from chat_groups where chat_group_code = $1", withTc)
+	if roomTcID != tcID {
+		t.Fatalf("tc of room %s = %d, want %d", withTc, roomTcID, tcID)
+	}
+	if n := len(s.OneSignal.Requests(http.MethodPost, "/v1/notifications")); n == 0 {
+		t.Fatal("room assigned notification is not pushed to the tc")
+	}
+
+	// the room without tc is assigned to the least working tc by the invite
+	withoutTc := s.createChatRoom(m, "Lombok trip", "")
+	s.QueryValue(&roomTcID, "select coalesce(tc_id, 0) from chat_groups where chat_group_code = $1", withoutTc)
+	if roomTcID != 0 {
+		t.Fatalf("tc of room %s = %d, want unassigned", withoutTc, roomTcID)
+	}
+
+	s.Must(m.Client, http.MethodPut, "/v1/chats/invite-tc", map[string]interface{}{"chat_group_code": withoutTc}, nil)
+	s.QueryValue(&roomTcID, "select tc_id from chat_groups where chat_group_code = $1", withoutTc)
+	if roomTcID != tcID {
+		t.Fatalf("tc of invited room %s = %d, want %d", withoutTc, roomTcID, tcID)
+	}
+
+	code, _ := s.Do(m.Client, http.MethodPut, "/v1/chats/invite-tc", map[string]interface{}{"chat_group_code": "CG-unknown"})
+	if code == http.StatusOK {
+		t.Fatal("invite tc into unknown room should fail")
+	}
+}
+
+func TestChatGroupMembership(t *testing.T) {
+	s := newTestServer(t)
+
+	tc := s.loginUser("tc@e2e.test")
+	otherTc := s.loginUser("tc2@e2e.test")
+	m := s.registerMember("wayan", "+6281200000006")
+	outsider := s.registerMember("putu", "+6281200000007")
+
+	room := s.createChatRoom(m, "Komodo trip", "u-e2e-tc")
+	// the other tc is part of a chat group, but not of the room
+	s.createChatRoom(outsider, "Flores trip", "u-e2e-tc2")
+
+	s.Must(m.Client, http.MethodGet, "/v1/chats/"+room, nil, nil)
+	s.Must(tc, http.MethodGet, "/v1/chats/"+room, nil, nil)
+
+	if code, _ := s.Do(otherTc, http.MethodGet, "/v1/chats/"+room, nil); code == http.StatusOK {
+		t.Fatal("tc of another chat group should not get the history of the room")
+	}
+	if code, _ := s.Do(outsider.Client, http.MethodGet, "/v1/chats/"+room, nil); code == http.StatusOK {
+		t.Fatal("member outside of the chat group should not get the history of the room")
+	}
+	code, _ := s.Do(outsider.Client, http.MethodPost, "/v1/chats/"+room+"/rating", map[string]interface{}{"tc_code": "u-e2e-tc", "rating": 5})
+	if code == http.StatusOK {
+		t.Fatal("member outside of the chat group should not rate the tc of the room")
+	}
+	if code, _ := s.Do(m.Client, http.MethodPost, "/v1/chats/"+room+"/rating", map[string]interface{}{"tc_code": "u-e2e-tc", "rating": 5}); code == http.StatusOK {
+		t.Fatal("tc should not be rated while the chat session is still open")
+	}
+
+	var ratings int
+	s.QueryValue(&ratings, "select count(*) from tc_ratings")
+	if ratings != 0 {
+		t.Fatalf("tc ratings = %d, want 0", ratings)
+	}
+
+	// the ended session is rated by the member of the room
+	if _, err := s.DB.Exec(context.Background(), "update chat_groups set status = false where chat_group_code = $1", room); err != nil {
+		t.Fatal(err)
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/chats/"+room+"/rating", map[string]interface{}{"tc_code": "u-e2e-tc", "rating": 5}, nil)
+}
+
+func TestItineraryCreation(t *testing.T) {
+	s := newTestServer(t)
+
+	m := s.registerMember("dewi", "+6281200000003")
+	room := s.createChatRoom(m, "Komodo trip", "")
+
+	var itin struct {
+		ItinCode      string `json:"itin_code"`
+		MemberCode    string `json:"member_code"`
+		ChatGroupCode string `json:"chat_group_code"`
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/member-itin", map[string]interface{}{
+		"title":           "Komodo 4D3N",
+		"destination":     "Labuan Bajo",
+		"start_date":      "2030-07-01 00:00:00",
+		"end_date":        "2030-07-04 00:00:00",
+		"details":         []map[string]interface{}{{"day": 1, "title": "Arrival"}},
+		"group_chat_code": room,
+	}, &itin)
+
+	if len(itin.ItinCode) == 0 || itin.MemberCode != m.Code || itin.ChatGroupCode != room {
+		t.Fatalf("created itin = %+v", itin)
+	}
+
+	var roomItin string
+	s.QueryValue(&roomItin, "select mi.itin_code from chat_groups cg join member_itins mi on mi.id = cg.member_itin_id where cg.chat_group_code = $1", room)
+	if roomItin != itin.ItinCode {
+		t.Fatalf("itin of room %s = %s, want %s", room, roomItin, itin.ItinCode)
+	}
+
+	var detail struct {
+		MemberCode string `json:"member_code"`
+	}
+	s.Must(m.Client, http.MethodGet, "/v1/member-itin/"+itin.ItinCode, nil, &detail)
+	if detail.MemberCode != m.Code {
+		t.Fatalf("owner of itin %s = %s, want %s", itin.ItinCode, detail.MemberCode, m.Code)
+	}
+
+	// the room has the itinerary already
+	code, _ := s.Do(m.Client, http.MethodPost, "/v1/member-itin", map[string]interface{}{
+		"title":           "Komodo again",
+		"details":         []map[string]interface{}{{"day": 1}},
+		"group_chat_code": room,
+	})
+	if code == http.StatusOK {
+		t.Fatal("second itinerary of the room should fail")
+	}
+}
+
+func TestOrderPaymentWebhook(t *testing.T) {
+	s := newTestServer(t)
+
+	tc := s.loginUser("tc@e2e.test")
+	m := s.registerMember("agus", "+6281200000004")
+	room := s.createChatRoom(m, "Raja Ampat trip", "u-e2e-tc")
+
+	var order struct {
+		OrderCode     string `json:"order_code"`
+		TotalPrice    int64  `json:"total_price"`
+		TotalPricePpn int64  `json:"total_price_ppn"`
+	}
+	s.Must(tc, http.MethodPost, "/v1/orders", map[string]interface{}{
+		"title":              "Raja Ampat 5D4N",
+		"description":        "Liveaboard",
+		"paid_by_code":       m.Code,
+		"chat_group_code":    room,
+		"order_type":         model.ORDER_TYPE_CUSTOM,
+		"additional_details": "-",
+		"items": []map[string]interface{}{
+			{"item_type": "itinerary", "name": "Raja Ampat 5D4N", "quantity": 1, "unit_price": 15000000},
+			{"item_type": "stuff", "stuff_code": "STF-E2E-001", "quantity": 2},
+		},
+	}, &order)
+
+	amount := order.TotalPricePpn
+	if amount == 0 {
+		amount = order.TotalPrice
+	}
+	if len(order.OrderCode) == 0 || amount < 15500000 {
+		t.Fatalf("created order = %+v", order)
+	}
+
+	// the stuff line is priced from the catalog
+	var stuffName string
+	var stuffSubtotal int64
+	s.QueryValue(&stuffName, "select oi.name from order_items oi join orders o on o.id = oi.order_id where o.order_code = $1 and oi.ref_code = 'STF-E2E-001'", order.OrderCode)
+	s.QueryValue(&stuffSubtotal, "select oi.subtotal from order_items oi join orders o on o.id = oi.order_id where o.order_code = $1 and oi.ref_code = 'STF-E2E-001'", order.OrderCode)
+	if stuffName != "Snorkeling Gear" || stuffSubtotal != 500000 {
+		t.Fatalf("stuff item = %s %d, want Snorkeling Gear 500000", stuffName, stuffSubtotal)
+	}
+
+	// the amount must match the order
+	code, _ := s.Do(m.Client, http.MethodPost, "/v1/orders/payment", map[string]interface{}{"order_code": order.OrderCode, "amount": amount - 1})
+	if code == http.StatusOK {
+		t.Fatal("payment with wrong amount should fail")
+	}
+
+	var pay struct {
+		PaymentURL    string `json:"payment_url"`
+		PaymentStatus string `json:"payment_status"`
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/orders/payment", map[string]interface{}{"order_code": order.OrderCode, "amount": amount}, &pay)
+	if pay.PaymentURL != s.Midtrans.URL+"/snap/v2/vtweb/snap-"+order.OrderCode || pay.PaymentStatus != model.PAYMENT_STATUS_PROCESS {
+		t.Fatalf("payment = %+v", pay)
+	}
+
+	snap := s.Midtrans.Requests(http.MethodPost, "/snap/v1/transactions")
+	if len(snap) != 1 {
+		t.Fatalf("%d snap transactions, want 1", len(snap))
+	}
+	details, _ := snap[0].Body["transaction_details"].(map[string]interface{})
+	if details["order_id"] != order.OrderCode || int64(details["gross_amount"].(float64)) != amount {
+		t.Fatalf("snap transaction = %v", details)
+	}
+
+	// midtrans notify the settlement, the webhook is public
+	s.Must(client{}, http.MethodPost, "/v1/order/midtrans/notification", midtransNotification(order.OrderCode, "settlement", amount), nil)
+
+	var orderStatus, paymentStatus string
+	s.QueryValue(&orderStatus, "select order_status from orders where order_code = $1", order.OrderCode)
+	s.QueryValue(&paymentStatus, "select op.payment_status from order_payments op join orders o on o.id = op.order_id where o.order_code = $1", order.OrderCode)
+	if orderStatus != model.ORDER_STATUS_PAID || paymentStatus != model.PAYMENT_STATUS_PAID {
+		t.Fatalf("order %s = %s, payment = %s, want paid", order.OrderCode, orderStatus, paymentStatus)
+	}
+
+	// the items of the paid order can not be repriced
+	code, _ = s.Do(tc, http.MethodPut, "/v1/orders/"+order.OrderCode, map[string]interface{}{
+		"title":        "Raja Ampat 5D4N",
+		"paid_by_code": m.Code,
+		"order_type":   model.ORDER_TYPE_CUSTOM,
+		"items": []map[string]interface{}{
+			{"item_type": "itinerary", "name": "Raja Ampat 5D4N", "quantity": 1, "unit_price": 1000},
+		},
+	})
+	if code == http.StatusOK {
+		t.Fatal("items of the paid order should not be changed")
+	}
+
+	// the invoice & receipt are stored in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		objects, _ := s.Storage.List("e2e/invoices/")
+		if len(objects) >= 2 {
+			// the numbered invoices are only downloaded with the presigned url
+			for _, o := range objects {
+				if !s.Storage.IsPrivate(o.Key) {
+					t.Fatalf("invoice document %s is stored as public", o.Key)
+				}
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d invoice documents are stored, want invoice & receipt", len(objects))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCancelOrderWithOpenPayment(t *testing.T) {
+	s := newTestServer(t)
+
+	tc := s.loginUser("tc@e2e.test")
+	m := s.registerMember("rina", "+6281200000005")
+	room := s.createChatRoom(m, "Bromo trip", "u-e2e-tc")
+
+	var order struct {
+		OrderCode     string `json:"order_code"`
+		TotalPrice    int64  `json:"total_price"`
+		TotalPricePpn int64  `json:"total_price_ppn"`
+	}
+	s.Must(tc, http.MethodPost, "/v1/orders", map[string]interface{}{
+		"title":              "Bromo 2D1N",
+		"description":        "Sunrise tour",
+		"paid_by_code":       m.Code,
+		"chat_group_code":    room,
+		"order_type":         model.ORDER_TYPE_CUSTOM,
+		"additional_details": "-",
+		"items": []map[string]interface{}{
+			{"item_type": "itinerary", "name": "Bromo 2D1N", "quantity": 1, "unit_price": 2000000},
+		},
+	}, &order)
+
+	amount := order.TotalPricePpn
+	if amount == 0 {
+		amount = order.TotalPrice
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/orders/payment", map[string]interface{}{"order_code": order.OrderCode, "amount": amount}, nil)
+
+	// the virtual account is open, the order can not be cancelled
+	s.Must(client{}, http.MethodPost, "/v1/order/midtrans/notification", midtransNotification(order.OrderCode, "pending", amount), nil)
+	code, _ := s.Do(m.Client, http.MethodPost, "/v1/orders/"+order.OrderCode+"/cancel", map[string]interface{}{"reason": "change of plan"})
+	if code == http.StatusOK {
+		t.Fatal("order with payment in progress should not be cancelled")
+	}
+
+	// the transaction is expired on our side but still paid at midtrans after the order is cancelled
+	if _, err := s.DB.Exec(context.Background(), "update order_payments set expired_date = now() - interval '1 minute' from orders o where o.id = order_payments.order_id and o.order_code = $1", order.OrderCode); err != nil {
+		t.Fatal(err)
+	}
+	s.Must(m.Client, http.MethodPost, "/v1/orders/"+order.OrderCode+"/cancel", map[string]interface{}{"reason": "change of plan"}, nil)
+
+	// the forged settlement is not signed with the server key, no refund is created
+	forged := midtransNotification(order.OrderCode, "settlement", amount)
+	forged["signature_key"] = strings.Repeat("0", 128)
+	if code, _ := s.Do(client{}, http.MethodPost, "/v1/order/midtrans/notification", forged); code != http.StatusUnauthorized {
+		t.Fatalf("forged notification = %d, want %d", code, http.StatusUnauthorized)
+	}
+	var refunds int
+	s.QueryValue(&refunds, "select count(*) from order_refunds r join orders o on o.id = r.order_id where o.order_code = $1", order.OrderCode)
+	if refunds != 0 {
+		t.Fatal("refund is created by the forged notification")
+	}
+
+	s.Must(client{}, http.MethodPost, "/v1/order/midtrans/notification", midtransNotification(order.OrderCode, "settlement", amount), nil)
+
+	var orderStatus, refundStatus string
+	var refundAmount int64
+	s.QueryValue(&orderStatus, "select order_status from orders where order_code = $1", order.OrderCode)
+	s.QueryValue(&refundStatus, "select r.refund_status from order_refunds r join orders o on o.id = r.order_id where o.order_code = $1", order.OrderCode)
+	s.QueryValue(&refundAmount, "select r.refund_amount from order_refunds r join orders o on o.id = r.order_id where o.order_code = $1", order.OrderCode)
+	if orderStatus != model.ORDER_STATUS_CANCEL || refundStatus != model.REFUND_STATUS_PENDING || refundAmount != amount {
+		t.Fatalf("order %s = %s, refund = %s %d, want cancelled with pending refund of %d", order.OrderCode, orderStatus, refundStatus, refundAmount, amount)
+	}
+
+	// the late settlement of the cancelled order is not invoiced
+	var invoices int
+	s.QueryValue(&invoices, "select count(*) from order_invoices i join orders o on o.id = i.order_id where o.order_code = $1", order.OrderCode)
+	if invoices != 0 {
+		t.Fatalf("cancelled order %s has %d invoices, want none", order.OrderCode, invoices)
+	}
+}
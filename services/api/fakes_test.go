@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"panorama/lib/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeRequest request received by the fake service
+type fakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// fakeService local fake of the external service, records every request
+type fakeService struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []fakeRequest
+}
+
+// newFakeService start the fake with the routes, the fake is closed after the test
+func newFakeService(t *testing.T, routes func(f *fakeService, r chi.Router)) *fakeService {
+	f := &fakeService{}
+	r := chi.NewRouter()
+	r.Use(f.record)
+	routes(f, r)
+
+	f.Server = httptest.NewServer(r)
+	t.Cleanup(f.Server.Close)
+
+	return f
+}
+
+// record keep the request, the body is still readable by the route
+func (f *fakeService) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+		req := fakeRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone()}
+		json.Unmarshal(b, &req.Body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requests received requests of the method & path
+func (f *fakeService) Requests(method, path string) []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var list []fakeRequest
+	for _, r := range f.requests {
+		if r.Method == method && r.Path == path {
+			list = append(list, r)
+		}
+	}
+
+	return list
+}
+
+// writeJSON ...
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newFakeCitcall citcall gateway, every sms is sent
+func newFakeCitcall(t *testing.T) *fakeService {
+	return newFakeService(t, func(f *fakeService, r chi.Router) {
+		r.Post("/v3/{method}", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"rc": 0, "info": "OK"})
+		})
+	})
+}
+
+// newFakeOneSignal onesignal api, the added device is player-<n>
+func newFakeOneSignal(t *testing.T) *fakeService {
+	return newFakeService(t, func(f *fakeService, r chi.Router) {
+		r.Post("/v1/players", func(w http.ResponseWriter, r *http.Request) {
+			n := len(f.Requests(http.MethodPost, "/v1/players"))
+			writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": fmt.Sprintf("player-%d", n)})
+		})
+		r.Get("/v1/players/{id}", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"id":           chi.URLParam(r, "id"),
+				"device_type":  5,
+				"device_model": "e2e",
+			})
+		})
+		r.Post("/v1/notifications", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"id": "notification", "recipients": 1})
+		})
+	})
+}
+
+// newFakeSendGrid sendgrid mail api, every mail is accepted
+func newFakeSendGrid(t *testing.T) *fakeService {
+	return newFakeService(t, func(f *fakeService, r chi.Router) {
+		r.Post("/v3/mail/send", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+	})
+}
+
+// newFakeMidtrans midtrans snap & core api, the status of every transaction is settlement
+func newFakeMidtrans(t *testing.T) *fakeService {
+	return newFakeService(t, func(f *fakeService, r chi.Router) {
+		r.Post("/snap/v1/transactions", func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				TransactionDetails struct {
+					OrderID string `json:"order_id"`
+				} `json:"transaction_details"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+
+			token := "snap-" + req.TransactionDetails.OrderID
+			writeJSON(w, http.StatusCreated, map[string]interface{}{
+				"token":        token,
+				"redirect_url": f.URL + "/snap/v2/vtweb/" + token,
+			})
+		})
+		r.Get("/v2/{orderID}/status", func(w http.ResponseWriter, r *http.Request) {
+			orderID := chi.URLParam(r, "orderID")
+			for _, req := range f.Requests(http.MethodPost, "/snap/v1/transactions") {
+				details, _ := req.Body["transaction_details"].(map[string]interface{})
+				if details["order_id"] == orderID {
+					writeJSON(w, http.StatusOK, midtransNotification(orderID, "settlement", int64(details["gross_amount"].(float64))))
+					return
+				}
+			}
+
+			writeJSON(w, http.StatusOK, map[string]interface{}{"status_code": "404", "status_message": "Transaction doesn't exist."})
+		})
+	})
+}
+
+// midtransNotification payload of the midtrans notification (webhook) of the transaction, signed with the server key
+func midtransNotification(orderID, status string, amount int64) map[string]interface{} {
+	grossAmount := fmt.Sprintf("%d.00", amount)
+	signature := sha512.Sum512([]byte(orderID + "200" + grossAmount + "e2e-server-key"))
+
+	return map[string]interface{}{
+		"status_code":        "200",
+		"signature_key":      hex.EncodeToString(signature[:]),
+		"status_message":     "midtrans payment notification",
+		"transaction_id":     "trx-" + orderID,
+		"transaction_time":   time.Now().Format("2006-01-02 15:04:05"),
+		"transaction_status": status,
+		"payment_type":       "bank_transfer",
+		"fraud_status":       "accept",
+		"order_id":           orderID,
+		"gross_amount":       grossAmount,
+	}
+}
+
+// memStorage in memory storage in place of s3
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	private map[string]bool
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: map[string][]byte{}, private: map[string]bool{}}
+}
+
+// IsPrivate whether the object is stored as private
+func (s *memStorage) IsPrivate(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.private[key]
+}
+
+// Put ...
+func (s *memStorage) Put(key string, data []byte, mime string, private bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = append([]byte{}, data...)
+	s.private[key] = private
+	return nil
+}
+
+// PutReader ...
+func (s *memStorage) PutReader(key string, r io.Reader, mime string, private bool) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(key, data, mime, private)
+}
+
+// Get ...
+func (s *memStorage) Get(key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("storage: %s not found", key)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete ...
+func (s *memStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+	delete(s.private, key)
+	return nil
+}
+
+// Presign ...
+func (s *memStorage) Presign(key string, expire time.Duration) (string, error) {
+	return "memory://" + key, nil
+}
+
+// URL ...
+func (s *memStorage) URL(key string) string {
+	return "memory://" + key
+}
+
+// Key ...
+func (s *memStorage) Key(url string) (string, bool) {
+	if !strings.HasPrefix(url, "memory://") {
+		return "", false
+	}
+
+	return strings.TrimPrefix(url, "memory://"), true
+}
+
+// List ...
+func (s *memStorage) List(prefix string) ([]storage.Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []storage.Object
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, storage.Object{Key: key, Size: int64(len(data)), LastModified: time.Now()})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+
+	return list, nil
+}
@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// analyticsParam granularity (day, week, month) & date range of the analytics, the last 30 days by default
+func analyticsParam(r *http.Request) (map[string]interface{}, error) {
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -29)
+
+	param := map[string]interface{}{
+		"granularity": model.ANALYTICS_DAY,
+		"limit":       10,
+	}
+
+	if g, ok := r.URL.Query()["granularity"]; ok && len(g[0]) > 0 {
+		if !model.IsAnalyticsGranularity(g[0]) {
+			return param, fmt.Errorf("Granularity %s is not supported.", g[0])
+		}
+		param["granularity"] = g[0]
+	}
+
+	if s, ok := r.URL.Query()["start_date"]; ok && len(s[0]) > 0 {
+		parseStartTime, err := time.Parse("2006-01-02", s[0])
+		if err != nil {
+			return param, err
+		}
+		startDate = parseStartTime
+	}
+
+	if e, ok := r.URL.Query()["end_date"]; ok && len(e[0]) > 0 {
+		parseEndTime, err := time.Parse("2006-01-02", e[0])
+		if err != nil {
+			return param, err
+		}
+		endDate = parseEndTime
+	}
+
+	if startDate.After(endDate) {
+		return param, fmt.Errorf("%s", "Start date should not be more end date")
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil && l > 0 {
+			param["limit"] = l
+		}
+	}
+
+	param["start_date"] = startDate.Format("2006-01-02")
+	param["end_date"] = endDate.Format("2006-01-02")
+
+	return param, nil
+}
+
+// setAnalyticsRefreshed add the refresh time of the rollups into the param, the data is as fresh as the refresh
+func setAnalyticsRefreshed(db *pgxpool.Conn, ctx context.Context, m model.Contract, param map[string]interface{}) {
+	param["refreshed_date"] = nil
+	if refreshed, err := m.GetAnalyticsRefreshedDate(db, ctx); err == nil && refreshed.Valid {
+		param["refreshed_date"] = refreshed.Time
+	}
+}
+
+// GetAnalyticsRevenueAct paid payments per period and order type (admin)
+func (h *Contract) GetAnalyticsRevenueAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetAnalyticsRevenue(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AnalyticsRevenueRes{}
+	for _, a := range list {
+		var res response.AnalyticsRevenueRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-revenue", Row: response.AnalyticsRevenueRes{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetAnalyticsOrdersAct orders per period and order type (admin)
+func (h *Contract) GetAnalyticsOrdersAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetAnalyticsOrders(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AnalyticsOrderRes{}
+	for _, a := range list {
+		var res response.AnalyticsOrderRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-orders", Row: response.AnalyticsOrderRes{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetAnalyticsFunnelAct chat created -> itinerary created -> order created -> paid per period (admin)
+func (h *Contract) GetAnalyticsFunnelAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetAnalyticsFunnel(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AnalyticsFunnelRes{}
+	for _, a := range list {
+		var res response.AnalyticsFunnelRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-funnel", Row: response.AnalyticsFunnelRes{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetAnalyticsSuggestionsAct views vs clones of suggestion itineraries per period with the most viewed (admin)
+func (h *Contract) GetAnalyticsSuggestionsAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	series, err := m.GetAnalyticsSuggestions(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	top, err := m.GetAnalyticsTopSuggestions(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	res := response.AnalyticsSuggestionRes{
+		Series: []response.AnalyticsSuggestionItemRes{},
+		Top:    []response.AnalyticsSuggestionItemRes{},
+	}
+	for _, a := range series {
+		var item response.AnalyticsSuggestionItemRes
+		res.Series = append(res.Series, item.Transform(a))
+	}
+	for _, a := range top {
+		var item response.AnalyticsSuggestionItemRes
+		res.Top = append(res.Top, item.Transform(a))
+	}
+
+	// only the series is exported
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-suggestions", Row: response.AnalyticsSuggestionItemRes{}, Rows: exportSlice(res.Series)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, res, param)
+}
+
+// GetAnalyticsDestinationsAct destinations with the most itineraries in the date range (admin)
+func (h *Contract) GetAnalyticsDestinationsAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetAnalyticsTopDestinations(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AnalyticsDestinationRes{}
+	for _, a := range list {
+		var res response.AnalyticsDestinationRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-destinations", Row: response.AnalyticsDestinationRes{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetAnalyticsTcPerformanceAct chats, orders, revenue & rating of tc in the date range (admin)
+func (h *Contract) GetAnalyticsTcPerformanceAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param, err := analyticsParam(r)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetAnalyticsTcPerformance(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AnalyticsTcRes{}
+	for _, a := range list {
+		var res response.AnalyticsTcRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{Name: "analytics-tc-performance", Row: response.AnalyticsTcRes{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, listResponse, param)
+}
+
+// RefreshAnalyticsAct refresh the rollups of analytics now instead of waiting the schedule (admin)
+func (h *Contract) RefreshAnalyticsAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	if err = m.RefreshAnalytics(db, ctx); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	param := map[string]interface{}{}
+	setAnalyticsRefreshed(db, ctx, m, param)
+	h.SendSuccess(w, h.EmptyJSONArr(), param)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"panorama/lib/audit"
+	"panorama/lib/export"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// audit record the change of the entity by the request actor, before is nil on create & after is nil on delete.
+// The change is already committed, the error is only logged
+func (h *Contract) audit(r *http.Request, db audit.Execer, entityType, entityID, action string, before, after interface{}) {
+	req := audit.FromContext(r.Context())
+	if req == nil {
+		req = audit.NewRequest(r, h.GetChannel(r), h.TrustedProxies)
+		if _, ok := r.Context().Value("identifier").(map[string]string); ok {
+			req.ActorCode = h.GetUserCode(r.Context())
+			req.ActorRole = h.GetUserRole(r.Context())
+		}
+	}
+	req.Record()
+
+	m := model.Contract{App: h.App}
+	ctx := h.Context(r)
+	if err := m.AddAuditLog(db, ctx, req.Entry(entityType, entityID, action, before, after)); err != nil {
+		h.Log.FromContext(ctx).WithError(err).Errorf("Failed to audit %s %s %s", action, entityType, entityID)
+	}
+}
+
+// GetListAuditLogAct audit trail of the changes (admin), also exported with format csv / xlsx
+func (h *Contract) GetListAuditLogAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"keyword":     "",
+		"page":        1,
+		"limit":       10,
+		"offset":      0,
+		"sort":        "desc",
+		"actor_code":  "",
+		"actor_role":  "",
+		"channel":     "",
+		"entity_type": "",
+		"entity_id":   "",
+		"action":      "",
+		"start_date":  "",
+		"end_date":    "",
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if sort, ok := r.URL.Query()["sort"]; ok && len(sort[0]) > 0 && strings.ToLower(sort[0]) == "asc" {
+		param["sort"] = "asc"
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	for _, key := range []string{"keyword", "actor_code", "actor_role", "channel", "entity_type", "entity_id", "action"} {
+		if v, ok := r.URL.Query()[key]; ok && len(v[0]) > 0 {
+			param[key] = v[0]
+		}
+	}
+
+	for _, key := range []string{"start_date", "end_date"} {
+		if v, ok := r.URL.Query()[key]; ok && len(v[0]) > 0 {
+			if _, err := time.Parse("2006-01-02", v[0]); err != nil {
+				h.SendBadRequest(w, err.Error())
+				return
+			}
+			param[key] = v[0]
+		}
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "audit-logs",
+			Row:  response.AuditLogRes{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountListAuditLog(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportListAuditLog(db, ctx, param, func(a model.AuditLogEnt) error {
+					var res response.AuditLogRes
+					return enc.Encode(res.Transform(a))
+				})
+			},
+		})
+		return
+	}
+
+	logs, err := m.GetListAuditLog(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.AuditLogRes{}
+	for _, a := range logs {
+		var res response.AuditLogRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetAuditLogAct ...
+func (h *Contract) GetAuditLogAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	a, _ := m.GetAuditLogByID(db, ctx, id)
+	if a.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Audit log %d not found.", id))
+		return
+	}
+
+	var res response.AuditLogRes
+	h.SendSuccess(w, res.Transform(a), nil)
+}
@@ -12,7 +12,6 @@ import (
 	"panorama/services/api/handler/response"
 	"panorama/services/api/model"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -36,7 +35,7 @@ func (h *Contract) RegisterAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -96,6 +95,14 @@ func (h *Contract) RegisterAct(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// invited email wait for the registered member accept the invite
+		err = m.LinkMemberItinInviteByEmail(tx, ctx, req.Email, member.ID)
+		if err != nil {
+			tx.Rollback(ctx)
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
 		// add member temporary to member itin relation
 		mTemp, err := m.GetListMemberTemporaryByEmail(db, ctx, req.Email)
 		if err != nil && err != sql.ErrNoRows {
@@ -104,11 +111,11 @@ func (h *Contract) RegisterAct(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(mTemp) > 0 {
-			var arrstr []string
+			var rels []model.MemberItinRelationEnt
 			for _, v := range mTemp {
-				arrstr = append(arrstr, "("+strconv.Itoa(int(v.MemberItinID))+","+strconv.Itoa(int(member.ID))+",current_timestamp)")
+				rels = append(rels, model.MemberItinRelationEnt{MemberItinID: v.MemberItinID, MemberID: member.ID})
 			}
-			err = m.AddMemberItinRelationBatch(ctx, tx, strings.Join(arrstr, ","))
+			err = m.AddMemberItinRelationBatch(ctx, tx, rels)
 			if err != nil {
 				tx.Rollback(ctx)
 				h.SendBadRequest(w, err.Error())
@@ -131,11 +138,11 @@ func (h *Contract) RegisterAct(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(chatMemberTemp) > 0 {
-			var arrstr []string
+			var rels []model.ChatGroupRelationEnt
 			for _, v := range chatMemberTemp {
-				arrstr = append(arrstr, "("+strconv.Itoa(int(member.ID))+","+strconv.Itoa(int(v.ChatGroupID))+",current_timestamp)")
+				rels = append(rels, model.ChatGroupRelationEnt{MemberID: member.ID, ChatGroupID: v.ChatGroupID})
 			}
-			err = m.AddChatGroupRelationBatch(ctx, tx, strings.Join(arrstr, ","))
+			err = m.AddChatGroupRelationBatch(ctx, tx, rels)
 			if err != nil {
 				tx.Rollback(ctx)
 				h.SendBadRequest(w, err.Error())
@@ -157,12 +164,12 @@ func (h *Contract) RegisterAct(w http.ResponseWriter, r *http.Request) {
 
 	// 1. need send token for phone validations
 	if _, err := m.SendToken(db, ctx, h.GetChannel(r), model.ActRegPhone, model.TokenViaPhone, req.Phone, role, token); err != nil {
-		fmt.Printf("error send token phone: %s", err.Error())
+		h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the phone")
 	}
 
 	// 2. need send token for email validations
 	if _, err = m.SendToken(db, ctx, h.GetChannel(r), model.ActRegEmail, model.TokenViaEmail, req.Email, role, token); err != nil {
-		fmt.Printf("error send token email: %s", err.Error())
+		h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 	}
 
 	res = res.Transform(member)
@@ -192,7 +199,7 @@ func (h *Contract) AuthCheckTokenPhoneAct(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -236,7 +243,7 @@ func (h *Contract) LoginAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -287,15 +294,15 @@ func (h *Contract) LoginAct(w http.ResponseWriter, r *http.Request) {
 			otp, _ = utils.Generate(`[\d]{4}`)
 
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegPhone, model.TokenViaPhone, userPhone, userRole, otp); err != nil {
-				fmt.Printf("error send token phone: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the phone")
 			}
 			// 2. need send token for email validations again both of channel app & cms
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, otp); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		} else if channel == model.ChannelCMS {
 			if otp, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, otp); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		}
 	}
@@ -357,7 +364,7 @@ func (h *Contract) ForgotPassAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -420,15 +427,15 @@ func (h *Contract) ForgotPassAct(w http.ResponseWriter, r *http.Request) {
 		// 1. need send token for phone validations again only app
 		if channel == model.ChannelCustApp {
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegPhone, model.TokenViaPhone, userPhone, userRole, token); err != nil {
-				fmt.Printf("error send token phone: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the phone")
 			}
 			// 2. need send token for email validations again both of channel app & cms
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, token); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		} else if channel == model.ChannelCMS {
 			if token, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, token); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		}
 		responseMessage = fmt.Sprintf("Account %s has been registered and need activated", userEmail)
@@ -497,7 +504,7 @@ func (h *Contract) ForgotChangePassAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -563,7 +570,7 @@ func (h *Contract) SendTokenAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -642,14 +649,14 @@ func (h *Contract) SendTokenAct(w http.ResponseWriter, r *http.Request) {
 	if typeToken == "register" && userID != 0 && !userStatus {
 		if channel == model.ChannelCustApp {
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegPhone, model.TokenViaPhone, userPhone, userRole, token); err != nil {
-				fmt.Printf("error send token phone: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the phone")
 			}
 			if _, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, token); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		} else if channel == model.ChannelCMS {
 			if token, err = m.SendToken(db, ctx, channel, model.ActRegEmail, model.TokenViaEmail, userEmail, userRole, token); err != nil {
-				fmt.Printf("error send token email: %s", err.Error())
+				h.Log.FromContext(ctx).WithError(err).Error("Failed to send the token to the email")
 			}
 		}
 	} else {
@@ -4,12 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"panorama/lib/export"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
 	"panorama/services/api/model"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 //AddCitcallLogs log handler otp from citcall provider
@@ -17,7 +19,7 @@ func (h *Contract) AddCitcallLogs(w http.ResponseWriter, r *http.Request) {
 	citcallType := chi.URLParam(r, "type")
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendSuccess(w, err.Error(), nil)
@@ -90,7 +92,7 @@ func (h *Contract) GetLogsList(w http.ResponseWriter, r *http.Request) {
 		param["provider"] = provider[0]
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -100,6 +102,23 @@ func (h *Contract) GetLogsList(w http.ResponseWriter, r *http.Request) {
 	defer db.Release()
 
 	m := model.Contract{App: h.App}
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "call-logs",
+			Row:  response.CallLogResponse{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountListCallLogs(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportListCallLogs(db, ctx, param, func(l model.CallLogEnt) error {
+					var res response.CallLogResponse
+					return enc.Encode(res.Transform(l))
+				})
+			},
+		})
+		return
+	}
+
 	logs, err := m.GetListCallLogs(db, ctx, param)
 	if err != nil && sql.ErrNoRows != nil {
 		h.SendBadRequest(w, err.Error())
@@ -0,0 +1,647 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"panorama/lib/agora"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	rtctokenbuilder "github.com/AgoraIO/Tools/DynamicKey/AgoraDynamicKey/go/src/RtcTokenBuilder"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// default ringing time before the call is marked as missed
+const defaultCallRingTimeout = 45
+
+// callActor user that doing the call action
+type callActor struct {
+	ID   int32
+	Code string
+	Name string
+	Role string
+}
+
+func (h *Contract) agoraContract() agora.Contract {
+	return agora.Contract{
+		AppID:    h.Config.GetString("agora.app_id"),
+		AppCert:  h.Config.GetString("agora.app_cert"),
+		TokenTTL: uint32(h.Config.GetInt("agora.token_ttl")),
+	}
+}
+
+func (h *Contract) isCallRingExpired(cs model.CallSessionEnt) bool {
+	timeout := h.Config.GetInt("agora.ring_timeout")
+	if timeout <= 0 {
+		timeout = defaultCallRingTimeout
+	}
+
+	return cs.Status == model.CALL_STATUS_RINGING && time.Since(cs.CreatedDate) > time.Duration(timeout)*time.Second
+}
+
+// getCallActor get the customer or tc that is a participant of the chat group
+func (h *Contract) getCallActor(db *pgxpool.Conn, ctx context.Context, r *http.Request, m model.Contract, chatCode string) (callActor, error) {
+	actor := callActor{
+		Code: h.GetUserCode(r.Context()),
+		Role: h.GetUserRole(r.Context()),
+	}
+
+	// validasi user yang bukan bagian dari chat group
+	id, err := m.IsExistInGroupChat(db, ctx, chatCode, actor.Code)
+	if id <= 0 {
+		return actor, fmt.Errorf("Access denied for call in this chat")
+	}
+	if err != nil {
+		return actor, err
+	}
+
+	switch actor.Role {
+	case "customer":
+		member, err := m.GetMemberBy(db, ctx, "member_code", actor.Code)
+		if member.ID == 0 {
+			return actor, fmt.Errorf("Customer %s not found.", actor.Code)
+		}
+		if err != nil {
+			return actor, err
+		}
+		actor.ID = member.ID
+		actor.Name = member.Name
+	case "tc":
+		user, err := m.GetUserByCode(db, ctx, actor.Code)
+		if user.ID == 0 {
+			return actor, fmt.Errorf("Tc %s not found.", actor.Code)
+		}
+		if err != nil {
+			return actor, err
+		}
+		actor.ID = user.ID
+		actor.Name = user.Name
+	default:
+		return actor, fmt.Errorf("Access denied for call in this chat")
+	}
+
+	return actor, nil
+}
+
+// notifyCallParticipants send call notification to all participant of chat group except the actor
+func (h *Contract) notifyCallParticipants(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, m model.Contract, chatGroup model.ChatGroupEnt, cs model.CallSessionEnt, actorCode, subject string) error {
+	users, err := m.GetListUserGroupChat(db, ctx, chatGroup.ID)
+	if err != nil {
+		return err
+	}
+
+	var players []model.DeviceListEnt
+	for _, u := range users {
+		if u.UserCode == "" || u.UserCode == actorCode {
+			continue
+		}
+
+		p, err := m.GetListPlayerByUserCodeAndRole(db, ctx, u.UserCode, u.Role)
+		if err != nil {
+			return err
+		}
+		players = append(players, p...)
+	}
+
+	_, err = m.SendNotifications(tx, db, ctx, players, model.NotificationContent{
+		Subject:    subject,
+		RoomName:   chatGroup.Name,
+		CallerName: cs.StartedName,
+		CallType:   cs.CallType,
+	})
+
+	return err
+}
+
+// finishCallSession end the call session, the call that never answered is marked as missed
+func (h *Contract) finishCallSession(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, m model.Contract, chatGroup model.ChatGroupEnt, cs model.CallSessionEnt) (model.CallSessionEnt, error) {
+	now := time.Now().In(time.UTC)
+
+	cs.EndedDate.Time, cs.EndedDate.Valid = now, true
+	if cs.AnsweredDate.Valid {
+		cs.Status = model.CALL_STATUS_ENDED
+		cs.Duration = int32(now.Sub(cs.AnsweredDate.Time).Seconds())
+	} else {
+		cs.Status = model.CALL_STATUS_MISSED
+	}
+
+	err := m.UpdateCallSession(tx, ctx, cs)
+	if err != nil {
+		return cs, err
+	}
+
+	err = m.LeaveAllCallParticipant(tx, ctx, cs.ID, now)
+	if err != nil {
+		return cs, err
+	}
+
+	// Write the call summary into chat history
+	message := fmt.Sprintf("Missed %s call", cs.CallType)
+	if cs.Status == model.CALL_STATUS_ENDED {
+		message = fmt.Sprintf("%s call ended · %02d:%02d", strings.Title(cs.CallType), cs.Duration/60, cs.Duration%60)
+	}
+	_, err = m.CreateChatMessage(tx, ctx, model.ChatMessagesEnt{
+		ChatGroupID: cs.ChatGroupID,
+		UserID:      cs.StartedBy,
+		Role:        cs.StartedRole,
+		Message:     message,
+	})
+	if err != nil {
+		return cs, err
+	}
+
+	if cs.Status == model.CALL_STATUS_MISSED {
+		err = h.notifyCallParticipants(tx, db, ctx, m, chatGroup, cs, cs.StartedCode, model.NOTIF_SUBJ_CALL_MISSED)
+	}
+
+	return cs, err
+}
+
+// StartCallAct start voice or video call in chat group and ring the other participants
+func (h *Contract) StartCallAct(w http.ResponseWriter, r *http.Request) {
+	var err error
+	code := chi.URLParam(r, "code")
+	req := request.StartCallReq{}
+	if err = h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if err = h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	actor, err := h.getCallActor(db, ctx, r, m, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	chatGroup, err := m.GetGroupChatsCreatedBy(db, ctx, code)
+	if chatGroup.ID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Group code %s not found.", code))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	// only one active call in the chat group, the concurrent start waits until the other is committed
+	err = m.LockCallChatGroup(tx, ctx, chatGroup.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	active, _ := m.GetActiveCallSessionByChatID(db, ctx, chatGroup.ID)
+	if active.ID > 0 {
+		if !h.isCallRingExpired(active) {
+			h.SendBadRequest(w, "There is an active call in this chat, please join the call")
+			tx.Rollback(ctx)
+			return
+		}
+
+		_, err = h.finishCallSession(tx, db, ctx, m, chatGroup, active)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	callCode := m.SetCallCode()
+	cs, err := m.AddCallSession(tx, ctx, model.CallSessionEnt{
+		CallCode:    callCode,
+		ChatGroupID: chatGroup.ID,
+		ChannelName: callCode,
+		CallType:    req.CallType,
+		Status:      model.CALL_STATUS_RINGING,
+		StartedBy:   actor.ID,
+		StartedRole: actor.Role,
+	})
+	if psql.IsUniqueViolation(err, model.CallSessionActiveIndex) {
+		h.SendConflict(w, "There is an active call in this chat, please join the call")
+		tx.Rollback(ctx)
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+	cs.StartedName = actor.Name
+	cs.StartedCode = actor.Code
+
+	participant, token, err := h.joinCallParticipant(tx, ctx, m, cs, actor)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Ring the other participants of chat group
+	err = h.notifyCallParticipants(tx, db, ctx, m, chatGroup, cs, actor.Code, model.NOTIF_SUBJ_CALL_INCOMING)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.CallTokenRes
+	res = res.Transform(cs, participant, h.Config.GetString("agora.app_id"), token)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// joinCallParticipant join the actor into the call and generate agora token for the participant
+func (h *Contract) joinCallParticipant(tx pgx.Tx, ctx context.Context, m model.Contract, cs model.CallSessionEnt, actor callActor) (model.CallParticipantEnt, string, error) {
+	now := time.Now().In(time.UTC)
+
+	participant := model.CallParticipantEnt{
+		CallSessionID: cs.ID,
+		UserID:        actor.ID,
+		Role:          actor.Role,
+	}
+	participant.JoinedDate.Time, participant.JoinedDate.Valid = now, true
+	participant.TokenExpiredDate.Time, participant.TokenExpiredDate.Valid = now, true
+
+	participant, err := m.UpsertCallParticipant(tx, ctx, participant)
+	if err != nil {
+		return participant, "", err
+	}
+
+	// agora uid is the participant id
+	token, expiredDate, err := h.agoraContract().GenerateRtcTokenWithExpiry(uint32(participant.ID), cs.ChannelName, rtctokenbuilder.RolePublisher)
+	if err != nil {
+		return participant, "", err
+	}
+
+	err = m.UpdateCallParticipantToken(tx, ctx, participant.ID, expiredDate)
+	participant.TokenExpiredDate.Time = expiredDate
+
+	return participant, token, err
+}
+
+// JoinCallAct answer or rejoin the active call of chat group
+func (h *Contract) JoinCallAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	callCode := chi.URLParam(r, "callCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	actor, err := h.getCallActor(db, ctx, r, m, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	cs, err := m.GetCallSessionByCode(db, ctx, code, callCode)
+	if cs.ID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Call %s not found.", callCode))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !cs.IsActive() {
+		h.SendBadRequest(w, "The call has ended")
+		return
+	}
+
+	chatGroup, err := m.GetGroupChatsCreatedBy(db, ctx, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if h.isCallRingExpired(cs) {
+		_, err = h.finishCallSession(tx, db, ctx, m, chatGroup, cs)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		h.SendBadRequest(w, "The call has been missed")
+		return
+	}
+
+	// the call is answered when other participant join
+	if cs.Status == model.CALL_STATUS_RINGING && !(cs.StartedBy == actor.ID && cs.StartedRole == actor.Role) {
+		cs.Status = model.CALL_STATUS_ONGOING
+		cs.AnsweredDate.Time, cs.AnsweredDate.Valid = time.Now().In(time.UTC), true
+
+		err = m.UpdateCallSession(tx, ctx, cs)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	participant, token, err := h.joinCallParticipant(tx, ctx, m, cs, actor)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.CallTokenRes
+	res = res.Transform(cs, participant, h.Config.GetString("agora.app_id"), token)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// RenewCallTokenAct generate new agora token for participant before the token expires
+func (h *Contract) RenewCallTokenAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	callCode := chi.URLParam(r, "callCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	actor, err := h.getCallActor(db, ctx, r, m, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	cs, err := m.GetCallSessionByCode(db, ctx, code, callCode)
+	if cs.ID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Call %s not found.", callCode))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !cs.IsActive() {
+		h.SendBadRequest(w, "The call has ended")
+		return
+	}
+
+	participant, err := m.GetCallParticipant(db, ctx, cs.ID, actor.ID, actor.Role)
+	if participant.ID <= 0 || !participant.JoinedDate.Valid || participant.LeftDate.Valid {
+		h.SendBadRequest(w, "You have not joined this call")
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	token, expiredDate, err := h.agoraContract().GenerateRtcTokenWithExpiry(uint32(participant.ID), cs.ChannelName, rtctokenbuilder.RolePublisher)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.UpdateCallParticipantToken(tx, ctx, participant.ID, expiredDate)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	participant.TokenExpiredDate.Time, participant.TokenExpiredDate.Valid = expiredDate, true
+
+	var res response.CallTokenRes
+	res = res.Transform(cs, participant, h.Config.GetString("agora.app_id"), token)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// LeaveCallAct participant leave the call, the call is finished when less than two participants remain
+func (h *Contract) LeaveCallAct(w http.ResponseWriter, r *http.Request) {
+	h.stopCall(w, r, false)
+}
+
+// EndCallAct end the call for all participants
+func (h *Contract) EndCallAct(w http.ResponseWriter, r *http.Request) {
+	h.stopCall(w, r, true)
+}
+
+func (h *Contract) stopCall(w http.ResponseWriter, r *http.Request, endAll bool) {
+	code := chi.URLParam(r, "code")
+	callCode := chi.URLParam(r, "callCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	actor, err := h.getCallActor(db, ctx, r, m, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	cs, err := m.GetCallSessionByCode(db, ctx, code, callCode)
+	if cs.ID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Call %s not found.", callCode))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !cs.IsActive() {
+		h.SendBadRequest(w, "The call has ended")
+		return
+	}
+
+	chatGroup, err := m.GetGroupChatsCreatedBy(db, ctx, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.LeaveCallParticipant(tx, ctx, cs.ID, actor.ID, actor.Role)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	if !endAll {
+		remaining, err := m.CountJoinedCallParticipant(tx, ctx, cs.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+
+		// the caller cancel the ringing call or nobody left to talk with
+		endAll = remaining < 2 || cs.Status == model.CALL_STATUS_RINGING
+	}
+
+	if endAll {
+		cs, err = h.finishCallSession(tx, db, ctx, m, chatGroup, cs)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.CallSessionRes
+	res = res.Transform(cs)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// GetCallHistoryAct list of call history in chat group
+func (h *Contract) GetCallHistoryAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	param := map[string]interface{}{
+		"page":   1,
+		"limit":  10,
+		"offset": 0,
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil && l > 0 {
+			param["limit"] = l
+		}
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	if h.GetUserRole(r.Context()) != "admin" {
+		_, err = h.getCallActor(db, ctx, r, m, code)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	list, err := m.GetListCallSessionByChatCode(db, ctx, code, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.CallSessionRes{}
+	for _, a := range list {
+		var res response.CallSessionRes
+		res = res.Transform(a)
+		listResponse = append(listResponse, res)
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// GetListCancellationPolicyAct list cancellation policy (admin)
+func (h *Contract) GetListCancellationPolicyAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	policies, err := m.GetListCancellationPolicy(db, ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.CancellationPolicyRes{}
+	for _, a := range policies {
+		var res response.CancellationPolicyRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// setCancellationPolicyItin set itinerary of policy by the itin code
+func setCancellationPolicyItin(db *pgxpool.Conn, ctx context.Context, m model.Contract, p model.CancellationPolicyEnt, itinCode string) (model.CancellationPolicyEnt, error) {
+	p.MemberItinID = sql.NullInt32{}
+	p.ItinCode = ""
+	if len(itinCode) == 0 {
+		return p, nil
+	}
+
+	itinID, _ := m.GetMemberItinID(db, ctx, itinCode)
+	if itinID == 0 {
+		return p, fmt.Errorf("Itinerary %s not found.", itinCode)
+	}
+	p.MemberItinID = sql.NullInt32{Int32: itinID, Valid: true}
+	p.ItinCode = itinCode
+
+	return p, nil
+}
+
+// AddCancellationPolicyAct add new cancellation policy (admin)
+func (h *Contract) AddCancellationPolicyAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	req := request.CancellationPolicyReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	policy, err := req.Transform(model.CancellationPolicyEnt{})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	adminCode := h.GetUserCode(r.Context())
+	userAdmin, _ := m.GetUserByCode(db, ctx, adminCode)
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", adminCode))
+		return
+	}
+
+	policy, err = setCancellationPolicyItin(db, ctx, m, policy, req.ItinCode)
+	if err != nil {
+		h.SendNotfound(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	policy.CreatedBy = sql.NullInt32{Int32: userAdmin.ID, Valid: true}
+	policy, err = m.AddCancellationPolicy(tx, ctx, policy)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Add New Cancellation Policy",
+		Activity:  fmt.Sprintf("Add New Cancellation Policy %s", policy.Name),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.CancellationPolicyRes
+	h.SendSuccess(w, res.Transform(policy), nil)
+}
+
+// UpdateCancellationPolicyAct update cancellation policy (admin), the refund of cancelled order is not changed
+func (h *Contract) UpdateCancellationPolicyAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	req := request.CancellationPolicyReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	policy, _ := m.GetCancellationPolicyByID(db, ctx, int32(id))
+	if policy.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Cancellation policy %d not found.", id))
+		return
+	}
+
+	policy, err = req.Transform(policy)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	policy, err = setCancellationPolicyItin(db, ctx, m, policy, req.ItinCode)
+	if err != nil {
+		h.SendNotfound(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	policy, err = m.UpdateCancellationPolicy(tx, ctx, policy)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Update Cancellation Policy",
+		Activity:  fmt.Sprintf("Update Cancellation Policy %s", policy.Name),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.CancellationPolicyRes
+	h.SendSuccess(w, res.Transform(policy), nil)
+}
+
+// DeleteCancellationPolicyAct delete cancellation policy (admin)
+func (h *Contract) DeleteCancellationPolicyAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	policy, _ := m.GetCancellationPolicyByID(db, ctx, int32(id))
+	if policy.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Cancellation policy %d not found.", id))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteCancellationPolicy(tx, ctx, policy.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Delete Cancellation Policy",
+		Activity:  fmt.Sprintf("Delete Cancellation Policy %s", policy.Name),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+}
@@ -1,49 +1,35 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"panorama/lib/agora"
 	"panorama/lib/array"
+	"panorama/lib/metrics"
 	"panorama/lib/psql"
 	"panorama/lib/utils"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
 	"panorama/services/api/model"
 
-	rtctokenbuilder "github.com/AgoraIO/Tools/DynamicKey/AgoraDynamicKey/go/src/RtcTokenBuilder"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
-// ChatAct ...
-func (h *Contract) ChatAct(w http.ResponseWriter, r *http.Request) {
-	token, err := agora.Contract{
-		AppID:   h.Config.GetString("agora.app_id"),
-		AppCert: h.Config.GetString("agora.app_cert"),
-	}.GenerateRtcToken(1, "test channel", rtctokenbuilder.RoleAdmin)
-	if err != nil {
-		h.SendBadRequest(w, err.Error())
-		return
-	}
-
-	h.SendSuccess(w, map[string]interface{}{"token": token}, nil)
-}
-
 // CreateChatGroup ...
 func (h *Contract) CreateChatGroup(w http.ResponseWriter, r *http.Request) {
 
 	var err error
 	var tcID int32
 	var mItin model.MemberItinEnt
-	var mTemp, mRel []string
+	var mTemp []model.ChatMemberTemporary
+	var mRel []model.ChatGroupRelationEnt
 	var res response.ChatGroupRes
 
 	req := request.NewChatGroupReq{}
@@ -56,7 +42,7 @@ func (h *Contract) CreateChatGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -142,16 +128,16 @@ func (h *Contract) CreateChatGroup(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			if mbr.ID <= 0 {
-				mTemp = append(mTemp, "('"+v+"',"+strconv.Itoa(int(cg.ID))+",current_timestamp)")
+				mTemp = append(mTemp, model.ChatMemberTemporary{Email: v, ChatGroupID: cg.ID})
 
 			} else {
-				mRel = append(mRel, "("+strconv.Itoa(int(mbr.ID))+","+strconv.Itoa(int(cg.ID))+",current_timestamp)")
+				mRel = append(mRel, model.ChatGroupRelationEnt{MemberID: mbr.ID, ChatGroupID: cg.ID})
 			}
 		}
 
 		// add member temporary to chat member temporary
 		if len(mTemp) > 0 {
-			err = m.AddChatMemberTempBatch(ctx, tx, strings.Join(mTemp, ","))
+			err = m.AddChatMemberTempBatch(ctx, tx, mTemp)
 			if err != nil {
 				h.SendBadRequest(w, psql.ParseErr(err))
 				tx.Rollback(ctx)
@@ -161,7 +147,7 @@ func (h *Contract) CreateChatGroup(w http.ResponseWriter, r *http.Request) {
 
 		// add member is active to chat group relation
 		if len(mRel) > 0 {
-			err = m.AddChatGroupRelationBatch(ctx, tx, strings.Join(mRel, ","))
+			err = m.AddChatGroupRelationBatch(ctx, tx, mRel)
 			if err != nil {
 				h.SendBadRequest(w, psql.ParseErr(err))
 				tx.Rollback(ctx)
@@ -253,7 +239,7 @@ func (h *Contract) InviteTcToGroupChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -274,6 +260,17 @@ func (h *Contract) InviteTcToGroupChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// get current tc of chat group for handover
+	chatGroupID, prevTcID, err := m.GetChatGroupCurrentTc(db, ctx, req.ChatGroupCode)
+	if chatGroupID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Group code %s not found.", req.ChatGroupCode))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
 	tx, err := db.Begin(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -287,6 +284,24 @@ func (h *Contract) InviteTcToGroupChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// record the tc changes, or pass the pending handover to the invited tc
+	if prevTcID > 0 && prevTcID != idTc {
+		_, err = m.AddChatTcHandover(tx, ctx, model.ChatTcHandoverEnt{
+			ChatGroupID: chatGroupID,
+			FromTc:      model.UserEnt{ID: prevTcID},
+			ToTc:        model.UserEnt{ID: idTc},
+			Notes:       req.Notes,
+			ChangedBy:   h.GetUserRole(r.Context()),
+		})
+	} else {
+		err = m.AssignPendingChatTcHandover(tx, ctx, chatGroupID, idTc)
+	}
+	if err != nil {
+		tx.Rollback(ctx)
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
 	member, _ := m.GetMemberBy(db, ctx, "member_code", h.GetUserCode(r.Context()))
 
 	// Activity user
@@ -331,7 +346,7 @@ func (h *Contract) ChatMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -395,6 +410,14 @@ func (h *Contract) ChatMessage(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			// Pass the handover notes of previous tc to the new tc
+			err = m.AssignPendingChatTcHandover(tx, ctx, chatGroup.ID, tcNewID)
+			if err != nil {
+				h.SendBadRequest(w, err.Error())
+				tx.Rollback(ctx)
+				return
+			}
+
 			// Activity user new TC
 			logtc := model.LogActivityUserEnt{
 				UserID:    int64(tcNewID),
@@ -465,6 +488,7 @@ func (h *Contract) ChatMessage(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	metrics.MessageSent(role)
 
 	var res response.ChatGroupMessageRes
 	res = res.Transform(cm)
@@ -548,7 +572,7 @@ func (h *Contract) GetChatListAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -612,7 +636,7 @@ func (h *Contract) GetHistoryChatByCode(w http.ResponseWriter, r *http.Request)
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -662,7 +686,7 @@ func (h *Contract) GetHistoryChatByCode(w http.ResponseWriter, r *http.Request)
 	}
 
 	// get list user in group chat
-	listUser, err := m.GetListUserGroupChat(db, ctx, strconv.Itoa(int(chatList.ID)))
+	listUser, err := m.GetListUserGroupChat(db, ctx, chatList.ID)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		return
@@ -724,7 +748,18 @@ func (h *Contract) LeaveSessionChatAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	// handover notes is optional
+	req := request.LeaveSessionReq{}
+	if err = h.Bind(r, &req); err != nil && err != io.EOF {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if err = h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -761,6 +796,19 @@ func (h *Contract) LeaveSessionChatAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Handover notes for the next tc
+	_, err = m.AddChatTcHandover(tx, ctx, model.ChatTcHandoverEnt{
+		ChatGroupID: chatGroup.ID,
+		FromTc:      tcLeave,
+		Notes:       req.Notes,
+		ChangedBy:   h.GetUserRole(r.Context()),
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
 	// Activity user old TC
 	log := model.LogActivityUserEnt{
 		UserID:    int64(tcLeave.ID),
@@ -812,7 +860,7 @@ func (h *Contract) UpdateIsReadMessages(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -47,7 +46,7 @@ func (h *Contract) GetDashboardAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -55,6 +54,24 @@ func (h *Contract) GetDashboardAct(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Release()
 
+	// export the daily visits series of the dashboard
+	if format := exportFormat(r); len(format) > 0 {
+		log, err := m.GetDailyVisitsAct(db, ctx, param)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		listResponse := []response.DashboardDailyVisitsResponse{}
+		for _, a := range log {
+			var res response.DashboardDailyVisitsResponse
+			listResponse = append(listResponse, res.Transform(a))
+		}
+
+		h.SendExport(w, r, db, ctx, exportJob{Name: "daily-visits", Row: response.DashboardDailyVisitsResponse{}, Rows: exportSlice(listResponse)})
+		return
+	}
+
 	var result interface{}
 	if role == "admin" {
 		var res response.DashboardAdminResponse
@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"panorama/lib/array"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// GetListCurrencyAct supported currencies
+func (h *Contract) GetListCurrencyAct(w http.ResponseWriter, r *http.Request) {
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	currencies, err := m.GetListCurrency(db, ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.CurrencyRes{}
+	for _, a := range currencies {
+		var res response.CurrencyRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// GetListExchangeRateAct list exchange rate (admin), ?date=yyyy-mm-dd filter the rate effective on the date
+func (h *Contract) GetListExchangeRateAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"currency": "",
+		"date":     "",
+		"page":     1,
+		"limit":    10,
+		"offset":   0,
+		"sort":     "desc",
+		"order":    "er.effective_date",
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if sort, ok := r.URL.Query()["sort"]; ok && len(sort[0]) > 0 && strings.ToLower(sort[0]) == "asc" {
+		param["sort"] = "asc"
+	}
+
+	if order, ok := r.URL.Query()["order"]; ok && len(order[0]) > 0 {
+		arrStr := new(array.ArrStr)
+		if exist, _ := arrStr.InArray(order[0], []string{"id", "currency_code", "effective_date"}); exist {
+			param["order"] = "er." + order[0]
+		}
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	if currency, ok := r.URL.Query()["currency"]; ok && len(currency[0]) > 0 {
+		param["currency"] = currency[0]
+	}
+
+	if date, ok := r.URL.Query()["date"]; ok && len(date[0]) > 0 {
+		param["date"] = date[0]
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	rates, err := m.GetListExchangeRate(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		return
+	}
+
+	listResponse := []response.ExchangeRateRes{}
+	for _, a := range rates {
+		var res response.ExchangeRateRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
+
+// AddExchangeRateAct add exchange rate (admin), the rate of the same currency and date is replaced
+func (h *Contract) AddExchangeRateAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	req := request.ExchangeRateReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	rate, err := req.Transform(model.ExchangeRateEnt{Source: model.EXCHANGE_RATE_SOURCE_MANUAL})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	h.saveExchangeRates(w, r, []model.ExchangeRateEnt{rate})
+}
+
+// ImportExchangeRateAct import exchange rate from csv file (admin), the columns of
+// each row are currency_code, rate and effective_date (yyyy-mm-dd)
+func (h *Contract) ImportExchangeRateAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(header.Filename)) != ".csv" {
+		h.SendBadRequest(w, "Content type is not allowed.")
+		return
+	}
+
+	rates := []model.ExchangeRateEnt{}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		// header and empty row is skipped
+		if len(row) == 0 || (len(row) == 1 && len(strings.TrimSpace(row[0])) == 0) {
+			continue
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(row[0]), "currency_code") {
+			continue
+		}
+		if len(row) < 3 {
+			h.SendBadRequest(w, fmt.Sprintf("Line %d: currency_code, rate and effective_date is required.", line))
+			return
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		if err != nil || value <= 0 {
+			h.SendBadRequest(w, fmt.Sprintf("Line %d: invalid rate %s.", line, row[1]))
+			return
+		}
+
+		rate, err := request.ExchangeRateReq{CurrencyCode: row[0], Rate: value, EffectiveDate: row[2]}.
+			Transform(model.ExchangeRateEnt{Source: model.EXCHANGE_RATE_SOURCE_IMPORT})
+		if err != nil {
+			h.SendBadRequest(w, fmt.Sprintf("Line %d: %s", line, err.Error()))
+			return
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if len(rates) == 0 {
+		h.SendBadRequest(w, "Exchange rate file is empty.")
+		return
+	}
+
+	h.saveExchangeRates(w, r, rates)
+}
+
+// saveExchangeRates save all rates in one transaction
+func (h *Contract) saveExchangeRates(w http.ResponseWriter, r *http.Request, rates []model.ExchangeRateEnt) {
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	for _, a := range rates {
+		if a.CurrencyCode == model.BASE_CURRENCY {
+			h.SendBadRequest(w, fmt.Sprintf("Exchange rate of %s can not be changed.", model.BASE_CURRENCY))
+			return
+		}
+		if _, err := m.GetCurrency(db, ctx, a.CurrencyCode); err != nil {
+			h.SendBadRequest(w, fmt.Sprintf("Currency %s is not supported.", a.CurrencyCode))
+			return
+		}
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.ExchangeRateRes{}
+	for _, a := range rates {
+		a.CreatedBy = sql.NullInt32{Int32: userAdmin.ID, Valid: true}
+		a, err = m.AddExchangeRate(tx, ctx, a)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		var res response.ExchangeRateRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Add Exchange Rate",
+		Activity:  fmt.Sprintf("Add %d Exchange Rate (%s)", len(rates), rates[0].Source),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// DeleteExchangeRateAct delete exchange rate (admin), the rate that is locked by order is kept on the order
+func (h *Contract) DeleteExchangeRateAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	rate, _ := m.GetExchangeRateByID(db, ctx, int32(id))
+	if rate.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Exchange rate %d not found.", id))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteExchangeRate(tx, ctx, rate.ID)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Delete Exchange Rate",
+		Activity:  fmt.Sprintf("Delete Exchange Rate %s %s", rate.CurrencyCode, rate.EffectiveDate.Format("2006-01-02")),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+}
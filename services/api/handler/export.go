@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"panorama/lib/export"
+	"panorama/lib/utils"
+	"panorama/services/api/model"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// exportJob list or report that written into csv / xlsx
+type exportJob struct {
+	// Name prefix of the file name
+	Name string
+	// Row response of a row, the json fields are the columns of the file
+	Row interface{}
+	// Count total rows, nil when the list is small enough to be always exported directly
+	Count func(db *pgxpool.Conn, ctx context.Context) (int, error)
+	// Rows encode the rows of the list
+	Rows func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error
+}
+
+// exportFormat csv or xlsx of the request, empty for json
+func exportFormat(r *http.Request) string {
+	if format, ok := r.URL.Query()["format"]; ok && len(format[0]) > 0 {
+		return strings.ToLower(format[0])
+	}
+
+	return ""
+}
+
+// exportSlice rows of the list that already loaded, used by the small reports
+func exportSlice(list interface{}) func(*pgxpool.Conn, context.Context, *export.Encoder) error {
+	return func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+		v := reflect.ValueOf(list)
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// SendExport write the list into csv / xlsx (admin), `columns` select the columns of the file.
+// The big list (more than export.background_threshold rows) or `background=true` is exported in background
+// and the download link is sent to the admin email
+func (h *Contract) SendExport(w http.ResponseWriter, r *http.Request, db *pgxpool.Conn, ctx context.Context, job exportJob) {
+	// the list of public route (call logs) has no identifier
+	if _, ok := r.Context().Value("identifier").(map[string]string); !ok || h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	format := exportFormat(r)
+	if !export.IsFormat(format) {
+		h.SendBadRequest(w, fmt.Sprintf("Format %s is not supported, use csv or xlsx.", format))
+		return
+	}
+
+	var columns []string
+	if c, ok := r.URL.Query()["columns"]; ok && len(c[0]) > 0 {
+		columns = strings.Split(c[0], ",")
+	}
+
+	// validate the columns before writing anything
+	if _, err := export.NewEncoder(ioutil.Discard, format, job.Row, columns); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	background := false
+	if b, ok := r.URL.Query()["background"]; ok && b[0] == "true" {
+		background = true
+	}
+
+	var count int
+	if job.Count != nil {
+		var err error
+		count, err = job.Count(db, ctx)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		threshold := h.Config.GetInt("export.background_threshold")
+		if threshold <= 0 {
+			threshold = 10000
+		}
+		if count > threshold {
+			background = true
+		}
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", job.Name, time.Now().Format("20060102-150405"), format)
+
+	if background {
+		h.sendBackgroundExport(w, r, db, ctx, job, format, columns, fileName, count)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+
+	enc, err := export.NewEncoder(w, format, job.Row, columns)
+	if err == nil {
+		err = job.Rows(db, ctx, enc)
+	}
+	if err == nil {
+		err = enc.Close()
+	}
+
+	// the file is partly sent, the error can only be logged
+	if err != nil {
+		h.Log.FromContext(ctx).WithError(err).Errorf("Failed to export %s", fileName)
+	}
+}
+
+// sendBackgroundExport write the file into the private storage & email the download link to the admin
+func (h *Contract) sendBackgroundExport(w http.ResponseWriter, r *http.Request, db *pgxpool.Conn, ctx context.Context, job exportJob, format string, columns []string, fileName string, count int) {
+	if h.Storage == nil {
+		h.SendBadRequest(w, "Storage is not configured, the export can not be processed in background.")
+		return
+	}
+
+	m := model.Contract{App: h.App}
+	userCode := h.GetUserCode(r.Context())
+	u, err := m.GetUserByCode(db, ctx, userCode)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if len(u.Email) == 0 {
+		h.SendBadRequest(w, "Email of the admin is empty, the download link can not be sent.")
+		return
+	}
+
+	ttlHours := 24
+	if t := h.Config.GetInt("export.url_ttl"); t > 0 {
+		ttlHours = t
+	}
+
+	rTail, _ := utils.Generate(`[a-zA-Z0-9]{16}`)
+	key := fmt.Sprintf("%s/exports/%s/%s/%s", h.Config.GetString("aws.s3.filepath"), userCode, rTail, fileName)
+
+	go func() {
+		ctx := h.Context(r)
+		db, err := h.DB.Acquire(ctx)
+		if err != nil {
+			h.Log.FromContext(ctx).WithError(err).Errorf("Failed to export %s", fileName)
+			return
+		}
+		defer db.Release()
+
+		// the rows are streamed into the storage, the failed encoding abort the upload
+		pr, pw := io.Pipe()
+		encoded := make(chan error, 1)
+		go func() {
+			enc, err := export.NewEncoder(pw, format, job.Row, columns)
+			if err == nil {
+				err = job.Rows(db, ctx, enc)
+			}
+			if err == nil {
+				err = enc.Close()
+			}
+			pw.CloseWithError(err)
+			encoded <- err
+		}()
+
+		err = h.Storage.PutReader(key, pr, export.ContentType(format), true)
+		// the encoder is unblocked when the upload is stopped before the end of file
+		pr.CloseWithError(err)
+		if encErr := <-encoded; encErr != nil {
+			err = encErr
+		}
+
+		var url string
+		if err == nil {
+			url, err = h.Storage.Presign(key, time.Duration(ttlHours)*time.Hour)
+		}
+		if err != nil {
+			h.Log.FromContext(ctx).WithError(err).Errorf("Failed to export %s", fileName)
+			return
+		}
+
+		err = m.SendExportMail(ctx, u.Email, model.DataEmailExport{
+			Name:         u.Name,
+			FileName:     fileName,
+			Rows:         count,
+			URL:          url,
+			ExpiredHours: ttlHours,
+		})
+		if err != nil {
+			h.Log.FromContext(ctx).WithError(err).Errorf("Failed to export %s", fileName)
+		}
+	}()
+
+	h.SendSuccessCustomMsg(w, map[string]interface{}{
+		"file_name": fileName,
+		"email":     u.Email,
+		"rows":      count,
+	}, nil, "The export is processed in background, the download link will be sent to your email.")
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+
+	"panorama/lib/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ServeFileAct serve the file of local storage, private file is only served by the presigned url
+func (h *Contract) ServeFileAct(w http.ResponseWriter, r *http.Request) {
+	local, ok := h.Storage.(*storage.Local)
+	if !ok {
+		h.SendNotfound(w, "File not found.")
+		return
+	}
+
+	key := chi.URLParam(r, "*")
+	f, info, err := local.Open(key)
+	if err != nil {
+		h.SendNotfound(w, "File not found.")
+		return
+	}
+	defer f.Close()
+
+	q := r.URL.Query()
+	if len(q.Get("signature")) > 0 || local.IsPrivate(info) {
+		if !local.Verify(key, q.Get("expires"), q.Get("signature")) {
+			h.SendUnAuthorizedData(w)
+			return
+		}
+	}
+
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+}
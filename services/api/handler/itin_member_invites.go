@@ -0,0 +1,480 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"panorama/lib/array"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// getItinMemberRole role of companion from request group member, default is viewer
+func getItinMemberRole(groupMember map[string]interface{}) (string, error) {
+	role, _ := groupMember["member_role"].(string)
+	if role == "" {
+		return model.ITIN_ROLE_VIEWER, nil
+	}
+
+	arrStr := new(array.ArrStr)
+	if exist, _ := arrStr.InArray(role, []string{model.ITIN_ROLE_EDITOR, model.ITIN_ROLE_VIEWER}); !exist {
+		return "", fmt.Errorf("Role %s is invalid.", role)
+	}
+
+	return role, nil
+}
+
+// inviteItinMember add invite of email into member itin, the invite link is emailed by mailItinInvites after commit
+func (h *Contract) inviteItinMember(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, m model.Contract, itin model.MemberItinEnt, owner model.MemberEnt, email, role string) (model.MemberItinInviteEnt, error) {
+	invite := model.MemberItinInviteEnt{
+		InviteCode:   m.SetItinInviteCode(),
+		MemberItinID: itin.ID,
+		Email:        email,
+		Role:         role,
+		InvitedBy:    owner.ID,
+		ExpiredDate:  m.ItinInviteExpiredDate(),
+	}
+
+	member, _ := m.GetMemberByEmail(db, ctx, email)
+	if member.ID != 0 {
+		invite.MemberID = sql.NullInt32{Int32: member.ID, Valid: true}
+	}
+
+	return m.AddMemberItinInvite(tx, ctx, invite)
+}
+
+// mailItinInvites send the invite link of every invite, the failure is logged
+func (h *Contract) mailItinInvites(ctx context.Context, m model.Contract, invites []model.MemberItinInviteEnt, sender, itinTitle string) {
+	for _, invite := range invites {
+		h.mailItinInvite(ctx, m, invite, sender, itinTitle)
+	}
+}
+
+func (h *Contract) mailItinInvite(ctx context.Context, m model.Contract, invite model.MemberItinInviteEnt, sender, itinTitle string) error {
+	dataEmail := model.DataEmailInviteItinMember{
+		Sender:        sender,
+		URL:           m.ItinInviteURL(invite),
+		ItineraryName: itinTitle,
+		EmailInvite:   invite.Email,
+	}
+	subject := fmt.Sprintf("[Panorama] Invitation Trip %s", dataEmail.ItineraryName)
+	err := m.SendingMail(ctx, model.ActInviteGroupItinMember, subject, dataEmail.EmailInvite, dataEmail)
+	if err != nil {
+		h.Log.FromContext(ctx).WithError(err).Errorf("Failed to send the invitation to %s", invite.Email)
+	}
+
+	return err
+}
+
+// getItinInviteOwner validate the login customer is the owner of member itin
+func (h *Contract) getItinInviteOwner(db *pgxpool.Conn, ctx context.Context, r *http.Request, m model.Contract, itin model.MemberItinEnt) (model.MemberEnt, bool) {
+	if h.GetUserRole(r.Context()) != "customer" {
+		return model.MemberEnt{}, false
+	}
+
+	member, _ := m.GetMemberByCode(db, ctx, h.GetUserCode(r.Context()))
+	if member.ID == 0 || member.ID != itin.CreatedBy {
+		return member, false
+	}
+
+	return member, true
+}
+
+// GetMemberItinInvitesAct list of invite in member itin
+func (h *Contract) GetMemberItinInvitesAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	// only companion of member itin can see the invites
+	if h.GetUserRole(r.Context()) == "customer" {
+		member, _ := m.GetMemberByCode(db, ctx, h.GetUserCode(r.Context()))
+		role, err := m.GetMemberItinRole(db, ctx, itin.ID, member.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+		if role == "" {
+			h.SendUnAuthorizedData(w)
+			return
+		}
+	}
+
+	list, err := m.GetListMemberItinInviteByItinCode(db, ctx, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.MemberItinInviteRes{}
+	for _, a := range list {
+		var res response.MemberItinInviteRes
+		res = res.Transform(a)
+		listResponse = append(listResponse, res)
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// ResendMemberItinInviteAct send again the invite link with new invite code and expired date, only for owner
+func (h *Contract) ResendMemberItinInviteAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	inviteCode := chi.URLParam(r, "inviteCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	owner, ok := h.getItinInviteOwner(db, ctx, r, m, itin)
+	if !ok {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	invite, _ := m.GetMemberItinInviteByCode(db, ctx, inviteCode)
+	if invite.ID == 0 || invite.MemberItinID != itin.ID {
+		h.SendNotfound(w, fmt.Sprintf("Invite %s not found.", inviteCode))
+		return
+	}
+	if invite.Status == model.ITIN_INVITE_ACCEPTED {
+		h.SendBadRequest(w, "Invitation has been accepted")
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	invite.InviteCode = m.SetItinInviteCode()
+	invite.ExpiredDate = m.ItinInviteExpiredDate()
+	err = m.ResendMemberItinInvite(tx, ctx, invite.ID, invite.InviteCode, invite.ExpiredDate)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	invite.Status = model.ITIN_INVITE_PENDING
+	invite.ResendCount++
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	go h.mailItinInvite(ctx, m, invite, owner.Name, itin.Title)
+
+	var res response.MemberItinInviteRes
+	h.SendSuccess(w, res.Transform(invite), nil)
+}
+
+// RevokeMemberItinInviteAct revoke the invite, accepted invite remove the companion from member itin
+func (h *Contract) RevokeMemberItinInviteAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	inviteCode := chi.URLParam(r, "inviteCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	owner, ok := h.getItinInviteOwner(db, ctx, r, m, itin)
+	if !ok {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	invite, _ := m.GetMemberItinInviteByCode(db, ctx, inviteCode)
+	if invite.ID == 0 || invite.MemberItinID != itin.ID {
+		h.SendNotfound(w, fmt.Sprintf("Invite %s not found.", inviteCode))
+		return
+	}
+
+	chatGroup, err := m.GetChatGroupByItinID(db, ctx, itin.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = h.removeItinInvite(tx, ctx, m, invite, chatGroup.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Activity user logging in process
+	log := model.LogActivityUserEnt{
+		UserID:    int64(owner.ID),
+		Role:      "customer",
+		Title:     "Revoke Trip Invitation",
+		Activity:  fmt.Sprintf("Revoke invitation of %s from Trip Itin %s", invite.Email, itin.Title),
+		EventType: r.Method,
+	}
+	_, err = m.AddLogActivity(tx, ctx, log)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+}
+
+// removeItinInvite delete the invite with the temporaries and relations of the invited member
+func (h *Contract) removeItinInvite(tx pgx.Tx, ctx context.Context, m model.Contract, invite model.MemberItinInviteEnt, chatGroupID int32) error {
+	err := m.DeleteMemberItinInvite(tx, ctx, invite.ID)
+	if err != nil {
+		return err
+	}
+
+	err = m.DeleteMemberTempByEmailAndItinID(tx, ctx, invite.Email, invite.MemberItinID)
+	if err != nil {
+		return err
+	}
+
+	if chatGroupID > 0 {
+		err = m.DeleteChatMemberTempByEmailAndChatID(tx, ctx, invite.Email, chatGroupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if invite.Status == model.ITIN_INVITE_ACCEPTED && invite.MemberID.Valid {
+		err = m.DeleteMemberItinRelation(tx, ctx, invite.MemberID.Int32, invite.MemberItinID)
+		if err != nil {
+			return err
+		}
+
+		if chatGroupID > 0 {
+			err = m.DeleteChatGroupRelation(tx, ctx, invite.MemberID.Int32, chatGroupID)
+		}
+	}
+
+	return err
+}
+
+// AcceptMemberItinInviteAct invited customer accept the invite and join the member itin
+func (h *Contract) AcceptMemberItinInviteAct(w http.ResponseWriter, r *http.Request) {
+	h.respondItinInvite(w, r, model.ITIN_INVITE_ACCEPTED)
+}
+
+// DeclineMemberItinInviteAct invited customer decline the invite
+func (h *Contract) DeclineMemberItinInviteAct(w http.ResponseWriter, r *http.Request) {
+	h.respondItinInvite(w, r, model.ITIN_INVITE_DECLINED)
+}
+
+func (h *Contract) respondItinInvite(w http.ResponseWriter, r *http.Request, status string) {
+	if h.GetUserRole(r.Context()) != "customer" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	var err error
+	req := request.MemberItinInviteReq{}
+	if err = h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if err = h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	inviteCode, err := m.ParseItinInviteToken(req.Token)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	invite, _ := m.GetMemberItinInviteByCode(db, ctx, inviteCode)
+	if invite.ID == 0 {
+		h.SendNotfound(w, "Invitation not found.")
+		return
+	}
+
+	member, _ := m.GetMemberByCode(db, ctx, h.GetUserCode(r.Context()))
+	if member.ID == 0 {
+		h.SendNotfound(w, "Member not found.")
+		return
+	}
+	if !strings.EqualFold(member.Email, invite.Email) {
+		h.SendBadRequest(w, "Invitation is not for this account")
+		return
+	}
+
+	switch invite.CurrentStatus() {
+	case model.ITIN_INVITE_EXPIRED:
+		h.SendBadRequest(w, "Invitation has expired")
+		return
+	case model.ITIN_INVITE_ACCEPTED, model.ITIN_INVITE_DECLINED:
+		h.SendBadRequest(w, fmt.Sprintf("Invitation has been %s", invite.Status))
+		return
+	}
+
+	chatGroup, err := m.GetChatGroupByItinID(db, ctx, invite.MemberItinID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.RespondMemberItinInvite(tx, ctx, invite.ID, member.ID, status)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// the invited email is no longer temporary member
+	err = m.DeleteMemberTempByEmailAndItinID(tx, ctx, invite.Email, invite.MemberItinID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if chatGroup.ID > 0 {
+		err = m.DeleteChatMemberTempByEmailAndChatID(tx, ctx, invite.Email, chatGroup.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	activity := fmt.Sprintf("Decline invitation of Trip Itin %s", invite.MemberItin.Title)
+	if status == model.ITIN_INVITE_ACCEPTED {
+		activity = fmt.Sprintf("Join Trip Itin %s", invite.MemberItin.Title)
+
+		relationExist, _ := m.GetMemberItinRelationByMemberIDAndMemberItinID(db, ctx, member.ID, invite.MemberItinID)
+		if relationExist.ID == 0 {
+			_, err = m.AddMemberItinRelation(tx, ctx, model.MemberItinRelationEnt{
+				MemberItinID: invite.MemberItinID,
+				MemberID:     member.ID,
+				Role:         invite.Role,
+			})
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+		}
+
+		if chatGroup.ID > 0 {
+			exist, _ := m.IsExistInGroupChat(db, ctx, chatGroup.ChatGroupCode, member.MemberCode)
+			if exist <= 0 {
+				err = m.AddChatGroupRelationBatch(ctx, tx, []model.ChatGroupRelationEnt{{MemberID: member.ID, ChatGroupID: chatGroup.ID}})
+				if err != nil {
+					h.SendBadRequest(w, psql.ParseErr(err))
+					tx.Rollback(ctx)
+					return
+				}
+			}
+		}
+	}
+
+	// Activity user logging in process
+	log := model.LogActivityUserEnt{
+		UserID:    int64(member.ID),
+		Role:      "customer",
+		Title:     "Trip Invitation",
+		Activity:  activity,
+		EventType: r.Method,
+	}
+	_, err = m.AddLogActivity(tx, ctx, log)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	invite.Status = status
+	invite.Member = member
+
+	var res response.MemberItinInviteRes
+	h.SendSuccess(w, res.Transform(invite), nil)
+}
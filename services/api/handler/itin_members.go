@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -9,6 +8,7 @@ import (
 	// "fmt"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
 	"panorama/lib/psql"
 	"panorama/lib/utils"
 	"panorama/services/api/handler/request"
@@ -24,7 +24,7 @@ import (
 
 //GetMemberItAct
 func (h *Contract) GetMemberItinAct(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -104,7 +104,7 @@ func (h *Contract) GetItinMemberList(w http.ResponseWriter, r *http.Request) {
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -134,7 +134,7 @@ func (h *Contract) DelMemberItinAct(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -159,6 +159,7 @@ func (h *Contract) DelMemberItinAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := m.GetMemberItinByCode(db, ctx, code)
 	err = m.DelMemberItin(tx, ctx, code)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -188,13 +189,14 @@ func (h *Contract) DelMemberItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER_ITIN, code, audit.ActionDelete, before, nil)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
 
 // AddMemberItinAct add new member itinerary
 func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
-	var mTemp, mRel []string
+	var mTemp []model.ChatMemberTemporary
 
 	role := h.GetUserRole(r.Context())
 	if role == "admin" {
@@ -237,7 +239,7 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -284,6 +286,28 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 	memberOwnerID := memberOwner.ID
 	activityProcess := fmt.Sprintf("Add New Trip Itin %s", memberItinCreated.Title)
 
+	// Member itin that cloned from suggestion itin
+	if len(req.SugItinCode) > 0 {
+		sugItinID, _ := m.GetSugItinID(db, ctx, req.SugItinCode)
+		if sugItinID == 0 {
+			h.SendNotfound(w, fmt.Sprintf("Suggestion itin %s not found.", req.SugItinCode))
+			tx.Rollback(ctx)
+			return
+		}
+
+		_, err = m.AddItinSugEvent(tx, ctx, model.ItinSugEventEnt{
+			ItinSugID:    sugItinID,
+			MemberID:     sql.NullInt32{Int32: memberOwnerID, Valid: true},
+			MemberItinID: sql.NullInt32{Int32: memberItinCreated.ID, Valid: true},
+			EventType:    model.ITIN_SUG_EVENT_CLONE,
+		})
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
 	// Adjust user TC input member itin
 	var userTcID int32
 	if role == "tc" {
@@ -334,18 +358,23 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 
 	// Assign member itin relation group member or assign member temporary
 	var memberItinGroups []map[string]interface{}
+	// the invites are emailed after commit
+	var invites []model.MemberItinInviteEnt
 	memberItinGroups = append(memberItinGroups, map[string]interface{}{
 		"member_code":     memberOwner.MemberCode,
 		"member_name":     memberOwner.Name,
 		"member_username": memberOwner.Username,
 		"member_email":    memberOwner.Email,
 		"member_img":      memberOwner.Img.String,
+		"member_role":     model.ITIN_ROLE_OWNER,
+		"invite_status":   model.ITIN_INVITE_ACCEPTED,
 		"is_owner":        true,
 		"itin_code":       memberItinCreated.ItinCode,
 	})
 	if len(req.GroupMembers) > 0 {
 		// Append list email temporary
 		var tempListEmail []string
+		memberGroupRoles := map[string]string{}
 		for _, groupMember := range req.GroupMembers {
 			memberGroupEmail := fmt.Sprintf("%s", groupMember["member_email"])
 			if memberGroupEmail != "" && memberOwner.Email != memberGroupEmail {
@@ -354,6 +383,13 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 					tx.Rollback(ctx)
 					return
 				}
+				memberGroupRole, err := getItinMemberRole(groupMember)
+				if err != nil {
+					h.SendBadRequest(w, err.Error())
+					tx.Rollback(ctx)
+					return
+				}
+				memberGroupRoles[memberGroupEmail] = memberGroupRole
 				tempListEmail = append(tempListEmail, memberGroupEmail)
 			}
 		}
@@ -372,37 +408,31 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		// Assign adjustment member itin relation
+		// Invite registered member, the member join itin & chat group after accept the invite
 		arrInt32 := new(array.ArrInt32)
 		listTempsMemberIDFiltered := arrInt32.Unique(tempListRelationMemberID)
 		if len(listTempsMemberIDFiltered) > 0 {
 			for i := 0; i < len(listTempsMemberIDFiltered); i++ {
 				memberGroupID := listTempsMemberIDFiltered[i]
 				memberGroup, _ := m.GetMemberBy(db, ctx, "id", fmt.Sprintf("%d", memberGroupID))
-				memberItinRelationFormatted := model.MemberItinRelationEnt{
-					MemberItinID: memberItinCreated.ID,
-					MemberID:     memberGroupID,
-				}
-				memberItinRelationCreated, err := m.AddMemberItinRelation(tx, ctx, memberItinRelationFormatted)
+				invite, err := h.inviteItinMember(tx, db, ctx, m, memberItinCreated, memberOwner, memberGroup.Email, memberGroupRoles[memberGroup.Email])
 				if err != nil {
 					h.SendBadRequest(w, psql.ParseErr(err))
 					tx.Rollback(ctx)
 					return
 				}
-				memberItinRelationCreated.MemberEnt = memberGroup
-				memberItinRelationCreated.MemberItinEnt = memberItinCreated
+				invites = append(invites, invite)
 				memberItinGroups = append(memberItinGroups, map[string]interface{}{
-					"member_code":     memberItinRelationCreated.MemberEnt.MemberCode,
-					"member_name":     memberItinRelationCreated.MemberEnt.Name,
-					"member_username": memberItinRelationCreated.MemberEnt.Username,
-					"member_email":    memberItinRelationCreated.MemberEnt.Email,
-					"member_img":      memberItinRelationCreated.MemberEnt.Img.String,
+					"member_code":     memberGroup.MemberCode,
+					"member_name":     memberGroup.Name,
+					"member_username": memberGroup.Username,
+					"member_email":    memberGroup.Email,
+					"member_img":      memberGroup.Img.String,
+					"member_role":     invite.Role,
+					"invite_status":   invite.Status,
 					"is_owner":        false,
 					"itin_code":       memberItinCreated.ItinCode,
 				})
-
-				// append email member for query add to chat group relation
-				mRel = append(mRel, "("+strconv.Itoa(int(memberGroup.ID))+","+strconv.Itoa(int(chatGroupID))+",current_timestamp)")
 			}
 		}
 
@@ -423,47 +453,33 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 					return
 				}
 				memberTempCreated.MemberItin = memberItinCreated
+				invite, err := h.inviteItinMember(tx, db, ctx, m, memberItinCreated, memberOwner, memberTempCreated.Email, memberGroupRoles[memberTempCreated.Email])
+				if err != nil {
+					h.SendBadRequest(w, psql.ParseErr(err))
+					tx.Rollback(ctx)
+					return
+				}
+				invites = append(invites, invite)
 				memberItinGroups = append(memberItinGroups, map[string]interface{}{
 					"member_code":     "",
 					"member_name":     "",
 					"member_username": "",
 					"member_email":    memberTempCreated.Email,
 					"member_img":      "",
+					"member_role":     invite.Role,
+					"invite_status":   invite.Status,
 					"is_owner":        false,
 					"itin_code":       memberItinCreated.ItinCode,
 				})
-				dataEmail := model.DataEmailInviteItinMember{
-					Sender:        memberOwner.Name,
-					URL:           "https://panoramatest.page.link/test",
-					ItineraryName: memberItinCreated.Title,
-					EmailInvite:   memberTempCreated.Email,
-				}
-				subject := fmt.Sprintf("[Panorama] Invitation Trip %s", dataEmail.ItineraryName)
-				err = m.SendingMail(model.ActInviteGroupItinMember, subject, dataEmail.EmailInvite, dataEmail)
-				if err != nil {
-					fmt.Printf("error send email to %s : %s", memberTempCreated.Email, err.Error())
-					tx.Rollback(ctx)
-					return
-				}
 
 				// append email member for query add to chat group temporary
-				mTemp = append(mTemp, "('"+memberTempCreated.Email+"',"+strconv.Itoa(int(chatGroupID))+",current_timestamp)")
+				mTemp = append(mTemp, model.ChatMemberTemporary{Email: memberTempCreated.Email, ChatGroupID: chatGroupID})
 			}
 		}
 
 		// Add member temporary to chat member temporary
 		if len(mTemp) > 0 {
-			err = m.AddChatMemberTempBatch(ctx, tx, strings.Join(mTemp, ","))
-			if err != nil {
-				h.SendBadRequest(w, psql.ParseErr(err))
-				tx.Rollback(ctx)
-				return
-			}
-		}
-
-		// Add member is active to chat group relation
-		if len(mRel) > 0 {
-			err = m.AddChatGroupRelationBatch(ctx, tx, strings.Join(mRel, ","))
+			err = m.AddChatMemberTempBatch(ctx, tx, mTemp)
 			if err != nil {
 				h.SendBadRequest(w, psql.ParseErr(err))
 				tx.Rollback(ctx)
@@ -495,13 +511,15 @@ func (h *Contract) AddMemberItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER_ITIN, memberItinCreated.ItinCode, audit.ActionCreate, nil, memberItinCreated)
+	go h.mailItinInvites(ctx, m, invites, memberOwner.Name, memberItinCreated.Title)
 
 	h.SendSuccess(w, res.Transform(memberItinCreated), nil)
 }
 
 // UpdateMemberItinAct edit member itinerary
 func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
-	var mTemp, mRel []string
+	var mTemp []model.ChatMemberTemporary
 
 	role := h.GetUserRole(r.Context())
 	if role == "admin" {
@@ -553,7 +571,7 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -595,7 +613,31 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
-	if memberItinExist.CreatedBy != memberOwner.ID {
+	memberActorID := memberOwner.ID
+	if role == "customer" {
+		// Companion with editor role can update the itinerary, only owner can manage the companions
+		memberActor, _ := m.GetMemberByCode(db, ctx, h.GetUserCode(r.Context()))
+		memberActorID = memberActor.ID
+		itinRole, err := m.GetMemberItinRole(db, ctx, memberItinExist.ID, memberActor.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		if itinRole != model.ITIN_ROLE_OWNER && itinRole != model.ITIN_ROLE_EDITOR {
+			h.SendUnAuthorizedData(w)
+			tx.Rollback(ctx)
+			return
+		}
+		if itinRole == model.ITIN_ROLE_EDITOR {
+			if len(req.GroupMembers) > 0 {
+				h.SendBadRequest(w, "Only owner of itinerary can manage the companions")
+				tx.Rollback(ctx)
+				return
+			}
+		}
+		memberOwner, _ = m.GetMemberBy(db, ctx, "id", fmt.Sprintf("%d", memberItinExist.CreatedBy))
+	} else if memberItinExist.CreatedBy != memberOwner.ID {
 		h.SendNotfound(w, fmt.Sprintf("Itinerary %s member %s not found.", memberItinExist.ItinCode, memberOwner.Name))
 		tx.Rollback(ctx)
 		return
@@ -611,7 +653,6 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 	memberItinUpdated.CreatedDate = memberItinExist.CreatedDate
 	memberItinUpdated.MemberEnt = memberOwner
 	memberItinUpdated.ItinCode = code
-	memberOwnerID := memberOwner.ID
 	activityProcess := fmt.Sprintf("Update Trip Itin %s", memberItinUpdated.Title)
 
 	// Adjust user TC edit member itin
@@ -659,18 +700,23 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 
 	// Assign member itin relation group member or assign member temporary
 	var memberItinGroups []map[string]interface{}
+	// the invites are emailed after commit
+	var invites []model.MemberItinInviteEnt
 	memberItinGroups = append(memberItinGroups, map[string]interface{}{
 		"member_code":     memberOwner.MemberCode,
 		"member_name":     memberOwner.Name,
 		"member_username": memberOwner.Username,
 		"member_email":    memberOwner.Email,
 		"member_img":      memberOwner.Img.String,
+		"member_role":     model.ITIN_ROLE_OWNER,
+		"invite_status":   model.ITIN_INVITE_ACCEPTED,
 		"is_owner":        true,
 		"itin_code":       memberItinUpdated.ItinCode,
 	})
 	if len(req.GroupMembers) > 0 {
 		// Append list email temporary
 		var tempListEmail []string
+		memberGroupRoles := map[string]string{}
 		for _, groupMember := range req.GroupMembers {
 			memberGroupEmail := fmt.Sprintf("%s", groupMember["member_email"])
 			if memberGroupEmail != "" && memberOwner.Email != memberGroupEmail {
@@ -679,10 +725,36 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 					tx.Rollback(ctx)
 					return
 				}
+				memberGroupRole, err := getItinMemberRole(groupMember)
+				if err != nil {
+					h.SendBadRequest(w, err.Error())
+					tx.Rollback(ctx)
+					return
+				}
+				memberGroupRoles[memberGroupEmail] = memberGroupRole
 				tempListEmail = append(tempListEmail, memberGroupEmail)
 			}
 		}
 
+		// Revoke the invite of email that no longer in group members
+		listInviteExist, err := m.GetListMemberItinInviteByItinCode(db, ctx, code)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		arrStrInvite := new(array.ArrStr)
+		for _, invite := range listInviteExist {
+			if exist, _ := arrStrInvite.InArray(invite.Email, tempListEmail); !exist {
+				err := m.DeleteMemberItinInvite(tx, ctx, invite.ID)
+				if err != nil {
+					h.SendBadRequest(w, err.Error())
+					tx.Rollback(ctx)
+					return
+				}
+			}
+		}
+
 		// Append list email temporary & list member id temporary by member exist
 		var tempListTempsEmail []string
 		var tempListRelationMemberID []int32
@@ -726,33 +798,40 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 				memberGroupID := listTempsMemberIDFiltered[i]
 				memberGroup, _ := m.GetMemberBy(db, ctx, "id", fmt.Sprintf("%d", memberGroupID))
 				memberRelationExist, _ := m.GetMemberItinRelationByMemberIDAndMemberItinID(db, ctx, memberGroup.ID, memberItinUpdated.ID)
-				// Check relation itin member exist
+				// Check relation itin member exist, invite the member that is not a companion yet
 				if memberRelationExist.ID == 0 {
-					memberItinRelationFormatted := model.MemberItinRelationEnt{
-						MemberItinID: memberItinUpdated.ID,
-						MemberID:     memberGroupID,
-					}
-					memberItinRelationCreated, err := m.AddMemberItinRelation(tx, ctx, memberItinRelationFormatted)
-					if err != nil {
-						h.SendBadRequest(w, psql.ParseErr(err))
-						tx.Rollback(ctx)
-						return
+					inviteExist, _ := m.GetMemberItinInviteByEmail(db, ctx, memberItinUpdated.ID, memberGroup.Email)
+					if inviteExist.ID == 0 || inviteExist.CurrentStatus() != model.ITIN_INVITE_PENDING {
+						inviteExist, err = h.inviteItinMember(tx, db, ctx, m, memberItinUpdated, memberOwner, memberGroup.Email, memberGroupRoles[memberGroup.Email])
+						if err != nil {
+							h.SendBadRequest(w, psql.ParseErr(err))
+							tx.Rollback(ctx)
+							return
+						}
+						invites = append(invites, inviteExist)
 					}
-					memberItinRelationCreated.MemberEnt = memberGroup
-					memberItinRelationCreated.MemberItinEnt = memberItinUpdated
 					memberItinGroups = append(memberItinGroups, map[string]interface{}{
-						"member_code":     memberItinRelationCreated.MemberEnt.MemberCode,
-						"member_name":     memberItinRelationCreated.MemberEnt.Name,
-						"member_username": memberItinRelationCreated.MemberEnt.Username,
-						"member_email":    memberItinRelationCreated.MemberEnt.Email,
-						"member_img":      memberItinRelationCreated.MemberEnt.Img.String,
+						"member_code":     memberGroup.MemberCode,
+						"member_name":     memberGroup.Name,
+						"member_username": memberGroup.Username,
+						"member_email":    memberGroup.Email,
+						"member_img":      memberGroup.Img.String,
+						"member_role":     inviteExist.Role,
+						"invite_status":   inviteExist.CurrentStatus(),
 						"is_owner":        false,
 						"itin_code":       memberItinUpdated.ItinCode,
 					})
-
-					// append email member for query add to chat group relation
-					mRel = append(mRel, "("+strconv.Itoa(int(memberGroup.ID))+","+strconv.Itoa(int(chatGroupID))+",current_timestamp)")
 				} else {
+					// the role of companion is changed by the owner
+					if memberGroupRole := memberGroupRoles[memberGroup.Email]; len(memberGroupRole) > 0 && memberGroupRole != memberRelationExist.Role {
+						err = m.UpdateMemberItinRelationRole(tx, ctx, memberRelationExist.ID, memberGroupRole)
+						if err != nil {
+							h.SendBadRequest(w, psql.ParseErr(err))
+							tx.Rollback(ctx)
+							return
+						}
+						memberRelationExist.Role = memberGroupRole
+					}
 					memberRelationExist.MemberEnt = memberGroup
 					memberItinGroups = append(memberItinGroups, map[string]interface{}{
 						"member_code":     memberRelationExist.MemberEnt.MemberCode,
@@ -760,6 +839,8 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 						"member_username": memberRelationExist.MemberEnt.Username,
 						"member_email":    memberRelationExist.MemberEnt.Email,
 						"member_img":      memberRelationExist.MemberEnt.Img.String,
+						"member_role":     memberRelationExist.Role,
+						"invite_status":   model.ITIN_INVITE_ACCEPTED,
 						"is_owner":        false,
 						"itin_code":       memberItinUpdated.ItinCode,
 					})
@@ -807,39 +888,47 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 						return
 					}
 					memberTempCreated.MemberItin = memberItinUpdated
+					invite, err := h.inviteItinMember(tx, db, ctx, m, memberItinUpdated, memberOwner, memberTempCreated.Email, memberGroupRoles[memberTempCreated.Email])
+					if err != nil {
+						h.SendBadRequest(w, psql.ParseErr(err))
+						tx.Rollback(ctx)
+						return
+					}
+					invites = append(invites, invite)
 					memberItinGroups = append(memberItinGroups, map[string]interface{}{
 						"member_code":     "",
 						"member_name":     "",
 						"member_username": "",
 						"member_email":    memberTempCreated.Email,
 						"member_img":      "",
+						"member_role":     invite.Role,
+						"invite_status":   invite.Status,
 						"is_owner":        false,
 						"itin_code":       memberItinUpdated.ItinCode,
 					})
-					dataEmail := model.DataEmailInviteItinMember{
-						Sender:        memberOwner.Name,
-						URL:           "https://panoramatest.page.link/test",
-						ItineraryName: memberItinUpdated.Title,
-						EmailInvite:   memberTempCreated.Email,
-					}
-					subject := fmt.Sprintf("[Panorama] Invitation Trip %s", dataEmail.ItineraryName)
-					err = m.SendingMail(model.ActInviteGroupItinMember, subject, dataEmail.EmailInvite, dataEmail)
-					if err != nil {
-						fmt.Printf("error send email to %s : %s", memberTempCreated.Email, err.Error())
-						tx.Rollback(ctx)
-						return
-					}
 
 					// append email member for query add to chat group temporary
-					mTemp = append(mTemp, "('"+memberTempCreated.Email+"',"+strconv.Itoa(int(chatGroupID))+",current_timestamp)")
+					mTemp = append(mTemp, model.ChatMemberTemporary{Email: memberTempCreated.Email, ChatGroupID: chatGroupID})
 				} else {
 					memberTempExist.MemberItin = memberItinUpdated
+					inviteExist, _ := m.GetMemberItinInviteByEmail(db, ctx, memberItinUpdated.ID, memberTempExist.Email)
+					if inviteExist.ID == 0 || inviteExist.CurrentStatus() != model.ITIN_INVITE_PENDING {
+						inviteExist, err = h.inviteItinMember(tx, db, ctx, m, memberItinUpdated, memberOwner, memberTempExist.Email, memberGroupRoles[memberTempExist.Email])
+						if err != nil {
+							h.SendBadRequest(w, psql.ParseErr(err))
+							tx.Rollback(ctx)
+							return
+						}
+						invites = append(invites, inviteExist)
+					}
 					memberItinGroups = append(memberItinGroups, map[string]interface{}{
 						"member_code":     "",
 						"member_name":     "",
 						"member_username": "",
 						"member_email":    memberTempExist.Email,
 						"member_img":      "",
+						"member_role":     inviteExist.Role,
+						"invite_status":   inviteExist.CurrentStatus(),
 						"is_owner":        false,
 						"itin_code":       memberItinUpdated.ItinCode,
 					})
@@ -849,17 +938,7 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 
 		// Add member temporary to chat member temporary
 		if len(mTemp) > 0 {
-			err = m.AddChatMemberTempBatch(ctx, tx, strings.Join(mTemp, ","))
-			if err != nil {
-				h.SendBadRequest(w, psql.ParseErr(err))
-				tx.Rollback(ctx)
-				return
-			}
-		}
-
-		// Add member is active to chat group relation
-		if len(mRel) > 0 {
-			err = m.AddChatGroupRelationBatch(ctx, tx, strings.Join(mRel, ","))
+			err = m.AddChatMemberTempBatch(ctx, tx, mTemp)
 			if err != nil {
 				h.SendBadRequest(w, psql.ParseErr(err))
 				tx.Rollback(ctx)
@@ -871,7 +950,7 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 
 	// Activity user logging in process
 	log := model.LogActivityUserEnt{
-		UserID:    int64(memberOwnerID),
+		UserID:    int64(memberActorID),
 		Role:      h.GetUserRole(r.Context()),
 		Title:     "Update Itin",
 		Activity:  activityProcess,
@@ -890,6 +969,8 @@ func (h *Contract) UpdateMemberItinAct(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER_ITIN, code, audit.ActionUpdate, memberItinExist, memberItinUpdated)
+	go h.mailItinInvites(ctx, m, invites, memberOwner.Name, memberItinUpdated.Title)
 
 	h.SendSuccess(w, res.Transform(memberItinUpdated), nil)
 }
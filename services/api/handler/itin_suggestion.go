@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
@@ -21,7 +22,7 @@ import (
 // GetSugItinAct ...
 func (h *Contract) GetSugItinAct(w http.ResponseWriter, r *http.Request) {
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -55,6 +56,18 @@ func (h *Contract) GetSugItinAct(w http.ResponseWriter, r *http.Request) {
 				tx.Rollback(ctx)
 				return
 			}
+
+			member, _ := m.GetMemberByCode(db, ctx, h.GetUserCode(r.Context()))
+			_, err = m.AddItinSugEvent(tx, ctx, model.ItinSugEventEnt{
+				ItinSugID: s.ID,
+				MemberID:  sql.NullInt32{Int32: member.ID, Valid: member.ID > 0},
+				EventType: model.ITIN_SUG_EVENT_VIEW,
+			})
+			if err != nil {
+				h.SendBadRequest(w, err.Error())
+				tx.Rollback(ctx)
+				return
+			}
 		}
 
 		// Commit transaction
@@ -94,7 +107,7 @@ func (h *Contract) AddSugItinAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -194,6 +207,7 @@ func (h *Contract) AddSugItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_SUG_ITIN, sugItin.ItinCode, audit.ActionCreate, nil, sugItin)
 
 	var res response.DetailItinSugResponse
 	res = res.Transform(sugItin)
@@ -223,7 +237,7 @@ func (h *Contract) UpdateSugItinAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -308,6 +322,7 @@ func (h *Contract) UpdateSugItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_SUG_ITIN, code, audit.ActionUpdate, sug, sugItin)
 
 	var res response.DetailItinSugResponse
 	res = res.Transform(sugItin)
@@ -319,7 +334,7 @@ func (h *Contract) DelSugItinAct(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -344,6 +359,7 @@ func (h *Contract) DelSugItinAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := m.GetSugItinByCode(db, ctx, code)
 	err = m.DelSugItin(tx, ctx, code)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -373,6 +389,7 @@ func (h *Contract) DelSugItinAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_SUG_ITIN, code, audit.ActionDelete, before, nil)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -462,7 +479,7 @@ func (h *Contract) GetItinSugList(w http.ResponseWriter, r *http.Request) {
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
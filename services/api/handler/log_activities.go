@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"panorama/lib/export"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// GetListLogActivityAct activity logs of admin, tc & customer (admin), also exported with format csv / xlsx
+func (h *Contract) GetListLogActivityAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"keyword":    "",
+		"page":       1,
+		"limit":      10,
+		"offset":     0,
+		"sort":       "desc",
+		"role":       "",
+		"code":       "",
+		"event_type": "",
+		"start_date": "",
+		"end_date":   "",
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if sort, ok := r.URL.Query()["sort"]; ok && len(sort[0]) > 0 && strings.ToLower(sort[0]) == "asc" {
+		param["sort"] = "asc"
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	for _, key := range []string{"keyword", "role", "code", "event_type"} {
+		if v, ok := r.URL.Query()[key]; ok && len(v[0]) > 0 {
+			param[key] = v[0]
+		}
+	}
+
+	for _, key := range []string{"start_date", "end_date"} {
+		if v, ok := r.URL.Query()[key]; ok && len(v[0]) > 0 {
+			if _, err := time.Parse("2006-01-02", v[0]); err != nil {
+				h.SendBadRequest(w, err.Error())
+				return
+			}
+			param[key] = v[0]
+		}
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "activity-logs",
+			Row:  response.LogActivityResponse{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountListLogActivityUser(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportListLogActivityUser(db, ctx, param, func(a model.LogActivityUserEnt) error {
+					var res response.LogActivityResponse
+					return enc.Encode(res.Transform(a))
+				})
+			},
+		})
+		return
+	}
+
+	logs, err := m.GetListLogActivityUser(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.LogActivityResponse{}
+	for _, a := range logs {
+		var res response.LogActivityResponse
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
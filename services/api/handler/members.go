@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
+	"panorama/lib/export"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // AddMemberAct ...
@@ -36,7 +39,7 @@ func (h *Contract) AddMemberAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -86,6 +89,7 @@ func (h *Contract) AddMemberAct(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER, member.MemberCode, audit.ActionCreate, nil, member)
 
 	h.SendSuccess(w, res, nil)
 }
@@ -98,7 +102,7 @@ func (h *Contract) GetMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -127,7 +131,7 @@ func (h *Contract) GetMemberStatistik(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -197,7 +201,7 @@ func (h *Contract) GetMemberList(w http.ResponseWriter, r *http.Request) {
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -207,6 +211,23 @@ func (h *Contract) GetMemberList(w http.ResponseWriter, r *http.Request) {
 	defer db.Release()
 
 	m := model.Contract{App: h.App}
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "members",
+			Row:  response.MemberResponse{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountListMember(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportListMember(db, ctx, param, func(a model.MemberEnt) error {
+					var res response.MemberResponse
+					return enc.Encode(res.Transform(a))
+				})
+			},
+		})
+		return
+	}
+
 	members, err := m.GetListMember(db, ctx, param)
 	if err != nil && sql.ErrNoRows != nil {
 		h.SendBadRequest(w, err.Error())
@@ -240,7 +261,7 @@ func (h *Contract) UpdateMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -265,6 +286,7 @@ func (h *Contract) UpdateMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := data
 	data = req.Transform(data)
 
 	// Update member
@@ -303,6 +325,7 @@ func (h *Contract) UpdateMember(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER, mcode, audit.ActionUpdate, before, member)
 
 	h.SendSuccess(w, res, nil)
 }
@@ -321,7 +344,7 @@ func (h *Contract) UpdateMemberPassTokenAct(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -368,7 +391,7 @@ func (h *Contract) UpdateMemberPassAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -394,6 +417,7 @@ func (h *Contract) UpdateMemberPassAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	before, _ := m.GetMemberByCode(db, ctx, code)
 	err = m.UpdatePassword(tx, ctx, h.GetChannel(r), code, req.Password)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -408,6 +432,8 @@ func (h *Contract) UpdateMemberPassAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	after, _ := m.GetMemberByCode(db, ctx, code)
+	h.audit(r, db, model.AUDIT_MEMBER, code, audit.ActionUpdate, before, after)
 
 	h.SendSuccess(w, response, nil)
 }
@@ -435,7 +461,7 @@ func (h *Contract) UpdateMemberPhoneAct(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -463,6 +489,7 @@ func (h *Contract) UpdateMemberPhoneAct(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before := member
 	phoneUpdated, err := m.UpdatePhoneMember(tx, ctx, req.Phone, code)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -482,6 +509,7 @@ func (h *Contract) UpdateMemberPhoneAct(w http.ResponseWriter, r *http.Request)
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER, code, audit.ActionUpdate, before, member)
 
 	h.SendSuccess(w, response, nil)
 }
@@ -495,7 +523,7 @@ func (h *Contract) DeleteMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -516,6 +544,7 @@ func (h *Contract) DeleteMember(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	before := data
 	data.IsActive = false
 
 	// edit is active to false
@@ -565,6 +594,7 @@ func (h *Contract) DeleteMember(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER, code, audit.ActionDelete, before, data)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -578,7 +608,7 @@ func (h *Contract) ForceDeleteMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -599,6 +629,7 @@ func (h *Contract) ForceDeleteMember(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	before := data
 	data.IsActive = false
 
 	// force Deleted Member
@@ -648,6 +679,7 @@ func (h *Contract) ForceDeleteMember(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_MEMBER, code, audit.ActionDelete, before, nil)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -70,7 +69,7 @@ func (h *Contract) GetListNotifAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -100,7 +99,7 @@ func (h *Contract) GetNotifAct(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -159,7 +158,7 @@ func (h *Contract) GetCounterNotifAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -187,7 +186,7 @@ func (h *Contract) UpdateIsReadNotification(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -236,7 +235,7 @@ func (h *Contract) DeleteNotificationAct(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -294,7 +293,7 @@ func (h *Contract) DeleteNotificationAct(w http.ResponseWriter, r *http.Request)
 func (h *Contract) DeleteAllNotificationAct(w http.ResponseWriter, r *http.Request) {
 	var err error
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
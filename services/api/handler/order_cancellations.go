@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"panorama/lib/audit"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// isOrderCancellable order that can be cancelled by the customer, the trip in progress can not be cancelled
+func isOrderCancellable(status string) bool {
+	return status == model.ORDER_STATUS_PENDING || status == model.ORDER_STATUS_PARTIAL || status == model.ORDER_STATUS_PAID
+}
+
+// GetOrderCancellationAct preview fee of the cancellation, the saved refund is shown for the cancelled order
+func (h *Contract) GetOrderCancellationAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	var res response.OrderCancellationRes
+
+	refund, _ := m.GetOrderRefundByOrderID(db, ctx, order.ID)
+	if refund.ID != 0 {
+		var policy model.CancellationPolicyEnt
+		if refund.PolicyID.Valid {
+			policy, _ = m.GetCancellationPolicyByID(db, ctx, refund.PolicyID.Int32)
+		}
+		h.SendSuccess(w, res.Transform(order, policy, refund), nil)
+		return
+	}
+
+	if !isOrderCancellable(order.OrderStatus) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is %s and can not be cancelled.", order.OrderCode, model.OrderStatusDesc(order.OrderStatus)))
+		return
+	}
+
+	policy, refund, err := m.GetOrderCancellation(db, ctx, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	h.SendSuccess(w, res.Transform(order, policy, refund), nil)
+}
+
+// CancelOrderAct cancel order by the payer, the fee of cancellation policy is taken from the paid amount
+// and the rest is saved as the pending refund
+func (h *Contract) CancelOrderAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	req := request.CancelOrderReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	before := order
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if actor.Role != "customer" || !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if !isOrderCancellable(order.OrderStatus) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is %s and can not be cancelled.", order.OrderCode, model.OrderStatusDesc(order.OrderStatus)))
+		return
+	}
+
+	pending, err := m.HasPendingPayment(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if pending {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s has payment in progress and can not be cancelled until it is paid or expired.", order.OrderCode))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	transition, policy, refund, err := m.CancelOrder(tx, db, ctx, order, model.OrderStatusActor{
+		Type: model.ORDER_ACTOR_MEMBER,
+		ID:   sql.NullInt32{Int32: actor.ID, Valid: true},
+		Name: actor.Name,
+	}, req.Reason)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+	if transition.History.ID == 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is already cancelled.", order.OrderCode))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Send Notifications - To User (TC of order and Admin)
+	payment, _ := m.GetPaymentOrderByOrderID(db, ctx, order.ID)
+	paymentMethod := payment.PaymentType
+	if len(paymentMethod) == 0 {
+		paymentMethod = "order"
+	}
+	notifContentUser := model.NotificationContent{
+		Subject:       model.NOTIF_SUBJ_ORDER_CANCEL,
+		TripName:      order.MemberItin.Title,
+		OrderCode:     order.OrderCode,
+		PaymentMethod: paymentMethod,
+	}
+
+	userPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, "", "admin")
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if len(order.UserEnt.UserCode) > 0 && order.UserEnt.Role != "admin" {
+		tcPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, order.UserEnt.UserCode, "tc")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		userPlayers = append(userPlayers, tcPlayers...)
+	}
+	_, err = m.SendNotifications(tx, db, ctx, userPlayers, notifContentUser)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.OrderCancellationRes
+	order.OrderStatus = transition.Order.OrderStatus
+	h.audit(r, db, model.AUDIT_ORDER, code, audit.ActionUpdate, before, order)
+	h.SendSuccess(w, res.Transform(order, policy, refund), nil)
+}
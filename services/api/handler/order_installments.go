@@ -0,0 +1,571 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"panorama/lib/metrics"
+	"panorama/lib/payment"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// isOrderPayable order that still waiting for payment, full or partially paid
+func isOrderPayable(order model.OrderEnt) bool {
+	return order.OrderStatus == model.ORDER_STATUS_PENDING || order.OrderStatus == model.ORDER_STATUS_PARTIAL
+}
+
+// GetOrderInstallmentsAct payment schedule and outstanding balance of order
+func (h *Contract) GetOrderInstallmentsAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	order.Installments, err = m.GetListOrderInstallmentByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.OrderInstallmentsRes
+	h.SendSuccess(w, res.Transform(order), nil)
+}
+
+// AddOrderInstallmentsAct create payment schedule of order, deposit first then the balance before the trip start
+func (h *Contract) AddOrderInstallmentsAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	req := request.OrderInstallmentPlanReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if order.OrderStatus != model.ORDER_STATUS_PENDING {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	orderPayment, _ := m.GetPaymentOrderByOrderID(db, ctx, order.ID)
+	if orderPayment.PaymentStatus == model.PAYMENT_STATUS_PAID || orderPayment.IsPending() {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is in payment process.", code))
+		return
+	}
+
+	// split order and payment schedule can not be combined
+	shares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if len(shares) > 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is split among companions.", code))
+		return
+	}
+
+	// the schedule can not be changed when any installment is paid or waiting for payment
+	currentInstallments, err := m.GetListOrderInstallmentByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	for _, a := range currentInstallments {
+		if a.PaymentStatus == model.PAYMENT_STATUS_PAID || a.IsPending() {
+			h.SendBadRequest(w, fmt.Sprintf("%s is in payment process, payment schedule of order %s can not be changed.", a.Title, code))
+			return
+		}
+	}
+
+	if order.MemberItin.StartDate.IsZero() {
+		h.SendBadRequest(w, fmt.Sprintf("Start date of trip %s is required for payment schedule.", order.MemberItin.Title))
+		return
+	}
+
+	var totalPercent int
+	for _, a := range req.Installments {
+		totalPercent += a.Percent
+	}
+	if totalPercent != 100 {
+		h.SendBadRequest(w, fmt.Sprintf("Total percent of installments must be 100, got %d.", totalPercent))
+		return
+	}
+
+	// first installment is the deposit, the remaining amount is charged into the last installment
+	totalAmount := orderTotalAmount(order)
+	installments := []model.OrderPaymentEnt{}
+	var scheduledAmount int64
+	for i, a := range req.Installments {
+		dueDate := m.InstallmentDepositDueDate()
+		if i > 0 {
+			dueDate = order.MemberItin.StartDate.AddDate(0, 0, -a.DueDaysBeforeStart)
+
+			prev := installments[i-1]
+			if dueDate.Before(prev.DueDate.Time) {
+				h.SendBadRequest(w, fmt.Sprintf("Due date of %s must be after due date of %s.", a.Title, prev.Title))
+				return
+			}
+		}
+
+		amount := totalAmount * int64(a.Percent) / 100
+		if i == len(req.Installments)-1 {
+			amount = totalAmount - scheduledAmount
+		}
+		scheduledAmount += amount
+
+		installments = append(installments, model.OrderPaymentEnt{
+			OrderID:       order.ID,
+			InstallmentNo: int32(i + 1),
+			Title:         a.Title,
+			Amount:        amount,
+			DueDate:       sql.NullTime{Time: dueDate, Valid: true},
+		})
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteOrderInstallmentByOrderID(tx, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	for i, a := range installments {
+		installments[i], err = m.AddOrderInstallment(tx, ctx, a)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// order payment keep the summary of all installments
+	if orderPayment.ID == 0 {
+		_, err = m.AddOrderPayment(db, ctx, tx, model.OrderPaymentEnt{
+			OrderID:       order.ID,
+			Amount:        totalAmount,
+			PaymentStatus: model.PAYMENT_STATUS_PROCESS,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Order Payment Schedule",
+		Activity:  fmt.Sprintf("Create %d Installments For Order %s", len(installments), order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Send Notifications - To Member (Customer)
+	memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, order.MemberEnt.MemberCode, "customer")
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	_, err = m.SendNotifications(tx, db, ctx, memberPlayers, model.NotificationContent{
+		Subject:   model.NOTIF_SUBJ_ORDER_INCOME,
+		TripName:  order.MemberItin.Title,
+		OrderCode: order.OrderCode,
+	})
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	order.Installments = installments
+
+	var res response.OrderInstallmentsRes
+	h.SendSuccess(w, res.Transform(order), nil)
+}
+
+// PayOrderInstallmentAct get snap payment of the next unpaid installment, only for the payer of order
+func (h *Contract) PayOrderInstallmentAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+	if !isOrderPayable(order) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if actor.Role != "customer" || actor.ID != order.PaidBy {
+		h.SendNotfound(w, fmt.Sprintf("Order paid by %s is invalid.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	installments, err := m.GetListOrderInstallmentByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	// installment is paid in order of the schedule
+	var installment model.OrderPaymentEnt
+	for _, a := range installments {
+		if a.PaymentStatus != model.PAYMENT_STATUS_PAID {
+			installment = a
+			break
+		}
+	}
+	if installment.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Unpaid installment of order %s not found.", code))
+		return
+	}
+	if installment.IsPending() {
+		h.SendBadRequest(w, fmt.Sprintf("%s is waiting for payment, please complete the payment.", installment.Title))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.OrderInstallmentPaymentRes
+	res.PaymentCode = m.SetPaymentTransactionCode(fmt.Sprintf("%s-I%d", order.OrderCode, installment.InstallmentNo))
+
+	paymentService := payment.New(h.App)
+	paramMidtrans := paymentService.SetMidtransParam(actor.Email, actor.Name, res.PaymentCode, installment.Amount)
+	midtransResponse, err := paymentService.GetMidtransPaymentURL(ctx, paramMidtrans)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if midtransResponse["redirect_url"] == "" {
+		h.SendBadRequest(w, "failed to get snap url midtrans.")
+		tx.Rollback(ctx)
+		return
+	}
+	res.Token = midtransResponse["token"]
+	res.PaymentURL = midtransResponse["redirect_url"]
+
+	err = m.UpdateOrderInstallmentTransaction(tx, ctx, installment.ID, res.PaymentCode, res.PaymentURL)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Pay Order Installment",
+		Activity:  fmt.Sprintf("Pay %s Of Order %s", installment.Title, order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	installment.PaymentURL = res.PaymentURL
+	installment.PaymentStatus = model.PAYMENT_STATUS_PROCESS
+	installment.PaymentType = ""
+	res.Installment = res.Installment.Transform(installment)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// midtransOrderInstallmentNotification update installment of the midtrans transaction, the order partially paid until all installments are paid
+func (h *Contract) midtransOrderInstallmentNotification(w http.ResponseWriter, ctx context.Context, db *pgxpool.Conn, tx pgx.Tx, m model.Contract, req request.OrderPaymentMidtransNotification, installment model.OrderPaymentEnt) {
+	order, _ := m.GetOrderByOrderCode(db, ctx, installment.OrderCode)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", installment.OrderCode))
+		tx.Rollback(ctx)
+		return
+	}
+
+	paymentService := payment.New(h.App)
+	midtransStatus := paymentService.GetMidtransStatus(req.PaymentType, req.TransactionStatus, req.FraudStatus)
+	paymentStatus := midtransStatus["payment_status"]
+
+	// the paid amount must match the amount of installment
+	if paymentStatus == model.PAYMENT_STATUS_PAID {
+		grossAmount, err := model.ParseMidtransAmount(req.GrossAmount)
+		if err != nil || grossAmount != installment.Amount {
+			h.SendBadRequest(w, fmt.Sprintf("Gross amount %s of transaction %s does not match the installment amount %d.", req.GrossAmount, req.OrderID, installment.Amount))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	installmentSetter := model.OrderPaymentEnt{
+		ID:            installment.ID,
+		PaymentStatus: paymentStatus,
+		PaymentType:   req.PaymentType,
+	}
+	if req.TransactionStatus == payment.MIDTRANS_TRANSACTION_STATUS_PENDING {
+		installmentSetter.ExpiredDate = time.Now().Add(time.Minute * payment.DURATION_EXPIRED).In(time.UTC)
+	}
+
+	// Assign payload from midtrans webhook notification
+	encode, _ := json.Marshal(req)
+	resArray := make(map[string]interface{})
+	err := json.Unmarshal(encode, &resArray)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		tx.Rollback(ctx)
+		return
+	}
+	installmentSetter.Payloads = resArray
+
+	err = m.UpdateOrderInstallmentStatus(tx, ctx, installmentSetter)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// the installment that is settled after the order is cancelled is refunded
+	if paymentStatus == model.PAYMENT_STATUS_PAID && installment.PaymentStatus != model.PAYMENT_STATUS_PAID && order.OrderStatus == model.ORDER_STATUS_CANCEL {
+		err = m.TopUpOrderRefund(tx, ctx, order.ID, installment.Amount)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// order is completed when all installments are paid, otherwise partially paid
+	var paymentStatusDesc string
+	if paymentStatus == model.PAYMENT_STATUS_PAID && isOrderPayable(order) {
+		unpaid, err := m.CountUnpaidOrderInstallment(tx, ctx, order.ID)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		orderStatus := model.ORDER_STATUS_PARTIAL
+		paymentStatusDesc = model.PAYMENT_STATUS_PARTIAL_DESC
+		if unpaid == 0 {
+			orderStatus = model.ORDER_STATUS_PAID
+			paymentStatusDesc = model.PAYMENT_STATUS_PAID_DESC
+		}
+
+		_, err = m.TransitionOrderStatus(tx, db, ctx, order.ID, orderStatus, midtransActor, fmt.Sprintf("Installment %s is paid", req.OrderID))
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		if unpaid == 0 {
+			err = m.UpdateOrderPaymentStatus(tx, ctx, order.ID, model.PAYMENT_STATUS_PAID)
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+
+			_, err = m.IssueOrderInvoices(tx, ctx, order.ID)
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+		}
+	}
+
+	// Send Notifications - To Member (Customer)
+	if paymentStatus != model.PAYMENT_STATUS_PROCESS {
+		subjectCust := model.NOTIF_SUBJ_ORDER_VERIF
+		if paymentStatus == model.PAYMENT_STATUS_CANCEL {
+			subjectCust = model.NOTIF_SUBJ_ORDER_FAIL
+		}
+
+		memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, order.MemberEnt.MemberCode, "customer")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, memberPlayers, model.NotificationContent{
+			Subject:       subjectCust,
+			TripName:      order.MemberItin.Title,
+			OrderCode:     order.OrderCode,
+			PaymentMethod: req.PaymentType,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	if paymentStatusDesc == model.PAYMENT_STATUS_PAID_DESC {
+		// Send Notifications - To User (TC)
+		tcPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, order.UserEnt.UserCode, "tc")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, tcPlayers, model.NotificationContent{
+			Subject:    model.NOTIF_SUBJ_ORDER_CLIENT_COMPLETE,
+			RoomName:   order.MemberItin.Title,
+			ClientName: order.MemberEnt.Name,
+			OrderCode:  order.OrderCode,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// Send Notifications - To User (Admin, TC)
+	if len(paymentStatusDesc) > 0 {
+		userPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, "", "")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, userPlayers, model.NotificationContent{
+			Subject:       model.NOTIF_SUBJ_ORDER_HISTORY,
+			TripName:      order.MemberItin.Title,
+			StatusPayment: paymentStatusDesc,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	if paymentStatus == model.PAYMENT_STATUS_PAID && installment.PaymentStatus != model.PAYMENT_STATUS_PAID {
+		metrics.PaymentSettled(req.PaymentType)
+	}
+
+	if paymentStatusDesc == model.PAYMENT_STATUS_PAID_DESC {
+		go m.PublishOrderInvoices(ctx, order.ID)
+	}
+
+	h.SendSuccess(w, req, nil)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"panorama/lib/upload"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// invoiceURLTTL lifetime of the presigned url of the invoice pdf
+const invoiceURLTTL = 5 * time.Minute
+
+// GetOrderInvoiceAct tax invoice or payment receipt (?type=receipt) of the paid order,
+// the pdf is returned as attachment with ?download=true
+func (h *Contract) GetOrderInvoiceAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	docType := model.INVOICE_DOC_INVOICE
+	if t, ok := r.URL.Query()["type"]; ok && t[0] == model.INVOICE_DOC_RECEIPT {
+		docType = model.INVOICE_DOC_RECEIPT
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	invoices, err := m.GetListOrderInvoiceByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var invoice model.OrderInvoiceEnt
+	for _, a := range invoices {
+		if a.DocType == docType {
+			invoice = a
+		}
+	}
+	if invoice.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("The %s of order %s is not issued yet.", docType, code))
+		return
+	}
+
+	// the pdf that failed to be stored after payment is stored again
+	var file []byte
+	if len(invoice.FilePath) == 0 {
+		invoice, file, err = m.StoreOrderInvoice(db, ctx, invoice)
+		if err != nil && len(file) == 0 {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	if d, ok := r.URL.Query()["download"]; ok && d[0] == "true" {
+		if len(file) == 0 {
+			file, err = m.RenderOrderInvoice(db, ctx, invoice)
+			if err != nil {
+				h.SendBadRequest(w, err.Error())
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", upload.ContentTypePDF)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.Filename()))
+		w.Header().Set("Content-Length", strconv.Itoa(len(file)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(file)
+		return
+	}
+
+	// the pdf is private, the link expires after invoice.url_ttl seconds
+	var fileURL string
+	if len(invoice.FilePath) > 0 {
+		ttl := invoiceURLTTL
+		if s := h.Config.GetInt("invoice.url_ttl"); s > 0 {
+			ttl = time.Duration(s) * time.Second
+		}
+
+		fileURL, err = h.Storage.Presign(invoice.FilePath, ttl)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	var res response.OrderInvoiceRes
+	h.SendSuccess(w, res.Transform(invoice, fileURL), nil)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// orderItems build line items of order from request, price of stuff item is taken from the catalog.
+// Foreign price is converted into rupiah with the rate that is locked by the order, the currency
+// that is not locked yet takes the current rate and is returned with the locked rates
+func orderItems(db *pgxpool.Conn, ctx context.Context, m model.Contract, req []request.OrderItemReq, rates []model.ExchangeRateEnt) ([]model.OrderItemEnt, []model.ExchangeRateEnt, error) {
+	items := []model.OrderItemEnt{}
+
+	for _, i := range req {
+		item := model.OrderItemEnt{
+			ItemType:    i.ItemType,
+			Name:        i.Name,
+			Description: i.Description,
+			Quantity:    i.Quantity,
+			UnitPrice:   i.UnitPrice,
+			IsTaxable:   true,
+			Currency:    strings.ToUpper(i.Currency),
+		}
+		if i.IsTaxable != nil {
+			item.IsTaxable = *i.IsTaxable
+		}
+
+		if i.ItemType == model.ORDER_ITEM_STUFF {
+			stuff, price, err := m.GetStuffPrice(db, ctx, i.StuffCode)
+			if err != nil {
+				return items, rates, fmt.Errorf("Stuff %s not found.", i.StuffCode)
+			}
+			item.RefCode = stuff.Code
+			item.Name = stuff.Name
+			item.Currency = price.Currency
+			item.UnitPrice = price.Amount
+			if len(item.Description) == 0 {
+				item.Description = stuff.Description
+			}
+		}
+
+		if len(item.Currency) == 0 {
+			item.Currency = model.BASE_CURRENCY
+		}
+
+		var err error
+		item.ExchangeRate, rates, err = orderExchangeRate(db, ctx, m, item.Currency, rates)
+		if err != nil {
+			return items, rates, err
+		}
+		item.SourceUnitPrice = item.UnitPrice
+		item.UnitPrice = item.ExchangeRate.ToIDR(item.SourceUnitPrice)
+
+		item.Subtotal = int64(item.Quantity) * item.UnitPrice
+		if i.ItemType == model.ORDER_ITEM_DISCOUNT {
+			item.Subtotal = -item.Subtotal
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rates, nil
+}
+
+// isOrderRepriceable items of draft or pending order can be changed until the payment is paid or in process,
+// the invoice of the paid order is issued with the items
+func isOrderRepriceable(order model.OrderEnt, orderPayment model.OrderPaymentEnt) bool {
+	if order.OrderStatus != model.ORDER_STATUS_DRAFT && order.OrderStatus != model.ORDER_STATUS_PENDING {
+		return false
+	}
+
+	return orderPayment.PaymentStatus != model.PAYMENT_STATUS_PAID && !orderPayment.IsPending()
+}
+
+// orderExchangeRate rate of the currency that is locked by the order, or the current rate
+// when the currency is not used yet by the order
+func orderExchangeRate(db *pgxpool.Conn, ctx context.Context, m model.Contract, currency string, rates []model.ExchangeRateEnt) (model.ExchangeRateEnt, []model.ExchangeRateEnt, error) {
+	if currency == model.BASE_CURRENCY {
+		return model.ExchangeRateEnt{CurrencyCode: model.BASE_CURRENCY, Rate: 1}, rates, nil
+	}
+
+	for _, a := range rates {
+		if a.CurrencyCode == currency {
+			return a, rates, nil
+		}
+	}
+
+	rate, err := m.GetExchangeRate(db, ctx, currency, time.Now().In(time.UTC))
+	if err != nil {
+		return rate, rates, fmt.Errorf("Exchange rate of %s is not available.", currency)
+	}
+
+	return rate, append(rates, rate), nil
+}
+
+// orderPricing compute total of order items with pricing rule from settings
+func orderPricing(db *pgxpool.Conn, ctx context.Context, m model.Contract, items []model.OrderItemEnt) (model.OrderPricing, model.OrderTotal, error) {
+	p, err := m.GetOrderPricing(db, ctx)
+	if err != nil {
+		return p, model.OrderTotal{}, err
+	}
+
+	t := p.Calculate(items)
+	if t.Subtotal < 0 {
+		return p, t, fmt.Errorf("Discount of order is greater than the order amount.")
+	}
+
+	return p, t, nil
+}
@@ -0,0 +1,849 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"panorama/lib/metrics"
+	"panorama/lib/payment"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// orderActor customer, tc or admin that doing the order action
+type orderActor struct {
+	ID        int32
+	Name      string
+	Email     string
+	Role      string
+	IsManager bool // organizer (paid by member) or tc of the order
+}
+
+// getOrderActor get the login user of the order
+func (h *Contract) getOrderActor(db *pgxpool.Conn, ctx context.Context, r *http.Request, m model.Contract, order model.OrderEnt) (orderActor, error) {
+	code := h.GetUserCode(r.Context())
+	actor := orderActor{Role: h.GetUserRole(r.Context())}
+
+	switch actor.Role {
+	case "customer":
+		member, err := m.GetMemberByCode(db, ctx, code)
+		if member.ID == 0 {
+			return actor, fmt.Errorf("Customer %s not found.", code)
+		}
+		if err != nil {
+			return actor, err
+		}
+		actor.ID = member.ID
+		actor.Name = member.Name
+		actor.Email = member.Email
+		actor.IsManager = member.ID == order.PaidBy
+	case "tc", "admin":
+		user, err := m.GetUserByCode(db, ctx, code)
+		if user.ID == 0 {
+			return actor, fmt.Errorf("User %s not found.", code)
+		}
+		if err != nil {
+			return actor, err
+		}
+		actor.ID = user.ID
+		actor.Name = user.Name
+		actor.Email = user.Email
+		actor.IsManager = actor.Role == "admin" || user.ID == order.TcID
+	default:
+		return actor, fmt.Errorf("Access denied for this order")
+	}
+
+	return actor, nil
+}
+
+// orderTotalAmount amount of order that must be paid, include ppn
+func orderTotalAmount(order model.OrderEnt) int64 {
+	if order.TotalPricePpn > 0 {
+		return order.TotalPricePpn
+	}
+
+	return order.TotalPrice
+}
+
+// payOrderShares create one midtrans snap transaction for the shares
+func (h *Contract) payOrderShares(tx pgx.Tx, ctx context.Context, m model.Contract, actor orderActor, shares []model.OrderPaymentShareEnt, prefix string, coveredBy sql.NullInt32) (response.OrderSharePaymentRes, error) {
+	var res response.OrderSharePaymentRes
+	var ids []int32
+
+	for _, s := range shares {
+		res.Amount += s.Amount
+		ids = append(ids, s.ID)
+	}
+	res.TransactionCode = m.SetPaymentTransactionCode(prefix)
+
+	paymentService := payment.New(h.App)
+	paramMidtrans := paymentService.SetMidtransParam(actor.Email, actor.Name, res.TransactionCode, res.Amount)
+	midtransResponse, err := paymentService.GetMidtransPaymentURL(ctx, paramMidtrans)
+	if err != nil {
+		return res, err
+	}
+	if midtransResponse["redirect_url"] == "" {
+		return res, fmt.Errorf("failed to get snap url midtrans.")
+	}
+	res.Token = midtransResponse["token"]
+	res.PaymentURL = midtransResponse["redirect_url"]
+
+	err = m.UpdateOrderPaymentShareTransaction(tx, ctx, ids, res.TransactionCode, res.PaymentURL, coveredBy)
+	if err != nil {
+		return res, err
+	}
+
+	res.Shares = []response.OrderShareRes{}
+	for _, s := range shares {
+		var shareRes response.OrderShareRes
+		s.TransactionCode = res.TransactionCode
+		s.PaymentURL = res.PaymentURL
+		s.PaymentStatus = model.PAYMENT_STATUS_PROCESS
+		s.PaymentType = ""
+		s.ExpiredDate = sql.NullTime{}
+		if coveredBy.Valid {
+			s.CoveredBy = coveredBy
+			s.CoveredByName = actor.Name
+		}
+		res.Shares = append(res.Shares, shareRes.Transform(s))
+	}
+
+	return res, nil
+}
+
+// GetOrderSharesAct list share of split order
+func (h *Contract) GetOrderSharesAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	shares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	// besides organizer and tc, only companion that has share can see the shares
+	if !actor.IsManager {
+		isMember := false
+		for _, s := range shares {
+			if s.MemberID == actor.ID {
+				isMember = true
+				break
+			}
+		}
+		if actor.Role != "customer" || !isMember {
+			h.SendUnAuthorizedData(w)
+			return
+		}
+	}
+
+	var res response.OrderSharesRes
+	h.SendSuccess(w, res.Transform(order, shares), nil)
+}
+
+// SplitOrderAct split order payment among the companions of the trip, equal or custom share
+func (h *Contract) SplitOrderAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	req := request.OrderSplitReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if order.OrderStatus != model.ORDER_STATUS_PENDING {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	orderPayment, _ := m.GetPaymentOrderByOrderID(db, ctx, order.ID)
+	if orderPayment.PaymentStatus == model.PAYMENT_STATUS_PAID {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s has been paid.", code))
+		return
+	}
+	if orderPayment.IsPending() {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is in payment process, it can not be split.", code))
+		return
+	}
+
+	// the order can not split again when any share is paid or waiting for payment
+	currentShares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	for _, s := range currentShares {
+		if s.PaymentStatus == model.PAYMENT_STATUS_PAID || s.IsPending() {
+			h.SendBadRequest(w, fmt.Sprintf("Share of %s is in payment process, order %s can not be split again.", s.Member.Name, code))
+			return
+		}
+	}
+
+	// split order and payment schedule can not be combined
+	installments, err := m.GetListOrderInstallmentByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if len(installments) > 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s has payment schedule.", code))
+		return
+	}
+
+	// share only for companion of the trip
+	totalAmount := orderTotalAmount(order)
+	organizerIdx := 0
+	shares := []model.OrderPaymentShareEnt{}
+	var customAmount int64
+	for i, s := range req.Shares {
+		member, _ := m.GetMemberByCode(db, ctx, s.MemberCode)
+		if member.ID == 0 {
+			h.SendNotfound(w, fmt.Sprintf("Member %s not found.", s.MemberCode))
+			return
+		}
+
+		for _, share := range shares {
+			if share.MemberID == member.ID {
+				h.SendBadRequest(w, fmt.Sprintf("Member %s has more than one share.", s.MemberCode))
+				return
+			}
+		}
+
+		if member.ID != order.PaidBy {
+			role, err := m.GetMemberItinRole(db, ctx, order.MemberItinID, member.ID)
+			if err != nil {
+				h.SendBadRequest(w, err.Error())
+				return
+			}
+			if role == "" {
+				h.SendBadRequest(w, fmt.Sprintf("Member %s is not companion of this trip.", s.MemberCode))
+				return
+			}
+		} else {
+			organizerIdx = i
+		}
+
+		if req.SplitType == model.ORDER_SPLIT_CUSTOM && s.Amount <= 0 {
+			h.SendBadRequest(w, fmt.Sprintf("Amount share of member %s must be greater than 0.", s.MemberCode))
+			return
+		}
+		customAmount += s.Amount
+
+		shares = append(shares, model.OrderPaymentShareEnt{
+			OrderID:  order.ID,
+			MemberID: member.ID,
+			Amount:   s.Amount,
+		})
+	}
+
+	if req.SplitType == model.ORDER_SPLIT_EQUAL {
+		// remaining of equal share is charged into the organizer
+		amount := totalAmount / int64(len(shares))
+		for i := range shares {
+			shares[i].Amount = amount
+		}
+		shares[organizerIdx].Amount += totalAmount - amount*int64(len(shares))
+	} else if customAmount != totalAmount {
+		h.SendBadRequest(w, fmt.Sprintf("Total share %d does not match with order amount %d.", customAmount, totalAmount))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteOrderPaymentShareByOrderID(tx, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	for _, s := range shares {
+		s.ShareCode = m.SetOrderShareCode()
+		_, err = m.AddOrderPaymentShare(tx, ctx, s)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// order payment keep the summary of all shares
+	if orderPayment.ID == 0 {
+		_, err = m.AddOrderPayment(db, ctx, tx, model.OrderPaymentEnt{
+			OrderID:       order.ID,
+			Amount:        totalAmount,
+			PaymentStatus: model.PAYMENT_STATUS_PROCESS,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Split Order",
+		Activity:  fmt.Sprintf("Split Order Payment %s Into %d Shares", order.OrderCode, len(shares)),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	list, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var res response.OrderSharesRes
+	h.SendSuccess(w, res.Transform(order, list), nil)
+}
+
+// PayOrderShareAct get snap payment of the login customer share
+func (h *Contract) PayOrderShareAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+	if !isOrderPayable(order) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if actor.Role != "customer" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	share, _ := m.GetOrderPaymentShareByMemberID(db, ctx, order.ID, actor.ID)
+	if share.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Share of order %s not found.", code))
+		return
+	}
+	if share.PaymentStatus == model.PAYMENT_STATUS_PAID {
+		h.SendBadRequest(w, fmt.Sprintf("Share %s has been paid.", share.ShareCode))
+		return
+	}
+	if share.IsPending() {
+		h.SendBadRequest(w, fmt.Sprintf("Share %s is waiting for payment, please complete the payment.", share.ShareCode))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	res, err := h.payOrderShares(tx, ctx, m, actor, []model.OrderPaymentShareEnt{share}, share.ShareCode, share.CoveredBy)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Pay Order Share",
+		Activity:  fmt.Sprintf("Pay Share %s Of Order %s", share.ShareCode, order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, res, nil)
+}
+
+// CoverOrderSharesAct organizer pay all remaining shares in one payment
+func (h *Contract) CoverOrderSharesAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+	if !isOrderPayable(order) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if actor.Role != "customer" || !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	list, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	// share that is waiting for the companion payment is not covered
+	shares := []model.OrderPaymentShareEnt{}
+	for _, s := range list {
+		if s.PaymentStatus != model.PAYMENT_STATUS_PAID && !s.IsPending() {
+			shares = append(shares, s)
+		}
+	}
+	if len(shares) == 0 {
+		h.SendBadRequest(w, fmt.Sprintf("No remaining share of order %s to be covered.", code))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	coveredBy := sql.NullInt32{Int32: actor.ID, Valid: true}
+	res, err := h.payOrderShares(tx, ctx, m, actor, shares, order.OrderCode+"-CVR", coveredBy)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Cover Order Share",
+		Activity:  fmt.Sprintf("Cover %d Remaining Shares Of Order %s", len(shares), order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, res, nil)
+}
+
+// RemindOrderSharesAct send reminder notification to the companions that not paid the share yet
+func (h *Contract) RemindOrderSharesAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+	if !isOrderPayable(order) {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is not waiting for payment.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	shares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	interval := m.OrderShareReminderInterval()
+	for i, s := range shares {
+		if s.PaymentStatus == model.PAYMENT_STATUS_PAID || s.MemberID == order.PaidBy || s.CoveredBy.Valid {
+			continue
+		}
+		if s.RemindedDate.Valid && time.Since(s.RemindedDate.Time) < interval {
+			continue
+		}
+
+		memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, s.Member.MemberCode, "customer")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, memberPlayers, model.NotificationContent{
+			Subject:      model.NOTIF_SUBJ_ORDER_SHARE_REMINDER,
+			TripName:     order.MemberItin.Title,
+			OrderCode:    order.OrderCode,
+			CustomerName: order.MemberEnt.Name,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		err = m.UpdateOrderPaymentShareReminded(tx, ctx, s.ID)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+		shares[i].RemindedDate = sql.NullTime{Time: time.Now().In(time.UTC), Valid: true}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.OrderSharesRes
+	h.SendSuccess(w, res.Transform(order, shares), nil)
+}
+
+// midtransOrderShareNotification update shares of the midtrans transaction, the order completed when all shares are paid
+func (h *Contract) midtransOrderShareNotification(w http.ResponseWriter, ctx context.Context, db *pgxpool.Conn, tx pgx.Tx, m model.Contract, req request.OrderPaymentMidtransNotification, shares []model.OrderPaymentShareEnt) {
+	order, _ := m.GetOrderByOrderCode(db, ctx, shares[0].OrderCode)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", shares[0].OrderCode))
+		tx.Rollback(ctx)
+		return
+	}
+
+	paymentService := payment.New(h.App)
+	midtransStatus := paymentService.GetMidtransStatus(req.PaymentType, req.TransactionStatus, req.FraudStatus)
+	paymentStatus := midtransStatus["payment_status"]
+
+	// the paid amount must match the shares of the transaction
+	if paymentStatus == model.PAYMENT_STATUS_PAID {
+		var amount int64
+		for _, sh := range shares {
+			amount += sh.Amount
+		}
+
+		grossAmount, err := model.ParseMidtransAmount(req.GrossAmount)
+		if err != nil || grossAmount != amount {
+			h.SendBadRequest(w, fmt.Sprintf("Gross amount %s of transaction %s does not match the shares amount %d.", req.GrossAmount, req.OrderID, amount))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	shareSetter := model.OrderPaymentShareEnt{
+		PaymentStatus: paymentStatus,
+		PaymentType:   req.PaymentType,
+	}
+	if req.TransactionStatus == payment.MIDTRANS_TRANSACTION_STATUS_PENDING {
+		shareSetter.ExpiredDate = sql.NullTime{Time: time.Now().Add(time.Minute * payment.DURATION_EXPIRED).In(time.UTC), Valid: true}
+	}
+
+	// Assign payload from midtrans webhook notification
+	encode, _ := json.Marshal(req)
+	resArray := make(map[string]interface{})
+	err := json.Unmarshal(encode, &resArray)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		tx.Rollback(ctx)
+		return
+	}
+	shareSetter.Payloads = resArray
+
+	err = m.UpdateOrderPaymentShareStatus(tx, ctx, req.OrderID, shareSetter)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// the shares that are settled after the order is cancelled are refunded
+	if paymentStatus == model.PAYMENT_STATUS_PAID && order.OrderStatus == model.ORDER_STATUS_CANCEL {
+		var amount int64
+		for _, sh := range shares {
+			if sh.PaymentStatus != model.PAYMENT_STATUS_PAID {
+				amount += sh.Amount
+			}
+		}
+
+		if amount > 0 {
+			err = m.TopUpOrderRefund(tx, ctx, order.ID, amount)
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+		}
+	}
+
+	// order is completed when all shares are paid, otherwise partially paid
+	isCompleted := false
+	if paymentStatus == model.PAYMENT_STATUS_PAID && isOrderPayable(order) {
+		unpaid, err := m.CountUnpaidOrderPaymentShare(tx, ctx, order.ID)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		if unpaid > 0 {
+			_, err = m.TransitionOrderStatus(tx, db, ctx, order.ID, model.ORDER_STATUS_PARTIAL, midtransActor, fmt.Sprintf("Payment shares of %s are paid", req.OrderID))
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+		}
+
+		if unpaid == 0 {
+			isCompleted = true
+			_, err = m.TransitionOrderStatus(tx, db, ctx, order.ID, model.ORDER_STATUS_PAID, midtransActor, "All payment shares are paid")
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+
+			err = m.UpdateOrderPaymentStatus(tx, ctx, order.ID, model.PAYMENT_STATUS_PAID)
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+
+			_, err = m.IssueOrderInvoices(tx, ctx, order.ID)
+			if err != nil {
+				h.SendBadRequest(w, psql.ParseErr(err))
+				tx.Rollback(ctx)
+				return
+			}
+		}
+	}
+
+	// Send Notifications - To Member (payer of the shares)
+	if paymentStatus != model.PAYMENT_STATUS_PROCESS {
+		subjectCust := model.NOTIF_SUBJ_ORDER_VERIF
+		if paymentStatus == model.PAYMENT_STATUS_CANCEL {
+			subjectCust = model.NOTIF_SUBJ_ORDER_FAIL
+		}
+
+		payerCode := shares[0].Member.MemberCode
+		if len(shares[0].CoveredByCode) > 0 {
+			payerCode = shares[0].CoveredByCode
+		}
+		memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, payerCode, "customer")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, memberPlayers, model.NotificationContent{
+			Subject:       subjectCust,
+			TripName:      order.MemberItin.Title,
+			OrderCode:     order.OrderCode,
+			PaymentMethod: req.PaymentType,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	if isCompleted {
+		// Send Notifications - To User (TC)
+		tcPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, order.UserEnt.UserCode, "tc")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, tcPlayers, model.NotificationContent{
+			Subject:    model.NOTIF_SUBJ_ORDER_CLIENT_COMPLETE,
+			RoomName:   order.MemberItin.Title,
+			ClientName: order.MemberEnt.Name,
+			OrderCode:  order.OrderCode,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+
+		// Send Notifications - To User (Admin, TC)
+		userPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, "", "")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		_, err = m.SendNotifications(tx, db, ctx, userPlayers, model.NotificationContent{
+			Subject:       model.NOTIF_SUBJ_ORDER_HISTORY,
+			TripName:      order.MemberItin.Title,
+			StatusPayment: model.PAYMENT_STATUS_PAID_DESC,
+		})
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	if paymentStatus == model.PAYMENT_STATUS_PAID && shares[0].PaymentStatus != model.PAYMENT_STATUS_PAID {
+		metrics.PaymentSettled(req.PaymentType)
+	}
+
+	if isCompleted {
+		go m.PublishOrderInvoices(ctx, order.ID)
+	}
+
+	h.SendSuccess(w, req, nil)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"panorama/lib/audit"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// GetOrderHistoryAct status history of order
+func (h *Contract) GetOrderHistoryAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	histories, err := m.GetListOrderStatusHistory(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.OrderStatusHistoryRes{}
+	for _, a := range histories {
+		var res response.OrderStatusHistoryRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// UpdateOrderStatusAct move order into the next status (tc of order or admin)
+func (h *Contract) UpdateOrderStatusAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	req := request.OrderStatusReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if actor.Role == "customer" || !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	transition, err := m.TransitionOrderStatus(tx, db, ctx, order.ID, req.Status, model.OrderStatusActor{
+		Type: model.ORDER_ACTOR_USER,
+		ID:   sql.NullInt32{Int32: actor.ID, Valid: true},
+		Name: actor.Name,
+	}, req.Reason)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// unpaid payment of the cancelled order can not be paid anymore
+	if req.Status == model.ORDER_STATUS_CANCEL && transition.History.ID != 0 {
+		err = m.CancelOrderInstallment(tx, ctx, order.ID)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Update Order Status",
+		Activity:  fmt.Sprintf("Update Order %s Status From %s To %s", order.OrderCode, model.OrderStatusDesc(transition.From), model.OrderStatusDesc(req.Status)),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	after, _ := m.GetOrderByOrderCode(db, ctx, code)
+	h.audit(r, db, model.AUDIT_ORDER, code, audit.ActionUpdate, order, after)
+
+	var res response.OrderStatusHistoryRes
+	if transition.History.ID == 0 {
+		transition.History = model.OrderStatusHistoryEnt{ToStatus: transition.Order.OrderStatus}
+	}
+	h.SendSuccess(w, res.Transform(transition.History), nil)
+}
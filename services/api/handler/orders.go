@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
+	"panorama/lib/export"
+	"panorama/lib/metrics"
 	"panorama/lib/payment"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
@@ -18,10 +21,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 func (h *Contract) GetDetailItinOrderMember(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -38,6 +42,24 @@ func (h *Contract) GetDetailItinOrderMember(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	s.Installments, err = m.GetListOrderInstallmentByOrderID(db, ctx, s.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	s.Items, err = m.GetListOrderItemByOrderID(db, ctx, s.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	s.ExchangeRates, err = m.GetListOrderExchangeRate(db, ctx, s.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
 	var res response.DetailOrderMemberResponse
 	res = res.Transform(s)
 
@@ -99,7 +121,7 @@ func (h *Contract) GetListItinOrderMember(w http.ResponseWriter, r *http.Request
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -109,6 +131,23 @@ func (h *Contract) GetListItinOrderMember(w http.ResponseWriter, r *http.Request
 	defer db.Release()
 
 	m := model.Contract{App: h.App}
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "orders",
+			Row:  response.ItinOrderMemberResponse{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountListItinOrderMember(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportListItinOrderMember(db, ctx, param, func(o model.OrderEnt) error {
+					var res response.ItinOrderMemberResponse
+					return enc.Encode(res.Transform(o))
+				})
+			},
+		})
+		return
+	}
+
 	orders, err := m.GetListItinOrderMember(db, ctx, param)
 	if err != nil && sql.ErrNoRows != nil {
 		h.SendBadRequest(w, err.Error())
@@ -145,7 +184,7 @@ func (h *Contract) AddOrderAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -186,12 +225,29 @@ func (h *Contract) AddOrderAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Compute total of order from line items
+	items, rates, err := orderItems(db, ctx, m, req.Items, []model.ExchangeRateEnt{})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	pricing, total, err := orderPricing(db, ctx, m, items)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
 	// Order setter request
 	orderSetter.ChatID = chat.ID
-	orderSetter.TotalPrice = int64(req.TotalPrice)
-	orderSetter.TotalPricePpn = int64(req.TotalPricePPN)
+	orderSetter.TotalPrice = total.Subtotal
+	orderSetter.TotalPricePpn = total.Total
 	orderSetter.Description = req.Description
 	orderSetter.OrderStatus = model.ORDER_STATUS_PENDING
+	if req.IsDraft {
+		orderSetter.OrderStatus = model.ORDER_STATUS_DRAFT
+	}
 	orderSetter.Title = req.Title
 	orderSetter.PaidBy = member.ID
 	orderSetter.MemberEnt = member
@@ -209,6 +265,39 @@ func (h *Contract) AddOrderAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Save line items and pricing of order
+	orderSaved.Items, err = m.AddOrderItems(tx, ctx, orderSaved.ID, items)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+	err = m.UpdateOrderPricing(tx, ctx, orderSaved.ID, pricing, total)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+	err = m.AddOrderExchangeRates(tx, ctx, orderSaved.ID, rates)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+	_, err = m.AddOrderStatusHistory(tx, ctx, model.OrderStatusHistoryEnt{
+		OrderID:   orderSaved.ID,
+		ToStatus:  orderSaved.OrderStatus,
+		ActorType: model.ORDER_ACTOR_USER,
+		ActorID:   sql.NullInt32{Int32: userTc.ID, Valid: true},
+		ActorName: userTc.Name,
+		Reason:    sql.NullString{String: "Order is created", Valid: true},
+	})
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
 	// Activity user logging in process
 	log := model.LogActivityUserEnt{
 		UserID:    int64(userTc.ID),
@@ -224,23 +313,25 @@ func (h *Contract) AddOrderAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send Notifications - To Member (Customer)
-	memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, member.MemberCode, "customer")
-	if err != nil {
-		h.SendBadRequest(w, err.Error())
-		tx.Rollback(ctx)
-		return
-	}
-	notifContentMember := model.NotificationContent{
-		Subject:   model.NOTIF_SUBJ_ORDER_INCOME,
-		TripName:  req.Title,
-		OrderCode: orderSaved.OrderCode,
-	}
-	_, err = m.SendNotifications(tx, db, ctx, memberPlayers, notifContentMember)
-	if err != nil {
-		h.SendBadRequest(w, psql.ParseErr(err))
-		tx.Rollback(ctx)
-		return
+	// Send Notifications - To Member (Customer), draft order is notified when it is published
+	if !req.IsDraft {
+		memberPlayers, err := m.GetListPlayerByUserCodeAndRole(db, ctx, member.MemberCode, "customer")
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		notifContentMember := model.NotificationContent{
+			Subject:   model.NOTIF_SUBJ_ORDER_INCOME,
+			TripName:  req.Title,
+			OrderCode: orderSaved.OrderCode,
+		}
+		_, err = m.SendNotifications(tx, db, ctx, memberPlayers, notifContentMember)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
 	}
 
 	// Send Notifications - To User (Admin, TC)
@@ -269,6 +360,8 @@ func (h *Contract) AddOrderAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_ORDER, orderSaved.OrderCode, audit.ActionCreate, nil, orderSaved)
+	metrics.OrderCreated(req.OrderType)
 
 	h.SendSuccess(w, res.Transform(orderSaved), nil)
 }
@@ -292,7 +385,7 @@ func (h *Contract) UpdateOrderAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -335,6 +428,52 @@ func (h *Contract) UpdateOrderAct(w http.ResponseWriter, r *http.Request) {
 	// transform request
 	order := req.Transform(orderExist)
 
+	// Recompute total of order when line items is changed
+	var items []model.OrderItemEnt
+	var rates []model.ExchangeRateEnt
+	var pricing model.OrderPricing
+	var total model.OrderTotal
+	if len(req.Items) > 0 {
+		if !isOrderRepriceable(orderExist, checkPay) {
+			h.SendBadRequest(w, fmt.Sprintf("Items of order %s can not be changed, the order is %s or in payment process.", code, model.OrderStatusDesc(orderExist.OrderStatus)))
+			tx.Rollback(ctx)
+			return
+		}
+		shares, _ := m.GetListOrderPaymentShareByOrderID(db, ctx, orderExist.ID)
+		installments, _ := m.GetListOrderInstallmentByOrderID(db, ctx, orderExist.ID)
+		if len(shares) > 0 || len(installments) > 0 {
+			h.SendBadRequest(w, fmt.Sprintf("Items of order %s can not be changed after the payment is split or scheduled.", code))
+			tx.Rollback(ctx)
+			return
+		}
+		redemption, _ := m.GetVoucherRedemptionByOrderID(db, ctx, orderExist.ID)
+		if redemption.ID != 0 {
+			h.SendBadRequest(w, fmt.Sprintf("Voucher of order %s must be removed before the items is changed.", code))
+			tx.Rollback(ctx)
+			return
+		}
+
+		rates, err = m.GetListOrderExchangeRate(db, ctx, orderExist.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		items, rates, err = orderItems(db, ctx, m, req.Items, rates)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		pricing, total, err = orderPricing(db, ctx, m, items)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		order.TotalPrice = total.Subtotal
+	}
+
 	order, err = m.UpdateOrderByCode(tx, ctx, order)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -342,6 +481,27 @@ func (h *Contract) UpdateOrderAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(items) > 0 {
+		_, err = m.AddOrderItems(tx, ctx, order.ID, items)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+		err = m.UpdateOrderPricing(tx, ctx, order.ID, pricing, total)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+		err = m.AddOrderExchangeRates(tx, ctx, order.ID, rates)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
 	// Activity user logging in process
 	log := model.LogActivityUserEnt{
 		UserID:    int64(member.ID),
@@ -383,6 +543,8 @@ func (h *Contract) UpdateOrderAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	after, _ := m.GetOrderByCodeDetail(db, ctx, code)
+	h.audit(r, db, model.AUDIT_ORDER, code, audit.ActionUpdate, orderExist, after)
 
 	h.SendSuccess(w, nil, nil)
 }
@@ -403,7 +565,15 @@ func (h *Contract) AddMidtransNotificationAct(w http.ResponseWriter, r *http.Req
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
+
+	// The notification is not authenticated, only the notification signed with the server key is processed
+	if !payment.New(h.App).VerifyMidtransSignature(req.OrderID, req.StatusCode, req.GrossAmount, req.SignKey) {
+		h.Log.FromContext(ctx).Warnf("midtrans notification of %s has invalid signature key", req.OrderID)
+		h.SendAuthError(w, "Invalid signature key.")
+		return
+	}
+
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -419,6 +589,25 @@ func (h *Contract) AddMidtransNotificationAct(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Split order is paid per share, the transaction code of shares is used as midtrans order id
+	shares, err := m.GetListOrderPaymentShareByTransactionCode(db, ctx, req.OrderID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if len(shares) > 0 {
+		h.midtransOrderShareNotification(w, ctx, db, tx, m, req, shares)
+		return
+	}
+
+	// Installment of payment schedule use the payment code as midtrans order id
+	installment, _ := m.GetOrderInstallmentByPaymentCode(db, ctx, req.OrderID)
+	if installment.ID != 0 {
+		h.midtransOrderInstallmentNotification(w, ctx, db, tx, m, req, installment)
+		return
+	}
+
 	// Check order data exist
 	order, _ := m.GetOrderByOrderCode(db, ctx, req.OrderID)
 	if order.ID == 0 {
@@ -441,26 +630,35 @@ func (h *Contract) AddMidtransNotificationAct(w http.ResponseWriter, r *http.Req
 	orderStatus := midtransStatus["order_status"]
 	paymentStatus := midtransStatus["payment_status"]
 
-	// Update order status
-	orderSetter := model.OrderEnt{
-		OrderCode:     order.OrderCode,
-		PaidBy:        order.PaidBy,
-		OrderStatus:   orderStatus,
-		TotalPrice:    order.TotalPrice,
-		TcID:          order.TcID,
-		OrderType:     order.OrderType,
-		Details:       order.Details,
-		Title:         order.Title,
-		ChatID:        order.ChatID,
-		Description:   order.Description,
-		TotalPricePpn: order.TotalPricePpn,
+	// the paid amount must match the amount of order payment
+	if paymentStatus == model.PAYMENT_STATUS_PAID {
+		grossAmount, err := model.ParseMidtransAmount(req.GrossAmount)
+		if err != nil || grossAmount != orderPayment.Amount {
+			h.SendBadRequest(w, fmt.Sprintf("Gross amount %s of transaction %s does not match the payment amount %d.", req.GrossAmount, req.OrderID, orderPayment.Amount))
+			tx.Rollback(ctx)
+			return
+		}
 	}
-	orderUpdated, err := m.UpdateOrderByCode(tx, ctx, orderSetter)
-	if err != nil {
-		h.SendBadRequest(w, err.Error())
+
+	// Update order status, the late notification that does not match the lifecycle of order
+	// (e.g. settlement of cancelled order) keeps the status so the payment is still recorded
+	transition, err := m.TransitionOrderStatus(tx, db, ctx, order.ID, orderStatus, midtransActor,
+		fmt.Sprintf("Midtrans transaction %s is %s", req.OrderID, req.TransactionStatus))
+	if _, ok := err.(model.OrderTransitionError); ok {
+		h.Log.FromContext(ctx).Warn(err)
+
+		// the failed notification of paid order does not cancel the settled payment
+		if model.IsOrderPaid(transition.From) && paymentStatus != model.PAYMENT_STATUS_PAID {
+			tx.Rollback(ctx)
+			h.SendSuccess(w, req, nil)
+			return
+		}
+	} else if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
 		tx.Rollback(ctx)
 		return
 	}
+	orderUpdated := transition.Order
 
 	// Setter payment Or update order payment
 	orderPaymentSetter := model.OrderPaymentEnt{
@@ -494,8 +692,30 @@ func (h *Contract) AddMidtransNotificationAct(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// the payment that is settled after the order is cancelled is refunded, the order is not completed
+	// so it has no invoices and the paid notifications
+	settledAfterCancel := paymentStatus == model.PAYMENT_STATUS_PAID && transition.From == model.ORDER_STATUS_CANCEL
+	if settledAfterCancel && orderPayment.PaymentStatus != model.PAYMENT_STATUS_PAID {
+		err = m.TopUpOrderRefund(tx, ctx, order.ID, orderPayment.Amount)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	// Number the tax invoice and receipt of the paid order
+	if paymentStatus == model.PAYMENT_STATUS_PAID && !settledAfterCancel {
+		_, err = m.IssueOrderInvoices(tx, ctx, orderUpdated.ID)
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
 	// Send Notifications
-	if paymentStatus != model.PAYMENT_STATUS_PROCESS {
+	if paymentStatus != model.PAYMENT_STATUS_PROCESS && !settledAfterCancel {
 		// Send Notifications - Assign subject with payment status
 		var subjectCust string
 		var subjectTC string
@@ -597,9 +817,23 @@ func (h *Contract) AddMidtransNotificationAct(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if paymentStatus == model.PAYMENT_STATUS_PAID {
+		if !settledAfterCancel {
+			go m.PublishOrderInvoices(ctx, orderUpdated.ID)
+		}
+
+		// the repeated notification of the paid payment is not counted again
+		if orderPayment.PaymentStatus != model.PAYMENT_STATUS_PAID {
+			metrics.PaymentSettled(req.PaymentType)
+		}
+	}
+
 	h.SendSuccess(w, req, nil)
 }
 
+// midtransActor actor of the status change by midtrans payment notification
+var midtransActor = model.OrderStatusActor{Type: model.ORDER_ACTOR_SYSTEM, Name: "midtrans"}
+
 // PostPaymentAct post payment process order (cust_app)
 func (h *Contract) PostPaymentAct(w http.ResponseWriter, r *http.Request) {
 	// Initial response handler
@@ -619,7 +853,7 @@ func (h *Contract) PostPaymentAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check db context
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -659,19 +893,79 @@ func (h *Contract) PostPaymentAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Split order is paid per share
+	shares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, orderExist.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if len(shares) > 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is split, please pay your share.", req.OrderCode))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Order with payment schedule is paid per installment
+	installments, err := m.GetListOrderInstallmentByOrderID(db, ctx, orderExist.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+	if len(installments) > 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s has payment schedule, please pay the installment.", req.OrderCode))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Order that is cancelled by the failed midtrans payment is reopened when the payment is renewed
+	if orderExist.OrderStatus == model.ORDER_STATUS_CANCEL {
+		last, _ := m.GetLastOrderStatusHistory(db, ctx, orderExist.ID)
+		if last.ToStatus != model.ORDER_STATUS_CANCEL || last.ActorName != midtransActor.Name {
+			h.SendBadRequest(w, fmt.Sprintf("Order %s is cancelled.", req.OrderCode))
+			tx.Rollback(ctx)
+			return
+		}
+
+		_, err = m.TransitionOrderStatus(tx, db, ctx, orderExist.ID, model.ORDER_STATUS_PENDING, model.OrderStatusActor{
+			Type: model.ORDER_ACTOR_MEMBER,
+			ID:   sql.NullInt32{Int32: member.ID, Valid: true},
+			Name: member.Name,
+		}, "Payment is renewed")
+		if err != nil {
+			h.SendBadRequest(w, psql.ParseErr(err))
+			tx.Rollback(ctx)
+			return
+		}
+		orderExist.OrderStatus = model.ORDER_STATUS_PENDING
+	}
+	if orderExist.OrderStatus != model.ORDER_STATUS_PENDING {
+		h.SendBadRequest(w, fmt.Sprintf("Order %s is %s and can not be paid.", req.OrderCode, strings.ToLower(model.OrderStatusDesc(orderExist.OrderStatus))))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Amount of payment must match with the server computed total of order
+	orderAmount := orderTotalAmount(orderExist)
+	if int64(req.Amount) != orderAmount {
+		h.SendBadRequest(w, fmt.Sprintf("Payment amount %d does not match with order amount %d.", req.Amount, orderAmount))
+		tx.Rollback(ctx)
+		return
+	}
+
 	// Create order payment default set expired date
 	orderPaymentSetter := model.OrderPaymentEnt{
 		OrderID:       orderExist.ID,
-		Amount:        int64(req.Amount),
+		Amount:        orderAmount,
 		PaymentStatus: model.PAYMENT_STATUS_PROCESS,
 	}
 
 	// Update url snap url midtrans
 	paymentService := payment.New(h.App)
 	orderCode := orderExist.OrderCode
-	orderAmount := int64(req.Amount)
 	paramMidtrans := paymentService.SetMidtransParam(member.Email, member.Name, orderCode, orderAmount)
-	midtransResponse, err := paymentService.GetMidtransPaymentURL(paramMidtrans)
+	midtransResponse, err := paymentService.GetMidtransPaymentURL(ctx, paramMidtrans)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		tx.Rollback(ctx)
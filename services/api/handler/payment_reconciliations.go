@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"panorama/lib/payment"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetListPaymentReconciliationAct list reconciliation of settlement file (admin)
+func (h *Contract) GetListPaymentReconciliationAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"page":   1,
+		"limit":  10,
+		"offset": 0,
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	list, err := m.GetListPaymentReconciliation(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.PaymentReconciliationRes{}
+	for _, a := range list {
+		var res response.PaymentReconciliationRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
+
+// ImportPaymentReconciliationAct match midtrans settlement export (csv in the "file" field) with the paid payments (admin)
+func (h *Contract) ImportPaymentReconciliationAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(header.Filename)) != ".csv" {
+		h.SendBadRequest(w, "Content type is not allowed.")
+		return
+	}
+
+	rows, err := model.ParseSettlementFile(file)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	rec, err := m.ReconcileSettlement(db, ctx, model.PaymentReconciliationEnt{
+		FileName:  filepath.Base(header.Filename),
+		CreatedBy: sql.NullInt32{Int32: userAdmin.ID, Valid: true},
+	}, rows)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	rec, err = m.AddPaymentReconciliation(tx, ctx, rec)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Import Settlement File",
+		Activity:  fmt.Sprintf("Import Settlement File %s, %d Rows Mismatched", rec.FileName, rec.MismatchedRows),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.PaymentReconciliationRes
+	h.SendSuccess(w, res.Transform(rec), nil)
+}
+
+// GetPaymentReconciliationAct detail reconciliation with the items filtered by ?result= (e.g. mismatch),
+// the report is returned as csv attachment with ?download=true (admin)
+func (h *Contract) GetPaymentReconciliationAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	var result string
+	if res, ok := r.URL.Query()["result"]; ok && len(res[0]) > 0 {
+		result = res[0]
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	rec, _ := m.GetPaymentReconciliationByID(db, ctx, int32(id))
+	if rec.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Reconciliation %d not found.", id))
+		return
+	}
+
+	rec.Items, err = m.GetListPaymentReconciliationItem(db, ctx, rec.ID, result)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if d, ok := r.URL.Query()["download"]; ok && d[0] == "true" {
+		var buf bytes.Buffer
+		if err = model.WritePaymentReconciliationReport(&buf, rec.Items); err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		filename := fmt.Sprintf("reconciliation-%d.csv", rec.ID)
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	var res response.PaymentReconciliationRes
+	h.SendSuccess(w, res.Transform(rec), nil)
+}
+
+// RecheckPaymentReconciliationItemAct check the item again with the midtrans status api (admin)
+func (h *Contract) RecheckPaymentReconciliationItemAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+	itemID, err := strconv.Atoi(chi.URLParam(r, "itemID"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid item id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	item, _ := m.GetPaymentReconciliationItemByID(db, ctx, int32(id), int32(itemID))
+	if item.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Reconciliation item %d not found.", itemID))
+		return
+	}
+
+	status, err := payment.New(h.App).GetMidtransTransactionStatus(ctx, item.MidtransOrderID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	item, err = m.RecheckPaymentReconciliationItem(db, tx, ctx, item, status.TransactionStatus, status.GrossAmount)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.PaymentReconciliationItemRes
+	h.SendSuccess(w, res.Transform(item), nil)
+}
@@ -0,0 +1,6 @@
+package request
+
+// StartCallReq : request payload to start voice/video call in chat group
+type StartCallReq struct {
+	CallType string `json:"call_type" validate:"required,oneof=voice video"`
+}
@@ -0,0 +1,57 @@
+package request
+
+import (
+	"database/sql"
+	"fmt"
+	"panorama/services/api/model"
+)
+
+// CancellationPolicyReq : cancellation policy that can be changed by admin, policy without order type
+// and itinerary is used for all order
+type CancellationPolicyReq struct {
+	Name          string                `json:"name" validate:"required,max=150"`
+	OrderType     string                `json:"order_type" validate:"omitempty,oneof=R C"`
+	ItinCode      string                `json:"itin_code"`
+	FreeUntilDays int32                 `json:"free_until_days" validate:"gte=0"`
+	Tiers         []CancellationTierReq `json:"tiers" validate:"omitempty,dive"`
+	IsActive      *bool                 `json:"is_active"`
+}
+
+// CancellationTierReq : fee percent of order that cancelled at least min days before the trip start
+type CancellationTierReq struct {
+	MinDays    int32 `json:"min_days" validate:"gte=0"`
+	FeePercent int32 `json:"fee_percent" validate:"gte=0,lte=100"`
+}
+
+// CancelOrderReq : cancel order by the customer
+type CancelOrderReq struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// Transform CancellationPolicyReq to CancellationPolicyEnt, itinerary of policy is set by the handler
+func (req CancellationPolicyReq) Transform(p model.CancellationPolicyEnt) (model.CancellationPolicyEnt, error) {
+	p.Tiers = []model.CancellationTierEnt{}
+	days := map[int32]bool{}
+	for _, t := range req.Tiers {
+		if days[t.MinDays] {
+			return p, fmt.Errorf("Tier of %d days is duplicated", t.MinDays)
+		}
+		if t.MinDays >= req.FreeUntilDays {
+			return p, fmt.Errorf("Min days of tier should be less than the free until days %d", req.FreeUntilDays)
+		}
+		days[t.MinDays] = true
+
+		p.Tiers = append(p.Tiers, model.CancellationTierEnt{MinDays: t.MinDays, FeePercent: t.FeePercent})
+	}
+
+	p.Name = req.Name
+	p.OrderType = sql.NullString{String: req.OrderType, Valid: len(req.OrderType) > 0}
+	p.FreeUntilDays = req.FreeUntilDays
+
+	p.IsActive = true
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+
+	return p, nil
+}
@@ -12,6 +12,12 @@ type NewChatGroupReq struct {
 // ChatGroupReq ...
 type ChatGroupReq struct {
 	ChatGroupCode string `json:"chat_group_code" validate:"required"`
+	Notes         string `json:"notes"`
+}
+
+// LeaveSessionReq handover notes for the next tc
+type LeaveSessionReq struct {
+	Notes string `json:"notes" validate:"max=2000"`
 }
 
 // ChatGroupMessagesReq ...
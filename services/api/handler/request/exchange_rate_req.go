@@ -0,0 +1,29 @@
+package request
+
+import (
+	"fmt"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+)
+
+// ExchangeRateReq : rupiah value of one major unit of the currency, effective from the date (yyyy-mm-dd)
+type ExchangeRateReq struct {
+	CurrencyCode  string  `json:"currency_code" validate:"required,len=3"`
+	Rate          float64 `json:"rate" validate:"required,gt=0"`
+	EffectiveDate string  `json:"effective_date" validate:"required"`
+}
+
+// Transform ExchangeRateReq to ExchangeRateEnt
+func (req ExchangeRateReq) Transform(e model.ExchangeRateEnt) (model.ExchangeRateEnt, error) {
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(req.EffectiveDate))
+	if err != nil {
+		return e, fmt.Errorf("Invalid effective date %s, the format is yyyy-mm-dd.", req.EffectiveDate)
+	}
+
+	e.CurrencyCode = strings.ToUpper(strings.TrimSpace(req.CurrencyCode))
+	e.Rate = req.Rate
+	e.EffectiveDate = date
+
+	return e, nil
+}
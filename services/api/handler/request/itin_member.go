@@ -5,20 +5,23 @@ import (
 	"math/rand"
 	"panorama/lib/utils"
 	"panorama/services/api/model"
+	"strings"
 	"time"
 )
 
 type MemberItinReq struct {
-	Title         string                   `json:"title" validate:"required"`
-	EstPrice      int                      `json:"est_price"`
-	MemberCode    string                   `json:"member_code"`
-	StartDate     string                   `json:"start_date"`
-	EndDate       string                   `json:"end_date"`
-	Destination   string                   `json:"destination"`
-	Details       []map[string]interface{} `json:"details" validate:"required"`
-	Img           string                   `json:"img"`
-	GroupChatCode string                   `json:"group_chat_code"`
-	GroupMembers  []map[string]interface{} `json:"group_members"`
+	Title            string                   `json:"title" validate:"required"`
+	EstPrice         int                      `json:"est_price"`
+	EstPriceCurrency string                   `json:"est_price_currency" validate:"omitempty,len=3"`
+	MemberCode       string                   `json:"member_code"`
+	StartDate        string                   `json:"start_date"`
+	EndDate          string                   `json:"end_date"`
+	Destination      string                   `json:"destination"`
+	Details          []map[string]interface{} `json:"details" validate:"required"`
+	Img              string                   `json:"img"`
+	GroupChatCode    string                   `json:"group_chat_code"`
+	GroupMembers     []map[string]interface{} `json:"group_members"`
+	SugItinCode      string                   `json:"sug_itin_code"` // suggestion itin that cloned
 }
 
 func (req MemberItinReq) ToMemberItinEnt(isNew bool) (model.MemberItinEnt, error) {
@@ -30,12 +33,13 @@ func (req MemberItinReq) ToMemberItinEnt(isNew bool) (model.MemberItinEnt, error
 	}
 
 	memberItin := model.MemberItinEnt{
-		ItinCode:      code,
-		Title:         req.Title,
-		EstPrice:      sql.NullInt64{Int64: int64(req.EstPrice), Valid: true},
-		Details:       req.Details,
-		Img:           sql.NullString{String: req.Img, Valid: true},
-		ChatGroupCode: req.GroupChatCode,
+		ItinCode:         code,
+		Title:            req.Title,
+		EstPrice:         sql.NullInt64{Int64: int64(req.EstPrice), Valid: true},
+		EstPriceCurrency: strings.ToUpper(req.EstPriceCurrency),
+		Details:          req.Details,
+		Img:              sql.NullString{String: req.Img, Valid: true},
+		ChatGroupCode:    req.GroupChatCode,
 	}
 
 	if req.Destination != "" {
@@ -57,3 +61,8 @@ func (req MemberItinReq) ToMemberItinEnt(isNew bool) (model.MemberItinEnt, error
 
 	return memberItin, nil
 }
+
+// MemberItinInviteReq accept or decline invite by the token of invite link
+type MemberItinInviteReq struct {
+	Token string `json:"token" validate:"required"`
+}
@@ -5,22 +5,36 @@ import (
 )
 
 type OrderReq struct {
-	Title         string `json:"title" validate:"required"`
-	Description   string `json:"description" validate:"required"`
-	PaidByCode    string `json:"paid_by_code" validate:"required"`
-	ChatGroupCode string `json:"chat_group_code" validate:"required"`
-	TotalPrice    int    `json:"total_price" validate:"required"`
-	TotalPricePPN int    `json:"total_price_ppn" validate:"required"`
-	OrderType     string `json:"order_type" validate:"required"`
-	Details       string `json:"additional_details" validate:"required"`
+	Title         string         `json:"title" validate:"required"`
+	Description   string         `json:"description" validate:"required"`
+	PaidByCode    string         `json:"paid_by_code" validate:"required"`
+	ChatGroupCode string         `json:"chat_group_code" validate:"required"`
+	OrderType     string         `json:"order_type" validate:"required"`
+	Details       string         `json:"additional_details" validate:"required"`
+	Items         []OrderItemReq `json:"items" validate:"required,min=1,dive"`
+	IsDraft       bool           `json:"is_draft"`
 }
 
 type OrderReqUpdate struct {
-	Title      string `json:"title"`
-	PaidByCode string `json:"paid_by_code" `
-	TotalPrice int    `json:"total_price" `
-	OrderType  string `json:"order_type" `
-	Details    string `json:"additional_details"`
+	Title      string         `json:"title"`
+	PaidByCode string         `json:"paid_by_code" `
+	OrderType  string         `json:"order_type" `
+	Details    string         `json:"additional_details"`
+	Items      []OrderItemReq `json:"items" validate:"omitempty,dive"`
+}
+
+// OrderItemReq : line item of order, price of stuff item is taken from the catalog and
+// unit price of discount item is deducted from the total. Unit price is in the smallest unit
+// of the currency (e.g. cent of USD), rupiah is used when the currency is empty
+type OrderItemReq struct {
+	ItemType    string `json:"item_type" validate:"required,oneof=itinerary stuff service_fee discount"`
+	StuffCode   string `json:"stuff_code" validate:"required_if=ItemType stuff"`
+	Name        string `json:"name" validate:"required_unless=ItemType stuff,max=150"`
+	Description string `json:"description"`
+	Quantity    int32  `json:"quantity" validate:"required,min=1"`
+	UnitPrice   int64  `json:"unit_price" validate:"gte=0"`
+	Currency    string `json:"currency" validate:"omitempty,len=3"`
+	IsTaxable   *bool  `json:"is_taxable"`
 }
 
 type OrderPaymentReq struct {
@@ -28,6 +42,18 @@ type OrderPaymentReq struct {
 	OrderCode string `json:"order_code" validate:"required"`
 }
 
+// OrderInstallmentPlanReq : payment schedule of order, the first installment is the deposit
+type OrderInstallmentPlanReq struct {
+	Installments []OrderInstallmentReq `json:"installments" validate:"required,min=2,dive"`
+}
+
+// OrderInstallmentReq : installment of payment schedule, due date is counted from start date of trip
+type OrderInstallmentReq struct {
+	Title              string `json:"title" validate:"required,max=50"`
+	Percent            int    `json:"percent" validate:"required,min=1,max=99"`
+	DueDaysBeforeStart int    `json:"due_days_before_start" validate:"gte=0"`
+}
+
 // VANumber : bank virtual account number
 type VANumberMidtrans struct {
 	Bank     string `json:"bank"`
@@ -102,10 +128,6 @@ func (u OrderReqUpdate) Transform(m model.OrderEnt) model.OrderEnt {
 		m.Title = u.Title
 	}
 
-	if u.TotalPrice > 0 {
-		m.TotalPrice = int64(u.TotalPrice)
-	}
-
 	if len(u.OrderType) > 0 {
 		m.OrderType = u.OrderType
 	}
@@ -116,3 +138,10 @@ func (u OrderReqUpdate) Transform(m model.OrderEnt) model.OrderEnt {
 
 	return m
 }
+
+// OrderStatusReq : move order into the next status by tc or admin, paid and partially paid
+// status is changed by the payment
+type OrderStatusReq struct {
+	Status string `json:"status" validate:"required,oneof=P IP C X R"`
+	Reason string `json:"reason" validate:"max=500"`
+}
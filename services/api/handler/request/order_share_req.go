@@ -0,0 +1,13 @@
+package request
+
+// OrderSplitReq : request payload to split order payment among companions
+type OrderSplitReq struct {
+	SplitType string          `json:"split_type" validate:"required,oneof=equal custom"`
+	Shares    []OrderShareReq `json:"shares" validate:"required,min=1,dive"`
+}
+
+// OrderShareReq : share of companion, amount is only used on custom split
+type OrderShareReq struct {
+	MemberCode string `json:"member_code" validate:"required"`
+	Amount     int64  `json:"amount" validate:"gte=0"`
+}
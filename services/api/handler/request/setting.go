@@ -1,9 +1,40 @@
 package request
 
+import (
+	"encoding/json"
+	"panorama/services/api/model"
+	"strings"
+)
+
+// SettingReq : set_content is the value as text ("11", "true") or the json array / object itself
 type SettingReq struct {
-	Group      string `json:"group"`
-	Key        string `json:"key"`
-	Label      string `json:"label"`
-	SetType    string `json:"set_type"`
-	SetContent string `json:"set_content"`
+	Group      string          `json:"group" validate:"required,max=50"`
+	Key        string          `json:"key" validate:"required,max=50"`
+	Label      string          `json:"label" validate:"required,max=100"`
+	Order      int             `json:"order"`
+	SetType    string          `json:"set_type" validate:"required"`
+	SetContent json.RawMessage `json:"set_content" validate:"required"`
+	IsActive   bool            `json:"is_active"`
+	IsPublic   bool            `json:"is_public"`
+}
+
+// Transform SettingReq to SettingEnt
+func (req SettingReq) Transform(s model.SettingEnt) model.SettingEnt {
+	content := strings.TrimSpace(string(req.SetContent))
+
+	var text string
+	if err := json.Unmarshal(req.SetContent, &text); err == nil {
+		content = strings.TrimSpace(text)
+	}
+
+	s.SetGroup = strings.TrimSpace(req.Group)
+	s.SetKey = strings.TrimSpace(req.Key)
+	s.SetLabel = strings.TrimSpace(req.Label)
+	s.SetOrder = req.Order
+	s.ContentType = req.SetType
+	s.ContentValue = content
+	s.IsActive = req.IsActive
+	s.IsPublic = req.IsPublic
+
+	return s
 }
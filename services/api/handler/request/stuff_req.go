@@ -3,6 +3,7 @@ package request
 import (
 	"panorama/services/api/model"
 	"strconv"
+	"strings"
 )
 
 // AddMemberReq ...
@@ -11,6 +12,7 @@ type AddStuffReq struct {
 	Image           string `json:"image" validate:"required"`
 	Description     string `json:"description" validate:"required"`
 	Price        	string `json:"price" validate:"required"`
+	Currency        string `json:"currency" validate:"omitempty,len=3"`
 	Type            int  `json:"type"`
 	IsActive 		bool   `json:"is_active"`
 }
@@ -20,6 +22,7 @@ type StuffReqUpdate struct {
 	Image    		string `json:"email"`
 	Description     string `json:"password"`
 	Price    		string `json:"phone"`
+	Currency        string `json:"currency" validate:"omitempty,len=3"`
 	Type     		int  `json:"type"`
 	IsActive 		string `json:"is_active"`
 }
@@ -44,6 +47,10 @@ func (s StuffReqUpdate) Transform(m model.StuffEnt) model.StuffEnt {
 		m.Price = s.Price
 	}
 
+	if len(s.Currency) > 0 {
+		m.CurrencyCode = strings.ToUpper(s.Currency)
+	}
+
 	if s.Type > 0 {
 		m.Type = int32(s.Type)
 	}
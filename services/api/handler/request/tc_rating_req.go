@@ -0,0 +1,8 @@
+package request
+
+// TcRatingReq : request payload customer rating for tc
+type TcRatingReq struct {
+	TcCode  string `json:"tc_code" validate:"required"`
+	Rating  int32  `json:"rating" validate:"required,min=1,max=5"`
+	Comment string `json:"comment" validate:"max=1000"`
+}
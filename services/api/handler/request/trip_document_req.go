@@ -0,0 +1,34 @@
+package request
+
+import (
+	"database/sql"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+)
+
+// TripDocumentReq : travel document that uploaded into member itin (multipart form)
+type TripDocumentReq struct {
+	DocumentType   string `json:"document_type" validate:"required,oneof=passport visa ticket insurance other"`
+	Title          string `json:"title" validate:"required,max=150"`
+	DocumentNumber string `json:"document_number" validate:"max=50"`
+	ExpiryDate     string `json:"expiry_date"`
+	MemberCode     string `json:"member_code"`
+}
+
+// Transform TripDocumentReq to TripDocumentEnt
+func (req TripDocumentReq) Transform(d model.TripDocumentEnt) (model.TripDocumentEnt, error) {
+	d.DocumentType = req.DocumentType
+	d.Title = strings.TrimSpace(req.Title)
+	d.DocumentNumber = sql.NullString{String: strings.TrimSpace(req.DocumentNumber), Valid: len(strings.TrimSpace(req.DocumentNumber)) > 0}
+
+	if len(req.ExpiryDate) > 0 {
+		expiryDate, err := time.Parse("2006-01-02", req.ExpiryDate)
+		if err != nil {
+			return d, err
+		}
+		d.ExpiryDate = sql.NullTime{Time: expiryDate, Valid: true}
+	}
+
+	return d, nil
+}
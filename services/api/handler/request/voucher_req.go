@@ -0,0 +1,79 @@
+package request
+
+import (
+	"database/sql"
+	"fmt"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+)
+
+// VoucherReq : voucher setting that can be changed by admin
+type VoucherReq struct {
+	Title            string   `json:"title" validate:"required,max=150"`
+	Description      string   `json:"description"`
+	DiscountType     string   `json:"discount_type" validate:"required,oneof=percent fixed"`
+	DiscountValue    int64    `json:"discount_value" validate:"required,gt=0"`
+	MaxDiscount      int64    `json:"max_discount" validate:"gte=0"`
+	MinSpend         int64    `json:"min_spend" validate:"gte=0"`
+	StartDate        string   `json:"start_date" validate:"required"`
+	EndDate          string   `json:"end_date" validate:"required"`
+	UsageLimit       int32    `json:"usage_limit" validate:"gte=0"`
+	UsageLimitMember int32    `json:"usage_limit_member" validate:"gte=0"`
+	Destinations     []string `json:"destinations" validate:"omitempty,dive,required"`
+	OrderTypes       []string `json:"order_types" validate:"omitempty,dive,oneof=R C"`
+	IsActive         *bool    `json:"is_active"`
+}
+
+// AddVoucherReq : new voucher, code of voucher can not be changed
+type AddVoucherReq struct {
+	VoucherCode string `json:"voucher_code" validate:"required,alphanum,max=50"`
+	VoucherReq
+}
+
+// ApplyVoucherReq : apply voucher into order
+type ApplyVoucherReq struct {
+	VoucherCode string `json:"voucher_code" validate:"required"`
+}
+
+// Transform VoucherReq to VoucherEnt, zero usage limit and max discount is unlimited
+func (req VoucherReq) Transform(v model.VoucherEnt) (model.VoucherEnt, error) {
+	startDate, err := time.Parse("2006-01-02 15:04:05", req.StartDate)
+	if err != nil {
+		return v, err
+	}
+	endDate, err := time.Parse("2006-01-02 15:04:05", req.EndDate)
+	if err != nil {
+		return v, err
+	}
+	if !endDate.After(startDate) {
+		return v, fmt.Errorf("End date should be after start date")
+	}
+	if req.DiscountType == model.VOUCHER_TYPE_PERCENT && req.DiscountValue > 100 {
+		return v, fmt.Errorf("Discount value of percent voucher should not be more than 100")
+	}
+
+	v.Title = req.Title
+	v.Description = req.Description
+	v.DiscountType = req.DiscountType
+	v.DiscountValue = req.DiscountValue
+	v.MaxDiscount = sql.NullInt64{Int64: req.MaxDiscount, Valid: req.MaxDiscount > 0}
+	v.MinSpend = req.MinSpend
+	v.StartDate = startDate
+	v.EndDate = endDate
+	v.UsageLimit = sql.NullInt32{Int32: req.UsageLimit, Valid: req.UsageLimit > 0}
+	v.UsageLimitMember = sql.NullInt32{Int32: req.UsageLimitMember, Valid: req.UsageLimitMember > 0}
+	v.OrderTypes = req.OrderTypes
+
+	v.Destinations = []string{}
+	for _, d := range req.Destinations {
+		v.Destinations = append(v.Destinations, strings.TrimSpace(d))
+	}
+
+	v.IsActive = true
+	if req.IsActive != nil {
+		v.IsActive = *req.IsActive
+	}
+
+	return v, nil
+}
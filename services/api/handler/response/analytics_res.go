@@ -0,0 +1,140 @@
+package response
+
+import (
+	"panorama/services/api/model"
+)
+
+// AnalyticsRevenueRes ...
+type AnalyticsRevenueRes struct {
+	Period        string `json:"period"`
+	OrderType     string `json:"order_type"`
+	TotalPayments int64  `json:"total_payments"`
+	TotalAmount   int64  `json:"total_amount"`
+}
+
+// Transform AnalyticsRevenueRes ...
+func (r AnalyticsRevenueRes) Transform(m model.AnalyticsRevenueEnt) AnalyticsRevenueRes {
+	r.Period = m.Period.Format("2006-01-02")
+	r.OrderType = m.OrderType
+	r.TotalPayments = m.TotalPayments
+	r.TotalAmount = m.TotalAmount
+
+	return r
+}
+
+// AnalyticsOrderRes ...
+type AnalyticsOrderRes struct {
+	Period          string `json:"period"`
+	OrderType       string `json:"order_type"`
+	TotalOrders     int64  `json:"total_orders"`
+	PaidOrders      int64  `json:"paid_orders"`
+	CancelledOrders int64  `json:"cancelled_orders"`
+	TotalPrice      int64  `json:"total_price"`
+}
+
+// Transform AnalyticsOrderRes ...
+func (r AnalyticsOrderRes) Transform(m model.AnalyticsOrderEnt) AnalyticsOrderRes {
+	r.Period = m.Period.Format("2006-01-02")
+	r.OrderType = m.OrderType
+	r.TotalOrders = m.TotalOrders
+	r.PaidOrders = m.PaidOrders
+	r.CancelledOrders = m.CancelledOrders
+	r.TotalPrice = m.TotalPrice
+
+	return r
+}
+
+// AnalyticsFunnelRes ...
+type AnalyticsFunnelRes struct {
+	Period      string `json:"period"`
+	Chats       int64  `json:"chats"`
+	Itineraries int64  `json:"itineraries"`
+	Orders      int64  `json:"orders"`
+	PaidOrders  int64  `json:"paid_orders"`
+}
+
+// Transform AnalyticsFunnelRes ...
+func (r AnalyticsFunnelRes) Transform(m model.AnalyticsFunnelEnt) AnalyticsFunnelRes {
+	r.Period = m.Period.Format("2006-01-02")
+	r.Chats = m.Chats
+	r.Itineraries = m.Itineraries
+	r.Orders = m.Orders
+	r.PaidOrders = m.PaidOrders
+
+	return r
+}
+
+// AnalyticsSuggestionRes views & clones per period with the most viewed suggestion itineraries
+type AnalyticsSuggestionRes struct {
+	Series []AnalyticsSuggestionItemRes `json:"series"`
+	Top    []AnalyticsSuggestionItemRes `json:"top"`
+}
+
+// AnalyticsSuggestionItemRes ...
+type AnalyticsSuggestionItemRes struct {
+	Period    string  `json:"period,omitempty"`
+	ItinCode  string  `json:"itin_code,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Views     int64   `json:"views"`
+	Clones    int64   `json:"clones"`
+	CloneRate float64 `json:"clone_rate"`
+}
+
+// Transform AnalyticsSuggestionItemRes ...
+func (r AnalyticsSuggestionItemRes) Transform(m model.AnalyticsSuggestionEnt) AnalyticsSuggestionItemRes {
+	if !m.Period.IsZero() {
+		r.Period = m.Period.Format("2006-01-02")
+	}
+	r.ItinCode = m.ItinCode
+	r.Title = m.Title
+	r.Views = m.Views
+	r.Clones = m.Clones
+
+	if m.Views > 0 {
+		r.CloneRate = float64(m.Clones) / float64(m.Views)
+	}
+
+	return r
+}
+
+// AnalyticsDestinationRes ...
+type AnalyticsDestinationRes struct {
+	Destination string `json:"destination"`
+	Itineraries int64  `json:"itineraries"`
+	Orders      int64  `json:"orders"`
+	PaidOrders  int64  `json:"paid_orders"`
+}
+
+// Transform AnalyticsDestinationRes ...
+func (r AnalyticsDestinationRes) Transform(m model.AnalyticsDestinationEnt) AnalyticsDestinationRes {
+	r.Destination = m.Destination
+	r.Itineraries = m.Itineraries
+	r.Orders = m.Orders
+	r.PaidOrders = m.PaidOrders
+
+	return r
+}
+
+// AnalyticsTcRes ...
+type AnalyticsTcRes struct {
+	UserCode  string  `json:"user_code"`
+	Name      string  `json:"name"`
+	Chats     int64   `json:"chats"`
+	Orders    int64   `json:"orders"`
+	Revenue   int64   `json:"revenue"`
+	Ratings   int64   `json:"ratings"`
+	AvgRating float64 `json:"avg_rating"`
+}
+
+// Transform AnalyticsTcRes ...
+func (r AnalyticsTcRes) Transform(m model.AnalyticsTcEnt) AnalyticsTcRes {
+	r.UserCode = m.UserCode
+	r.Name = m.Name
+	r.Chats = m.Chats
+	r.Orders = m.Orders
+	r.Revenue = m.Revenue
+	r.Ratings = m.Ratings
+	r.AvgRating = m.AvgRating
+
+	return r
+}
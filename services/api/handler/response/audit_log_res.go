@@ -0,0 +1,55 @@
+package response
+
+import (
+	"encoding/json"
+	"panorama/services/api/model"
+	"time"
+)
+
+// AuditLogRes ...
+type AuditLogRes struct {
+	ID          int64           `json:"id"`
+	ActorCode   string          `json:"actor_code"`
+	ActorRole   string          `json:"actor_role"`
+	Channel     string          `json:"channel"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    string          `json:"entity_id"`
+	Action      string          `json:"action"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	Diff        json.RawMessage `json:"diff"`
+	IP          string          `json:"ip"`
+	UserAgent   string          `json:"user_agent"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	CreatedDate time.Time       `json:"created_date"`
+}
+
+// Transform from audit log model to audit log response, the empty snapshot is null
+func (r AuditLogRes) Transform(m model.AuditLogEnt) AuditLogRes {
+	r.ID = m.ID
+	r.ActorCode = m.ActorCode.String
+	r.ActorRole = m.ActorRole.String
+	r.Channel = m.Channel.String
+	r.EntityType = m.EntityType
+	r.EntityID = m.EntityID.String
+	r.Action = m.Action
+	r.Before = rawJSON(m.BeforeData)
+	r.After = rawJSON(m.AfterData)
+	r.Diff = rawJSON(m.Diff)
+	r.IP = m.IP.String
+	r.UserAgent = m.UserAgent.String
+	r.Method = m.Method.String
+	r.Path = m.Path.String
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
+
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return json.RawMessage(b)
+}
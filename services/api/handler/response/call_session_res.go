@@ -0,0 +1,61 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// CallSessionRes ...
+type CallSessionRes struct {
+	CallCode     string    `json:"call_code"`
+	ChannelName  string    `json:"channel_name"`
+	CallType     string    `json:"call_type"`
+	Status       string    `json:"status"`
+	StartedName  string    `json:"started_by_name"`
+	StartedRole  string    `json:"started_by_role"`
+	AnsweredDate string    `json:"answered_date"`
+	EndedDate    string    `json:"ended_date"`
+	Duration     int32     `json:"duration"`
+	CreatedDate  time.Time `json:"created_date"`
+}
+
+// Transform CallSessionRes ...
+func (r CallSessionRes) Transform(m model.CallSessionEnt) CallSessionRes {
+	r.CallCode = m.CallCode
+	r.ChannelName = m.ChannelName
+	r.CallType = m.CallType
+	r.Status = m.Status
+	r.StartedName = m.StartedName
+	r.StartedRole = m.StartedRole
+	r.Duration = m.Duration
+	r.CreatedDate = m.CreatedDate
+
+	if m.AnsweredDate.Valid {
+		r.AnsweredDate = m.AnsweredDate.Time.Format(time.RFC3339)
+	}
+	if m.EndedDate.Valid {
+		r.EndedDate = m.EndedDate.Time.Format(time.RFC3339)
+	}
+
+	return r
+}
+
+// CallTokenRes agora token of the participant
+type CallTokenRes struct {
+	Call             CallSessionRes `json:"call"`
+	AppID            string         `json:"app_id"`
+	UID              uint32         `json:"uid"`
+	Token            string         `json:"token"`
+	TokenExpiredDate time.Time      `json:"token_expired_date"`
+}
+
+// Transform CallTokenRes ...
+func (r CallTokenRes) Transform(m model.CallSessionEnt, p model.CallParticipantEnt, appID, token string) CallTokenRes {
+	r.Call = r.Call.Transform(m)
+	r.AppID = appID
+	r.UID = uint32(p.ID)
+	r.Token = token
+	r.TokenExpiredDate = p.TokenExpiredDate.Time
+
+	return r
+}
@@ -0,0 +1,85 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// CancellationPolicyRes ...
+type CancellationPolicyRes struct {
+	ID            int32                 `json:"id"`
+	Name          string                `json:"name"`
+	OrderType     string                `json:"order_type"`
+	ItinCode      string                `json:"itin_code"`
+	FreeUntilDays int32                 `json:"free_until_days"`
+	Tiers         []CancellationTierRes `json:"tiers"`
+	IsActive      bool                  `json:"is_active"`
+	CreatedDate   time.Time             `json:"created_date"`
+}
+
+// CancellationTierRes ...
+type CancellationTierRes struct {
+	MinDays    int32 `json:"min_days"`
+	FeePercent int32 `json:"fee_percent"`
+}
+
+// Transform CancellationPolicyRes ...
+func (r CancellationPolicyRes) Transform(m model.CancellationPolicyEnt) CancellationPolicyRes {
+	r.ID = m.ID
+	r.Name = m.Name
+	r.OrderType = m.OrderType.String
+	r.ItinCode = m.ItinCode
+	r.FreeUntilDays = m.FreeUntilDays
+	r.IsActive = m.IsActive
+	r.CreatedDate = m.CreatedDate
+
+	r.Tiers = []CancellationTierRes{}
+	for _, t := range m.Tiers {
+		r.Tiers = append(r.Tiers, CancellationTierRes{MinDays: t.MinDays, FeePercent: t.FeePercent})
+	}
+
+	return r
+}
+
+// OrderCancellationRes fee and refund of order that is (or will be) cancelled by the customer
+type OrderCancellationRes struct {
+	OrderCode    string                 `json:"order_code"`
+	OrderStatus  string                 `json:"order_status"`
+	Policy       *CancellationPolicyRes `json:"policy"`
+	DaysBefore   *int32                 `json:"days_before"`
+	FeePercent   int32                  `json:"fee_percent"`
+	TotalPrice   int64                  `json:"total_price"`
+	PaidAmount   int64                  `json:"paid_amount"`
+	FeeAmount    int64                  `json:"fee_amount"`
+	RefundAmount int64                  `json:"refund_amount"`
+	RefundStatus string                 `json:"refund_status"`
+	Reason       string                 `json:"reason"`
+}
+
+// Transform OrderCancellationRes ...
+func (r OrderCancellationRes) Transform(o model.OrderEnt, p model.CancellationPolicyEnt, m model.OrderRefundEnt) OrderCancellationRes {
+	r.OrderCode = o.OrderCode
+	r.OrderStatus = o.OrderStatus
+	r.FeePercent = m.FeePercent
+	r.TotalPrice = o.TotalPrice
+	if o.TotalPricePpn > 0 {
+		r.TotalPrice = o.TotalPricePpn
+	}
+	r.PaidAmount = m.PaidAmount
+	r.FeeAmount = m.FeeAmount
+	r.RefundAmount = m.RefundAmount
+	r.RefundStatus = m.RefundStatus
+	r.Reason = m.Reason.String
+
+	if p.ID != 0 {
+		var policy CancellationPolicyRes
+		policy = policy.Transform(p)
+		r.Policy = &policy
+	}
+	if m.DaysBefore.Valid {
+		days := m.DaysBefore.Int32
+		r.DaysBefore = &days
+	}
+
+	return r
+}
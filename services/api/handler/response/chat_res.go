@@ -1,11 +1,10 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 // ChatGroupRes ...
@@ -90,7 +89,7 @@ func (r ChatGroupOrderRes) Transform(m model.ChatGroupEnt) ChatGroupOrderRes {
 		if IsUrl(m.Member.Img.String) {
 			r.MemberImg = m.Member.Img.String
 		} else {
-			r.MemberImg = viper.GetString("aws.s3.public_url") + m.Member.Img.String
+			r.MemberImg = storage.URL(m.Member.Img.String)
 		}
 
 	} else {
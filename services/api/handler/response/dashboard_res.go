@@ -11,6 +11,7 @@ type DashboardAdminResponse struct {
 	DashboardActiveChatsResponse map[string]interface{}         `json:"active_chats"`
 	DashboardUsersOnlineResponse map[string]interface{}         `json:"users_online"`
 	DashboardTcOnlineResponse    map[string]interface{}         `json:"tc_online"`
+	DashboardVouchersResponse    map[string]interface{}         `json:"voucher_redemptions"`
 	DashboardDailyVisitsResponse []DashboardDailyVisitsResponse `json:"daily_visits"`
 }
 
@@ -21,6 +22,7 @@ func (r DashboardAdminResponse) Transform(i model.DashboardEnt) DashboardAdminRe
 	r.DashboardActiveChatsResponse = i.ActiveChats
 	r.DashboardUsersOnlineResponse = i.UsersOnline
 	r.DashboardTcOnlineResponse = i.TcOnline
+	r.DashboardVouchersResponse = i.Vouchers
 
 	var listResponse []DashboardDailyVisitsResponse
 	for _, g := range i.DailyVisitsEnt {
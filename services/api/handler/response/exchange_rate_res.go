@@ -0,0 +1,65 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// MoneyRes amount in the smallest unit of the currency with the formatted text
+type MoneyRes struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Display  string `json:"display"`
+}
+
+// Transform from money model to money response
+func (r MoneyRes) Transform(m model.Money) MoneyRes {
+	r.Amount = m.Amount
+	r.Currency = m.Currency
+	if len(r.Currency) == 0 {
+		r.Currency = model.BASE_CURRENCY
+	}
+	r.Display = m.String()
+
+	return r
+}
+
+// CurrencyRes ...
+type CurrencyRes struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Symbol    string `json:"symbol"`
+	MinorUnit int32  `json:"minor_unit"`
+}
+
+// Transform from currency model to currency response
+func (r CurrencyRes) Transform(m model.CurrencyEnt) CurrencyRes {
+	r.Code = m.Code
+	r.Name = m.Name
+	r.Symbol = m.Symbol
+	r.MinorUnit = m.MinorUnit
+
+	return r
+}
+
+// ExchangeRateRes ...
+type ExchangeRateRes struct {
+	ID            int32     `json:"id,omitempty"`
+	CurrencyCode  string    `json:"currency_code"`
+	Rate          float64   `json:"rate"`
+	EffectiveDate string    `json:"effective_date"`
+	Source        string    `json:"source,omitempty"`
+	CreatedDate   time.Time `json:"created_date"`
+}
+
+// Transform from exchange rate model to exchange rate response
+func (r ExchangeRateRes) Transform(m model.ExchangeRateEnt) ExchangeRateRes {
+	r.ID = m.ID
+	r.CurrencyCode = m.CurrencyCode
+	r.Rate = m.Rate
+	r.EffectiveDate = m.EffectiveDate.Format("2006-01-02")
+	r.Source = m.Source
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
@@ -1,30 +1,32 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 // ItinmemberResponse ...
 type ItinMemberResponse struct {
-	ItinCode      string                   `json:"itin_code"`
-	MemberCode    string                   `json:"member_code"`
-	Name          string                   `json:"name"`
-	Destination   string                   `json:"destination"`
-	Title         string                   `json:"title"`
-	EstPrice      int64                    `json:"est_price"`
-	StartDate     time.Time                `json:"start_date"`
-	EndDate       time.Time                `json:"end_date"`
-	CreatedDate   time.Time                `json:"created_date"`
-	DayPeriod     string                   `json:"day_period"`
-	ChatGroupCode string                   `json:"chat_group_code"`
-	Img           string                   `json:"img"`
-	Details       []map[string]interface{} `json:"detail"`
-	GroupMembers  []map[string]interface{} `json:"group_members"`
+	ItinCode         string                   `json:"itin_code"`
+	MemberCode       string                   `json:"member_code"`
+	Name             string                   `json:"name"`
+	Destination      string                   `json:"destination"`
+	Title            string                   `json:"title"`
+	EstPrice         int64                    `json:"est_price"`
+	EstPriceCurrency string                   `json:"est_price_currency"`
+	EstPriceIDR      *int64                   `json:"est_price_idr"`
+	StartDate        time.Time                `json:"start_date"`
+	EndDate          time.Time                `json:"end_date"`
+	CreatedDate      time.Time                `json:"created_date"`
+	DayPeriod        string                   `json:"day_period"`
+	ChatGroupCode    string                   `json:"chat_group_code"`
+	Img              string                   `json:"img"`
+	Images           map[string]string        `json:"images,omitempty"`
+	Details          []map[string]interface{} `json:"detail"`
+	GroupMembers     []map[string]interface{} `json:"group_members"`
 }
 
 // Transform from itin member model to itin member response
@@ -38,6 +40,15 @@ func (r ItinMemberResponse) Transform(i model.MemberItinEnt) ItinMemberResponse
 	r.CreatedDate = i.CreatedDate
 	r.DayPeriod = strconv.Itoa(int(i.DayPeriod)) + "D" + strconv.Itoa(int(i.DayPeriod-1)) + "N"
 	r.EstPrice = i.EstPrice.Int64
+	r.EstPriceCurrency = i.EstPriceCurrency
+	if len(r.EstPriceCurrency) == 0 {
+		r.EstPriceCurrency = model.BASE_CURRENCY
+	}
+	if i.EstPriceIDR.Valid {
+		r.EstPriceIDR = &i.EstPriceIDR.Int64
+	} else if r.EstPriceCurrency == model.BASE_CURRENCY {
+		r.EstPriceIDR = &r.EstPrice
+	}
 	r.StartDate = i.StartDate
 	r.EndDate = i.EndDate
 	r.ChatGroupCode = i.ChatGroupCode
@@ -48,7 +59,8 @@ func (r ItinMemberResponse) Transform(i model.MemberItinEnt) ItinMemberResponse
 		if IsUrl(i.Img.String) {
 			r.Img = i.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + i.Img.String
+			r.Img = storage.URL(i.Img.String)
+			r.Images = ImageURLs(i.Img.String)
 		}
 
 	} else {
@@ -72,3 +84,51 @@ func (r ItinMemberSimpleRes) Transform(i model.MemberItinEnt) ItinMemberSimpleRe
 
 	return r
 }
+
+// MemberItinInviteRes ...
+type MemberItinInviteRes struct {
+	InviteCode    string    `json:"invite_code"`
+	ItinCode      string    `json:"itin_code"`
+	ItinTitle     string    `json:"itin_title"`
+	Email         string    `json:"email"`
+	MemberCode    string    `json:"member_code"`
+	MemberName    string    `json:"member_name"`
+	MemberImg     string    `json:"member_img"`
+	Role          string    `json:"role"`
+	Status        string    `json:"status"`
+	InvitedBy     string    `json:"invited_by"`
+	ResendCount   int32     `json:"resend_count"`
+	ExpiredDate   time.Time `json:"expired_date"`
+	RespondedDate string    `json:"responded_date"`
+	CreatedDate   time.Time `json:"created_date"`
+}
+
+// Transform from member itin invite model to member itin invite response
+func (r MemberItinInviteRes) Transform(i model.MemberItinInviteEnt) MemberItinInviteRes {
+	r.InviteCode = i.InviteCode
+	r.ItinCode = i.MemberItin.ItinCode
+	r.ItinTitle = i.MemberItin.Title
+	r.Email = i.Email
+	r.MemberCode = i.Member.MemberCode
+	r.MemberName = i.Member.Name
+	r.Role = i.Role
+	r.Status = i.CurrentStatus()
+	r.InvitedBy = i.InvitedByName
+	r.ResendCount = i.ResendCount
+	r.ExpiredDate = i.ExpiredDate
+	r.CreatedDate = i.CreatedDate
+
+	if i.RespondedDate.Valid {
+		r.RespondedDate = i.RespondedDate.Time.Format(time.RFC3339)
+	}
+
+	if len(strings.TrimSpace(i.Member.Img.String)) > 0 {
+		if IsUrl(i.Member.Img.String) {
+			r.MemberImg = i.Member.Img.String
+		} else {
+			r.MemberImg = storage.URL(i.Member.Img.String)
+		}
+	}
+
+	return r
+}
@@ -2,12 +2,12 @@ package response
 
 import (
 	"net/url"
+	"panorama/lib/storage"
+	"panorama/lib/upload"
 	"panorama/services/api/model"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 // ItinSugResponse ...
@@ -37,7 +37,7 @@ func (r DetailItinSugResponse) Transform(i model.ItinSugEnt) DetailItinSugRespon
 		if IsUrl(i.Img.String) {
 			r.Img = i.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + i.Img.String
+			r.Img = storage.URL(i.Img.String)
 		}
 
 	} else {
@@ -74,7 +74,7 @@ func (r ItinSugResponse) Transform(i model.ItinSugEnt) ItinSugResponse {
 		if IsUrl(i.Img.String) {
 			r.Img = i.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + i.Img.String
+			r.Img = storage.URL(i.Img.String)
 		}
 
 	} else {
@@ -89,3 +89,18 @@ func IsUrl(str string) bool {
 	u, err := url.Parse(str)
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
+
+// ImageURLs urls of the image renditions, nil when the image is not uploaded through the image pipeline
+func ImageURLs(path string) map[string]string {
+	paths := upload.RenditionPaths(path)
+	if paths == nil {
+		return nil
+	}
+
+	urls := map[string]string{}
+	for name, p := range paths {
+		urls[name] = storage.URL(p)
+	}
+
+	return urls
+}
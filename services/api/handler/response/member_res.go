@@ -1,25 +1,26 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
 
 	"github.com/andanhm/go-prettytime"
-	"github.com/spf13/viper"
 )
 
 // MemberResponse ...
 type MemberResponse struct {
-	MemberCode     string `json:"member_code"`
-	Username       string `json:"username"`
-	Name           string `json:"name"`
-	Gender         string `json:"Gender"`
-	Email          string `json:"email"`
-	Phone          string `json:"phone"`
-	Img            string `json:"image"`
-	LastActiveDate string `json:"last_active_date"`
-	TotalVisited   int32  `json:"total_visited"`
-	Token          string `json:"token"`
-	IsActive       bool   `json:"is_active"`
+	MemberCode     string            `json:"member_code"`
+	Username       string            `json:"username"`
+	Name           string            `json:"name"`
+	Gender         string            `json:"Gender"`
+	Email          string            `json:"email"`
+	Phone          string            `json:"phone"`
+	Img            string            `json:"image"`
+	Images         map[string]string `json:"images,omitempty"`
+	LastActiveDate string            `json:"last_active_date"`
+	TotalVisited   int32             `json:"total_visited"`
+	Token          string            `json:"token"`
+	IsActive       bool              `json:"is_active"`
 }
 
 // Transform from member model to member response
@@ -34,7 +35,8 @@ func (r MemberResponse) Transform(m model.MemberEnt) MemberResponse {
 		if IsUrl(m.Img.String) {
 			r.Img = m.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + m.Img.String
+			r.Img = storage.URL(m.Img.String)
+			r.Images = ImageURLs(m.Img.String)
 		}
 
 	} else {
@@ -60,6 +62,7 @@ type MemberDetailResponse struct {
 	Name                 string               `json:"name"`
 	Gender               string               `json:"Gender"`
 	Img                  string               `json:"image"`
+	Images               map[string]string    `json:"images,omitempty"`
 	LastSeen             string               `json:"last_seen"`
 	DetailActivityMember DetailActivityMember `json:"summary_activity"`
 	RecentActivityUser   []RecentActivityUser `json:"log_activity"`
@@ -72,7 +75,8 @@ func (r MemberDetailResponse) Transform(m model.MemberEnt) MemberDetailResponse
 		if IsUrl(m.Img.String) {
 			r.Img = m.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + m.Img.String
+			r.Img = storage.URL(m.Img.String)
+			r.Images = ImageURLs(m.Img.String)
 		}
 
 	} else {
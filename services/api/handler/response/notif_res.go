@@ -2,13 +2,12 @@ package response
 
 import (
 	"fmt"
+	"panorama/lib/storage"
 	"panorama/lib/utils"
 	"panorama/services/api/model"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 type NotifResponse struct {
@@ -54,7 +53,7 @@ func (r NotifResponse) Transform(m model.NotificationEnt) NotifResponse {
 		if IsUrl(m.User.Img.String) {
 			userImg = m.User.Img.String
 		} else {
-			userImg = viper.GetString("aws.s3.public_url") + m.User.Img.String
+			userImg = storage.URL(m.User.Img.String)
 		}
 	}
 	r.UserImg = userImg
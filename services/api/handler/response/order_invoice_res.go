@@ -0,0 +1,37 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// OrderInvoiceRes ...
+type OrderInvoiceRes struct {
+	OrderCode   string    `json:"order_code"`
+	DocType     string    `json:"doc_type"`
+	DocNo       string    `json:"doc_no"`
+	Amount      int64     `json:"amount"`
+	PpnAmount   int64     `json:"ppn_amount"`
+	PaymentType string    `json:"payment_type"`
+	FileURL     string    `json:"file_url"`
+	IssuedDate  time.Time `json:"issued_date"`
+	EmailedDate string    `json:"emailed_date"`
+}
+
+// Transform OrderInvoiceRes, the file url is the presigned url of the private pdf
+func (r OrderInvoiceRes) Transform(m model.OrderInvoiceEnt, fileURL string) OrderInvoiceRes {
+	r.OrderCode = m.OrderCode
+	r.DocType = m.DocType
+	r.DocNo = m.DocNo
+	r.Amount = m.Amount
+	r.PpnAmount = m.PpnAmount
+	r.PaymentType = m.PaymentType
+	r.IssuedDate = m.IssuedDate
+	r.FileURL = fileURL
+
+	if m.EmailedDate.Valid {
+		r.EmailedDate = m.EmailedDate.Time.Format(time.RFC3339)
+	}
+
+	return r
+}
@@ -1,26 +1,36 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
 	"strings"
 	"time"
-
-	"github.com/spf13/viper"
 )
 
 // ItinOrderMember Response Detail
 
 type DetailOrderMemberResponse struct {
-	MemberCode  string    `json:"member_code"`
-	TcName      string    `json:"tc_name"`
-	MemberName  string    `json:"member_name"`
-	OrderCode   string    `json:"order_code"`
-	Title       string    `json:"title"`
-	Details     string    `json:"detail_pdf"`
-	PaidBy      int32     `json:"paid_by"`
-	TotalPrice  int64     `json:"total_price"`
-	OrderType   string    `json:"order_type"`
-	CreatedDate time.Time `json:"created_date"`
+	MemberCode         string                `json:"member_code"`
+	TcName             string                `json:"tc_name"`
+	MemberName         string                `json:"member_name"`
+	OrderCode          string                `json:"order_code"`
+	Title              string                `json:"title"`
+	Details            string                `json:"detail_pdf"`
+	PaidBy             int32                 `json:"paid_by"`
+	TotalPrice         int64                 `json:"total_price"`
+	TotalPricePpn      int64                 `json:"total_price_ppn"`
+	PpnRate            float64               `json:"ppn_rate"`
+	PpnAmount          int64                 `json:"ppn_amount"`
+	RoundingAmount     int64                 `json:"rounding_amount"`
+	Items              []OrderItemRes        `json:"items"`
+	Currency           string                `json:"currency"`
+	ExchangeRates      []ExchangeRateRes     `json:"exchange_rates"`
+	OrderType          string                `json:"order_type"`
+	OrderStatus        string                `json:"order_status"`
+	PaidAmount         int64                 `json:"paid_amount"`
+	OutstandingBalance int64                 `json:"outstanding_balance"`
+	Installments       []OrderInstallmentRes `json:"installments"`
+	CreatedDate        time.Time             `json:"created_date"`
 }
 
 // Transform from order model to detail order member response
@@ -35,7 +45,7 @@ func (r DetailOrderMemberResponse) Transform(i model.OrderEnt) DetailOrderMember
 		if IsUrl(i.Details) {
 			r.Details = i.Details
 		} else {
-			r.Details = viper.GetString("aws.s3.public_url") + i.Details
+			r.Details = storage.URL(i.Details)
 		}
 
 	} else {
@@ -44,13 +54,95 @@ func (r DetailOrderMemberResponse) Transform(i model.OrderEnt) DetailOrderMember
 
 	r.PaidBy = i.PaidBy
 	r.TotalPrice = i.TotalPrice
+	r.TotalPricePpn = i.TotalPricePpn
+	r.PpnRate = i.PpnRate
+	r.PpnAmount = i.PpnAmount
+	r.RoundingAmount = i.RoundingAmount
 	r.OrderType = i.OrderType
+	r.OrderStatus = i.OrderStatus
 	r.CreatedDate = i.CreatedDate
 
+	r.Items = []OrderItemRes{}
+	for _, a := range i.Items {
+		var res OrderItemRes
+		r.Items = append(r.Items, res.Transform(a))
+	}
+
+	r.Currency = model.BASE_CURRENCY
+	r.ExchangeRates = []ExchangeRateRes{}
+	for _, a := range i.ExchangeRates {
+		var res ExchangeRateRes
+		r.ExchangeRates = append(r.ExchangeRates, res.Transform(a))
+	}
+
+	r.Installments = []OrderInstallmentRes{}
+	for _, a := range i.Installments {
+		var res OrderInstallmentRes
+		r.Installments = append(r.Installments, res.Transform(a))
+	}
+	r.PaidAmount, r.OutstandingBalance = orderBalance(i)
+
 	return r
 
 }
 
+// OrderItemRes line item of order
+type OrderItemRes struct {
+	ItemType    string `json:"item_type"`
+	RefCode     string `json:"ref_code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Quantity    int32  `json:"quantity"`
+	UnitPrice   int64  `json:"unit_price"`
+	Subtotal    int64  `json:"subtotal"`
+	IsTaxable   bool   `json:"is_taxable"`
+
+	// price in the source currency, unit price and subtotal above are in rupiah
+	SourceUnitPrice MoneyRes `json:"source_unit_price"`
+	SourceSubtotal  MoneyRes `json:"source_subtotal"`
+	ExchangeRate    float64  `json:"exchange_rate"`
+}
+
+// Transform from order item model to order item response
+func (r OrderItemRes) Transform(i model.OrderItemEnt) OrderItemRes {
+	r.ItemType = i.ItemType
+	r.RefCode = i.RefCode
+	r.Name = i.Name
+	r.Description = i.Description
+	r.Quantity = i.Quantity
+	r.UnitPrice = i.UnitPrice
+	r.Subtotal = i.Subtotal
+	r.IsTaxable = i.IsTaxable
+
+	r.SourceUnitPrice = MoneyRes{}.Transform(model.Money{Amount: i.SourceUnitPrice, Currency: i.Currency, MinorUnit: i.ExchangeRate.MinorUnit})
+	r.SourceSubtotal = MoneyRes{}.Transform(i.SourceSubtotal())
+	r.ExchangeRate = i.ExchangeRate.Rate
+
+	return r
+}
+
+// orderBalance paid amount and outstanding balance of order
+func orderBalance(i model.OrderEnt) (int64, int64) {
+	var paid int64
+
+	total := i.TotalPrice
+	if i.TotalPricePpn > 0 {
+		total = i.TotalPricePpn
+	}
+
+	if model.IsOrderPaid(i.OrderStatus) {
+		return total, 0
+	}
+
+	for _, a := range i.Installments {
+		if a.PaymentStatus == model.PAYMENT_STATUS_PAID {
+			paid += a.Amount
+		}
+	}
+
+	return paid, total - paid
+}
+
 // ItinOrderMember Response List
 type ItinOrderMemberResponse struct {
 	Title         string `json:"title"`
@@ -78,7 +170,7 @@ func (r ItinOrderMemberResponse) Transform(i model.OrderEnt) ItinOrderMemberResp
 		if IsUrl(i.Details) {
 			r.Details = i.Details
 		} else {
-			r.Details = viper.GetString("aws.s3.public_url") + i.Details
+			r.Details = storage.URL(i.Details)
 		}
 
 	} else {
@@ -97,7 +189,7 @@ func (r ItinOrderMemberResponse) Transform(i model.OrderEnt) ItinOrderMemberResp
 		if IsUrl(i.MemberEnt.Img.String) {
 			memberImage = i.MemberEnt.Img.String
 		} else {
-			memberImage = viper.GetString("aws.s3.public_url") + i.MemberEnt.Img.String
+			memberImage = storage.URL(i.MemberEnt.Img.String)
 		}
 
 	}
@@ -132,3 +224,70 @@ func (r OrderPaymentResponse) Transform(i model.OrderPaymentEnt) OrderPaymentRes
 
 	return r
 }
+
+// OrderInstallmentRes installment of order payment schedule
+type OrderInstallmentRes struct {
+	InstallmentNo int32     `json:"installment_no"`
+	Title         string    `json:"title"`
+	Amount        int64     `json:"amount"`
+	PaymentStatus string    `json:"payment_status"`
+	PaymentType   string    `json:"payment_type"`
+	PaymentURL    string    `json:"payment_url"`
+	DueDate       string    `json:"due_date"`
+	PaidDate      string    `json:"paid_date"`
+	IsOverdue     bool      `json:"is_overdue"`
+	CreatedDate   time.Time `json:"created_date"`
+}
+
+// Transform from order payment model of installment
+func (r OrderInstallmentRes) Transform(i model.OrderPaymentEnt) OrderInstallmentRes {
+	r.InstallmentNo = i.InstallmentNo
+	r.Title = i.Title
+	r.Amount = i.Amount
+	r.PaymentStatus = i.PaymentStatus
+	r.PaymentType = i.PaymentType
+	r.PaymentURL = i.PaymentURL
+	r.CreatedDate = i.CreatedDate
+
+	if i.DueDate.Valid {
+		r.DueDate = i.DueDate.Time.Format(time.RFC3339)
+		r.IsOverdue = i.PaymentStatus != model.PAYMENT_STATUS_PAID && i.DueDate.Time.Before(time.Now())
+	}
+	if i.PaidDate.Valid {
+		r.PaidDate = i.PaidDate.Time.Format(time.RFC3339)
+	}
+
+	return r
+}
+
+// OrderInstallmentsRes payment schedule of order
+type OrderInstallmentsRes struct {
+	OrderCode          string                `json:"order_code"`
+	OrderStatus        string                `json:"order_status"`
+	PaidAmount         int64                 `json:"paid_amount"`
+	OutstandingBalance int64                 `json:"outstanding_balance"`
+	Installments       []OrderInstallmentRes `json:"installments"`
+}
+
+// Transform from order model with the installments
+func (r OrderInstallmentsRes) Transform(i model.OrderEnt) OrderInstallmentsRes {
+	r.OrderCode = i.OrderCode
+	r.OrderStatus = i.OrderStatus
+	r.PaidAmount, r.OutstandingBalance = orderBalance(i)
+
+	r.Installments = []OrderInstallmentRes{}
+	for _, a := range i.Installments {
+		var res OrderInstallmentRes
+		r.Installments = append(r.Installments, res.Transform(a))
+	}
+
+	return r
+}
+
+// OrderInstallmentPaymentRes snap payment of installment
+type OrderInstallmentPaymentRes struct {
+	PaymentCode string              `json:"payment_code"`
+	Token       string              `json:"token"`
+	PaymentURL  string              `json:"payment_url"`
+	Installment OrderInstallmentRes `json:"installment"`
+}
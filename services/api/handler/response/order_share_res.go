@@ -0,0 +1,85 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// OrderShareRes ...
+type OrderShareRes struct {
+	ShareCode     string    `json:"share_code"`
+	MemberCode    string    `json:"member_code"`
+	MemberName    string    `json:"member_name"`
+	Amount        int64     `json:"amount"`
+	PaymentStatus string    `json:"payment_status"`
+	PaymentType   string    `json:"payment_type"`
+	PaymentURL    string    `json:"payment_url"`
+	CoveredByCode string    `json:"covered_by_code"`
+	CoveredByName string    `json:"covered_by_name"`
+	ExpiredDate   string    `json:"expired_date"`
+	PaidDate      string    `json:"paid_date"`
+	RemindedDate  string    `json:"reminded_date"`
+	CreatedDate   time.Time `json:"created_date"`
+}
+
+// Transform OrderShareRes ...
+func (r OrderShareRes) Transform(m model.OrderPaymentShareEnt) OrderShareRes {
+	r.ShareCode = m.ShareCode
+	r.MemberCode = m.Member.MemberCode
+	r.MemberName = m.Member.Name
+	r.Amount = m.Amount
+	r.PaymentStatus = m.PaymentStatus
+	r.PaymentType = m.PaymentType
+	r.PaymentURL = m.PaymentURL
+	r.CoveredByCode = m.CoveredByCode
+	r.CoveredByName = m.CoveredByName
+	r.CreatedDate = m.CreatedDate
+
+	if m.ExpiredDate.Valid {
+		r.ExpiredDate = m.ExpiredDate.Time.Format(time.RFC3339)
+	}
+	if m.PaidDate.Valid {
+		r.PaidDate = m.PaidDate.Time.Format(time.RFC3339)
+	}
+	if m.RemindedDate.Valid {
+		r.RemindedDate = m.RemindedDate.Time.Format(time.RFC3339)
+	}
+
+	return r
+}
+
+// OrderSharesRes summary of split order payment
+type OrderSharesRes struct {
+	OrderCode   string          `json:"order_code"`
+	OrderStatus string          `json:"order_status"`
+	TotalAmount int64           `json:"total_amount"`
+	PaidAmount  int64           `json:"paid_amount"`
+	Shares      []OrderShareRes `json:"shares"`
+}
+
+// Transform OrderSharesRes ...
+func (r OrderSharesRes) Transform(o model.OrderEnt, shares []model.OrderPaymentShareEnt) OrderSharesRes {
+	r.OrderCode = o.OrderCode
+	r.OrderStatus = o.OrderStatus
+	r.Shares = []OrderShareRes{}
+
+	for _, s := range shares {
+		var res OrderShareRes
+		r.TotalAmount += s.Amount
+		if s.PaymentStatus == model.PAYMENT_STATUS_PAID {
+			r.PaidAmount += s.Amount
+		}
+		r.Shares = append(r.Shares, res.Transform(s))
+	}
+
+	return r
+}
+
+// OrderSharePaymentRes snap payment of one or more shares
+type OrderSharePaymentRes struct {
+	TransactionCode string          `json:"transaction_code"`
+	Amount          int64           `json:"amount"`
+	Token           string          `json:"token"`
+	PaymentURL      string          `json:"payment_url"`
+	Shares          []OrderShareRes `json:"shares"`
+}
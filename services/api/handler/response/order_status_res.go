@@ -0,0 +1,34 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// OrderStatusHistoryRes ...
+type OrderStatusHistoryRes struct {
+	FromStatus     string    `json:"from_status"`
+	FromStatusDesc string    `json:"from_status_desc"`
+	ToStatus       string    `json:"to_status"`
+	ToStatusDesc   string    `json:"to_status_desc"`
+	ActorType      string    `json:"actor_type"`
+	ActorName      string    `json:"actor_name"`
+	Reason         string    `json:"reason"`
+	CreatedDate    time.Time `json:"created_date"`
+}
+
+// Transform from order status history model to order status history response
+func (r OrderStatusHistoryRes) Transform(m model.OrderStatusHistoryEnt) OrderStatusHistoryRes {
+	if m.FromStatus.Valid {
+		r.FromStatus = m.FromStatus.String
+		r.FromStatusDesc = model.OrderStatusDesc(m.FromStatus.String)
+	}
+	r.ToStatus = m.ToStatus
+	r.ToStatusDesc = model.OrderStatusDesc(m.ToStatus)
+	r.ActorType = m.ActorType
+	r.ActorName = m.ActorName
+	r.Reason = m.Reason.String
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
@@ -0,0 +1,95 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// PaymentReconciliationRes ...
+type PaymentReconciliationRes struct {
+	ID             int32                          `json:"id"`
+	FileName       string                         `json:"file_name"`
+	PeriodStart    *time.Time                     `json:"period_start"`
+	PeriodEnd      *time.Time                     `json:"period_end"`
+	TotalRows      int32                          `json:"total_rows"`
+	MatchedRows    int32                          `json:"matched_rows"`
+	MismatchedRows int32                          `json:"mismatched_rows"`
+	SettledAmount  int64                          `json:"settled_amount"`
+	CreatedDate    time.Time                      `json:"created_date"`
+	Items          []PaymentReconciliationItemRes `json:"items,omitempty"`
+}
+
+// Transform PaymentReconciliationRes ...
+func (r PaymentReconciliationRes) Transform(m model.PaymentReconciliationEnt) PaymentReconciliationRes {
+	r.ID = m.ID
+	r.FileName = m.FileName
+	r.TotalRows = m.TotalRows
+	r.MatchedRows = m.MatchedRows
+	r.MismatchedRows = m.MismatchedRows
+	r.SettledAmount = m.SettledAmount
+	r.CreatedDate = m.CreatedDate
+
+	if m.PeriodStart.Valid {
+		r.PeriodStart = &m.PeriodStart.Time
+	}
+	if m.PeriodEnd.Valid {
+		r.PeriodEnd = &m.PeriodEnd.Time
+	}
+
+	if m.Items != nil {
+		r.Items = []PaymentReconciliationItemRes{}
+		for _, i := range m.Items {
+			var res PaymentReconciliationItemRes
+			r.Items = append(r.Items, res.Transform(i))
+		}
+	}
+
+	return r
+}
+
+// PaymentReconciliationItemRes ...
+type PaymentReconciliationItemRes struct {
+	ID              int32      `json:"id"`
+	MidtransOrderID string     `json:"midtrans_order_id"`
+	OrderCode       string     `json:"order_code"`
+	PaymentSource   string     `json:"payment_source"`
+	TransactionID   string     `json:"transaction_id"`
+	PaymentType     string     `json:"payment_type"`
+	TransactionTime *time.Time `json:"transaction_time"`
+	MidtransStatus  string     `json:"midtrans_status"`
+	MidtransAmount  *int64     `json:"midtrans_amount"`
+	LocalStatus     string     `json:"local_status"`
+	LocalAmount     *int64     `json:"local_amount"`
+	Result          string     `json:"result"`
+	Note            string     `json:"note"`
+	CheckedDate     *time.Time `json:"checked_date"`
+}
+
+// Transform PaymentReconciliationItemRes ...
+func (r PaymentReconciliationItemRes) Transform(m model.PaymentReconciliationItemEnt) PaymentReconciliationItemRes {
+	r.ID = m.ID
+	r.MidtransOrderID = m.MidtransOrderID
+	r.OrderCode = m.OrderCode.String
+	r.PaymentSource = m.PaymentSource.String
+	r.TransactionID = m.TransactionID.String
+	r.PaymentType = m.PaymentType.String
+	r.MidtransStatus = m.MidtransStatus.String
+	r.LocalStatus = m.LocalStatus.String
+	r.Result = m.Result
+	r.Note = m.Note.String
+
+	if m.TransactionTime.Valid {
+		r.TransactionTime = &m.TransactionTime.Time
+	}
+	if m.MidtransAmount.Valid {
+		r.MidtransAmount = &m.MidtransAmount.Int64
+	}
+	if m.LocalAmount.Valid {
+		r.LocalAmount = &m.LocalAmount.Int64
+	}
+	if m.CheckedDate.Valid {
+		r.CheckedDate = &m.CheckedDate.Time
+	}
+
+	return r
+}
@@ -0,0 +1,38 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// SettingRes ...
+type SettingRes struct {
+	ID          int32       `json:"id"`
+	Group       string      `json:"group"`
+	Key         string      `json:"key"`
+	Label       string      `json:"label"`
+	Order       int         `json:"order"`
+	SetType     string      `json:"set_type"`
+	SetContent  interface{} `json:"set_content"`
+	IsActive    bool        `json:"is_active"`
+	IsPublic    bool        `json:"is_public"`
+	CreatedDate time.Time   `json:"created_date"`
+	UpdatedDate *time.Time  `json:"updated_date"`
+}
+
+// Transform from setting model to setting response, the content is typed by the set type
+func (r SettingRes) Transform(m model.SettingEnt) SettingRes {
+	r.ID = m.ID
+	r.Group = m.SetGroup
+	r.Key = m.SetKey
+	r.Label = m.SetLabel
+	r.Order = m.SetOrder
+	r.SetType = m.ContentType
+	r.SetContent = m.Value()
+	r.IsActive = m.IsActive
+	r.IsPublic = m.IsPublic
+	r.CreatedDate = m.CreatedDate
+	r.UpdatedDate = m.UpdatedDate
+
+	return r
+}
@@ -1,9 +1,8 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
-
-	"github.com/spf13/viper"
 )
 
 // MemberResponse ...
@@ -11,8 +10,11 @@ type StuffResponse struct {
 	Code       		string `json:"code"`
 	Name 	   		string `json:"name"`
 	Image      		string  `json:"image"`
+	Images          map[string]string `json:"images,omitempty"`
 	Description		string `json:"description"`
 	Price    		string `json:"price"`
+	Currency        string `json:"currency"`
+	PriceIDR        *int64 `json:"price_idr"`
 	Type       		int32 `json:"type"`
 }
 
@@ -26,7 +28,8 @@ func (r StuffResponse) Transform(m model.StuffEnt) StuffResponse {
 		if IsUrl(m.Image.String) {
 			r.Image = m.Image.String
 		} else {
-			r.Image = viper.GetString("aws.s3.public_url") + m.Image.String
+			r.Image = storage.URL(m.Image.String)
+			r.Images = ImageURLs(m.Image.String)
 		}
 
 	} else {
@@ -35,6 +38,7 @@ func (r StuffResponse) Transform(m model.StuffEnt) StuffResponse {
 
 	r.Description = m.Description
 	r.Price = m.Price
+	r.Currency, r.PriceIDR = stuffCurrency(m)
 	r.Type = m.Type
 
 	return r
@@ -43,8 +47,11 @@ func (r StuffResponse) Transform(m model.StuffEnt) StuffResponse {
 type StuffListResponse struct {
 	Name 	   		string `json:"name"`
 	Image      		string  `json:"image"`
+	Images          map[string]string `json:"images,omitempty"`
 	Description		string `json:"description"`
 	Price    		string `json:"price"`
+	Currency        string `json:"currency"`
+	PriceIDR        *int64 `json:"price_idr"`
 	CreatedDate     string `json:"created_date"`
 }
 
@@ -59,7 +66,8 @@ func (r StuffListResponse) Transform(i model.StuffEnt) StuffListResponse {
 		if IsUrl(i.Image.String) {
 			r.Image = i.Image.String
 		} else {
-			r.Image = viper.GetString("aws.s3.public_url") + i.Image.String
+			r.Image = storage.URL(i.Image.String)
+			r.Images = ImageURLs(i.Image.String)
 		}
 
 	} else {
@@ -67,6 +75,7 @@ func (r StuffListResponse) Transform(i model.StuffEnt) StuffListResponse {
 	}
 	r.Description = i.Description
 	r.Price = i.Price
+	r.Currency, r.PriceIDR = stuffCurrency(i)
 	r.CreatedDate = timeday
 
 	return r
@@ -75,8 +84,11 @@ func (r StuffListResponse) Transform(i model.StuffEnt) StuffListResponse {
 type StuffDetailResponse struct {
 	Name 	   		string `json:"name"`
 	Image      		string  `json:"image"`
+	Images          map[string]string `json:"images,omitempty"`
 	Description		string `json:"description"`
 	Price    		string `json:"price"`
+	Currency        string `json:"currency"`
+	PriceIDR        *int64 `json:"price_idr"`
 }
 
 // Transform from order model to itin order member response
@@ -87,7 +99,8 @@ func (r StuffDetailResponse) Transform(i model.StuffEnt) StuffDetailResponse {
 		if IsUrl(i.Image.String) {
 			r.Image = i.Image.String
 		} else {
-			r.Image = viper.GetString("aws.s3.public_url") + i.Image.String
+			r.Image = storage.URL(i.Image.String)
+			r.Images = ImageURLs(i.Image.String)
 		}
 
 	} else {
@@ -95,6 +108,27 @@ func (r StuffDetailResponse) Transform(i model.StuffEnt) StuffDetailResponse {
 	}
 	r.Description = i.Description
 	r.Price = i.Price
+	r.Currency, r.PriceIDR = stuffCurrency(i)
 
 	return r
-}
\ No newline at end of file
+}
+
+// stuffCurrency currency of stuff price and the rupiah price, null when the rate is not available
+func stuffCurrency(m model.StuffEnt) (string, *int64) {
+	currency := m.CurrencyCode
+	if len(currency) == 0 {
+		currency = model.BASE_CURRENCY
+	}
+
+	if !m.PriceIDR.Valid && currency == model.BASE_CURRENCY {
+		if price, err := model.ParseMoney(m.Price, model.CurrencyEnt{Code: currency}); err == nil {
+			return currency, &price.Amount
+		}
+	}
+	if !m.PriceIDR.Valid {
+		return currency, nil
+	}
+	price := m.PriceIDR.Int64
+
+	return currency, &price
+}
@@ -0,0 +1,92 @@
+package response
+
+import (
+	"math"
+	"panorama/lib/storage"
+	"panorama/services/api/model"
+	"strings"
+	"time"
+)
+
+// TcRatingResponse ...
+type TcRatingResponse struct {
+	Rating      int32     `json:"rating"`
+	Comment     string    `json:"comment"`
+	MemberCode  string    `json:"member_code"`
+	MemberName  string    `json:"member_name"`
+	CreatedDate time.Time `json:"created_date"`
+}
+
+// Transform from tc rating model to tc rating response
+func (r TcRatingResponse) Transform(m model.TcRatingEnt) TcRatingResponse {
+	r.Rating = m.Rating
+	r.Comment = m.Comment
+	r.MemberCode = m.Member.MemberCode
+	r.MemberName = m.Member.Name
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
+
+// TcRatingReportResponse ...
+type TcRatingReportResponse struct {
+	TcCode      string           `json:"tc_code"`
+	TcName      string           `json:"tc_name"`
+	TcImg       string           `json:"tc_img"`
+	TotalRating int32            `json:"total_rating"`
+	AvgRating   float64          `json:"avg_rating"`
+	TotalStar   map[string]int32 `json:"total_star"`
+}
+
+// Transform from tc rating report model to tc rating report response
+func (r TcRatingReportResponse) Transform(m model.TcRatingReportEnt) TcRatingReportResponse {
+	r.TcCode = m.User.UserCode
+	r.TcName = m.User.Name
+	r.TotalRating = m.TotalRating
+	r.AvgRating = roundRating(m.AvgRating)
+	r.TotalStar = m.TotalStar
+
+	if len(strings.TrimSpace(m.User.Img.String)) > 0 {
+		if IsUrl(m.User.Img.String) {
+			r.TcImg = m.User.Img.String
+		} else {
+			r.TcImg = storage.URL(m.User.Img.String)
+		}
+	}
+
+	return r
+}
+
+// ChatTcHandoverResponse ...
+type ChatTcHandoverResponse struct {
+	FromTcCode   string    `json:"from_tc_code"`
+	FromTcName   string    `json:"from_tc_name"`
+	ToTcCode     string    `json:"to_tc_code"`
+	ToTcName     string    `json:"to_tc_name"`
+	Notes        string    `json:"notes"`
+	ChangedBy    string    `json:"changed_by"`
+	CreatedDate  time.Time `json:"created_date"`
+	AssignedDate string    `json:"assigned_date"`
+}
+
+// Transform from handover model to handover response
+func (r ChatTcHandoverResponse) Transform(m model.ChatTcHandoverEnt) ChatTcHandoverResponse {
+	r.FromTcCode = m.FromTc.UserCode
+	r.FromTcName = m.FromTc.Name
+	r.ToTcCode = m.ToTc.UserCode
+	r.ToTcName = m.ToTc.Name
+	r.Notes = m.Notes
+	r.ChangedBy = m.ChangedBy
+	r.CreatedDate = m.CreatedDate
+
+	if m.AssignedDate.Valid {
+		r.AssignedDate = m.AssignedDate.Time.Format(time.RFC3339)
+	}
+
+	return r
+}
+
+// roundRating round average rating into one decimal
+func roundRating(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,81 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"strings"
+	"time"
+)
+
+// TripDocumentRes ...
+type TripDocumentRes struct {
+	DocumentCode      string     `json:"document_code"`
+	DocumentType      string     `json:"document_type"`
+	Title             string     `json:"title"`
+	DocumentNumber    string     `json:"document_number"`
+	ExpiryDate        string     `json:"expiry_date"`
+	ExpiryWarning     string     `json:"expiry_warning"`
+	MemberCode        string     `json:"member_code"`
+	MemberName        string     `json:"member_name"`
+	FileName          string     `json:"file_name"`
+	FileMime          string     `json:"file_mime"`
+	FileSize          int64      `json:"file_size"`
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiredAt *time.Time `json:"download_expired_at,omitempty"`
+	CreatedDate       time.Time  `json:"created_date"`
+}
+
+// Transform TripDocumentRes, warning of passport expiry is computed by the handler
+func (r TripDocumentRes) Transform(m model.TripDocumentEnt, expiryWarning string) TripDocumentRes {
+	r.DocumentCode = m.DocumentCode
+	r.DocumentType = m.DocumentType
+	r.Title = m.Title
+	r.DocumentNumber = m.DocumentNumber.String
+	r.ExpiryWarning = expiryWarning
+	r.MemberCode = m.MemberCode
+	r.MemberName = m.MemberName
+	r.FileName = m.FileName
+	r.FileMime = m.FileMime
+	r.FileSize = m.FileSize
+	r.CreatedDate = m.CreatedDate
+
+	if m.ExpiryDate.Valid {
+		r.ExpiryDate = m.ExpiryDate.Time.Format("2006-01-02")
+	}
+
+	return r
+}
+
+// MaskNumber document number of the list, only the last 4 characters are shown and the full number is
+// read from the detail that is logged
+func (r TripDocumentRes) MaskNumber() TripDocumentRes {
+	n := []rune(r.DocumentNumber)
+	if len(n) <= 4 {
+		r.DocumentNumber = strings.Repeat("*", len(n))
+		return r
+	}
+	r.DocumentNumber = strings.Repeat("*", len(n)-4) + string(n[len(n)-4:])
+
+	return r
+}
+
+// TripDocumentAccessLogRes ...
+type TripDocumentAccessLogRes struct {
+	ActorType   string    `json:"actor_type"`
+	ActorName   string    `json:"actor_name"`
+	Action      string    `json:"action"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	CreatedDate time.Time `json:"created_date"`
+}
+
+// Transform TripDocumentAccessLogRes ...
+func (r TripDocumentAccessLogRes) Transform(m model.TripDocumentAccessLogEnt) TripDocumentAccessLogRes {
+	r.ActorType = m.ActorType
+	r.ActorName = m.ActorName
+	r.Action = m.Action
+	r.IPAddress = m.IPAddress.String
+	r.UserAgent = m.UserAgent.String
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
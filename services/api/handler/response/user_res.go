@@ -1,11 +1,11 @@
 package response
 
 import (
+	"panorama/lib/storage"
 	"panorama/services/api/model"
 	"strings"
 
 	"github.com/andanhm/go-prettytime"
-	"github.com/spf13/viper"
 )
 
 // UsersResponse ...
@@ -15,10 +15,12 @@ type UsersResponse struct {
 	Phone    string `json:"phone"`
 	Email    string `json:"email"`
 	// Password      string `json:"password"`
-	Img           string `json:"image"`
-	Role          string `json:"role"`
-	LastVisitDate string `json:"last_visit_date"`
-	TotalClient   int32  `json:"total_client"`
+	Img           string  `json:"image"`
+	Role          string  `json:"role"`
+	LastVisitDate string  `json:"last_visit_date"`
+	TotalClient   int32   `json:"total_client"`
+	AvgRating     float64 `json:"avg_rating"`
+	TotalRating   int32   `json:"total_rating"`
 }
 
 // Transform from member model to member response
@@ -33,7 +35,7 @@ func (r UsersResponse) Transform(m model.UserEnt) UsersResponse {
 		if IsUrl(m.Img.String) {
 			r.Img = m.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + m.Img.String
+			r.Img = storage.URL(m.Img.String)
 		}
 
 	} else {
@@ -48,6 +50,8 @@ func (r UsersResponse) Transform(m model.UserEnt) UsersResponse {
 	r.Role = m.Role
 	r.LastVisitDate = t
 	r.TotalClient = m.TotalClient.Int32
+	r.AvgRating = roundRating(m.AvgRating.Float64)
+	r.TotalRating = m.TotalRating.Int32
 
 	return r
 }
@@ -63,6 +67,7 @@ type TcDetailResponse struct {
 	SummaryActivityTc     SummaryActivityTc       `json:"summary_activity"`
 	RecentActivityUser    []RecentActivityUser    `json:"log_activity_user"`
 	ActiveClientConsultan []ActiveClientConsultan `json:"active_client_consultan"`
+	LatestRatings         []TcRatingResponse      `json:"latest_ratings"`
 }
 
 // TcDetailResponse ...
@@ -79,7 +84,7 @@ func (r TcDetailResponse) Transform(m model.UserEnt) TcDetailResponse {
 		if IsUrl(m.Img.String) {
 			r.Img = m.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + m.Img.String
+			r.Img = storage.URL(m.Img.String)
 		}
 
 	} else {
@@ -99,17 +104,27 @@ func (r TcDetailResponse) Transform(m model.UserEnt) TcDetailResponse {
 		act = append(act, resAct)
 	}
 
+	var ratings []TcRatingResponse
+	for _, g := range m.TcRatings {
+		var resRating TcRatingResponse
+		resRating = resRating.Transform(g)
+		ratings = append(ratings, resRating)
+	}
+
 	r.RecentActivityUser = listResponse
 	r.ActiveClientConsultan = act
+	r.LatestRatings = ratings
 
 	return r
 }
 
 // SummaryActivityTc ...
 type SummaryActivityTc struct {
-	TotalClient         int32 `json:"total_client"`
-	TripBooked          int32 `json:"trip_booked"`
-	CustomPackageBooked int32 `json:"custom_package_booked"`
+	TotalClient         int32   `json:"total_client"`
+	TripBooked          int32   `json:"trip_booked"`
+	CustomPackageBooked int32   `json:"custom_package_booked"`
+	AvgRating           float64 `json:"avg_rating"`
+	TotalRating         int32   `json:"total_rating"`
 }
 
 // SummaryActivityTc ...
@@ -118,6 +133,8 @@ func (r SummaryActivityTc) Transform(m model.UserEnt) SummaryActivityTc {
 	r.TotalClient = m.TotalClient.Int32
 	r.TripBooked = m.TotalOrd.Int32
 	r.CustomPackageBooked = m.TotalCustomOrder.Int32
+	r.AvgRating = roundRating(m.AvgRating.Float64)
+	r.TotalRating = m.TotalRating.Int32
 
 	return r
 }
@@ -148,7 +165,7 @@ func (r AdminDetailResponse) Transform(m model.UserEnt) AdminDetailResponse {
 		if IsUrl(m.Img.String) {
 			r.Img = m.Img.String
 		} else {
-			r.Img = viper.GetString("aws.s3.public_url") + m.Img.String
+			r.Img = storage.URL(m.Img.String)
 		}
 
 	} else {
@@ -228,3 +245,29 @@ func (r ActiveClientConsultan) Transform(v model.ActiveClientConsultan) ActiveCl
 
 	return r
 }
+
+// LogActivityResponse activity log of admin, tc or customer
+type LogActivityResponse struct {
+	Role        string `json:"role"`
+	UserCode    string `json:"user_code"`
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Activity    string `json:"activity"`
+	EventType   string `json:"event_type"`
+	CreatedDate string `json:"created_date"`
+}
+
+// Transform LogActivityResponse ...
+func (r LogActivityResponse) Transform(log model.LogActivityUserEnt) LogActivityResponse {
+	r.Role = log.Role
+	r.UserCode = log.UserCode.String
+	r.Name = log.Name.String
+	r.Title = log.Title
+	r.Activity = log.Activity
+	r.EventType = log.EventType
+	if log.CreatedDate.Valid {
+		r.CreatedDate = log.CreatedDate.Time.Format("2006-01-02 15:04:05")
+	}
+
+	return r
+}
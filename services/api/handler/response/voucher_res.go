@@ -0,0 +1,99 @@
+package response
+
+import (
+	"panorama/services/api/model"
+	"time"
+)
+
+// VoucherRes ...
+type VoucherRes struct {
+	VoucherCode      string    `json:"voucher_code"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	DiscountType     string    `json:"discount_type"`
+	DiscountValue    int64     `json:"discount_value"`
+	MaxDiscount      int64     `json:"max_discount"`
+	MinSpend         int64     `json:"min_spend"`
+	StartDate        time.Time `json:"start_date"`
+	EndDate          time.Time `json:"end_date"`
+	UsageLimit       int32     `json:"usage_limit"`
+	UsageLimitMember int32     `json:"usage_limit_member"`
+	UsedCount        int32     `json:"used_count"`
+	Destinations     []string  `json:"destinations"`
+	OrderTypes       []string  `json:"order_types"`
+	IsActive         bool      `json:"is_active"`
+	CreatedDate      time.Time `json:"created_date"`
+}
+
+// Transform VoucherRes ...
+func (r VoucherRes) Transform(m model.VoucherEnt) VoucherRes {
+	r.VoucherCode = m.VoucherCode
+	r.Title = m.Title
+	r.Description = m.Description
+	r.DiscountType = m.DiscountType
+	r.DiscountValue = m.DiscountValue
+	r.MaxDiscount = m.MaxDiscount.Int64
+	r.MinSpend = m.MinSpend
+	r.StartDate = m.StartDate
+	r.EndDate = m.EndDate
+	r.UsageLimit = m.UsageLimit.Int32
+	r.UsageLimitMember = m.UsageLimitMember.Int32
+	r.UsedCount = m.UsedCount
+	r.IsActive = m.IsActive
+	r.CreatedDate = m.CreatedDate
+
+	r.Destinations = []string{}
+	if len(m.Destinations) > 0 {
+		r.Destinations = m.Destinations
+	}
+	r.OrderTypes = []string{}
+	if len(m.OrderTypes) > 0 {
+		r.OrderTypes = m.OrderTypes
+	}
+
+	return r
+}
+
+// VoucherRedemptionRes ...
+type VoucherRedemptionRes struct {
+	OrderCode      string    `json:"order_code"`
+	MemberCode     string    `json:"member_code"`
+	MemberName     string    `json:"member_name"`
+	DiscountAmount int64     `json:"discount_amount"`
+	CreatedDate    time.Time `json:"created_date"`
+}
+
+// Transform VoucherRedemptionRes ...
+func (r VoucherRedemptionRes) Transform(m model.VoucherRedemptionEnt) VoucherRedemptionRes {
+	r.OrderCode = m.OrderCode
+	r.MemberCode = m.MemberCode
+	r.MemberName = m.MemberName
+	r.DiscountAmount = m.DiscountAmount
+	r.CreatedDate = m.CreatedDate
+
+	return r
+}
+
+// OrderVoucherRes total of order after voucher is applied or removed
+type OrderVoucherRes struct {
+	OrderCode      string `json:"order_code"`
+	VoucherCode    string `json:"voucher_code"`
+	DiscountAmount int64  `json:"discount_amount"`
+	TotalPrice     int64  `json:"total_price"`
+	PpnAmount      int64  `json:"ppn_amount"`
+	RoundingAmount int64  `json:"rounding_amount"`
+	TotalPricePpn  int64  `json:"total_price_ppn"`
+}
+
+// Transform OrderVoucherRes ...
+func (r OrderVoucherRes) Transform(o model.OrderEnt, v model.VoucherEnt, discount int64) OrderVoucherRes {
+	r.OrderCode = o.OrderCode
+	r.VoucherCode = v.VoucherCode
+	r.DiscountAmount = discount
+	r.TotalPrice = o.TotalPrice
+	r.PpnAmount = o.PpnAmount
+	r.RoundingAmount = o.RoundingAmount
+	r.TotalPricePpn = o.TotalPricePpn
+
+	return r
+}
@@ -1,19 +1,154 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"net/http"
+	"panorama/lib/audit"
+	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
 	"panorama/services/api/model"
 	"strconv"
 
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
+// GetListSettingAct settings of all groups (admin)
+func (h *Contract) GetListSettingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"group":   "",
+		"keyword": "",
+		"page":    1,
+		"limit":   -1,
+		"offset":  0,
+	}
+
+	if group, ok := r.URL.Query()["group"]; ok && len(group[0]) > 0 {
+		param["group"] = group[0]
+	}
+
+	if keyword, ok := r.URL.Query()["keyword"]; ok && len(keyword[0]) > 0 {
+		param["keyword"] = keyword[0]
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	if param["limit"].(int) != -1 {
+		param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	settings, err := m.GetListSetting(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.SettingRes{}
+	for _, s := range settings {
+		var res response.SettingRes
+		listResponse = append(listResponse, res.Transform(s))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetSettingAct ...
+func (h *Contract) GetSettingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	s, _ := m.GetSettingByID(db, ctx, int32(id))
+	if s.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Setting %d not found.", id))
+		return
+	}
+
+	var res response.SettingRes
+	h.SendSuccess(w, res.Transform(s), nil)
+}
+
+// GetPublicSettingAct active & public settings of the group by key (without login),
+// e.g. app banners, support contacts, minimum app version
+func (h *Contract) GetPublicSettingAct(w http.ResponseWriter, r *http.Request) {
+	group := chi.URLParam(r, "group")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	settings, err := m.GetPublicSettings(db, ctx, group)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if len(settings) == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Settings %s not found.", group))
+		return
+	}
+
+	res := map[string]interface{}{}
+	for _, s := range settings {
+		res[s.SetKey] = s.Value()
+	}
+
+	h.SendSuccess(w, res, nil)
+}
+
 // AddSettingAct ...
 func (h *Contract) AddSettingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
 	var err error
 	req := request.SettingReq{}
 	if err = h.Bind(r, &req); err != nil {
@@ -26,29 +161,32 @@ func (h *Contract) AddSettingAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
 	defer db.Release()
-	if err = m.AddSetting(db, ctx, model.SettingEnt{
-		SetGroup:     req.Group,
-		SetKey:       req.Key,
-		SetLabel:     req.Label,
-		ContentType:  req.SetType,
-		ContentValue: req.SetContent,
-	}); err != nil {
-		h.SendBadRequest(w, err.Error())
+
+	s, err := m.AddSetting(db, ctx, req.Transform(model.SettingEnt{}))
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
 		return
 	}
+	h.audit(r, db, model.AUDIT_SETTING, strconv.Itoa(int(s.ID)), audit.ActionCreate, nil, s)
 
-	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+	var res response.SettingRes
+	h.SendSuccess(w, res.Transform(s), nil)
 }
 
 // UpdateSettingAct ...
 func (h *Contract) UpdateSettingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
 	var err error
 	req := request.SettingReq{}
 	if err = h.Bind(r, &req); err != nil {
@@ -68,23 +206,64 @@ func (h *Contract) UpdateSettingAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	m := model.Contract{App: h.App}
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
 	defer db.Release()
-	if err = m.UpdateSetting(db, ctx, int32(id32), model.SettingEnt{
-		SetGroup:     req.Group,
-		SetKey:       req.Key,
-		SetLabel:     req.Label,
-		ContentType:  req.SetType,
-		ContentValue: req.SetContent,
-	}); err != nil {
+
+	old, _ := m.GetSettingByID(db, ctx, int32(id32))
+	if old.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Setting %s not found.", id))
+		return
+	}
+
+	s, err := m.UpdateSetting(db, ctx, old.ID, req.Transform(old))
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		return
+	}
+	h.audit(r, db, model.AUDIT_SETTING, id, audit.ActionUpdate, old, s)
+
+	var res response.SettingRes
+	h.SendSuccess(w, res.Transform(s), nil)
+}
+
+// DeleteSettingAct ...
+func (h *Contract) DeleteSettingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.SendBadRequest(w, "invalid id")
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	s, _ := m.GetSettingByID(db, ctx, int32(id))
+	if s.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Setting %d not found.", id))
+		return
+	}
+
+	if err = m.DeleteSetting(db, ctx, s.ID); err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		return
+	}
+	h.audit(r, db, model.AUDIT_SETTING, strconv.Itoa(id), audit.ActionDelete, s, nil)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -1,11 +1,11 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
@@ -32,7 +32,7 @@ func (h *Contract) AddStuffAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -56,11 +56,28 @@ func (h *Contract) AddStuffAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Price in foreign currency is saved in decimal text of the currency
+	currency := model.CurrencyEnt{Code: model.BASE_CURRENCY}
+	if len(req.Currency) > 0 {
+		currency, err = m.GetCurrency(db, ctx, req.Currency)
+		if err != nil {
+			h.SendBadRequest(w, fmt.Sprintf("Currency %s is not supported.", req.Currency))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+	if _, err = model.ParseMoney(req.Price, currency); err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
 	stuff, err := m.AddStuff(db, ctx, model.StuffEnt{
 		Name:  		  req.Name,
 		Image:        sql.NullString{String: req.Image, Valid: true},
 		Description:  req.Description,
 		Price:     	  req.Price,
+		CurrencyCode: currency.Code,
 		Type:         int32(req.Type),
 		IsActive: 	  req.IsActive,
 		CreatedDate:  time.Time{},
@@ -129,6 +146,8 @@ func (h *Contract) AddStuffAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.audit(r, db, model.AUDIT_STUFF, stuff.Code, audit.ActionCreate, nil, stuff)
+
 	var res response.StuffResponse
 	res = res.Transform(stuff)
 
@@ -182,7 +201,7 @@ func (h *Contract) GetListStuffAct(w http.ResponseWriter, r *http.Request) {
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -209,7 +228,7 @@ func (h *Contract) GetListStuffAct(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Contract) GeDetailStuffAct(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -254,7 +273,7 @@ func (h *Contract) UpdateDataStuffAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -301,7 +320,7 @@ func (h *Contract) DeleteStuffAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -327,6 +346,7 @@ func (h *Contract) DeleteStuffAct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// edit status to false
+	before := dataStuff
 	dataStuff.IsActive = false
 	err = m.UpdateIsActiveData(tx, ctx, code, dataStuff)
 	if err != nil {
@@ -342,6 +362,7 @@ func (h *Contract) DeleteStuffAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_STUFF, code, audit.ActionDelete, before, dataStuff)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// AddTcRatingAct customer give rating to tc of the chat session, only after the session with the tc is ended
+func (h *Contract) AddTcRatingAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "customer" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	var err error
+	code := chi.URLParam(r, "code")
+	req := request.TcRatingReq{}
+	if err = h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if err = h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	// validasi user yang bukan bagian dari chat group
+	id, err := m.IsExistInGroupChat(db, ctx, code, h.GetUserCode(r.Context()))
+	if id <= 0 {
+		h.SendBadRequest(w, "Access denied for rating this chat")
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	member, err := m.GetMemberBy(db, ctx, "member_code", h.GetUserCode(r.Context()))
+	if member.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Customer %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	chatGroupID, _, err := m.GetChatGroupCurrentTc(db, ctx, code)
+	if chatGroupID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Group code %s not found.", code))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tc, err := m.GetUserByCode(db, ctx, req.TcCode)
+	if tc.ID == 0 || tc.Role != "tc" {
+		h.SendNotfound(w, fmt.Sprintf("Tc with code %s not found.", req.TcCode))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	// only tc that served the chat group can be rated
+	served, err := m.IsTcServedChatGroup(db, ctx, chatGroupID, tc.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !served {
+		h.SendBadRequest(w, "Tc has never been assigned to this chat")
+		return
+	}
+
+	// the session is rated after it is ended or the tc is replaced
+	open, err := m.IsTcInOpenSession(db, ctx, chatGroupID, tc.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if open {
+		h.SendBadRequest(w, "Tc can be rated after the chat session is ended")
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	rating, err := m.AddTcRating(tx, ctx, model.TcRatingEnt{
+		ChatGroupID: chatGroupID,
+		TcID:        tc.ID,
+		MemberID:    member.ID,
+		Rating:      req.Rating,
+		Comment:     req.Comment,
+	})
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Activity user
+	log := model.LogActivityUserEnt{
+		UserID:    int64(member.ID),
+		Role:      "customer",
+		Title:     "Rated a travel consultant",
+		Activity:  fmt.Sprintf("Rated %s %d stars", tc.Name, req.Rating),
+		EventType: r.Method,
+	}
+	_, err = m.AddLogActivity(tx, ctx, log)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	rating.Member = member
+
+	var res response.TcRatingResponse
+	res = res.Transform(rating)
+
+	h.SendSuccess(w, res, nil)
+}
+
+// GetChatTcHandoverAct list of handover notes in the chat group, only for tc and admin
+func (h *Contract) GetChatTcHandoverAct(w http.ResponseWriter, r *http.Request) {
+	role := h.GetUserRole(r.Context())
+	if role != "tc" && role != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	chatGroupID, _, err := m.GetChatGroupCurrentTc(db, ctx, code)
+	if chatGroupID <= 0 {
+		h.SendNotfound(w, fmt.Sprintf("Group code %s not found.", code))
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if role == "tc" {
+		tc, err := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+
+		served, err := m.IsTcServedChatGroup(db, ctx, chatGroupID, tc.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+		if !served {
+			h.SendUnAuthorizedData(w)
+			return
+		}
+	}
+
+	list, err := m.GetListChatTcHandoverByChatCode(db, ctx, code)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var listResponse []response.ChatTcHandoverResponse
+	for _, a := range list {
+		var res response.ChatTcHandoverResponse
+		res = res.Transform(a)
+		listResponse = append(listResponse, res)
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// GetTcRatingReportAct report of tc ratings by period, only for admin
+func (h *Contract) GetTcRatingReportAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"start_date": "",
+		"end_date":   "",
+		"tc_code":    "",
+	}
+
+	if start_date, ok := r.URL.Query()["start_date"]; ok && len(start_date[0]) > 0 {
+		parseStartTime, err := time.Parse("2006-01-02", start_date[0])
+		if err != nil {
+			h.SendBadRequest(w, "Invalid start date format, should be YYYY-MM-DD")
+			return
+		}
+		param["start_date"] = parseStartTime.Format("2006-01-02")
+	}
+
+	if end_date, ok := r.URL.Query()["end_date"]; ok && len(end_date[0]) > 0 {
+		parseEndTime, err := time.Parse("2006-01-02", end_date[0])
+		if err != nil {
+			h.SendBadRequest(w, "Invalid end date format, should be YYYY-MM-DD")
+			return
+		}
+		param["end_date"] = parseEndTime.Format("2006-01-02")
+	}
+
+	if param["start_date"] != "" && param["end_date"] != "" {
+		parseStartTime, _ := time.Parse("2006-01-02", param["start_date"].(string))
+		parseEndTime, _ := time.Parse("2006-01-02", param["end_date"].(string))
+		if parseStartTime.After(parseEndTime) {
+			h.SendBadRequest(w, "Start date should not be more end date")
+			return
+		}
+	}
+
+	if tcCode, ok := r.URL.Query()["tc_code"]; ok && len(tcCode[0]) > 0 {
+		param["tc_code"] = tcCode[0]
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	report, err := m.GetTcRatingReport(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	var listResponse []response.TcRatingReportResponse
+	for _, a := range report {
+		var res response.TcRatingReportResponse
+		res = res.Transform(a)
+		listResponse = append(listResponse, res)
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
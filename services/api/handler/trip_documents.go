@@ -0,0 +1,485 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"panorama/lib/psql"
+	"panorama/lib/upload"
+	"panorama/lib/utils"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// documentURLTTL default lifetime of the presigned download url
+const documentURLTTL = 5 * time.Minute
+
+// tripDocumentActor companion or the assigned tc of member itin that access the documents
+type tripDocumentActor struct {
+	Type     string
+	ID       int32
+	Name     string
+	ItinRole string // role of companion, empty for tc
+}
+
+// getTripDocumentActor get the login user of the member itin, false when the user is not a companion or the assigned tc
+func (h *Contract) getTripDocumentActor(db *pgxpool.Conn, ctx context.Context, r *http.Request, m model.Contract, itin model.MemberItinEnt) (tripDocumentActor, bool, error) {
+	code := h.GetUserCode(r.Context())
+	var actor tripDocumentActor
+
+	switch h.GetUserRole(r.Context()) {
+	case "customer":
+		member, _ := m.GetMemberByCode(db, ctx, code)
+		if member.ID == 0 {
+			return actor, false, nil
+		}
+		role, err := m.GetMemberItinRole(db, ctx, itin.ID, member.ID)
+		if err != nil {
+			return actor, false, err
+		}
+
+		actor = tripDocumentActor{Type: model.DOCUMENT_ACTOR_MEMBER, ID: member.ID, Name: member.Name, ItinRole: role}
+		return actor, len(role) > 0, nil
+	case "tc":
+		user, _ := m.GetUserByCode(db, ctx, code)
+		if user.ID == 0 {
+			return actor, false, nil
+		}
+		tcID, err := m.GetMemberItinTcID(db, ctx, itin.ID)
+		if err != nil {
+			return actor, false, err
+		}
+
+		actor = tripDocumentActor{Type: model.DOCUMENT_ACTOR_USER, ID: user.ID, Name: user.Name}
+		return actor, tcID != 0 && tcID == user.ID, nil
+	}
+
+	return actor, false, nil
+}
+
+// canManageTripDocument uploader of the document, owner of member itin or the assigned tc
+func (a tripDocumentActor) canManageTripDocument(d model.TripDocumentEnt) bool {
+	return a.Type == model.DOCUMENT_ACTOR_USER || a.ItinRole == model.ITIN_ROLE_OWNER ||
+		(a.Type == d.UploadedByType && a.ID == d.UploadedBy)
+}
+
+// logTripDocumentAccess record the access of document
+func logTripDocumentAccess(tx pgx.Tx, ctx context.Context, r *http.Request, m model.Contract, actor tripDocumentActor, documentID int32, action string) error {
+	ip := m.TrustedProxies.ClientIP(r)
+	_, err := m.AddTripDocumentAccessLog(tx, ctx, model.TripDocumentAccessLogEnt{
+		DocumentID: documentID,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		Action:     action,
+		IPAddress:  sql.NullString{String: ip, Valid: len(ip) > 0},
+		UserAgent:  sql.NullString{String: r.UserAgent(), Valid: len(r.UserAgent()) > 0},
+	})
+
+	return err
+}
+
+// GetListTripDocumentAct documents of member itin filtered by ?type=, the document number is masked and
+// the file is only downloaded from the detail
+func (h *Contract) GetListTripDocumentAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	var documentType string
+	if t, ok := r.URL.Query()["type"]; ok && len(t[0]) > 0 {
+		documentType = t[0]
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	_, allowed, err := h.getTripDocumentActor(db, ctx, r, m, itin)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !allowed {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	documents, err := m.GetListTripDocument(db, ctx, itin.ID, documentType)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.TripDocumentRes{}
+	for _, a := range documents {
+		var res response.TripDocumentRes
+		listResponse = append(listResponse, res.Transform(a, a.ExpiryWarning(itin.StartDate, m.PassportValidMonth())).MaskNumber())
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// AddTripDocumentAct upload travel document of companion into member itin as private file
+func (h *Contract) AddTripDocumentAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	fm, fInfo, err := upload.Info{MaxSize: 5}.MultipartHandler(
+		w, r, "file", []string{"png", "jpg", "jpeg", "pdf"},
+	)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	req := request.TripDocumentReq{
+		DocumentType:   r.FormValue("document_type"),
+		Title:          r.FormValue("title"),
+		DocumentNumber: r.FormValue("document_number"),
+		ExpiryDate:     r.FormValue("expiry_date"),
+		MemberCode:     r.FormValue("member_code"),
+	}
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	document, err := req.Transform(model.TripDocumentEnt{})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	actor, allowed, err := h.getTripDocumentActor(db, ctx, r, m, itin)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !allowed {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	// document of the companion, customer upload own document by default
+	if len(req.MemberCode) > 0 {
+		member, _ := m.GetMemberByCode(db, ctx, req.MemberCode)
+		if member.ID == 0 {
+			h.SendNotfound(w, fmt.Sprintf("Customer %s not found.", req.MemberCode))
+			return
+		}
+		role, err := m.GetMemberItinRole(db, ctx, itin.ID, member.ID)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+		if len(role) == 0 {
+			h.SendBadRequest(w, fmt.Sprintf("Customer %s is not a companion of the trip.", req.MemberCode))
+			return
+		}
+		document.MemberID = sql.NullInt32{Int32: member.ID, Valid: true}
+		document.MemberCode = member.MemberCode
+		document.MemberName = member.Name
+	} else if actor.Type == model.DOCUMENT_ACTOR_MEMBER {
+		document.MemberID = sql.NullInt32{Int32: actor.ID, Valid: true}
+		document.MemberName = actor.Name
+		document.MemberCode = h.GetUserCode(r.Context())
+	}
+
+	file, err := ioutil.ReadAll(fm)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	rTail, _ := utils.Generate(`[a-zA-Z0-9]{32}`)
+
+	document.DocumentCode = m.SetTripDocumentCode()
+	document.MemberItinID = itin.ID
+	document.FilePath = fmt.Sprintf("%s/documents/%s/%s.%s", h.Config.GetString("aws.s3.filepath"), itin.ItinCode, rTail, fInfo.FileExt)
+	document.FileName = fInfo.Filename
+	document.FileMime = fInfo.FileMime
+	document.FileSize = int64(len(file))
+	document.UploadedByType = actor.Type
+	document.UploadedBy = actor.ID
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	document, err = m.AddTripDocument(tx, ctx, document)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = logTripDocumentAccess(tx, ctx, r, m, actor, document.ID, model.DOCUMENT_ACTION_UPLOAD)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	if err = h.Storage.Put(document.FilePath, file, document.FileMime, true); err != nil {
+		h.SendBadRequest(w, "Something problem when saving file: "+err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.TripDocumentRes
+	h.SendSuccess(w, res.Transform(document, document.ExpiryWarning(itin.StartDate, m.PassportValidMonth())), nil)
+}
+
+// GetTripDocumentAct detail document with the short-lived download url, every access is recorded
+func (h *Contract) GetTripDocumentAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	documentCode := chi.URLParam(r, "documentCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	actor, allowed, err := h.getTripDocumentActor(db, ctx, r, m, itin)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !allowed {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	document, _ := m.GetTripDocumentByCode(db, ctx, itin.ID, documentCode)
+	if document.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Document %s not found.", documentCode))
+		return
+	}
+
+	ttl := documentURLTTL
+	if s := h.Config.GetInt("document.url_ttl"); s > 0 {
+		ttl = time.Duration(s) * time.Second
+	}
+
+	url, err := h.Storage.Presign(document.FilePath, ttl)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = logTripDocumentAccess(tx, ctx, r, m, actor, document.ID, model.DOCUMENT_ACTION_DOWNLOAD)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.TripDocumentRes
+	res = res.Transform(document, document.ExpiryWarning(itin.StartDate, m.PassportValidMonth()))
+	expiredAt := time.Now().Add(ttl).In(time.UTC)
+	res.DownloadURL = url
+	res.DownloadExpiredAt = &expiredAt
+
+	h.SendSuccess(w, res, nil)
+}
+
+// DeleteTripDocumentAct delete document by the uploader, owner of member itin or the assigned tc
+func (h *Contract) DeleteTripDocumentAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	documentCode := chi.URLParam(r, "documentCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	actor, allowed, err := h.getTripDocumentActor(db, ctx, r, m, itin)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !allowed {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	document, _ := m.GetTripDocumentByCode(db, ctx, itin.ID, documentCode)
+	if document.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Document %s not found.", documentCode))
+		return
+	}
+	if !actor.canManageTripDocument(document) {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteTripDocument(tx, ctx, document.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = logTripDocumentAccess(tx, ctx, r, m, actor, document.ID, model.DOCUMENT_ACTION_DELETE)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// the file is removed so the leaked url can not be used anymore
+	if err = h.Storage.Delete(document.FilePath); err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+}
+
+// GetTripDocumentLogsAct access log of document (owner of member itin or the assigned tc)
+func (h *Contract) GetTripDocumentLogsAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	documentCode := chi.URLParam(r, "documentCode")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	itin, _ := m.GetMemberItinByCode(db, ctx, code)
+	if itin.ID == 0 {
+		h.SendNotfound(w, "Member itin not found.")
+		return
+	}
+
+	actor, allowed, err := h.getTripDocumentActor(db, ctx, r, m, itin)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !allowed || (actor.Type == model.DOCUMENT_ACTOR_MEMBER && actor.ItinRole != model.ITIN_ROLE_OWNER) {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	document, _ := m.GetTripDocumentByCode(db, ctx, itin.ID, documentCode)
+	if document.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Document %s not found.", documentCode))
+		return
+	}
+
+	logs, err := m.GetListTripDocumentAccessLog(db, ctx, document.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.TripDocumentAccessLogRes{}
+	for _, a := range logs {
+		var res response.TripDocumentAccessLogRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
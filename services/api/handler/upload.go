@@ -2,72 +2,129 @@ package handler
 
 import (
 	"fmt"
-	"io"
+	"io/ioutil"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"panorama/lib/upload"
 	"panorama/lib/utils"
 	"time"
 )
 
-// UploadAct ...
+// uploadPurpose size limit and folder of the upload, the limit in MB is configurable by upload.max_size.<purpose>
+// and the image dimension limit (width * height) by upload.max_pixels.<purpose>
+type uploadPurpose struct {
+	Folder     string
+	MaxSize    int64
+	MaxPixels  int
+	AllowedExt []string
+}
+
+var uploadPurposes = map[string]uploadPurpose{
+	"default":    {MaxSize: 1, MaxPixels: 16000000, AllowedExt: []string{"png", "jpg", "jpeg", "webp", "pdf"}},
+	"avatar":     {Folder: "avatars", MaxSize: 5, MaxPixels: 16000000, AllowedExt: []string{"png", "jpg", "jpeg", "webp"}},
+	"itin_cover": {Folder: "itin-covers", MaxSize: 10, MaxPixels: 40000000, AllowedExt: []string{"png", "jpg", "jpeg", "webp"}},
+	"stuff":      {Folder: "stuffs", MaxSize: 10, MaxPixels: 40000000, AllowedExt: []string{"png", "jpg", "jpeg", "webp"}},
+}
+
+// UploadAct upload file by ?purpose= (avatar, itin_cover, stuff), image is stored as thumb, medium and original renditions
 func (h *Contract) UploadAct(w http.ResponseWriter, r *http.Request) {
-	fm, fInfo, err := upload.Info{MaxSize: 1}.MultipartHandler(
-		w, r, "uploadfile", []string{"png", "jpg", "jpeg", "pdf"},
+	name := "default"
+	if p, ok := r.URL.Query()["purpose"]; ok && len(p[0]) > 0 {
+		name = p[0]
+	}
+
+	purpose, ok := uploadPurposes[name]
+	if !ok {
+		h.SendBadRequest(w, fmt.Sprintf("Upload purpose %s is not allowed.", name))
+		return
+	}
+	if size := h.Config.GetInt("upload.max_size." + name); size > 0 {
+		purpose.MaxSize = int64(size)
+	}
+	if pixels := h.Config.GetInt("upload.max_pixels." + name); pixels > 0 {
+		purpose.MaxPixels = pixels
+	}
+
+	fm, fInfo, err := upload.Info{MaxSize: purpose.MaxSize}.MultipartHandler(
+		w, r, "uploadfile", purpose.AllowedExt,
 	)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
 
-	_, fname, err := h.toS3(fm, fInfo, h.Config.GetString("aws.s3.filepath"))
+	path := h.Config.GetString("aws.s3.filepath")
+	if len(purpose.Folder) > 0 {
+		path += "/" + purpose.Folder
+	}
+
+	if !upload.IsImage(fInfo.FileMime) {
+		fname, err := h.toStorage(fm, fInfo, path)
+		if err != nil {
+			h.SendBadRequest(w, "Something problem when saving file: "+err.Error())
+			return
+		}
+
+		h.SendSuccess(w, map[string]interface{}{
+			"file_url":  h.Storage.URL(fname),
+			"file_path": fname,
+		}, nil)
+		return
+	}
+
+	paths, err := h.imageToStorage(fm, path, purpose.MaxPixels)
 	if err != nil {
 		h.SendBadRequest(w, "Something problem when saving file: "+err.Error())
 		return
 	}
 
+	renditions := map[string]string{}
+	for rendition, p := range paths {
+		renditions[rendition] = h.Storage.URL(p)
+	}
+
 	h.SendSuccess(w, map[string]interface{}{
-		"file_url":  h.Config.GetString("aws.s3.public_url") + fname,
-		"file_path": fname,
+		"file_url":   renditions[upload.RenditionOriginal],
+		"file_path":  paths[upload.RenditionOriginal],
+		"renditions": renditions,
 	}, nil)
 }
 
-// toS3 handle upload file from local to s3
-func (h *Contract) toS3(file multipart.File, fInfo upload.FileInfo, path string) (*os.File, string, error) {
+// imageToStorage process the image into renditions and store every rendition, the stored path is the original rendition
+func (h *Contract) imageToStorage(file multipart.File, path string, maxPixels int) (map[string]string, error) {
+	renditions, err := upload.ProcessImage(file, upload.DefaultRenditions, maxPixels)
+	if err != nil {
+		return nil, err
+	}
+
 	rand.Seed(time.Now().UnixNano())
 	rTail, _ := utils.Generate(`[a-zA-Z0-9]{15}`)
+	base := fmt.Sprintf("%s/%s", path, rTail)
 
-	localFilename := fmt.Sprintf("%s.%s", rTail, fInfo.FileExt)
-	newname := fmt.Sprintf("%s/%s", path, localFilename)
-	newpath := h.Config.GetString("upload_path") + "/" + localFilename
+	paths := map[string]string{}
+	for _, rd := range renditions {
+		paths[rd.Name] = upload.RenditionPath(base, rd.Name)
 
-	f, err := os.OpenFile(newpath, os.O_WRONLY|os.O_CREATE, 0666)
-	if err != nil {
-		return f, newname, err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, file)
-	if err != nil {
-		return f, newname, err
+		if err = h.Storage.Put(paths[rd.Name], rd.Data, rd.FileMime, false); err != nil {
+			return nil, err
+		}
 	}
 
-	// upload to s3
-	if err = upload.PushS3ByPath(f.Name(), upload.S3Info{
-		Key:      h.Config.GetString("aws.s3.key"),
-		Secret:   h.Config.GetString("aws.s3.secret"),
-		Region:   h.Config.GetString("aws.s3.region"),
-		Bucket:   h.Config.GetString("aws.s3.bucket"),
-		Filename: newname,
-		Filemime: fInfo.FileMime,
-		Filesize: fInfo.FileSize,
-	}); err != nil {
-		os.Remove(newpath)
-
-		return f, newname, err
+	return paths, nil
+}
+
+// toStorage store the uploaded file as it is
+func (h *Contract) toStorage(file multipart.File, fInfo upload.FileInfo, path string) (string, error) {
+	rand.Seed(time.Now().UnixNano())
+	rTail, _ := utils.Generate(`[a-zA-Z0-9]{15}`)
+
+	newname := fmt.Sprintf("%s/%s.%s", path, rTail, fInfo.FileExt)
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return newname, err
 	}
 
-	os.Remove(newpath)
-	return f, newname, nil
+	return newname, h.Storage.Put(newname, data, fInfo.FileMime, false)
 }
@@ -4,9 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"net/http"
 	"panorama/lib/array"
+	"panorama/lib/audit"
+	"panorama/lib/export"
 	"panorama/lib/psql"
 	"panorama/services/api/handler/request"
 	"panorama/services/api/handler/response"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -66,7 +68,7 @@ func (h *Contract) GetUserListAct(w http.ResponseWriter, r *http.Request) {
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -90,6 +92,23 @@ func (h *Contract) GetUserListAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if format := exportFormat(r); len(format) > 0 {
+		h.SendExport(w, r, db, ctx, exportJob{
+			Name: "users",
+			Row:  response.UsersResponse{},
+			Count: func(db *pgxpool.Conn, ctx context.Context) (int, error) {
+				return m.CountUser(db, ctx, param)
+			},
+			Rows: func(db *pgxpool.Conn, ctx context.Context, enc *export.Encoder) error {
+				return m.ExportUser(db, ctx, param, func(a model.UserEnt) error {
+					var res response.UsersResponse
+					return enc.Encode(res.Transform(a))
+				})
+			},
+		})
+		return
+	}
+
 	u, err := m.GetUser(db, ctx, param)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -135,7 +154,7 @@ func (h *Contract) GetDetailAdminAndTc(w http.ResponseWriter, r *http.Request) {
 
 		// get detail activity admin
 
-		ctx := context.Background()
+		ctx := h.Context(r)
 		db, err := h.DB.Acquire(ctx)
 		if err != nil {
 			h.SendBadRequest(w, err.Error())
@@ -169,7 +188,7 @@ func (h *Contract) GetDetailAdminAndTc(w http.ResponseWriter, r *http.Request) {
 
 	} else if param["role"].(string) == "tc" {
 
-		ctx := context.Background()
+		ctx := h.Context(r)
 		db, err := h.DB.Acquire(ctx)
 		if err != nil {
 			h.SendBadRequest(w, err.Error())
@@ -213,6 +232,14 @@ func (h *Contract) GetDetailAdminAndTc(w http.ResponseWriter, r *http.Request) {
 		}
 		u.ActiveClientConsultan = act
 
+		// get latest ratings from customer
+		ratings, err := m.GetListTcRatingByTcCode(db, ctx, code, 5)
+		if err != nil && err != sql.ErrNoRows {
+			h.SendBadRequest(w, err.Error())
+			return
+		}
+		u.TcRatings = ratings
+
 		var res response.TcDetailResponse
 		res = res.Transform(u)
 
@@ -239,7 +266,7 @@ func (h *Contract) AddUserAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -303,6 +330,7 @@ func (h *Contract) AddUserAct(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	h.audit(r, db, model.AUDIT_USER, user.UserCode, audit.ActionCreate, nil, user)
 
 	var res response.UsersResponse
 	res = res.Transform(user)
@@ -339,7 +367,7 @@ func (h *Contract) UpdateUserAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -363,6 +391,7 @@ func (h *Contract) UpdateUserAct(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	before := data
 
 	// check condition if email null
 	if req.Email == data.Email {
@@ -387,7 +416,7 @@ func (h *Contract) UpdateUserAct(w http.ResponseWriter, r *http.Request) {
 
 		// checking Password
 		match := CheckPasswordHash(password, data.Password)
-		log.Println("Match:   ", match)
+		h.Log.FromContext(ctx).Debug("Password match: ", match)
 
 		if match {
 			data = req.Transform(data)
@@ -424,6 +453,7 @@ func (h *Contract) UpdateUserAct(w http.ResponseWriter, r *http.Request) {
 		h.SendBadRequest(w, err.Error())
 		return
 	}
+	h.audit(r, db, model.AUDIT_USER, code, audit.ActionUpdate, before, data)
 
 	var res response.UsersResponse
 	res = res.Transform(data)
@@ -455,7 +485,7 @@ func (h *Contract) UpdateUserPassAct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -481,7 +511,7 @@ func (h *Contract) UpdateUserPassAct(w http.ResponseWriter, r *http.Request) {
 
 	// checking old Password
 	match := CheckPasswordHash(password, user.Password)
-	log.Println("Match:   ", match)
+	h.Log.FromContext(ctx).Debug("Password match: ", match)
 	if !match {
 		h.SendNotfound(w, fmt.Sprintf("Old Password %s is not match.", password))
 		return
@@ -500,6 +530,8 @@ func (h *Contract) UpdateUserPassAct(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	after, _ := m.GetUserByCode(db, ctx, code)
+	h.audit(r, db, model.AUDIT_USER, code, audit.ActionUpdate, user, after)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -519,7 +551,7 @@ func (h *Contract) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := h.Context(r)
 	db, err := h.DB.Acquire(ctx)
 	if err != nil {
 		h.SendBadRequest(w, err.Error())
@@ -545,6 +577,7 @@ func (h *Contract) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := data
 	data = req.Transform(data)
 	data.IsActive = false
 
@@ -621,6 +654,7 @@ func (h *Contract) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback(ctx)
 		return
 	}
+	h.audit(r, db, model.AUDIT_USER, code, audit.ActionDelete, before, data)
 
 	h.SendSuccess(w, h.EmptyJSONArr(), nil)
 }
@@ -0,0 +1,736 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"panorama/lib/array"
+	"panorama/lib/psql"
+	"panorama/services/api/handler/request"
+	"panorama/services/api/handler/response"
+	"panorama/services/api/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// GetListVoucherAct list voucher (admin)
+func (h *Contract) GetListVoucherAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	param := map[string]interface{}{
+		"keyword":   "",
+		"is_active": "",
+		"page":      1,
+		"limit":     10,
+		"offset":    0,
+		"sort":      "desc",
+		"order":     "v.id",
+	}
+
+	if page, ok := r.URL.Query()["page"]; ok && len(page[0]) > 0 {
+		if p, err := strconv.Atoi(page[0]); err == nil && p > 1 {
+			param["page"] = p
+		}
+	}
+
+	if sort, ok := r.URL.Query()["sort"]; ok && len(sort[0]) > 0 && strings.ToLower(sort[0]) == "asc" {
+		param["sort"] = "asc"
+	}
+
+	if order, ok := r.URL.Query()["order"]; ok && len(order[0]) > 0 {
+		arrStr := new(array.ArrStr)
+		if exist, _ := arrStr.InArray(order[0], []string{"id", "start_date", "end_date", "used_count"}); exist {
+			param["order"] = "v." + order[0]
+		}
+	}
+
+	if limit, ok := r.URL.Query()["limit"]; ok {
+		if l, err := strconv.Atoi(limit[0]); err == nil {
+			param["limit"] = l
+		}
+	}
+
+	if keyword, ok := r.URL.Query()["keyword"]; ok && len(keyword[0]) > 0 {
+		param["keyword"] = keyword[0]
+	}
+
+	if isActive, ok := r.URL.Query()["is_active"]; ok && (isActive[0] == "true" || isActive[0] == "false") {
+		param["is_active"] = isActive[0]
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	vouchers, err := m.GetListVoucher(db, ctx, param)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.VoucherRes{}
+	for _, a := range vouchers {
+		var res response.VoucherRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, param)
+}
+
+// GetVoucherAct detail voucher (admin)
+func (h *Contract) GetVoucherAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	code := chi.URLParam(r, "code")
+
+	voucher, _ := m.GetVoucherByCode(db, ctx, code)
+	if voucher.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Voucher %s not found.", code))
+		return
+	}
+
+	var res response.VoucherRes
+	h.SendSuccess(w, res.Transform(voucher), nil)
+}
+
+// AddVoucherAct add new voucher (admin)
+func (h *Contract) AddVoucherAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	req := request.AddVoucherReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	voucher, err := req.Transform(model.VoucherEnt{VoucherCode: strings.ToUpper(req.VoucherCode)})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	adminCode := h.GetUserCode(r.Context())
+	userAdmin, _ := m.GetUserByCode(db, ctx, adminCode)
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", adminCode))
+		return
+	}
+
+	exist, _ := m.GetVoucherByCode(db, ctx, voucher.VoucherCode)
+	if exist.ID != 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Voucher %s already exist.", voucher.VoucherCode))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	voucher.CreatedBy = userAdmin.ID
+	voucher, err = m.AddVoucher(tx, ctx, voucher)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Add New Voucher",
+		Activity:  fmt.Sprintf("Add New Voucher %s", voucher.VoucherCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.VoucherRes
+	h.SendSuccess(w, res.Transform(voucher), nil)
+}
+
+// UpdateVoucherAct update voucher (admin)
+func (h *Contract) UpdateVoucherAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+
+	req := request.VoucherReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	voucher, _ := m.GetVoucherByCode(db, ctx, code)
+	if voucher.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Voucher %s not found.", code))
+		return
+	}
+
+	voucher, err = req.Transform(voucher)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if voucher.UsageLimit.Valid && voucher.UsageLimit.Int32 < voucher.UsedCount {
+		h.SendBadRequest(w, fmt.Sprintf("Usage limit of voucher %s should not be less than the used count %d.", voucher.VoucherCode, voucher.UsedCount))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	voucher, err = m.UpdateVoucher(tx, ctx, voucher)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Update Voucher",
+		Activity:  fmt.Sprintf("Update Voucher %s", voucher.VoucherCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.VoucherRes
+	h.SendSuccess(w, res.Transform(voucher), nil)
+}
+
+// DeleteVoucherAct delete voucher (admin), applied voucher of order is kept
+func (h *Contract) DeleteVoucherAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	code := chi.URLParam(r, "code")
+
+	userAdmin, _ := m.GetUserByCode(db, ctx, h.GetUserCode(r.Context()))
+	if userAdmin.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("User Admin %s not found.", h.GetUserCode(r.Context())))
+		return
+	}
+
+	voucher, _ := m.GetVoucherByCode(db, ctx, code)
+	if voucher.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Voucher %s not found.", code))
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.DeleteVoucher(tx, ctx, voucher.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(userAdmin.ID),
+		Role:      userAdmin.Role,
+		Title:     "Delete Voucher",
+		Activity:  fmt.Sprintf("Delete Voucher %s", voucher.VoucherCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	h.SendSuccess(w, h.EmptyJSONArr(), nil)
+}
+
+// GetVoucherRedemptionsAct redemption history of voucher (admin)
+func (h *Contract) GetVoucherRedemptionsAct(w http.ResponseWriter, r *http.Request) {
+	if h.GetUserRole(r.Context()) != "admin" {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+	code := chi.URLParam(r, "code")
+
+	voucher, _ := m.GetVoucherByCode(db, ctx, code)
+	if voucher.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Voucher %s not found.", code))
+		return
+	}
+
+	redemptions, err := m.GetListVoucherRedemption(db, ctx, voucher.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	listResponse := []response.VoucherRedemptionRes{}
+	for _, a := range redemptions {
+		var res response.VoucherRedemptionRes
+		listResponse = append(listResponse, res.Transform(a))
+	}
+
+	h.SendSuccess(w, listResponse, nil)
+}
+
+// checkOrderVoucher order can be repriced by voucher, only before the payment is started
+func (h *Contract) checkOrderVoucher(db *pgxpool.Conn, ctx context.Context, m model.Contract, order model.OrderEnt) error {
+	if order.OrderStatus != model.ORDER_STATUS_PENDING {
+		return fmt.Errorf("Order %s is not waiting for payment.", order.OrderCode)
+	}
+
+	orderPayment, _ := m.GetPaymentOrderByOrderID(db, ctx, order.ID)
+	if orderPayment.PaymentStatus == model.PAYMENT_STATUS_PAID || orderPayment.IsPending() {
+		return fmt.Errorf("Order %s is in payment process.", order.OrderCode)
+	}
+
+	shares, err := m.GetListOrderPaymentShareByOrderID(db, ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if len(shares) > 0 {
+		return fmt.Errorf("Order %s is split among companions.", order.OrderCode)
+	}
+
+	installments, err := m.GetListOrderInstallmentByOrderID(db, ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	if len(installments) > 0 {
+		return fmt.Errorf("Order %s has payment schedule.", order.OrderCode)
+	}
+
+	return nil
+}
+
+// repriceOrder save line items of order and the computed total
+func repriceOrder(db *pgxpool.Conn, tx pgx.Tx, ctx context.Context, m model.Contract, order model.OrderEnt, items []model.OrderItemEnt) (model.OrderEnt, error) {
+	pricing, total, err := orderPricing(db, ctx, m, items)
+	if err != nil {
+		return order, err
+	}
+
+	order.Items, err = m.AddOrderItems(tx, ctx, order.ID, items)
+	if err != nil {
+		return order, err
+	}
+
+	err = m.UpdateOrderPricing(tx, ctx, order.ID, pricing, total)
+	if err != nil {
+		return order, err
+	}
+
+	order.TotalPrice = total.Subtotal
+	order.TotalPricePpn = total.Total
+	order.PpnRate = pricing.PpnRate
+	order.PpnAmount = total.PpnAmount
+	order.RoundingAmount = total.RoundingAmount
+
+	return order, nil
+}
+
+// ApplyOrderVoucherAct apply voucher into order, the previous voucher of order is replaced
+func (h *Contract) ApplyOrderVoucherAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	req := request.ApplyVoucherReq{}
+	if err := h.Bind(r, &req); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.Validator.Driver.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if err = h.checkOrderVoucher(db, ctx, m, order); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	voucher, _ := m.GetVoucherByCode(db, ctx, req.VoucherCode)
+	if voucher.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Voucher %s not found.", req.VoucherCode))
+		return
+	}
+	if !voucher.IsAvailable(time.Now()) {
+		h.SendBadRequest(w, fmt.Sprintf("Voucher %s is not available.", voucher.VoucherCode))
+		return
+	}
+	if !voucher.IsApplicable(order.MemberItin.Destination, order.OrderType) {
+		h.SendBadRequest(w, fmt.Sprintf("Voucher %s can not be used for order %s.", voucher.VoucherCode, order.OrderCode))
+		return
+	}
+
+	// discount is counted from the order amount before tax, without the previous voucher
+	currentItems, err := m.GetListOrderItemByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	items := []model.OrderItemEnt{}
+	var amount int64
+	for _, a := range currentItems {
+		if a.ItemType == model.ORDER_ITEM_VOUCHER {
+			continue
+		}
+		items = append(items, a)
+		amount += a.Subtotal
+	}
+	if amount < voucher.MinSpend {
+		h.SendBadRequest(w, fmt.Sprintf("Minimum spend of voucher %s is %d.", voucher.VoucherCode, voucher.MinSpend))
+		return
+	}
+
+	discount := voucher.Discount(amount)
+	if discount <= 0 {
+		h.SendBadRequest(w, fmt.Sprintf("Voucher %s does not give any discount for order %s.", voucher.VoucherCode, order.OrderCode))
+		return
+	}
+
+	redemption, _ := m.GetVoucherRedemptionByOrderID(db, ctx, order.ID)
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.ReleaseOrderVoucher(tx, ctx, redemption)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	// the global cap is checked by the row lock of redemption counter, then the cap of member
+	_, err = m.RedeemVoucher(tx, ctx, voucher.ID)
+	if err == pgx.ErrNoRows {
+		h.SendBadRequest(w, fmt.Sprintf("Voucher %s has reached the usage limit.", voucher.VoucherCode))
+		tx.Rollback(ctx)
+		return
+	}
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	if voucher.UsageLimitMember.Valid {
+		used, err := m.CountVoucherRedemptionByMember(tx, ctx, voucher.ID, order.PaidBy)
+		if err != nil {
+			h.SendBadRequest(w, err.Error())
+			tx.Rollback(ctx)
+			return
+		}
+		if used >= voucher.UsageLimitMember.Int32 {
+			h.SendBadRequest(w, fmt.Sprintf("Voucher %s has reached the usage limit of %s.", voucher.VoucherCode, order.MemberEnt.Name))
+			tx.Rollback(ctx)
+			return
+		}
+	}
+
+	_, err = m.AddVoucherRedemption(tx, ctx, model.VoucherRedemptionEnt{
+		VoucherID:      voucher.ID,
+		OrderID:        order.ID,
+		MemberID:       order.PaidBy,
+		DiscountAmount: discount,
+	})
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	items = append(items, model.OrderItemEnt{
+		ItemType:  model.ORDER_ITEM_VOUCHER,
+		RefCode:   voucher.VoucherCode,
+		Name:      voucher.Title,
+		Quantity:  1,
+		UnitPrice: discount,
+		Subtotal:  -discount,
+		IsTaxable: true,
+	})
+	order, err = repriceOrder(db, tx, ctx, m, order, items)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Apply Voucher",
+		Activity:  fmt.Sprintf("Apply Voucher %s For Order %s", voucher.VoucherCode, order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.OrderVoucherRes
+	h.SendSuccess(w, res.Transform(order, voucher, discount), nil)
+}
+
+// RemoveOrderVoucherAct remove the applied voucher of order
+func (h *Contract) RemoveOrderVoucherAct(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	ctx := h.Context(r)
+	db, err := h.DB.Acquire(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	defer db.Release()
+
+	m := model.Contract{App: h.App}
+
+	order, _ := m.GetOrderByOrderCode(db, ctx, code)
+	if order.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s not found.", code))
+		return
+	}
+
+	actor, err := h.getOrderActor(db, ctx, r, m, order)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	if !actor.IsManager {
+		h.SendUnAuthorizedData(w)
+		return
+	}
+
+	if err = h.checkOrderVoucher(db, ctx, m, order); err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	redemption, _ := m.GetVoucherRedemptionByOrderID(db, ctx, order.ID)
+	if redemption.ID == 0 {
+		h.SendNotfound(w, fmt.Sprintf("Order %s has no voucher.", code))
+		return
+	}
+	voucher, _ := m.GetVoucherByID(db, ctx, redemption.VoucherID)
+
+	currentItems, err := m.GetListOrderItemByOrderID(db, ctx, order.ID)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+	items := []model.OrderItemEnt{}
+	for _, a := range currentItems {
+		if a.ItemType != model.ORDER_ITEM_VOUCHER {
+			items = append(items, a)
+		}
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		return
+	}
+
+	err = m.ReleaseOrderVoucher(tx, ctx, redemption)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	order, err = repriceOrder(db, tx, ctx, m, order, items)
+	if err != nil {
+		h.SendBadRequest(w, psql.ParseErr(err))
+		tx.Rollback(ctx)
+		return
+	}
+
+	_, err = m.AddLogActivity(tx, ctx, model.LogActivityUserEnt{
+		UserID:    int64(actor.ID),
+		Role:      actor.Role,
+		Title:     "Remove Voucher",
+		Activity:  fmt.Sprintf("Remove Voucher %s From Order %s", voucher.VoucherCode, order.OrderCode),
+		EventType: r.Method,
+	})
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		h.SendBadRequest(w, err.Error())
+		tx.Rollback(ctx)
+		return
+	}
+
+	var res response.OrderVoucherRes
+	h.SendSuccess(w, res.Transform(order, voucher, 0), nil)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"panorama/bootstrap"
+	"panorama/lib/psql/psqltest"
+	"panorama/lib/storage"
+	"panorama/lib/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// redisTestAddrEnv local Redis of the end-to-end tests, e.g. localhost:6379. The settings are not cached
+// when it is not set
+const redisTestAddrEnv = "REDIS_TEST_ADDR"
+
+const (
+	templatesDir = "../../resources/templates"
+
+	// testPassword password of the seeded users & the registered members
+	testPassword = "secret123"
+)
+
+// testServer api on httptest server with the fakes of the external services
+type testServer struct {
+	*httptest.Server
+	t         *testing.T
+	DB        *pgxpool.Pool
+	Citcall   *fakeService
+	OneSignal *fakeService
+	SendGrid  *fakeService
+	Midtrans  *fakeService
+	Storage   *memStorage
+}
+
+// apiResponse response envelope of the api
+type apiResponse struct {
+	StatCode string          `json:"stat_code"`
+	StatMsg  string          `json:"stat_msg"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// client headers of the requests, token is empty for the public routes
+type client struct {
+	Channel string
+	Token   string
+	Player  string
+}
+
+// newTestServer migrate a fresh database with the seed fixtures then boot the routes of the api,
+// skipped when PSQL_TEST_DSN is not set
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	s := &testServer{
+		t:         t,
+		DB:        psqltest.Connect(t),
+		Citcall:   newFakeCitcall(t),
+		OneSignal: newFakeOneSignal(t),
+		SendGrid:  newFakeSendGrid(t),
+		Midtrans:  newFakeMidtrans(t),
+		Storage:   newMemStorage(),
+	}
+
+	if _, err := s.DB.Exec(context.Background(), utils.LoadFixture(t, "seed.sql")); err != nil {
+		t.Fatalf("seed fixtures: %v", err)
+	}
+
+	config := s.config()
+	app := &bootstrap.App{
+		Config:    config,
+		DB:        s.DB,
+		Validator: bootstrap.SetupValidator(config),
+		Log:       bootstrap.SetupLogger(config),
+		Storage:   s.Storage,
+	}
+	storage.SetDefault(s.Storage)
+
+	if addr := os.Getenv(redisTestAddrEnv); len(addr) > 0 {
+		rd, err := bootstrap.SetupRedis(addr, "", 15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flushRedis(t, rd)
+		t.Cleanup(func() {
+			flushRedis(t, rd)
+			rd.Close()
+		})
+
+		app.Redis = rd
+		app.RedisCache = rd
+	}
+
+	s.Server = httptest.NewServer(Boot{App: app}.Router())
+	t.Cleanup(s.Server.Close)
+
+	return s
+}
+
+// config of the api that point the external services to the fakes
+func (s *testServer) config() utils.Config {
+	dir := s.t.TempDir()
+	templates, err := filepath.Abs(templatesDir)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"app": map[string]interface{}{
+			"debug":  false,
+			"locale": "en",
+			"key":    "e2e-app-key",
+		},
+		"log": map[string]interface{}{
+			"default": "file",
+			"file":    map[string]interface{}{"source": filepath.Join(dir, "api.log")},
+		},
+		"resource_path": templates,
+		"aws": map[string]interface{}{
+			"s3": map[string]interface{}{"filepath": "e2e"},
+		},
+		// nothing listen on the port, the smtp mails fail fast
+		"mail": map[string]interface{}{
+			"host":      "127.0.0.1",
+			"port":      1,
+			"mail_from": "no-reply@e2e.test",
+			"mail_name": "Panorama",
+		},
+		"citcall":   map[string]interface{}{"api_key": "e2e-citcall", "api_url": s.Citcall.URL},
+		"onesignal": map[string]interface{}{"api_key": "e2e-onesignal", "app_id": "e2e", "api_url": s.OneSignal.URL},
+		"sendgrid":  map[string]interface{}{"api_key": "e2e-sendgrid", "api_url": s.SendGrid.URL},
+		"midtrans": map[string]interface{}{
+			"server_key": "e2e-server-key",
+			"client_key": "e2e-client-key",
+			"env_type":   "sandbox",
+			"api_url":    s.Midtrans.URL,
+		},
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "config.json")
+	if err = ioutil.WriteFile(file, b, 0600); err != nil {
+		s.t.Fatal(err)
+	}
+
+	return utils.NewViperConfig(dir, file)
+}
+
+// flushRedis ...
+func flushRedis(t *testing.T, rd *redis.Client) {
+	if err := rd.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("redis: %v", err)
+	}
+}
+
+// Do send the json request, returns the http status & the response envelope
+func (s *testServer) Do(c client, method, path string, body interface{}) (int, apiResponse) {
+	s.t.Helper()
+
+	var res apiResponse
+	b, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(b))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(bootstrap.XChannelHeader, c.Channel)
+	if len(c.Token) > 0 {
+		req.Header.Set(bootstrap.AuthHeader, c.Token)
+	}
+	if len(c.Player) > 0 {
+		req.Header.Set(bootstrap.XPlayer, c.Player)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+
+	return resp.StatusCode, res
+}
+
+// Must send the json request that must succeed, the data of the response is decoded into out
+func (s *testServer) Must(c client, method, path string, body, out interface{}) {
+	s.t.Helper()
+
+	code, res := s.Do(c, method, path, body)
+	if code != http.StatusOK {
+		s.t.Fatalf("%s %s = %d %s: %s", method, path, code, res.StatCode, res.StatMsg)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			s.t.Fatalf("%s %s data: %v", method, path, err)
+		}
+	}
+}
+
+// QueryValue scan the single value of the query
+func (s *testServer) QueryValue(dest interface{}, sql string, args ...interface{}) {
+	s.t.Helper()
+
+	if err := s.DB.QueryRow(context.Background(), sql, args...).Scan(dest); err != nil {
+		s.t.Fatalf("%s: %v", sql, err)
+	}
+}
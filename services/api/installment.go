@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"log"
+	"panorama/lib/psql"
+	"panorama/services/api/model"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ProcessInstallments send reminder of due installments and cancel the order of overdue installments,
+// meant to be run periodically from cron
+func (app Boot) ProcessInstallments(c *cli.Context) error {
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.App.DB = db
+
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m := model.Contract{App: app.App}
+
+	cancelled, err := m.CancelOverdueOrderInstallments(conn, ctx)
+	if err != nil {
+		return err
+	}
+
+	reminded, err := m.RemindOrderInstallments(conn, ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Installment -> %d order cancelled, %d installment reminded", cancelled, reminded)
+
+	return nil
+}
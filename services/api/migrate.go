@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"panorama/lib/psql"
+	"panorama/lib/psql/migrate"
+	"panorama/lib/utils"
+	"panorama/resources"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// MigrateUpFlags ...
+	MigrateUpFlags = []cli.Flag{
+		&cli.IntFlag{
+			Name:  "steps",
+			Usage: "Apply only the next n pending migrations, all of them by default",
+		},
+	}
+
+	// MigrateDownFlags ...
+	MigrateDownFlags = []cli.Flag{
+		&cli.IntFlag{
+			Name:  "steps",
+			Value: 1,
+			Usage: "Revert the last n applied migrations",
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "Revert every applied migration",
+		},
+	}
+
+	// MigrateCreateFlags ...
+	MigrateCreateFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:  "dir",
+			Value: filepath.Join("resources", resources.MigrationsDir),
+			Usage: "Dir of the migrations in the source tree",
+		},
+	}
+
+	// SeedFlags ...
+	SeedFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "Data sets to seed (admin, tcs, tags, itineraries), all of them by default",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Seed even when app.debug is off",
+		},
+	}
+)
+
+// seedName <order>_<set>.sql, e.g. 01_admin.sql
+var seedName = regexp.MustCompile(`^[0-9]+_([a-z0-9_]+)\.sql$`)
+
+// migrator of the migrations built into the binary
+func (app Boot) migrator() (*migrate.Migrator, *pgxpool.Pool, error) {
+	migrations, err := migrate.Load(resources.Migrations, resources.MigrationsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrate.New(db, migrations), db, nil
+}
+
+// checkSchema returns error when the schema is behind the migrations built into the binary
+func (app Boot) checkSchema(db *pgxpool.Pool) error {
+	migrations, err := migrate.Load(resources.Migrations, resources.MigrationsDir)
+	if err != nil {
+		return err
+	}
+
+	if err = migrate.New(db, migrations).Check(context.Background()); err != nil {
+		return fmt.Errorf("%w, run `migrate up` first", err)
+	}
+
+	return nil
+}
+
+// MigrateUp apply the pending migrations
+func (app Boot) MigrateUp(c *cli.Context) error {
+	m, db, err := app.migrator()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := m.Up(context.Background(), c.Int("steps"))
+	for _, mg := range applied {
+		fmt.Printf("Migrate up -> %06d_%s\n", mg.Version, mg.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("Migrate up -> no change")
+	}
+
+	return nil
+}
+
+// MigrateDown revert the last applied migrations
+func (app Boot) MigrateDown(c *cli.Context) error {
+	m, db, err := app.migrator()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	steps := c.Int("steps")
+	if c.Bool("all") {
+		steps = 0
+	} else if steps <= 0 {
+		return errors.New("steps must be greater than 0, use --all to revert every migration")
+	}
+
+	reverted, err := m.Down(context.Background(), steps)
+	for _, mg := range reverted {
+		fmt.Printf("Migrate down -> %06d_%s\n", mg.Version, mg.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(reverted) == 0 {
+		fmt.Println("Migrate down -> no change")
+	}
+
+	return nil
+}
+
+// MigrateStatus print the applied & pending migrations
+func (app Boot) MigrateStatus(c *cli.Context) error {
+	m, db, err := app.migrator()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	list, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range list {
+		status := "applied"
+		if !s.Applied {
+			status = "pending"
+			pending++
+		}
+		fmt.Printf("%06d  %-8s %s\n", s.Version, status, s.Name)
+	}
+
+	fmt.Printf("\nVersion %d of %d, %d pending", current, m.Latest(), pending)
+	if dirty {
+		fmt.Print(", dirty")
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// MigrateCreate create the up & down file of the next version in the source tree
+func (app Boot) MigrateCreate(c *cli.Context) error {
+	name := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(c.Args().First()), "_"), "_")
+	if len(name) == 0 {
+		return errors.New("name of the migration is required, e.g. migrate create add_column_x_table_y")
+	}
+
+	dir := c.String("dir")
+	migrations, err := migrate.Load(os.DirFS(dir), ".")
+	if err != nil {
+		return err
+	}
+
+	var version uint = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		fn := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", version, name, direction))
+		content := fmt.Sprintf("-- %s of %06d_%s\n", direction, version, name)
+		if err = ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Migrate create -> %s\n", fn)
+	}
+
+	return nil
+}
+
+// Seed insert the data sets for the local development, the sets are safe to seed again
+func (app Boot) Seed(c *cli.Context) error {
+	if !app.Debug && !c.Bool("force") {
+		return errors.New("seed is for the local development, app.debug is off (use --force to seed anyway)")
+	}
+
+	entries, err := fs.ReadDir(resources.Seeds, resources.SeedsDir)
+	if err != nil {
+		return err
+	}
+
+	// files of the data sets, in the order of the file names
+	var names, files []string
+	for _, e := range entries {
+		if match := seedName.FindStringSubmatch(e.Name()); match != nil {
+			names = append(names, match[1])
+			files = append(files, e.Name())
+		}
+	}
+
+	sets := map[string]bool{}
+	for _, s := range c.StringSlice("set") {
+		if !utils.Contains(names, s) {
+			return fmt.Errorf("seed: unknown data set %s, available: %s", s, strings.Join(names, ", "))
+		}
+		sets[s] = true
+	}
+
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = app.checkSchema(db); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for i, set := range names {
+		if len(sets) > 0 && !sets[set] {
+			continue
+		}
+
+		b, err := fs.ReadFile(resources.Seeds, path.Join(resources.SeedsDir, files[i]))
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, string(b)); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("seed %s: %w", set, err)
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		fmt.Printf("Seed -> %s\n", set)
+	}
+
+	return nil
+}
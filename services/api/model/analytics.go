@@ -0,0 +1,311 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"panorama/lib/psql"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	ANALYTICS_DAY   = "day"
+	ANALYTICS_WEEK  = "week"
+	ANALYTICS_MONTH = "month"
+
+	ITIN_SUG_EVENT_VIEW  = "view"
+	ITIN_SUG_EVENT_CLONE = "clone"
+)
+
+// analyticsViews daily rollups of the analytics, refreshed from cron
+var analyticsViews = []string{
+	"analytics_daily_revenue",
+	"analytics_daily_orders",
+	"analytics_daily_funnel",
+	"analytics_daily_suggestions",
+	"analytics_daily_destinations",
+	"analytics_daily_tc",
+}
+
+// ItinSugEventEnt view or clone of suggestion itinerary
+type ItinSugEventEnt struct {
+	ID           int32
+	ItinSugID    int32
+	MemberID     sql.NullInt32
+	MemberItinID sql.NullInt32
+	EventType    string
+	CreatedDate  time.Time
+}
+
+// AnalyticsRevenueEnt paid payments of the period and order type
+type AnalyticsRevenueEnt struct {
+	Period        time.Time
+	OrderType     string
+	TotalPayments int64
+	TotalAmount   int64
+}
+
+// AnalyticsOrderEnt orders of the period and order type
+type AnalyticsOrderEnt struct {
+	Period          time.Time
+	OrderType       string
+	TotalOrders     int64
+	PaidOrders      int64
+	CancelledOrders int64
+	TotalPrice      int64
+}
+
+// AnalyticsFunnelEnt chat -> itinerary -> order -> paid of the period
+type AnalyticsFunnelEnt struct {
+	Period      time.Time
+	Chats       int64
+	Itineraries int64
+	Orders      int64
+	PaidOrders  int64
+}
+
+// AnalyticsSuggestionEnt views & clones of suggestion itinerary, the period is zero on the top list
+type AnalyticsSuggestionEnt struct {
+	Period   time.Time
+	ItinCode string
+	Title    string
+	Views    int64
+	Clones   int64
+}
+
+// AnalyticsDestinationEnt ...
+type AnalyticsDestinationEnt struct {
+	Destination string
+	Itineraries int64
+	Orders      int64
+	PaidOrders  int64
+}
+
+// AnalyticsTcEnt performance of tc
+type AnalyticsTcEnt struct {
+	UserCode  string
+	Name      string
+	Chats     int64
+	Orders    int64
+	Revenue   int64
+	Ratings   int64
+	AvgRating float64
+}
+
+// IsAnalyticsGranularity ...
+func IsAnalyticsGranularity(granularity string) bool {
+	return granularity == ANALYTICS_DAY || granularity == ANALYTICS_WEEK || granularity == ANALYTICS_MONTH
+}
+
+// analyticsParamQuery granularity, start date & end date of the param
+func analyticsParamQuery(param map[string]interface{}) []interface{} {
+	return []interface{}{param["granularity"], param["start_date"], param["end_date"]}
+}
+
+// AddItinSugEvent ...
+func (c *Contract) AddItinSugEvent(tx pgx.Tx, ctx context.Context, e ItinSugEventEnt) (ItinSugEventEnt, error) {
+	e.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO itin_suggestion_events(itin_sug_id, member_id, member_itin_id, event_type, created_date)
+		VALUES($1, $2, $3, $4, $5) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, e.ItinSugID, e.MemberID, e.MemberItinID, e.EventType, e.CreatedDate).Scan(&e.ID)
+
+	return e, err
+}
+
+// RefreshAnalytics refresh the daily rollups, the views are still readable while refreshing
+func (c *Contract) RefreshAnalytics(db *pgxpool.Conn, ctx context.Context) error {
+	for _, view := range analyticsViews {
+		if _, err := db.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+psql.Ident(view)); err != nil {
+			return err
+		}
+
+		_, err := db.Exec(ctx, `INSERT INTO analytics_refreshes(view_name, refreshed_date) VALUES($1, $2)
+			ON CONFLICT (view_name) DO UPDATE SET refreshed_date = EXCLUDED.refreshed_date`, view, time.Now().In(time.UTC))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAnalyticsRefreshedDate the oldest refresh of the rollups, null when never refreshed
+func (c *Contract) GetAnalyticsRefreshedDate(db *pgxpool.Conn, ctx context.Context) (sql.NullTime, error) {
+	var refreshed sql.NullTime
+
+	err := db.QueryRow(ctx, `select min(refreshed_date) from analytics_refreshes`).Scan(&refreshed)
+
+	return refreshed, err
+}
+
+// GetAnalyticsRevenue ...
+func (c *Contract) GetAnalyticsRevenue(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsRevenueEnt, error) {
+	list := []AnalyticsRevenueEnt{}
+
+	rows, err := db.Query(ctx, `select date_trunc($1, day::timestamp)::date, order_type, sum(total_payments)::bigint, sum(total_amount)::bigint
+		from analytics_daily_revenue where day between $2 and $3
+		group by 1, 2 order by 1, 2`, analyticsParamQuery(param)...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsRevenueEnt
+		if err = rows.Scan(&a.Period, &a.OrderType, &a.TotalPayments, &a.TotalAmount); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsOrders ...
+func (c *Contract) GetAnalyticsOrders(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsOrderEnt, error) {
+	list := []AnalyticsOrderEnt{}
+
+	rows, err := db.Query(ctx, `select date_trunc($1, day::timestamp)::date, order_type, sum(total_orders)::bigint, sum(paid_orders)::bigint,
+			sum(cancelled_orders)::bigint, sum(total_price)::bigint
+		from analytics_daily_orders where day between $2 and $3
+		group by 1, 2 order by 1, 2`, analyticsParamQuery(param)...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsOrderEnt
+		if err = rows.Scan(&a.Period, &a.OrderType, &a.TotalOrders, &a.PaidOrders, &a.CancelledOrders, &a.TotalPrice); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsFunnel ...
+func (c *Contract) GetAnalyticsFunnel(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsFunnelEnt, error) {
+	list := []AnalyticsFunnelEnt{}
+
+	rows, err := db.Query(ctx, `select date_trunc($1, day::timestamp)::date, sum(chats)::bigint, sum(itineraries)::bigint, sum(orders)::bigint,
+			sum(paid_orders)::bigint
+		from analytics_daily_funnel where day between $2 and $3
+		group by 1 order by 1`, analyticsParamQuery(param)...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsFunnelEnt
+		if err = rows.Scan(&a.Period, &a.Chats, &a.Itineraries, &a.Orders, &a.PaidOrders); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsSuggestions views & clones of all suggestion itineraries per period
+func (c *Contract) GetAnalyticsSuggestions(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsSuggestionEnt, error) {
+	list := []AnalyticsSuggestionEnt{}
+
+	rows, err := db.Query(ctx, `select date_trunc($1, day::timestamp)::date, sum(views)::bigint, sum(clones)::bigint
+		from analytics_daily_suggestions where day between $2 and $3
+		group by 1 order by 1`, analyticsParamQuery(param)...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsSuggestionEnt
+		if err = rows.Scan(&a.Period, &a.Views, &a.Clones); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsTopSuggestions the most viewed suggestion itineraries of the date range
+func (c *Contract) GetAnalyticsTopSuggestions(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsSuggestionEnt, error) {
+	list := []AnalyticsSuggestionEnt{}
+
+	rows, err := db.Query(ctx, `select s.itin_code, s.title, sum(a.views)::bigint, sum(a.clones)::bigint
+		from analytics_daily_suggestions a
+		join itin_suggestions s on s.id = a.itin_sug_id
+		where a.day between $1 and $2
+		group by s.id order by 3 desc, 4 desc limit $3`, param["start_date"], param["end_date"], param["limit"])
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsSuggestionEnt
+		if err = rows.Scan(&a.ItinCode, &a.Title, &a.Views, &a.Clones); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsTopDestinations destinations with the most itineraries of the date range
+func (c *Contract) GetAnalyticsTopDestinations(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsDestinationEnt, error) {
+	list := []AnalyticsDestinationEnt{}
+
+	rows, err := db.Query(ctx, `select destination, sum(itineraries)::bigint, sum(orders)::bigint, sum(paid_orders)::bigint
+		from analytics_daily_destinations where day between $1 and $2
+		group by 1 order by 2 desc, 4 desc limit $3`, param["start_date"], param["end_date"], param["limit"])
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsDestinationEnt
+		if err = rows.Scan(&a.Destination, &a.Itineraries, &a.Orders, &a.PaidOrders); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// GetAnalyticsTcPerformance chats, orders, revenue & rating of tc in the date range, the highest revenue first
+func (c *Contract) GetAnalyticsTcPerformance(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AnalyticsTcEnt, error) {
+	list := []AnalyticsTcEnt{}
+
+	rows, err := db.Query(ctx, `select u.user_code, u.name, sum(a.chats)::bigint, sum(a.orders)::bigint, sum(a.revenue)::bigint,
+			sum(a.ratings)::bigint, coalesce(sum(a.rating_total)::float / nullif(sum(a.ratings), 0)::float, 0)
+		from analytics_daily_tc a
+		join users u on u.id = a.tc_id
+		where a.day between $1 and $2
+		group by u.id order by 5 desc, 3 desc limit $3`, param["start_date"], param["end_date"], param["limit"])
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a AnalyticsTcEnt
+		if err = rows.Scan(&a.UserCode, &a.Name, &a.Chats, &a.Orders, &a.Revenue, &a.Ratings, &a.AvgRating); err != nil {
+			return list, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"panorama/lib/audit"
+	"panorama/lib/psql"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	AUDIT_MEMBER      = "members"
+	AUDIT_USER        = "users"
+	AUDIT_SUG_ITIN    = "sug_itins"
+	AUDIT_MEMBER_ITIN = "member_itins"
+	AUDIT_ORDER       = "orders"
+	AUDIT_SETTING     = "settings"
+	AUDIT_STUFF       = "stuff"
+)
+
+// AuditLogEnt ...
+type AuditLogEnt struct {
+	ID          int64
+	ActorCode   sql.NullString
+	ActorRole   sql.NullString
+	Channel     sql.NullString
+	EntityType  string
+	EntityID    sql.NullString
+	Action      string
+	BeforeData  []byte
+	AfterData   []byte
+	Diff        []byte
+	IP          sql.NullString
+	UserAgent   sql.NullString
+	Method      sql.NullString
+	Path        sql.NullString
+	CreatedDate time.Time
+}
+
+// AddAuditLog record the change of the entity
+func (c *Contract) AddAuditLog(db audit.Execer, ctx context.Context, e audit.Entry) error {
+	return audit.Write(ctx, db, e)
+}
+
+// GetAuditLogByID ...
+func (c *Contract) GetAuditLogByID(db *pgxpool.Conn, ctx context.Context, id int64) (AuditLogEnt, error) {
+	q, _ := listAuditLogQuery(nil)
+
+	return scanListAuditLog(db.QueryRow(ctx, q+" WHERE a.id = $1", id))
+}
+
+// GetListAuditLog audit trail of the changes (admin)
+func (c *Contract) GetListAuditLog(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]AuditLogEnt, error) {
+	list := []AuditLogEnt{}
+
+	q, paramQuery := listAuditLogQuery(param)
+
+	{
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY a.id " + psql.Direction(param["sort"].(string))
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListAuditLog(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// CountListAuditLog total audit logs of the list filter
+func (c *Contract) CountListAuditLog(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listAuditLogQuery(param)
+
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
+
+// ExportListAuditLog all audit logs of the list filter, streamed into fn row by row
+func (c *Contract) ExportListAuditLog(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(AuditLogEnt) error) error {
+	q, paramQuery := listAuditLogQuery(param)
+
+	rows, err := db.Query(ctx, q+" ORDER BY a.id "+psql.Direction(param["sort"].(string)), paramQuery...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(a); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// PruneAuditLog delete the audit logs older than the time (retention), the append only trigger
+// of audit_logs only allow the delete within the prune transaction
+func (c *Contract) PruneAuditLog(db *pgxpool.Conn, ctx context.Context, before time.Time) (int64, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "SET LOCAL audit.prune = 'on'"); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, "delete from audit_logs where created_date < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+// listAuditLogQuery audit logs query of the list filter without order & pagination
+func listAuditLogQuery(param map[string]interface{}) (string, []interface{}) {
+	var where []string
+	var paramQuery []interface{}
+
+	q := `select a.id, a.actor_code, a.actor_role, a.channel, a.entity_type, a.entity_id, a.action,
+			a.before_data, a.after_data, a.diff, a.ip, a.user_agent, a.method, a.path, a.created_date
+		from audit_logs a`
+
+	if param == nil {
+		return q, paramQuery
+	}
+
+	for _, key := range []string{"actor_code", "actor_role", "channel", "entity_type", "entity_id", "action"} {
+		if len(param[key].(string)) > 0 {
+			paramQuery = append(paramQuery, param[key])
+			where = append(where, fmt.Sprintf("a.%s = $%d", key, len(paramQuery)))
+		}
+	}
+
+	if len(param["keyword"].(string)) > 0 {
+		paramQuery = append(paramQuery, "%"+strings.ToLower(param["keyword"].(string))+"%")
+		where = append(where, fmt.Sprintf("(lower(a.path) like $%d OR lower(a.entity_id) like $%d OR lower(a.diff::text) like $%d)",
+			len(paramQuery), len(paramQuery), len(paramQuery)))
+	}
+
+	if len(param["start_date"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["start_date"].(string)+" 00:00:00")
+		where = append(where, fmt.Sprintf("a.created_date >= $%d", len(paramQuery)))
+	}
+
+	if len(param["end_date"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["end_date"].(string)+" 23:59:59")
+		where = append(where, fmt.Sprintf("a.created_date <= $%d", len(paramQuery)))
+	}
+
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	return q, paramQuery
+}
+
+// scanListAuditLog ...
+func scanListAuditLog(row pgx.Row) (AuditLogEnt, error) {
+	var a AuditLogEnt
+	err := row.Scan(&a.ID, &a.ActorCode, &a.ActorRole, &a.Channel, &a.EntityType, &a.EntityID, &a.Action,
+		&a.BeforeData, &a.AfterData, &a.Diff, &a.IP, &a.UserAgent, &a.Method, &a.Path, &a.CreatedDate)
+
+	return a, err
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"math/rand"
 	"net/url"
 	"panorama/bootstrap"
 	"panorama/lib/citcall"
 	"panorama/lib/sendgrid"
+	"panorama/lib/storage"
 	"panorama/lib/utils"
 	"strings"
 	"time"
@@ -17,7 +17,6 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"github.com/spf13/viper"
 	mail "github.com/xhit/go-simple-mail/v2"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -142,7 +141,7 @@ func (c *Contract) isValidTokenAction(ch, via, username string) bool {
 	return false
 }
 
-func (c *Contract) sendDataMail(usedFor, subject, to string, dataMail interface{}) {
+func (c *Contract) sendDataMail(ctx context.Context, usedFor, subject, to string, dataMail interface{}) {
 	fn := fmt.Sprintf("%s/%s.html", c.Config.GetString("resource_path"), usedFor)
 
 	server := mail.NewSMTPClient()
@@ -157,14 +156,14 @@ func (c *Contract) sendDataMail(usedFor, subject, to string, dataMail interface{
 	// SMTP client
 	smtpClient, err := server.Connect()
 	if err != nil {
-		log.Println(err)
+		c.Log.FromContext(ctx).WithError(err).Error("Failed to connect to the smtp server")
 		return
 	}
 
 	// fill the html body
 	tpl, err := utils.ParseTpl(fn, dataMail)
 	if err != nil {
-		fmt.Println(err.Error())
+		c.Log.FromContext(ctx).WithError(err).Error("Failed to parse the mail template")
 		return
 	}
 
@@ -177,20 +176,76 @@ func (c *Contract) sendDataMail(usedFor, subject, to string, dataMail interface{
 
 	email.SetBody(mail.TextHTML, tpl)
 	if email.Error != nil {
-		log.Println(email.Error)
+		c.Log.FromContext(ctx).WithError(email.Error).Error("Failed to build the mail")
 		return
 	}
 
 	// Call Send and pass the client
 	err = email.Send(smtpClient)
 	if err != nil {
-		log.Println(err)
+		c.Log.FromContext(ctx).WithError(err).Error("Failed to send the mail")
 		return
 	} else {
-		log.Println("Email Sent")
+		c.Log.FromContext(ctx).WithField("used_for", usedFor).Info("Email Sent")
 	}
 }
 
+// MailAttachment file that attached into email
+type MailAttachment struct {
+	Filename string
+	Mime     string
+	Data     []byte
+}
+
+// sendDataMailAttachments send html email with attachments
+func (c *Contract) sendDataMailAttachments(ctx context.Context, usedFor, subject, to string, dataMail interface{}, attachments []MailAttachment) error {
+	fn := fmt.Sprintf("%s/%s.html", c.Config.GetString("resource_path"), usedFor)
+
+	server := mail.NewSMTPClient()
+
+	// SMTP Server
+	server.Host = c.Config.GetString("mail.host")
+	server.Port = c.Config.GetInt("mail.port")
+	server.Username = c.Config.GetString("mail.username")
+	server.Password = c.Config.GetString("mail.password")
+	server.Encryption = mail.EncryptionSTARTTLS
+
+	// SMTP client
+	smtpClient, err := server.Connect()
+	if err != nil {
+		return err
+	}
+
+	// fill the html body
+	tpl, err := utils.ParseTpl(fn, dataMail)
+	if err != nil {
+		return err
+	}
+
+	from := fmt.Sprintf("%s <%s>", c.Config.GetString("mail.mail_name"), c.Config.GetString("mail.mail_from"))
+	email := mail.NewMSG()
+	email.SetFrom(from).
+		AddTo(to).
+		SetSubject(subject)
+
+	email.SetBody(mail.TextHTML, tpl)
+	for _, a := range attachments {
+		email.AddAttachmentData(a.Data, a.Filename, a.Mime)
+	}
+	if email.Error != nil {
+		return email.Error
+	}
+
+	err = email.Send(smtpClient)
+	if err != nil {
+		return err
+	}
+
+	c.Log.FromContext(ctx).WithField("used_for", usedFor).Info("Email Sent")
+
+	return nil
+}
+
 // SendToken sending token for multiple action, type and channel
 func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor, via, username, role, tokenParam string) (string, error) {
 	if !c.isValidTokenAction(ch, via, username) {
@@ -200,7 +255,7 @@ func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor,
 	var token string
 	if  !c.isUsernameExists(db, ctx, ch, username) && usedFor != "change-phone" {
 		dataMail := DataEmailToken{
-			ExpiredTime: tokenExpiredMin,
+			ExpiredTime: c.tokenExpiredMinute(db, ctx),
 		}
 	
 		switch ch {
@@ -217,12 +272,12 @@ func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor,
 			dataMail.Description = "Please input the 4 digit code"
 	
 			if via == TokenViaEmail {
-				go c.sendDataMail(usedFor, tokenMailSubj[usedFor], username, dataMail)
+				go c.sendDataMail(ctx, usedFor, tokenMailSubj[usedFor], username, dataMail)
 			}
 	
 			if via == TokenViaPhone {
 				// Send SMS with token
-				sms, err := citcall.New(c.App).SendOTP(username, token, tokenExpiredMin)
+				sms, err := citcall.New(c.App).SendOTP(ctx, username, token, c.tokenExpiredMinute(db, ctx))
 				if err != nil {
 					return "", err
 				}
@@ -241,14 +296,14 @@ func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor,
 			dataMail.Description = "Please click the link"
 	
 			if via == TokenViaEmail {
-				go c.sendDataMail(usedFor, tokenMailSubj[usedFor], username, dataMail)
+				go c.sendDataMail(ctx, usedFor, tokenMailSubj[usedFor], username, dataMail)
 			}
 		}
 	} else if !c.isUsernameExists(db, ctx, ch, username) {
 		return "", fmt.Errorf("%s", "user doesn't exists")
 	} else {
 		dataMail := DataEmailToken{
-			ExpiredTime: tokenExpiredMin,
+			ExpiredTime: c.tokenExpiredMinute(db, ctx),
 		}
 	
 		switch ch {
@@ -265,12 +320,12 @@ func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor,
 			dataMail.Description = "Please input the 4 digit code"
 	
 			if via == TokenViaEmail {
-				go c.sendDataMail(usedFor, tokenMailSubj[usedFor], username, dataMail)
+				go c.sendDataMail(ctx, usedFor, tokenMailSubj[usedFor], username, dataMail)
 			}
 	
 			if via == TokenViaPhone {
 				// Send SMS with token
-				sms, err := citcall.New(c.App).SendOTP(username, token, tokenExpiredMin)
+				sms, err := citcall.New(c.App).SendOTP(ctx, username, token, c.tokenExpiredMinute(db, ctx))
 				if err != nil {
 					return "", err
 				}
@@ -289,7 +344,7 @@ func (c *Contract) SendToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor,
 			dataMail.Description = "Please click the link"
 	
 			if via == TokenViaEmail {
-				go c.sendDataMail(usedFor, tokenMailSubj[usedFor], username, dataMail)
+				go c.sendDataMail(ctx, usedFor, tokenMailSubj[usedFor], username, dataMail)
 			}
 		}
 	}
@@ -309,6 +364,11 @@ func (c *Contract) isUsernameExists(db *pgxpool.Conn, ctx context.Context, ch, u
 	return false
 }
 
+// tokenExpiredMinute expiry of the token from settings, tokenExpiredMin by default
+func (c *Contract) tokenExpiredMinute(db *pgxpool.Conn, ctx context.Context) int {
+	return c.SettingInt(db, ctx, "auth", "otp_expiry_min", tokenExpiredMin)
+}
+
 // addNewToken ...
 func (c *Contract) addNewToken(db *pgxpool.Conn, ctx context.Context, ch, usedFor, via, username, tokenParam string) (string, error) {
 	token := tokenParam
@@ -322,7 +382,7 @@ func (c *Contract) addNewToken(db *pgxpool.Conn, ctx context.Context, ch, usedFo
 	_, err := db.Exec(context.Background(),
 		`insert into token_logs(channel,used_for,via,username,token,exp_date,created_date)
 		values($1,$2,$3,$4,$5,$6,$7)`,
-		ch, usedFor, via, username, token, now.Add(time.Minute*tokenExpiredMin), now,
+		ch, usedFor, via, username, token, now.Add(time.Minute*time.Duration(c.tokenExpiredMinute(db, ctx))), now,
 	)
 	if err != nil {
 		return "", err
@@ -411,7 +471,7 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 		//check last active visit app
 		id, date, i, err := c.GetLogVisitApp(db, ctx, m.ID, "customer")
 		if err != nil && err == sql.ErrNoRows {
-			fmt.Println(err)
+			c.Log.FromContext(ctx).Error(err)
 			return nil, err
 		}
 
@@ -420,14 +480,14 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 			if date.IsZero() || DateEqual(date, time.Now()) {
 				err = c.UpdateTotalVisited(db, ctx, m.ID, i+1, "customer", id)
 				if err != nil {
-					fmt.Println(err)
+					c.Log.FromContext(ctx).Error(err)
 					return nil, err
 				}
 			} else {
 
 				err = c.AddLogVisitApp(db, ctx, m.ID, "customer")
 				if err != nil {
-					fmt.Println(err)
+					c.Log.FromContext(ctx).Error(err)
 					return nil, err
 				}
 			}
@@ -441,7 +501,7 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 			if IsUrl(m.Img.String) {
 				userImage = m.Img.String
 			} else {
-				userImage = viper.GetString("aws.s3.public_url") + m.Img.String
+				userImage = storage.URL(m.Img.String)
 			}
 	
 		} else {
@@ -466,7 +526,7 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 		//check last active visit app
 		id, date, i, err := c.GetLogVisitApp(db, ctx, u.ID, u.Role)
 		if err != nil && err == sql.ErrNoRows {
-			fmt.Println(err)
+			c.Log.FromContext(ctx).Error(err)
 			return nil, err
 		}
 
@@ -475,14 +535,14 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 			if date.IsZero() || DateEqual(date, time.Now()) {
 				err = c.UpdateTotalVisited(db, ctx, u.ID, i+1, u.Role, id)
 				if err != nil {
-					fmt.Println(err)
+					c.Log.FromContext(ctx).Error(err)
 					return nil, err
 				}
 			} else {
 
 				err = c.AddLogVisitApp(db, ctx, u.ID, u.Role)
 				if err != nil {
-					fmt.Println(err)
+					c.Log.FromContext(ctx).Error(err)
 					return nil, err
 				}
 			}
@@ -496,7 +556,7 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 			if IsUrl(u.Img.String) {
 				userImage = u.Img.String
 			} else {
-				userImage = viper.GetString("aws.s3.public_url") + u.Img.String
+				userImage = storage.URL(u.Img.String)
 			}
 	
 		} else {
@@ -527,9 +587,9 @@ func (c *Contract) AuthLogin(db *pgxpool.Conn, ctx context.Context, ch string, u
 }
 
 // SendingMail sending email into email to with data mail
-func (c *Contract) SendingMail(usedFor, subject, emailTo string, dataMail interface{}) error {
+func (c *Contract) SendingMail(ctx context.Context, usedFor, subject, emailTo string, dataMail interface{}) error {
 	if utils.IsEmail(emailTo) {
-		go c.sendDataMail(usedFor, subject, emailTo, dataMail)
+		go c.sendDataMail(ctx, usedFor, subject, emailTo, dataMail)
 
 		return nil
 	}
@@ -567,11 +627,11 @@ func (c *Contract) UpdatePassword(tx pgx.Tx, ctx context.Context, ch, username,
 	return err
 }
 
-func (c *Contract) SendingMailWSG(usedFor, subject, emailTo string, dataMail interface{}) error {
+func (c *Contract) SendingMailWSG(ctx context.Context, usedFor, subject, emailTo string, dataMail interface{}) error {
 	var err error
 
 	if utils.IsEmail(emailTo) {
-		err := c.sendDataMailWSG(usedFor, subject, emailTo, dataMail)
+		err := c.sendDataMailWSG(ctx, usedFor, subject, emailTo, dataMail)
 		if err != nil {
 			return err
 		}
@@ -580,25 +640,25 @@ func (c *Contract) SendingMailWSG(usedFor, subject, emailTo string, dataMail int
 	return err
 }
 
-func (c *Contract) sendDataMailWSG(usedFor, subject, to string, dataMail interface{}) error {
+func (c *Contract) sendDataMailWSG(ctx context.Context, usedFor, subject, to string, dataMail interface{}) error {
 	var err error
 
 	// Parsing data into html
 	fn := fmt.Sprintf("%s/%s.html", c.Config.GetString("resource_path"), usedFor)
 	template, err := utils.ParseTpl(fn, dataMail)
 	if err != nil {
-		log.Println(err.Error())
+		c.Log.FromContext(ctx).WithError(err).Error("Failed to parse the mail template")
 		return err
 	}
 
 	// Send mail with sendgrid
-	_, err = sendgrid.New(c.App).MailSender(subject, to, template)
+	_, err = sendgrid.New(c.App).MailSender(ctx, subject, to, template)
 	if err != nil {
-		log.Println(err.Error())
+		c.Log.FromContext(ctx).WithError(err).Error("Failed to send the mail")
 		return err
 	}
 
-	log.Println("Email Sent")
+	c.Log.FromContext(ctx).WithField("used_for", usedFor).Info("Email Sent")
 
 	return err
 }
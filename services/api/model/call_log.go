@@ -3,7 +3,7 @@ package model
 import (
 	"context"
 	"database/sql"
-	"strings"
+	"panorama/lib/psql"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -46,53 +46,74 @@ func (c *Contract) AddCallLog(tx pgx.Tx, ctx context.Context, cl CallLogEnt) (Ca
 // Get Call Logs List
 func (c *Contract) GetListCallLogs(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]CallLogEnt, error) {
 	list := []CallLogEnt{}
-	var where []string
-	var paramQuery []interface{}
 
-	sql := `select id, trx_id, provider, call_type, bill_price, payloads, created_date from call_logs`
+	q, paramQuery := listCallLogsQuery(param)
 
-	var q string = sql
-
-	if len(param["trx_id"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " trx_id = '"+param["trx_id"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
 	}
 
-	if len(param["call_type"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " call_type = '"+param["call_type"].(string)+"'")
+	defer rows.Close()
 
-		where = append(where, strings.Join(orWhere, " AND "))
+	for rows.Next() {
+		c, err := scanListCallLog(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, c)
 	}
+	return list, err
+}
 
-	if len(param["provider"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " provider = '"+param["provider"].(string)+"'")
+// CountListCallLogs total call logs of the list filter
+func (c *Contract) CountListCallLogs(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listCallLogsQuery(param)
 
-		where = append(where, strings.Join(orWhere, " AND "))
-	}
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
 
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
+// ExportListCallLogs all call logs of the list filter, streamed into fn row by row
+func (c *Contract) ExportListCallLogs(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(CallLogEnt) error) error {
+	q, paramQuery := listCallLogsQuery(param)
 
-	rows, err := db.Query(ctx, q, paramQuery...)
+	rows, err := db.Query(ctx, q+" ORDER BY id", paramQuery...)
 	if err != nil {
-		return list, err
+		return err
 	}
 
 	defer rows.Close()
-
 	for rows.Next() {
-		var c CallLogEnt
-		err = rows.Scan(&c.ID, &c.TrxID, &c.Provider, &c.CallType, &c.BillPrice, &c.Payloads, &c.CreatedDate)
+		l, err := scanListCallLog(rows)
 		if err != nil {
-			return list, err
+			return err
 		}
+		if err = fn(l); err != nil {
+			return err
+		}
+	}
 
-		list = append(list, c)
+	return rows.Err()
+}
+
+// listCallLogsQuery call logs query of the list filter
+func listCallLogsQuery(param map[string]interface{}) (string, []interface{}) {
+	var q psql.Query
+
+	for _, key := range []string{"trx_id", "call_type", "provider"} {
+		if len(param[key].(string)) > 0 {
+			q.Where(key+" = ?", param[key])
+		}
 	}
-	return list, err
+
+	return `select id, trx_id, provider, call_type, bill_price, payloads, created_date from call_logs` + q.WhereSQL(), q.Args
+}
+
+// scanListCallLog ...
+func scanListCallLog(rows pgx.Rows) (CallLogEnt, error) {
+	var c CallLogEnt
+	err := rows.Scan(&c.ID, &c.TrxID, &c.Provider, &c.CallType, &c.BillPrice, &c.Payloads, &c.CreatedDate)
+
+	return c, err
 }
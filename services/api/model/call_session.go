@@ -0,0 +1,241 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"panorama/lib/utils"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	CALL_TYPE_VOICE     = "voice"
+	CALL_TYPE_VIDEO     = "video"
+	CALL_STATUS_RINGING = "ringing"
+	CALL_STATUS_ONGOING = "ongoing"
+	CALL_STATUS_ENDED   = "ended"
+	CALL_STATUS_MISSED  = "missed"
+)
+
+// CallSessionEnt ...
+type CallSessionEnt struct {
+	ID           int32
+	CallCode     string
+	ChatGroupID  int32
+	ChannelName  string
+	CallType     string
+	Status       string
+	StartedBy    int32
+	StartedRole  string
+	StartedName  string
+	StartedCode  string
+	AnsweredDate sql.NullTime
+	EndedDate    sql.NullTime
+	Duration     int32
+	CreatedDate  time.Time
+}
+
+// CallParticipantEnt ...
+type CallParticipantEnt struct {
+	ID               int32
+	CallSessionID    int32
+	UserID           int32
+	Role             string
+	JoinedDate       sql.NullTime
+	LeftDate         sql.NullTime
+	TokenExpiredDate sql.NullTime
+	CreatedDate      time.Time
+}
+
+func (c *Contract) SetCallCode() string {
+	rand.Seed(time.Now().UnixNano())
+	code, _ := utils.Generate(`[a-z0-9]{8}`)
+	return fmt.Sprintf("CALL-%s-%s", time.Now().In(time.Local).Format("060102"), code)
+}
+
+// IsActive call session is still ringing or ongoing
+func (cs CallSessionEnt) IsActive() bool {
+	return cs.Status == CALL_STATUS_RINGING || cs.Status == CALL_STATUS_ONGOING
+}
+
+// AddCallSession add new call session of chat group
+func (c *Contract) AddCallSession(tx pgx.Tx, ctx context.Context, cs CallSessionEnt) (CallSessionEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO call_sessions(call_code, chat_group_id, channel_name, call_type, status, started_by, started_role, created_date) VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, cs.CallCode, cs.ChatGroupID, cs.ChannelName, cs.CallType, cs.Status, cs.StartedBy, cs.StartedRole, timeStamp).Scan(&lastInsID)
+
+	cs.ID = lastInsID
+	cs.CreatedDate = timeStamp
+
+	return cs, err
+}
+
+// CallSessionActiveIndex unique index of the ringing or ongoing call of chat group
+const CallSessionActiveIndex = "call_sessions_active_chat_group_id_idx"
+
+// LockCallChatGroup lock the chat group so the calls of chat group are started one by one
+func (c *Contract) LockCallChatGroup(tx pgx.Tx, ctx context.Context, chatGroupID int32) error {
+	var id int32
+
+	return tx.QueryRow(ctx, `select id from chat_groups where id = $1 for update`, chatGroupID).Scan(&id)
+}
+
+// UpdateCallSession update status, answered, ended date and duration of call session
+func (c *Contract) UpdateCallSession(tx pgx.Tx, ctx context.Context, cs CallSessionEnt) error {
+	var ID int32
+
+	sql := `UPDATE call_sessions SET status=$1, answered_date=$2, ended_date=$3, duration=$4 WHERE id=$5 RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, cs.Status, cs.AnsweredDate, cs.EndedDate, cs.Duration, cs.ID).Scan(&ID)
+
+	return err
+}
+
+const callSessionSelect = `
+	select
+		cs.id, cs.call_code, cs.chat_group_id, cs.channel_name, cs.call_type, cs.status,
+		cs.started_by, cs.started_role,
+		case when cs.started_role = 'customer' then m.name else u.name end started_name,
+		case when cs.started_role = 'customer' then m.member_code else u.user_code end started_code,
+		cs.answered_date, cs.ended_date, cs.duration, cs.created_date
+	from call_sessions cs
+	join chat_groups cg on cg.id = cs.chat_group_id
+	left join members m on m.id = cs.started_by and cs.started_role = 'customer'
+	left join users u on u.id = cs.started_by and cs.started_role != 'customer' `
+
+func scanCallSession(row pgx.Row) (CallSessionEnt, error) {
+	var cs CallSessionEnt
+	var startedName, startedCode sql.NullString
+
+	err := row.Scan(&cs.ID, &cs.CallCode, &cs.ChatGroupID, &cs.ChannelName, &cs.CallType, &cs.Status,
+		&cs.StartedBy, &cs.StartedRole, &startedName, &startedCode, &cs.AnsweredDate, &cs.EndedDate, &cs.Duration, &cs.CreatedDate)
+	cs.StartedName = startedName.String
+	cs.StartedCode = startedCode.String
+
+	return cs, err
+}
+
+// GetCallSessionByCode get call session by chat group code and call code
+func (c *Contract) GetCallSessionByCode(db *pgxpool.Conn, ctx context.Context, chatCode, callCode string) (CallSessionEnt, error) {
+	sql := callSessionSelect + `where cg.chat_group_code = $1 and cs.call_code = $2 limit 1`
+
+	return scanCallSession(db.QueryRow(ctx, sql, chatCode, callCode))
+}
+
+// GetActiveCallSessionByChatID get the ringing or ongoing call of chat group
+func (c *Contract) GetActiveCallSessionByChatID(db *pgxpool.Conn, ctx context.Context, chatGroupID int32) (CallSessionEnt, error) {
+	sql := callSessionSelect + `where cs.chat_group_id = $1 and cs.status in ($2, $3) order by cs.id desc limit 1`
+
+	return scanCallSession(db.QueryRow(ctx, sql, chatGroupID, CALL_STATUS_RINGING, CALL_STATUS_ONGOING))
+}
+
+// GetListCallSessionByChatCode call history of chat group
+func (c *Contract) GetListCallSessionByChatCode(db *pgxpool.Conn, ctx context.Context, code string, param map[string]interface{}) ([]CallSessionEnt, error) {
+	list := []CallSessionEnt{}
+
+	{
+		var count int
+		err := db.QueryRow(ctx, `select count(cs.id) from call_sessions cs join chat_groups cg on cg.id = cs.chat_group_id where cg.chat_group_code = $1`, code).Scan(&count)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	sql := callSessionSelect + `where cg.chat_group_code = $1 order by cs.created_date desc offset $2 limit $3`
+
+	rows, err := db.Query(ctx, sql, code, param["offset"], param["limit"])
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		cs, err := scanCallSession(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, cs)
+	}
+
+	return list, rows.Err()
+}
+
+// UpsertCallParticipant join participant into call session, the participant id is the agora uid
+func (c *Contract) UpsertCallParticipant(tx pgx.Tx, ctx context.Context, p CallParticipantEnt) (CallParticipantEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `
+		INSERT INTO call_participants(call_session_id, user_id, role, joined_date, token_expired_date, created_date)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (call_session_id, user_id, role) DO UPDATE
+		SET joined_date = coalesce(EXCLUDED.joined_date, call_participants.joined_date),
+			token_expired_date = EXCLUDED.token_expired_date,
+			left_date = null
+		RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, p.CallSessionID, p.UserID, p.Role, p.JoinedDate, p.TokenExpiredDate, timeStamp).Scan(&lastInsID)
+
+	p.ID = lastInsID
+	p.CreatedDate = timeStamp
+
+	return p, err
+}
+
+// GetCallParticipant get participant of call session
+func (c *Contract) GetCallParticipant(db *pgxpool.Conn, ctx context.Context, callSessionID, userID int32, role string) (CallParticipantEnt, error) {
+	var p CallParticipantEnt
+
+	sql := `select id, call_session_id, user_id, role, joined_date, left_date, token_expired_date, created_date from call_participants where call_session_id = $1 and user_id = $2 and role = $3`
+
+	err := db.QueryRow(ctx, sql, callSessionID, userID, role).Scan(&p.ID, &p.CallSessionID, &p.UserID, &p.Role, &p.JoinedDate, &p.LeftDate, &p.TokenExpiredDate, &p.CreatedDate)
+
+	return p, err
+}
+
+// UpdateCallParticipantToken save the expired date of renewed token
+func (c *Contract) UpdateCallParticipantToken(tx pgx.Tx, ctx context.Context, id int32, expiredDate time.Time) error {
+	_, err := tx.Exec(ctx, `UPDATE call_participants SET token_expired_date = $1 WHERE id = $2`, expiredDate, id)
+
+	return err
+}
+
+// LeaveCallParticipant participant leave the call session
+func (c *Contract) LeaveCallParticipant(tx pgx.Tx, ctx context.Context, callSessionID, userID int32, role string) error {
+	_, err := tx.Exec(ctx, `UPDATE call_participants SET left_date = $1 WHERE call_session_id = $2 and user_id = $3 and role = $4 and left_date is null`,
+		time.Now().In(time.UTC), callSessionID, userID, role)
+
+	return err
+}
+
+// CountJoinedCallParticipant total participant that still in the call session
+func (c *Contract) CountJoinedCallParticipant(tx pgx.Tx, ctx context.Context, callSessionID int32) (int32, error) {
+	var total int32
+
+	err := tx.QueryRow(ctx, `select count(id) from call_participants where call_session_id = $1 and joined_date is not null and left_date is null`, callSessionID).Scan(&total)
+
+	return total, err
+}
+
+// LeaveAllCallParticipant set all participant left when the call session is ended
+func (c *Contract) LeaveAllCallParticipant(tx pgx.Tx, ctx context.Context, callSessionID int32, leftDate time.Time) error {
+	_, err := tx.Exec(ctx, `UPDATE call_participants SET left_date = $1 WHERE call_session_id = $2 and joined_date is not null and left_date is null`, leftDate, callSessionID)
+
+	return err
+}
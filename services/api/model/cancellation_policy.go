@@ -0,0 +1,436 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	REFUND_STATUS_PENDING   = "pending"
+	REFUND_STATUS_PROCESSED = "processed"
+	REFUND_STATUS_NONE      = "none" // nothing to refund, e.g. the order is not paid yet
+)
+
+// CancellationPolicyEnt fee of order that cancelled by the customer, the policy of itinerary is used
+// before the policy of order type and the default policy (without order type and itinerary)
+type CancellationPolicyEnt struct {
+	ID            int32
+	Name          string
+	OrderType     sql.NullString
+	MemberItinID  sql.NullInt32
+	ItinCode      string
+	FreeUntilDays int32
+	IsActive      bool
+	Tiers         []CancellationTierEnt
+	CreatedBy     sql.NullInt32
+	CreatedDate   time.Time
+	UpdatedDate   sql.NullTime
+}
+
+// CancellationTierEnt fee percent of order that cancelled at least MinDays before the trip start
+type CancellationTierEnt struct {
+	MinDays    int32
+	FeePercent int32
+}
+
+// OrderRefundEnt cancellation fee and refund amount of the cancelled order
+type OrderRefundEnt struct {
+	ID            int32
+	OrderID       int32
+	PolicyID      sql.NullInt32
+	DaysBefore    sql.NullInt32
+	FeePercent    int32
+	PaidAmount    int64
+	FeeAmount     int64
+	RefundAmount  int64
+	RefundStatus  string
+	Reason        sql.NullString
+	CreatedBy     sql.NullInt32
+	CreatedDate   time.Time
+	ProcessedDate sql.NullTime
+}
+
+// FeePercent fee of the cancellation at days before the trip start, the tier with the highest
+// min days that is reached is used and the full fee is charged when no tier is reached
+func (p CancellationPolicyEnt) FeePercent(daysBefore int32) int32 {
+	if daysBefore >= p.FreeUntilDays {
+		return 0
+	}
+
+	fee := int32(100)
+	minDays := int32(-1)
+	for _, t := range p.Tiers {
+		if daysBefore >= t.MinDays && t.MinDays > minDays {
+			fee = t.FeePercent
+			minDays = t.MinDays
+		}
+	}
+
+	return fee
+}
+
+// Refund compute the fee of order total, the fee is taken from the paid amount and the rest is refunded.
+// Order without trip start date is cancelled without fee
+func (p CancellationPolicyEnt) Refund(total, paid int64, startDate, now time.Time) OrderRefundEnt {
+	r := OrderRefundEnt{
+		PolicyID:   sql.NullInt32{Int32: p.ID, Valid: p.ID != 0},
+		PaidAmount: paid,
+	}
+
+	if !startDate.IsZero() {
+		start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		days := int32(math.Floor(start.Sub(today).Hours() / 24))
+
+		r.DaysBefore = sql.NullInt32{Int32: days, Valid: true}
+		if p.ID != 0 {
+			r.FeePercent = p.FeePercent(days)
+		}
+	}
+
+	r.FeeAmount = total * int64(r.FeePercent) / 100
+	if r.FeeAmount > paid {
+		r.FeeAmount = paid
+	}
+	r.RefundAmount = paid - r.FeeAmount
+
+	r.RefundStatus = REFUND_STATUS_NONE
+	if r.RefundAmount > 0 {
+		r.RefundStatus = REFUND_STATUS_PENDING
+	}
+
+	return r
+}
+
+func init() {
+	OnOrderStatus(ORDER_STATUS_REFUNDED, processOrderRefund)
+}
+
+// processOrderRefund refund of the cancelled order is done when the order is moved into refunded
+func processOrderRefund(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, c *Contract, t OrderTransition) error {
+	_, err := tx.Exec(ctx, `UPDATE order_refunds SET refund_status = $1, processed_date = $2 WHERE order_id = $3 and refund_status = $4`,
+		REFUND_STATUS_PROCESSED, time.Now().In(time.UTC), t.Order.ID, REFUND_STATUS_PENDING)
+
+	return err
+}
+
+// AddCancellationPolicy add new policy with the tiers
+func (c *Contract) AddCancellationPolicy(tx pgx.Tx, ctx context.Context, p CancellationPolicyEnt) (CancellationPolicyEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO cancellation_policies(name, order_type, member_itin_id, free_until_days, is_active, created_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, p.Name, p.OrderType, p.MemberItinID, p.FreeUntilDays, p.IsActive, p.CreatedBy, timeStamp).Scan(&p.ID)
+	if err != nil {
+		return p, err
+	}
+	p.CreatedDate = timeStamp
+
+	return p, c.addCancellationTiers(tx, ctx, p)
+}
+
+// UpdateCancellationPolicy update policy, the tiers are replaced
+func (c *Contract) UpdateCancellationPolicy(tx pgx.Tx, ctx context.Context, p CancellationPolicyEnt) (CancellationPolicyEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	q := `UPDATE cancellation_policies SET name = $1, order_type = $2, member_itin_id = $3, free_until_days = $4, is_active = $5, updated_date = $6
+		WHERE id = $7`
+
+	_, err := tx.Exec(ctx, q, p.Name, p.OrderType, p.MemberItinID, p.FreeUntilDays, p.IsActive, timeStamp, p.ID)
+	if err != nil {
+		return p, err
+	}
+	p.UpdatedDate = sql.NullTime{Time: timeStamp, Valid: true}
+
+	_, err = tx.Exec(ctx, `delete from cancellation_policy_tiers where policy_id = $1`, p.ID)
+	if err != nil {
+		return p, err
+	}
+
+	return p, c.addCancellationTiers(tx, ctx, p)
+}
+
+func (c *Contract) addCancellationTiers(tx pgx.Tx, ctx context.Context, p CancellationPolicyEnt) error {
+	for _, t := range p.Tiers {
+		_, err := tx.Exec(ctx, `INSERT INTO cancellation_policy_tiers(policy_id, min_days, fee_percent) VALUES($1, $2, $3)`, p.ID, t.MinDays, t.FeePercent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteCancellationPolicy soft delete policy, the refund of cancelled order is kept
+func (c *Contract) DeleteCancellationPolicy(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `UPDATE cancellation_policies SET is_active = false, deleted_date = $1 WHERE id = $2`, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+const cancellationPolicySelect = `
+	select cp.id, cp.name, cp.order_type, cp.member_itin_id, mi.itin_code, cp.free_until_days, cp.is_active, cp.created_by, cp.created_date, cp.updated_date
+	from cancellation_policies cp
+	left join member_itins mi on mi.id = cp.member_itin_id `
+
+func scanCancellationPolicy(row pgx.Row) (CancellationPolicyEnt, error) {
+	var p CancellationPolicyEnt
+	var itinCode sql.NullString
+
+	err := row.Scan(&p.ID, &p.Name, &p.OrderType, &p.MemberItinID, &itinCode, &p.FreeUntilDays, &p.IsActive, &p.CreatedBy, &p.CreatedDate, &p.UpdatedDate)
+	p.ItinCode = itinCode.String
+
+	return p, err
+}
+
+// getCancellationTiers tiers of policy, the nearest to the trip start last
+func (c *Contract) getCancellationTiers(db *pgxpool.Conn, ctx context.Context, policyID int32) ([]CancellationTierEnt, error) {
+	list := []CancellationTierEnt{}
+
+	rows, err := db.Query(ctx, `select min_days, fee_percent from cancellation_policy_tiers where policy_id = $1 order by min_days desc`, policyID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var t CancellationTierEnt
+		if err = rows.Scan(&t.MinDays, &t.FeePercent); err != nil {
+			return list, err
+		}
+		list = append(list, t)
+	}
+
+	return list, rows.Err()
+}
+
+// GetCancellationPolicyByID policy that not deleted by the id
+func (c *Contract) GetCancellationPolicyByID(db *pgxpool.Conn, ctx context.Context, id int32) (CancellationPolicyEnt, error) {
+	p, err := scanCancellationPolicy(db.QueryRow(ctx, cancellationPolicySelect+`where cp.id = $1 and cp.deleted_date is null`, id))
+	if err != nil {
+		return p, err
+	}
+
+	p.Tiers, err = c.getCancellationTiers(db, ctx, p.ID)
+
+	return p, err
+}
+
+// GetListCancellationPolicy policies that not deleted with the tiers
+func (c *Contract) GetListCancellationPolicy(db *pgxpool.Conn, ctx context.Context) ([]CancellationPolicyEnt, error) {
+	list := []CancellationPolicyEnt{}
+
+	rows, err := db.Query(ctx, cancellationPolicySelect+`where cp.deleted_date is null order by cp.id`)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		p, err := scanCancellationPolicy(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, p)
+	}
+	if err = rows.Err(); err != nil {
+		return list, err
+	}
+	rows.Close()
+
+	for i := range list {
+		list[i].Tiers, err = c.getCancellationTiers(db, ctx, list[i].ID)
+		if err != nil {
+			return list, err
+		}
+	}
+
+	return list, nil
+}
+
+// GetOrderCancellationPolicy active policy of order, pgx.ErrNoRows is returned when no policy is matched
+func (c *Contract) GetOrderCancellationPolicy(db *pgxpool.Conn, ctx context.Context, o OrderEnt) (CancellationPolicyEnt, error) {
+	q := cancellationPolicySelect + `
+		where cp.deleted_date is null and cp.is_active = true
+			and (cp.member_itin_id = $1 or (cp.member_itin_id is null and (cp.order_type = $2 or cp.order_type is null)))
+		order by cp.member_itin_id is null, cp.order_type is null, cp.id desc
+		limit 1`
+
+	p, err := scanCancellationPolicy(db.QueryRow(ctx, q, o.MemberItinID, o.OrderType))
+	if err != nil {
+		return p, err
+	}
+
+	p.Tiers, err = c.getCancellationTiers(db, ctx, p.ID)
+
+	return p, err
+}
+
+// GetOrderPaidAmount amount of order that already paid by the installments and the shares
+func (c *Contract) GetOrderPaidAmount(db *pgxpool.Conn, ctx context.Context, o OrderEnt) (int64, error) {
+	if IsOrderPaid(o.OrderStatus) {
+		if o.TotalPricePpn > 0 {
+			return o.TotalPricePpn, nil
+		}
+		return o.TotalPrice, nil
+	}
+
+	var paid int64
+
+	sql := `select
+			coalesce((select sum(amount) from order_payments where order_id = $1 and installment_no > 0 and payment_status = $2), 0) +
+			coalesce((select sum(amount) from order_payment_shares where order_id = $1 and payment_status = $2), 0)`
+
+	err := db.QueryRow(ctx, sql, o.ID, PAYMENT_STATUS_PAID).Scan(&paid)
+
+	return paid, err
+}
+
+// AddOrderRefund save the refund of cancelled order
+func (c *Contract) AddOrderRefund(tx pgx.Tx, ctx context.Context, r OrderRefundEnt) (OrderRefundEnt, error) {
+	r.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO order_refunds(order_id, policy_id, days_before, fee_percent, paid_amount, fee_amount, refund_amount, refund_status, reason, created_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, r.OrderID, r.PolicyID, r.DaysBefore, r.FeePercent, r.PaidAmount, r.FeeAmount, r.RefundAmount, r.RefundStatus, r.Reason, r.CreatedBy, r.CreatedDate).Scan(&r.ID)
+
+	return r, err
+}
+
+// TopUpOrderRefund add the payment that is settled after the order is cancelled into the refund of order,
+// the late payment is refunded in full and the refund is created when the order has none
+func (c *Contract) TopUpOrderRefund(tx pgx.Tx, ctx context.Context, orderID int32, amount int64) error {
+	sql := `INSERT INTO order_refunds(order_id, paid_amount, refund_amount, refund_status, created_date)
+		VALUES($1, $2, $2, $3, $4)
+		ON CONFLICT (order_id) DO UPDATE SET
+			paid_amount = order_refunds.paid_amount + EXCLUDED.paid_amount,
+			refund_amount = order_refunds.refund_amount + EXCLUDED.refund_amount,
+			refund_status = EXCLUDED.refund_status`
+
+	_, err := tx.Exec(ctx, sql, orderID, amount, REFUND_STATUS_PENDING, time.Now().In(time.UTC))
+
+	return err
+}
+
+// HasPendingPayment order has midtrans transaction that still waiting to be paid (virtual account, QRIS),
+// the order is not cancelled until the transaction is paid or expired so the collected payment is refunded
+func (c *Contract) HasPendingPayment(db *pgxpool.Conn, ctx context.Context, orderID int32) (bool, error) {
+	orderPayment, err := c.GetPaymentOrderByOrderID(db, ctx, orderID)
+	if err != nil && err != pgx.ErrNoRows {
+		return false, err
+	}
+	if orderPayment.IsPending() {
+		return true, nil
+	}
+
+	installments, err := c.GetListOrderInstallmentByOrderID(db, ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	for _, i := range installments {
+		if i.IsPending() {
+			return true, nil
+		}
+	}
+
+	shares, err := c.GetListOrderPaymentShareByOrderID(db, ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	for _, sh := range shares {
+		if sh.IsPending() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetOrderCancellation policy, fee and refund of order when it is cancelled now
+func (c *Contract) GetOrderCancellation(db *pgxpool.Conn, ctx context.Context, o OrderEnt) (CancellationPolicyEnt, OrderRefundEnt, error) {
+	var refund OrderRefundEnt
+
+	policy, err := c.GetOrderCancellationPolicy(db, ctx, o)
+	if err != nil && err != pgx.ErrNoRows {
+		return policy, refund, err
+	}
+
+	paid, err := c.GetOrderPaidAmount(db, ctx, o)
+	if err != nil {
+		return policy, refund, err
+	}
+
+	total := o.TotalPrice
+	if o.TotalPricePpn > 0 {
+		total = o.TotalPricePpn
+	}
+
+	refund = policy.Refund(total, paid, o.MemberItin.StartDate, time.Now().In(time.UTC))
+	refund.OrderID = o.ID
+
+	return policy, refund, nil
+}
+
+// CancelOrder cancel the order with the fee of cancellation policy taken from the paid amount, the rest is saved
+// as the pending refund and the unpaid payments are cancelled. The order that is already cancelled is kept,
+// the transition has no history then
+func (c *Contract) CancelOrder(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, o OrderEnt, actor OrderStatusActor, reason string) (OrderTransition, CancellationPolicyEnt, OrderRefundEnt, error) {
+	var policy CancellationPolicyEnt
+	var refund OrderRefundEnt
+
+	transition, err := c.TransitionOrderStatus(tx, db, ctx, o.ID, ORDER_STATUS_CANCEL, actor, reason)
+	if err != nil || transition.History.ID == 0 {
+		return transition, policy, refund, err
+	}
+
+	// the paid amount is read after the order is locked so the payment in progress is counted
+	o.OrderStatus = transition.From
+	policy, refund, err = c.GetOrderCancellation(db, ctx, o)
+	if err != nil {
+		return transition, policy, refund, err
+	}
+
+	refund.Reason = sql.NullString{String: reason, Valid: len(reason) > 0}
+	refund.CreatedBy = actor.ID
+	refund, err = c.AddOrderRefund(tx, ctx, refund)
+	if err != nil {
+		return transition, policy, refund, err
+	}
+
+	// unpaid payment of the cancelled order can not be paid anymore
+	err = c.CancelOrderInstallment(tx, ctx, o.ID)
+	if err != nil {
+		return transition, policy, refund, err
+	}
+	err = c.CancelOrderPaymentShare(tx, ctx, o.ID)
+	if err != nil {
+		return transition, policy, refund, err
+	}
+
+	// voucher of the unpaid order can be used again
+	if refund.PaidAmount == 0 {
+		redemption, _ := c.GetVoucherRedemptionByOrderID(db, ctx, o.ID)
+		err = c.ReleaseOrderVoucher(tx, ctx, redemption)
+	}
+
+	return transition, policy, refund, err
+}
+
+// GetOrderRefundByOrderID refund of the cancelled order
+func (c *Contract) GetOrderRefundByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) (OrderRefundEnt, error) {
+	var r OrderRefundEnt
+
+	sql := `select id, order_id, policy_id, days_before, fee_percent, paid_amount, fee_amount, refund_amount, refund_status, reason, created_by, created_date, processed_date
+		from order_refunds where order_id = $1`
+
+	err := db.QueryRow(ctx, sql, orderID).Scan(&r.ID, &r.OrderID, &r.PolicyID, &r.DaysBefore, &r.FeePercent, &r.PaidAmount, &r.FeeAmount, &r.RefundAmount, &r.RefundStatus, &r.Reason, &r.CreatedBy, &r.CreatedDate, &r.ProcessedDate)
+
+	return r, err
+}
@@ -4,9 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"math"
-	"strings"
+	"panorama/lib/psql"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -120,11 +119,15 @@ func (c *Contract) GetIDGroupChatByCode(db *pgxpool.Conn, ctx context.Context, c
 }
 
 // AddChatMemberTempBatch add member temporary to chat
-func (c *Contract) AddChatMemberTempBatch(ctx context.Context, tx pgx.Tx, arrStr string) error {
-	var lastInsID int32
+func (c *Contract) AddChatMemberTempBatch(ctx context.Context, tx pgx.Tx, temps []ChatMemberTemporary) error {
+	now := time.Now().In(time.UTC)
+
+	var rows [][]interface{}
+	for _, t := range temps {
+		rows = append(rows, []interface{}{t.Email, t.ChatGroupID, now})
+	}
 
-	sql := `INSERT INTO chat_member_temporaries(email, chat_group_id, created_date) VALUES ` + arrStr + ` RETURNING id`
-	err := tx.QueryRow(ctx, sql).Scan(&lastInsID)
+	_, err := psql.InsertBatch(ctx, tx, "chat_member_temporaries", []string{"email", "chat_group_id", "created_date"}, rows)
 
 	return err
 }
@@ -132,8 +135,7 @@ func (c *Contract) AddChatMemberTempBatch(ctx context.Context, tx pgx.Tx, arrStr
 // GetChatList ...
 func (c *Contract) GetChatListBackupV1(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]ChatGroupEnt, error) {
 	list := []ChatGroupEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 	var lastMessage, tcCode, tcName, itinDestination, itinTitle, itinCode, orderCode, orderStatus, orderType, orderStatusDesc, tcReplacementDescription, memberName, memberCode, memberEmail sql.NullString
 	var itinDuration sql.NullInt32
 	var statusSession sql.NullBool
@@ -170,6 +172,11 @@ func (c *Contract) GetChatListBackupV1(db *pgxpool.Conn, ctx context.Context, pa
 			when o.order_status = 'P' then 'Waiting For Payment'
 			when o.order_status = 'C' then 'Completed'
 			when o.order_status = 'X' then 'Cancel'
+			when o.order_status = 'PP' then 'Partially Paid'
+			when o.order_status = 'D' then 'Draft'
+			when o.order_status = 'PD' then 'Paid'
+			when o.order_status = 'IP' then 'In Progress'
+			when o.order_status = 'R' then 'Refunded'
 			else o.order_status
 		end order_status_description,
 		case
@@ -309,45 +316,24 @@ func (c *Contract) GetChatListBackupV1(db *pgxpool.Conn, ctx context.Context, pa
 		order by cg.id, mic.created_date desc
 	) gcmic on gcmic.chat_group_code = cg.chat_group_code `
 
-	var queryString string = query
-
-	where = append(where, "o2.id is null")
+	w.Where("o2.id is null")
 
 	if len(param["member_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "mc.member_code = '"+param["member_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("mc.member_code = ?", param["member_code"])
 	}
 
 	if len(param["tc_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "u.user_code = '"+param["tc_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("u.user_code = ?", param["tc_code"])
 	}
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(cg.name) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(gcml.last_message) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(mc.name) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(mi.destination) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(mi.title) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(mi.itin_code) like lower('%"+param["keyword"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["keyword"].(string), "cg.name", "gcml.last_message", "mc.name", "mi.destination", "mi.title", "mi.itin_code")
 	}
 
-	if len(where) > 0 {
-		queryString += "WHERE " + strings.Join(where, " AND ")
-	}
+	var queryString string = query + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + queryString + ` ) AS data`
-
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, queryString, w.Args...)
 		if err != nil {
 			return list, err
 		}
@@ -362,15 +348,10 @@ func (c *Contract) GetChatListBackupV1(db *pgxpool.Conn, ctx context.Context, pa
 		param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 	}
 
-	if param["limit"].(int) == -1 {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+	queryString += " ORDER BY " + chatListOrder(param)
+	queryString += w.Page(param["offset"].(int), param["limit"].(int))
 
-	rows, err := db.Query(ctx, queryString, paramQuery...)
+	rows, err := db.Query(ctx, queryString, w.Args...)
 	if err != nil {
 		return list, err
 	}
@@ -412,7 +393,7 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 	var gc ChatGroupEnt
 	var messages, roles, messageID, nameUsers, chatDates, isReads, userCodes string
 	var itinTitle, itinCode, orderType, orderCode sql.NullString
-	var paramQuery []interface{}
+	var w psql.Query
 
 	{
 		var count int
@@ -439,15 +420,9 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 		param["count"] = count
 	}
 
-	var q string
-
-	if param["limit"].(int) == -1 {
-		q += "ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += "ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+	where := "chat_group_code = " + w.Arg(code)
+	q := "ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"cm.id"}, "cm.id")
+	q += w.Page(param["offset"].(int), param["limit"].(int))
 
 	query := `
 			select 
@@ -468,7 +443,7 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 				left join chat_messages cm on cm.chat_group_id = cg.id 
 				left join members m on m.id = cm.user_id
 				left join users us on us.id = cm.user_id
-				where chat_group_code = '` + code + `'
+				where ` + where + `
 				` + q + ` ) as a
 			left join ( 
 				select cgr.chat_group_id, count(cgr.member_id) total_member
@@ -479,13 +454,13 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 			join members m on m.id = a.createdby
 			group by a.name, chat_group_code, a.cg_id, mi.id,cgr.total_member, chat_group_type, m.id `
 
-	err := db.QueryRow(ctx, query, paramQuery...).Scan(&gc.ID, &gc.Name, &gc.ChatGroupCode, &gc.ChatGroupType,
+	err := db.QueryRow(ctx, query, w.Args...).Scan(&gc.ID, &gc.Name, &gc.ChatGroupCode, &gc.ChatGroupType,
 		&gc.Member.ID, &gc.Member.Name, &gc.Member.Email, &gc.Member.Img, &gc.Member.MemberCode,
 		&gc.TotalMember, &itinTitle, &itinCode,
 		// &orderType, &orderCode,
 		&messages, &roles, &messageID, &nameUsers, &chatDates, &isReads, &userCodes)
 	if err != nil {
-		fmt.Println(err)
+		c.Log.FromContext(ctx).WithError(err).WithField("chat_group_code", code).Error("Failed to read the chat history")
 		return gc, err
 	}
 
@@ -509,7 +484,7 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 	var mID []int32
 	err = json.Unmarshal([]byte(messageID), &mID)
 	if err != nil {
-		fmt.Println(err)
+		c.Log.FromContext(ctx).WithError(err).WithField("chat_group_code", code).Error("Failed to parse the message ids of the chat history")
 		return gc, err
 	}
 
@@ -544,7 +519,8 @@ func (c *Contract) GetChatHistoryByGroupCode(db *pgxpool.Conn, ctx context.Conte
 	return gc, err
 }
 
-// IsExistInGroupChat ...
+// IsExistInGroupChat count of the member or tc (code) in the chat group, zero when the code is not part of the group.
+// The code is matched only within the chat group, the tc of another group is not counted
 func (c *Contract) IsExistInGroupChat(db *pgxpool.Conn, ctx context.Context, groupCode string, code string) (int32, error) {
 
 	var id int32
@@ -567,7 +543,7 @@ func (c *Contract) IsExistInGroupChat(db *pgxpool.Conn, ctx context.Context, gro
 				left join members on members.id = cg.created_by
 				left join users us on us.id = cg.tc_id 
 			) a
-			where chat_group_code = $1 and member_code = $2 or user_code = $3
+			where chat_group_code = $1 and (member_code = $2 or user_code = $3)
  		`
 	err := db.QueryRow(ctx, query, groupCode, code, code).Scan(&id)
 	if err != nil {
@@ -634,7 +610,7 @@ func (c *Contract) CheckOtherMessages(db *pgxpool.Conn, ctx context.Context, gcI
 }
 
 // GetListUserGroupChat ...
-func (c *Contract) GetListUserGroupChat(db *pgxpool.Conn, ctx context.Context, gcID string) ([]ChatMessagesEnt, error) {
+func (c *Contract) GetListUserGroupChat(db *pgxpool.Conn, ctx context.Context, gcID int32) ([]ChatMessagesEnt, error) {
 
 	list := []ChatMessagesEnt{}
 	var name, email, code sql.NullString
@@ -644,27 +620,27 @@ func (c *Contract) GetListUserGroupChat(db *pgxpool.Conn, ctx context.Context, g
 			m.name, m.email, m.member_code as code, m.img, 'customer' as role
 		from chat_group_relations as cgr
 		left join members m on m.id = cgr.member_id
-		where cgr.chat_group_id = ` + gcID + `
+		where cgr.chat_group_id = $1
 		union
 		select 
 			m.name, m.email, m.member_code as code, m.img, 'customer' as role 
 		from chat_groups as cg
 		left join members m on m.id = cg.created_by
-		where cg.id = ` + gcID + `
+		where cg.id = $1
 		union
 		select 
 			us."name", us.email, us.user_code as code, us.img , 'tc' as role 
 		from chat_groups as cg
 		left join users us on us.id = cg.tc_id
-		where cg.id = ` + gcID + `
+		where cg.id = $1
 		union
 		select 
 			m.name, cmt.email, m.member_code as code, m.img,  'customer' as role 
 		from  chat_member_temporaries as cmt 
 		left join members m on m.email = cmt.email
-		where cmt.chat_group_id = ` + gcID + `
+		where cmt.chat_group_id = $1
  `
-	rows, err := db.Query(ctx, sql)
+	rows, err := db.Query(ctx, sql, gcID)
 	if err != nil {
 		return list, err
 	}
@@ -698,8 +674,7 @@ func (c *Contract) GetGroupChatByCode(db *pgxpool.Conn, ctx context.Context, cod
 // GetChatList ...
 func (c *Contract) GetChatList(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]ChatGroupEnt, error) {
 	list := []ChatGroupEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 	var lastMessage, tcCode, tcName, memberName, memberCode, memberEmail sql.NullString
 	var statusSession sql.NullBool
 	var messagesDate sql.NullTime
@@ -859,53 +834,28 @@ func (c *Contract) GetChatList(db *pgxpool.Conn, ctx context.Context, param map[
 	
 	`
 
-	var queryString string = query
-
 	if len(param["member_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "mc.member_code = '"+param["member_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("mc.member_code = ?", param["member_code"])
 	}
 
 	if len(param["tc_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "u.user_code = '"+param["tc_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("u.user_code = ?", param["tc_code"])
 	}
 
-	if len(param["chat_status"].(string)) > 0 {
-
-		if param["chat_status"].(string) == "new_chat" {
-			var orWhere []string
-			orWhere = append(orWhere, "gcml.tc_assigned = 'false'")
-			where = append(where, strings.Join(orWhere, " AND "))
-		} else if param["chat_status"].(string) == "active_chat" {
-			var orWhere []string
-			orWhere = append(orWhere, "gcml.tc_assigned = 'true'")
-			where = append(where, strings.Join(orWhere, " AND "))
-		}
-
+	if param["chat_status"].(string) == "new_chat" {
+		w.Where("gcml.tc_assigned = 'false'")
+	} else if param["chat_status"].(string) == "active_chat" {
+		w.Where("gcml.tc_assigned = 'true'")
 	}
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(cg.name) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(gcml.last_message) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(mc.name) like lower('%"+param["keyword"].(string)+"%')")
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["keyword"].(string), "cg.name", "gcml.last_message", "mc.name")
 	}
 
-	if len(where) > 0 {
-		queryString += "WHERE " + strings.Join(where, " AND ")
-	}
+	var queryString string = query + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + queryString + ` ) AS data`
-
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, queryString, w.Args...)
 		if err != nil {
 			return list, err
 		}
@@ -920,14 +870,9 @@ func (c *Contract) GetChatList(db *pgxpool.Conn, ctx context.Context, param map[
 		param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 	}
 
-	if param["limit"].(int) == -1 {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
-	rows, err := db.Query(ctx, queryString, paramQuery...)
+	queryString += " ORDER BY " + chatListOrder(param)
+	queryString += w.Page(param["offset"].(int), param["limit"].(int))
+	rows, err := db.Query(ctx, queryString, w.Args...)
 	if err != nil {
 		return list, err
 	}
@@ -965,3 +910,8 @@ func (c *Contract) GetChatList(db *pgxpool.Conn, ctx context.Context, param map[
 
 	return list, nil
 }
+
+// chatListOrder whitelisted order of the chat list
+func chatListOrder(param map[string]interface{}) string {
+	return psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"cg.id", "cg.created_date"}, "cg.created_date")
+}
@@ -2,16 +2,37 @@ package model
 
 import (
 	"context"
+	"panorama/lib/psql"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 )
 
+// ChatGroupRelationEnt member of the chat group
+type ChatGroupRelationEnt struct {
+	ID          int32
+	MemberID    int32
+	ChatGroupID int32
+	CreatedDate time.Time
+}
+
 // AddChatGroupRelationBatch add new member to chat group
-func (c *Contract) AddChatGroupRelationBatch(ctx context.Context, tx pgx.Tx, arrStr string) error {
-	var lastInsID int32
+func (c *Contract) AddChatGroupRelationBatch(ctx context.Context, tx pgx.Tx, rels []ChatGroupRelationEnt) error {
+	now := time.Now().In(time.UTC)
+
+	var rows [][]interface{}
+	for _, r := range rels {
+		rows = append(rows, []interface{}{r.MemberID, r.ChatGroupID, now})
+	}
+
+	_, err := psql.InsertBatch(ctx, tx, "chat_group_relations", []string{"member_id", "chat_group_id", "created_date"}, rows)
+
+	return err
+}
 
-	sql := `INSERT INTO chat_group_relations(member_id, chat_group_id, created_date) VALUES ` + arrStr + ` RETURNING id`
-	err := tx.QueryRow(ctx, sql).Scan(&lastInsID)
+// DeleteChatGroupRelation remove member from chat group
+func (c *Contract) DeleteChatGroupRelation(tx pgx.Tx, ctx context.Context, memberID, chatGroupID int32) error {
+	_, err := tx.Exec(ctx, `delete from chat_group_relations where member_id = $1 and chat_group_id = $2`, memberID, chatGroupID)
 
 	return err
 }
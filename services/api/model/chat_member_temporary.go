@@ -8,6 +8,13 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const chatMemberTempWithoutInvite = `
+	AND NOT EXISTS (
+		SELECT 1 FROM chat_groups cg
+		JOIN member_itin_invites mii ON mii.member_itin_id = cg.member_itin_id
+		WHERE cg.id = cmt.chat_group_id AND mii.email = cmt.email
+	)`
+
 type ChatMemberTemporary struct {
 	ID          int32
 	Email       string
@@ -15,11 +22,11 @@ type ChatMemberTemporary struct {
 	CreatedDate time.Time
 }
 
-// GetListChatMemberTempByEmail Get member temporary list by email
+// GetListChatMemberTempByEmail Get member temporary list by email, skip the chat of itinerary that wait for the invite accepted
 func (c *Contract) GetListChatMemberTempByEmail(db *pgxpool.Conn, ctx context.Context, email string) ([]ChatMemberTemporary, error) {
 	var mList []ChatMemberTemporary
 
-	query := `SELECT id, email, chat_group_id, created_date FROM chat_member_temporaries WHERE email = $1`
+	query := `SELECT id, email, chat_group_id, created_date FROM chat_member_temporaries cmt WHERE email = $1` + chatMemberTempWithoutInvite
 
 	rows, err := db.Query(ctx, query, email)
 	if err != nil {
@@ -42,7 +49,7 @@ func (c *Contract) GetListChatMemberTempByEmail(db *pgxpool.Conn, ctx context.Co
 // DeleteChatMemberTempByEmail delete chat member temporary by email
 func (c *Contract) DeleteChatMemberTempByEmail(ctx context.Context, tx pgx.Tx, email string) error {
 
-	sql := `delete from chat_member_temporaries as cmt where email = $1`
+	sql := `delete from chat_member_temporaries as cmt where email = $1` + chatMemberTempWithoutInvite
 
 	pgx, err := tx.Query(ctx, sql, email)
 
@@ -50,3 +57,10 @@ func (c *Contract) DeleteChatMemberTempByEmail(ctx context.Context, tx pgx.Tx, e
 
 	return err
 }
+
+// DeleteChatMemberTempByEmailAndChatID delete chat member temporary of the email in chat group
+func (c *Contract) DeleteChatMemberTempByEmailAndChatID(tx pgx.Tx, ctx context.Context, email string, chatGroupID int32) error {
+	_, err := tx.Exec(ctx, `delete from chat_member_temporaries where email = $1 and chat_group_id = $2`, email, chatGroupID)
+
+	return err
+}
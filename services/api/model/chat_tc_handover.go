@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ChatTcHandoverEnt ...
+type ChatTcHandoverEnt struct {
+	ID           int32
+	ChatGroupID  int32
+	FromTc       UserEnt
+	ToTc         UserEnt
+	Notes        string
+	ChangedBy    string
+	CreatedDate  time.Time
+	AssignedDate sql.NullTime
+}
+
+// AddChatTcHandover save handover notes when the tc of chat group is changed
+func (c *Contract) AddChatTcHandover(tx pgx.Tx, ctx context.Context, ho ChatTcHandoverEnt) (ChatTcHandoverEnt, error) {
+	var lastInsID int32
+	var fromTcID, toTcID sql.NullInt32
+	var assignedDate sql.NullTime
+	timeStamp := time.Now().In(time.UTC)
+
+	if ho.FromTc.ID > 0 {
+		fromTcID = sql.NullInt32{Int32: ho.FromTc.ID, Valid: true}
+	}
+	if ho.ToTc.ID > 0 {
+		toTcID = sql.NullInt32{Int32: ho.ToTc.ID, Valid: true}
+		assignedDate = sql.NullTime{Time: timeStamp, Valid: true}
+	}
+
+	sql := `INSERT INTO chat_tc_handovers(chat_group_id, from_tc_id, to_tc_id, notes, changed_by, created_date, assigned_date) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, ho.ChatGroupID, fromTcID, toTcID, ho.Notes, ho.ChangedBy, timeStamp, assignedDate).Scan(&lastInsID)
+
+	ho.ID = lastInsID
+	ho.CreatedDate = timeStamp
+	ho.AssignedDate = assignedDate
+
+	return ho, err
+}
+
+// AssignPendingChatTcHandover set the next tc into handover that still waiting for a new tc
+func (c *Contract) AssignPendingChatTcHandover(tx pgx.Tx, ctx context.Context, chatGroupID, toTcID int32) error {
+	sql := `UPDATE chat_tc_handovers SET to_tc_id = $1, assigned_date = $2 WHERE chat_group_id = $3 AND to_tc_id IS NULL`
+
+	_, err := tx.Exec(ctx, sql, toTcID, time.Now().In(time.UTC), chatGroupID)
+
+	return err
+}
+
+// GetListChatTcHandoverByChatCode ...
+func (c *Contract) GetListChatTcHandoverByChatCode(db *pgxpool.Conn, ctx context.Context, code string) ([]ChatTcHandoverEnt, error) {
+	list := []ChatTcHandoverEnt{}
+	var fromCode, fromName, toCode, toName, notes sql.NullString
+
+	sql := `
+		select
+			ho.id, ho.chat_group_id,
+			fu.user_code, fu.name,
+			tu.user_code, tu.name,
+			ho.notes, ho.changed_by, ho.created_date, ho.assigned_date
+		from chat_tc_handovers ho
+		join chat_groups cg on cg.id = ho.chat_group_id
+		left join users fu on fu.id = ho.from_tc_id
+		left join users tu on tu.id = ho.to_tc_id
+		where cg.chat_group_code = $1
+		order by ho.created_date desc`
+
+	rows, err := db.Query(ctx, sql, code)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var ho ChatTcHandoverEnt
+		err = rows.Scan(&ho.ID, &ho.ChatGroupID, &fromCode, &fromName, &toCode, &toName, &notes, &ho.ChangedBy, &ho.CreatedDate, &ho.AssignedDate)
+		if err != nil {
+			return list, err
+		}
+
+		ho.FromTc.UserCode = fromCode.String
+		ho.FromTc.Name = fromName.String
+		ho.ToTc.UserCode = toCode.String
+		ho.ToTc.Name = toName.String
+		ho.Notes = notes.String
+
+		list = append(list, ho)
+	}
+
+	return list, err
+}
+
+// IsTcServedChatGroup check the tc is the current tc or ever assigned to the chat group
+func (c *Contract) IsTcServedChatGroup(db *pgxpool.Conn, ctx context.Context, chatGroupID, tcID int32) (bool, error) {
+	var total int32
+
+	sql := `
+		select count(*) from (
+			select id from chat_groups where id = $1 and tc_id = $2
+			union all
+			select id from chat_tc_handovers where chat_group_id = $1 and (from_tc_id = $2 or to_tc_id = $2)
+		) a`
+
+	err := db.QueryRow(ctx, sql, chatGroupID, tcID).Scan(&total)
+
+	return total > 0, err
+}
+
+// IsTcInOpenSession tc is still the current tc of chat group and the session is not ended yet
+func (c *Contract) IsTcInOpenSession(db *pgxpool.Conn, ctx context.Context, chatGroupID, tcID int32) (bool, error) {
+	var total int32
+
+	err := db.QueryRow(ctx, `select count(id) from chat_groups where id = $1 and tc_id = $2 and status is not false`, chatGroupID, tcID).Scan(&total)
+
+	return total > 0, err
+}
+
+// GetChatGroupCurrentTc get chat group id and the current tc id of chat group
+func (c *Contract) GetChatGroupCurrentTc(db *pgxpool.Conn, ctx context.Context, code string) (int32, int32, error) {
+	var chatGroupID, tcID int32
+
+	sql := `select id, coalesce(tc_id, 0) from chat_groups where chat_group_code = $1`
+
+	err := db.QueryRow(ctx, sql, code).Scan(&chatGroupID, &tcID)
+
+	return chatGroupID, tcID, err
+}
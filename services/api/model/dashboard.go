@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -14,6 +15,7 @@ type DashboardEnt struct {
 	ActiveChats    map[string]interface{}
 	UsersOnline    map[string]interface{}
 	TcOnline       map[string]interface{}
+	Vouchers       map[string]interface{}
 	DailyVisitsEnt []DailyVisitsEnt
 }
 
@@ -104,6 +106,27 @@ func (c *Contract) GetDashboardAdmin(db *pgxpool.Conn, ctx context.Context, para
 			from log_visit_app lva
 			where lva.role = 'tc'
 		) tc_online
+	),
+	(
+		select row_to_json(vouchers) vouchers
+		from (
+			select
+				count(vr.id) as total,
+				coalesce(sum(vr.discount_amount), 0) as total_discount,
+				(
+					select
+						count(vr.id)
+					from voucher_redemptions vr
+					where vr.created_date between $1 and $2
+				) total_date,
+				(
+					select
+						coalesce(sum(vr.discount_amount), 0)
+					from voucher_redemptions vr
+					where vr.created_date between $1 and $2
+				) total_discount_date
+			from voucher_redemptions vr
+		) vouchers
 	)`
 
 	startDate := fmt.Sprintf("%v", time.Now().Format("2006-01-02")) + " 00:00:00"
@@ -117,7 +140,7 @@ func (c *Contract) GetDashboardAdmin(db *pgxpool.Conn, ctx context.Context, para
 	paramQuery = append(paramQuery, startDate)
 	paramQuery = append(paramQuery, endDate)
 
-	err := db.QueryRow(ctx, sql, paramQuery...).Scan(&d.BookedTrips, &d.ActiveTrips, &d.ActiveChats, &d.UsersOnline, &d.TcOnline)
+	err := db.QueryRow(ctx, sql, paramQuery...).Scan(&d.BookedTrips, &d.ActiveTrips, &d.ActiveChats, &d.UsersOnline, &d.TcOnline, &d.Vouchers)
 
 	return d, err
 }
@@ -127,9 +150,15 @@ func (c *Contract) GetDashboardTc(db *pgxpool.Conn, ctx context.Context, param m
 	var d DashboardEnt
 	var paramQuery []interface{}
 
+	var tcID int64
 	whereTc := ``
 	if len(param["created_by"].(string)) > 0 {
-		whereTc = `and cg.tc_id = ` + param["created_by"].(string)
+		id, err := strconv.ParseInt(param["created_by"].(string), 10, 32)
+		if err != nil {
+			return d, fmt.Errorf("invalid tc id %q", param["created_by"])
+		}
+		tcID = id
+		whereTc = `and cg.tc_id = $3`
 	}
 
 	sql := `select (
@@ -195,6 +224,9 @@ func (c *Contract) GetDashboardTc(db *pgxpool.Conn, ctx context.Context, param m
 
 	paramQuery = append(paramQuery, startDate)
 	paramQuery = append(paramQuery, endDate)
+	if len(whereTc) > 0 {
+		paramQuery = append(paramQuery, tcID)
+	}
 
 	err := db.QueryRow(ctx, sql, paramQuery...).Scan(&d.ActiveChats, &d.UsersOnline, &d.TcOnline)
 
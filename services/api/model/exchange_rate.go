@@ -0,0 +1,308 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"panorama/lib/psql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	// BASE_CURRENCY currency of order total and midtrans payment
+	BASE_CURRENCY = "IDR"
+
+	EXCHANGE_RATE_SOURCE_MANUAL = "manual"
+	EXCHANGE_RATE_SOURCE_IMPORT = "import"
+)
+
+// CurrencyEnt supported currency, amount is saved in the smallest unit (minor unit) of the currency
+type CurrencyEnt struct {
+	Code      string
+	Name      string
+	Symbol    string
+	MinorUnit int32
+	IsActive  bool
+}
+
+// Money amount in the smallest unit of the currency
+type Money struct {
+	Amount    int64
+	Currency  string
+	MinorUnit int32
+}
+
+// IDR money in rupiah
+func IDR(amount int64) Money {
+	return Money{Amount: amount, Currency: BASE_CURRENCY}
+}
+
+// String formatted amount with the currency, e.g. Rp 1.500.000 or USD 12.50
+func (m Money) String() string {
+	if len(m.Currency) == 0 || m.Currency == BASE_CURRENCY {
+		return FormatRupiah(m.Amount)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if m.MinorUnit <= 0 {
+		return fmt.Sprintf("%s %s%d", m.Currency, sign, amount)
+	}
+
+	unit := int64(math.Pow10(int(m.MinorUnit)))
+	return fmt.Sprintf("%s %s%d.%0*d", m.Currency, sign, amount/unit, int(m.MinorUnit), amount%unit)
+}
+
+// ParseMoney parse decimal text (e.g. 12.50) into the smallest unit of the currency,
+// rupiah text keeps the digits only so the legacy text like Rp 150.000 is still accepted
+func ParseMoney(s string, cur CurrencyEnt) (Money, error) {
+	m := Money{Currency: cur.Code, MinorUnit: cur.MinorUnit}
+
+	if cur.MinorUnit <= 0 {
+		amount, err := strconv.ParseInt(nonDigit.ReplaceAllString(s, ""), 10, 64)
+		if err != nil {
+			return m, fmt.Errorf("Invalid %s amount %s.", cur.Code, s)
+		}
+		m.Amount = amount
+
+		return m, nil
+	}
+
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return m, fmt.Errorf("Invalid %s amount %s.", cur.Code, s)
+	}
+	m.Amount = int64(math.Round(value * math.Pow10(int(cur.MinorUnit))))
+
+	return m, nil
+}
+
+// ExchangeRateEnt rupiah value of one major unit of the currency, effective from the date
+type ExchangeRateEnt struct {
+	ID            int32
+	CurrencyCode  string
+	MinorUnit     int32
+	Rate          float64
+	EffectiveDate time.Time
+	Source        string
+	CreatedBy     sql.NullInt32
+	CreatedDate   time.Time
+	UpdatedDate   sql.NullTime
+}
+
+// ToIDR convert the amount in the smallest unit of the currency into rupiah (half up)
+func (e ExchangeRateEnt) ToIDR(amount int64) int64 {
+	if e.CurrencyCode == BASE_CURRENCY {
+		return amount
+	}
+
+	return int64(math.Round(float64(amount) * e.Rate / math.Pow10(int(e.MinorUnit))))
+}
+
+// GetCurrency active currency by code
+func (c *Contract) GetCurrency(db *pgxpool.Conn, ctx context.Context, code string) (CurrencyEnt, error) {
+	var cur CurrencyEnt
+
+	err := db.QueryRow(ctx, `select currency_code, name, symbol, minor_unit, is_active from currencies where currency_code = $1 and is_active = true`, strings.ToUpper(code)).
+		Scan(&cur.Code, &cur.Name, &cur.Symbol, &cur.MinorUnit, &cur.IsActive)
+
+	return cur, err
+}
+
+// GetListCurrency active currencies
+func (c *Contract) GetListCurrency(db *pgxpool.Conn, ctx context.Context) ([]CurrencyEnt, error) {
+	list := []CurrencyEnt{}
+
+	rows, err := db.Query(ctx, `select currency_code, name, symbol, minor_unit, is_active from currencies where is_active = true order by currency_code`)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var cur CurrencyEnt
+		if err = rows.Scan(&cur.Code, &cur.Name, &cur.Symbol, &cur.MinorUnit, &cur.IsActive); err != nil {
+			return list, err
+		}
+		list = append(list, cur)
+	}
+
+	return list, rows.Err()
+}
+
+// GetExchangeRate latest rate of the currency that is effective at the time
+func (c *Contract) GetExchangeRate(db *pgxpool.Conn, ctx context.Context, code string, at time.Time) (ExchangeRateEnt, error) {
+	var e ExchangeRateEnt
+
+	code = strings.ToUpper(code)
+	if code == BASE_CURRENCY {
+		return ExchangeRateEnt{CurrencyCode: BASE_CURRENCY, Rate: 1, EffectiveDate: at}, nil
+	}
+
+	sql := `select er.id, er.currency_code, cu.minor_unit, er.rate, er.effective_date, er.source, er.created_by, er.created_date, er.updated_date
+		from exchange_rates er
+		join currencies cu on cu.currency_code = er.currency_code
+		where er.currency_code = $1 and er.effective_date <= $2::date
+		order by er.effective_date desc limit 1`
+
+	err := db.QueryRow(ctx, sql, code, at.Format("2006-01-02")).Scan(&e.ID, &e.CurrencyCode, &e.MinorUnit, &e.Rate, &e.EffectiveDate, &e.Source, &e.CreatedBy, &e.CreatedDate, &e.UpdatedDate)
+
+	return e, err
+}
+
+// AddExchangeRate save rate of the currency, the rate of the same effective date is replaced
+func (c *Contract) AddExchangeRate(tx pgx.Tx, ctx context.Context, e ExchangeRateEnt) (ExchangeRateEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO exchange_rates(currency_code, rate, effective_date, source, created_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (currency_code, effective_date) DO UPDATE SET rate = excluded.rate, source = excluded.source, updated_date = $6
+		RETURNING id, created_date`
+
+	err := tx.QueryRow(ctx, sql, strings.ToUpper(e.CurrencyCode), e.Rate, e.EffectiveDate.Format("2006-01-02"), e.Source, e.CreatedBy, timeStamp).Scan(&e.ID, &e.CreatedDate)
+
+	return e, err
+}
+
+// DeleteExchangeRate remove rate, the rate that is locked by order is kept on the order
+func (c *Contract) DeleteExchangeRate(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `delete from exchange_rates where id = $1`, id)
+
+	return err
+}
+
+// GetExchangeRateByID ...
+func (c *Contract) GetExchangeRateByID(db *pgxpool.Conn, ctx context.Context, id int32) (ExchangeRateEnt, error) {
+	var e ExchangeRateEnt
+
+	sql := `select er.id, er.currency_code, cu.minor_unit, er.rate, er.effective_date, er.source, er.created_by, er.created_date, er.updated_date
+		from exchange_rates er
+		join currencies cu on cu.currency_code = er.currency_code
+		where er.id = $1`
+
+	err := db.QueryRow(ctx, sql, id).Scan(&e.ID, &e.CurrencyCode, &e.MinorUnit, &e.Rate, &e.EffectiveDate, &e.Source, &e.CreatedBy, &e.CreatedDate, &e.UpdatedDate)
+
+	return e, err
+}
+
+// GetListExchangeRate list rate filtered by currency and effective date
+func (c *Contract) GetListExchangeRate(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]ExchangeRateEnt, error) {
+	list := []ExchangeRateEnt{}
+	var where []string
+	var paramQuery []interface{}
+
+	if len(param["currency"].(string)) > 0 {
+		paramQuery = append(paramQuery, strings.ToUpper(param["currency"].(string)))
+		where = append(where, fmt.Sprintf("er.currency_code = $%d", len(paramQuery)))
+	}
+
+	if len(param["date"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["date"])
+		where = append(where, fmt.Sprintf("er.effective_date <= $%d::date", len(paramQuery)))
+	}
+
+	q := `select er.id, er.currency_code, cu.minor_unit, er.rate, er.effective_date, er.source, er.created_by, er.created_date, er.updated_date
+		from exchange_rates er
+		join currencies cu on cu.currency_code = er.currency_code`
+
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	{
+		var count int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) FROM (`+q+`) AS data`, paramQuery...).Scan(&count)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"er.id", "er.currency_code", "er.effective_date"}, "er.effective_date")
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var e ExchangeRateEnt
+		err = rows.Scan(&e.ID, &e.CurrencyCode, &e.MinorUnit, &e.Rate, &e.EffectiveDate, &e.Source, &e.CreatedBy, &e.CreatedDate, &e.UpdatedDate)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+// GetListOrderExchangeRate rates that are locked by the order
+func (c *Contract) GetListOrderExchangeRate(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]ExchangeRateEnt, error) {
+	list := []ExchangeRateEnt{}
+
+	sql := `select oer.currency_code, cu.minor_unit, oer.rate, oer.effective_date, oer.created_date
+		from order_exchange_rates oer
+		join currencies cu on cu.currency_code = oer.currency_code
+		where oer.order_id = $1 order by oer.currency_code`
+
+	rows, err := db.Query(ctx, sql, orderID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var e ExchangeRateEnt
+		if err = rows.Scan(&e.CurrencyCode, &e.MinorUnit, &e.Rate, &e.EffectiveDate, &e.CreatedDate); err != nil {
+			return list, err
+		}
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+// AddOrderExchangeRates lock the rates on the order, the rate that is already locked is kept
+func (c *Contract) AddOrderExchangeRates(tx pgx.Tx, ctx context.Context, orderID int32, rates []ExchangeRateEnt) error {
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO order_exchange_rates(order_id, currency_code, rate, effective_date, created_date)
+		VALUES($1, $2, $3, $4, $5) ON CONFLICT (order_id, currency_code) DO NOTHING`
+
+	for _, e := range rates {
+		if e.CurrencyCode == BASE_CURRENCY {
+			continue
+		}
+
+		_, err := tx.Exec(ctx, sql, orderID, e.CurrencyCode, e.Rate, e.EffectiveDate.Format("2006-01-02"), timeStamp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
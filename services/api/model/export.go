@@ -0,0 +1,32 @@
+package model
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ActExportReady template of the export download link email
+const ActExportReady = "export_ready"
+
+// DataEmailExport ...
+type DataEmailExport struct {
+	Name         string
+	FileName     string
+	Rows         int
+	URL          string
+	ExpiredHours int
+}
+
+// countListQuery total rows of the list query
+func (c *Contract) countListQuery(db *pgxpool.Conn, ctx context.Context, q string, args ...interface{}) (int, error) {
+	var count int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) FROM ( `+q+` ) AS data`, args...).Scan(&count)
+
+	return count, err
+}
+
+// SendExportMail send the download link of the background export
+func (c *Contract) SendExportMail(ctx context.Context, to string, data DataEmailExport) error {
+	return c.sendDataMailAttachments(ctx, ActExportReady, "Your export "+data.FileName+" is ready", to, data, nil)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"math"
+	"panorama/lib/psql"
 	"strings"
 	"time"
 
@@ -14,23 +15,25 @@ import (
 )
 
 type MemberItinEnt struct {
-	ID            int32
-	ItinCode      string
-	Title         string
-	CreatedBy     int32
-	EstPrice      sql.NullInt64
-	StartDate     time.Time
-	EndDate       time.Time
-	Details       []map[string]interface{}
-	CreatedDate   time.Time
-	UpdatedDate   sql.NullTime
-	DeletedDate   sql.NullTime
-	Destination   string
-	Img           sql.NullString
-	DayPeriod     int32
-	MemberEnt     MemberEnt
-	GroupMembers  []map[string]interface{}
-	ChatGroupCode string
+	ID               int32
+	ItinCode         string
+	Title            string
+	CreatedBy        int32
+	EstPrice         sql.NullInt64
+	EstPriceCurrency string
+	EstPriceIDR      sql.NullInt64 // est price in rupiah with the latest rate
+	StartDate        time.Time
+	EndDate          time.Time
+	Details          []map[string]interface{}
+	CreatedDate      time.Time
+	UpdatedDate      sql.NullTime
+	DeletedDate      sql.NullTime
+	Destination      string
+	Img              sql.NullString
+	DayPeriod        int32
+	MemberEnt        MemberEnt
+	GroupMembers     []map[string]interface{}
+	ChatGroupCode    string
 }
 
 // GetMemberItinID get member itinerary by itenerary code
@@ -56,7 +59,7 @@ func (c *Contract) GetMemberItinByCode(db *pgxpool.Conn, ctx context.Context, co
 	var dest sql.NullString
 
 	sql := `select * from member_itins where itin_code = $1 limit 1`
-	err := db.QueryRow(ctx, sql, code).Scan(&m.ID, &m.ItinCode, &m.Title, &m.CreatedBy, &m.EstPrice, &m.StartDate, &m.EndDate, &m.Details, &m.CreatedDate, &m.UpdatedDate, &m.DeletedDate, &dest, &m.Img)
+	err := db.QueryRow(ctx, sql, code).Scan(&m.ID, &m.ItinCode, &m.Title, &m.CreatedBy, &m.EstPrice, &m.StartDate, &m.EndDate, &m.Details, &m.CreatedDate, &m.UpdatedDate, &m.DeletedDate, &dest, &m.Img, &m.EstPriceCurrency)
 	if err != nil {
 		return m, err
 	}
@@ -80,9 +83,13 @@ func (c *Contract) AddMemberItin(tx pgx.Tx, ctx context.Context, m MemberItinEnt
 
 	timeStamp := time.Now().In(time.UTC)
 
-	sql := `insert into member_itins(itin_code, title, destination, created_by, est_price, start_date, end_date, details, created_date, img) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	if len(m.EstPriceCurrency) == 0 {
+		m.EstPriceCurrency = BASE_CURRENCY
+	}
+
+	sql := `insert into member_itins(itin_code, title, destination, created_by, est_price, start_date, end_date, details, created_date, img, est_price_currency) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
 
-	paramQuery = append(paramQuery, m.ItinCode, m.Title, m.Destination, m.CreatedBy, m.EstPrice, m.StartDate, m.EndDate, m.Details, timeStamp, m.Img)
+	paramQuery = append(paramQuery, m.ItinCode, m.Title, m.Destination, m.CreatedBy, m.EstPrice, m.StartDate, m.EndDate, m.Details, timeStamp, m.Img, m.EstPriceCurrency)
 
 	err := tx.QueryRow(ctx, sql, paramQuery...).Scan(&lastInsID)
 
@@ -102,8 +109,7 @@ func (c *Contract) DelMemberItin(tx pgx.Tx, ctx context.Context, code string) er
 // GetListItinmember ...
 func (c *Contract) GetListItinMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]MemberItinEnt, error) {
 	list := []MemberItinEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 	var destination, chatGroupCode, memberName, memberCode sql.NullString
 	var startDate, endDate sql.NullTime
 
@@ -112,6 +118,8 @@ func (c *Contract) GetListItinMember(db *pgxpool.Conn, ctx context.Context, para
 		mi.title, 
 		mi.destination, 
 		mi.est_price, 
+		mi.est_price_currency,
+		to_idr(mi.est_price, mi.est_price_currency, now()) est_price_idr,
 		mi.start_date, 
 		mi.end_date, 
 		mi.img,
@@ -226,42 +234,22 @@ func (c *Contract) GetListItinMember(db *pgxpool.Conn, ctx context.Context, para
 		group by groups_itin.itin_code
 	) mg on mg.itin_code = ig.itin_code `
 
-	var queryString string = query
-
 	if len(param["member_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " ig.member_code = '"+param["member_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("ig.member_code = ?", param["member_code"])
 	}
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " lower(ig.name) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, " lower(mi.title) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, " lower(mi.itin_code) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, " lower(mi.destination) like lower('%"+param["keyword"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["keyword"].(string), "ig.name", "mi.title", "mi.itin_code", "mi.destination")
 	}
 
 	if len(param["created_by"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " m.member_code = '"+param["created_by"].(string)+"'")
-		orWhere = append(orWhere, " ig.member_code = m.member_code")
-
-		where = append(where, "("+strings.Join(orWhere, " AND ")+")")
+		w.Where("(m.member_code = ? AND ig.member_code = m.member_code)", param["created_by"])
 	}
 
-	if len(where) > 0 {
-		queryString += " WHERE " + strings.Join(where, " AND ")
-	}
+	var queryString string = query + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + queryString + ` ) AS data`
-
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, queryString, w.Args...)
 		if err != nil {
 			return list, err
 		}
@@ -275,15 +263,10 @@ func (c *Contract) GetListItinMember(db *pgxpool.Conn, ctx context.Context, para
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	if param["limit"].(int) == -1 {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+	queryString += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"mi.id", "mi.created_date"}, "mi.created_date")
+	queryString += w.Page(param["offset"].(int), param["limit"].(int))
 
-	rows, err := db.Query(ctx, queryString, paramQuery...)
+	rows, err := db.Query(ctx, queryString, w.Args...)
 	if err != nil {
 		return list, err
 	}
@@ -291,7 +274,7 @@ func (c *Contract) GetListItinMember(db *pgxpool.Conn, ctx context.Context, para
 
 	for rows.Next() {
 		var m MemberItinEnt
-		err = rows.Scan(&m.ItinCode, &m.Title, &destination, &m.EstPrice, &startDate, &endDate, &m.Img, &m.Details, &m.CreatedDate, &memberName, &memberCode, &chatGroupCode, &m.GroupMembers)
+		err = rows.Scan(&m.ItinCode, &m.Title, &destination, &m.EstPrice, &m.EstPriceCurrency, &m.EstPriceIDR, &startDate, &endDate, &m.Img, &m.Details, &m.CreatedDate, &memberName, &memberCode, &chatGroupCode, &m.GroupMembers)
 		if err != nil {
 			return list, err
 		}
@@ -323,9 +306,13 @@ func (c *Contract) UpdateMemberItin(tx pgx.Tx, ctx context.Context, m MemberItin
 	var ID int32
 	timeStamp := time.Now().In(time.UTC)
 
-	sql := `UPDATE member_itins SET title=$1, destination=$2, est_price=$3, start_date=$4, end_date=$5, details=$6, updated_date=$7, img=$8 WHERE itin_code=$9 RETURNING id`
+	if len(m.EstPriceCurrency) == 0 {
+		m.EstPriceCurrency = BASE_CURRENCY
+	}
+
+	sql := `UPDATE member_itins SET title=$1, destination=$2, est_price=$3, start_date=$4, end_date=$5, details=$6, updated_date=$7, img=$8, est_price_currency=$9 WHERE itin_code=$10 RETURNING id`
 
-	err := tx.QueryRow(ctx, sql, m.Title, m.Destination, m.EstPrice, m.StartDate, m.EndDate, m.Details, timeStamp, m.Img, code).Scan(&ID)
+	err := tx.QueryRow(ctx, sql, m.Title, m.Destination, m.EstPrice, m.StartDate, m.EndDate, m.Details, timeStamp, m.Img, m.EstPriceCurrency, code).Scan(&ID)
 
 	m.ID = ID
 
@@ -344,6 +331,8 @@ func (c *Contract) GetMemberItinWithGroupsByCode(db *pgxpool.Conn, ctx context.C
 		mi.title, 
 		mi.destination, 
 		mi.est_price, 
+		mi.est_price_currency,
+		to_idr(mi.est_price, mi.est_price_currency, now()) est_price_idr,
 		mi.start_date, 
 		mi.end_date, 
 		mi.img,
@@ -370,6 +359,7 @@ func (c *Contract) GetMemberItinWithGroupsByCode(db *pgxpool.Conn, ctx context.C
 				m.username member_username, 
 				m.email member_email,
 				m.img member_img,
+				'owner' member_role,
 				case 
 					when m.member_code is not null then true
 					else true 
@@ -388,6 +378,7 @@ func (c *Contract) GetMemberItinWithGroupsByCode(db *pgxpool.Conn, ctx context.C
 				m.username member_username, 
 				m.email member_email,
 				m.img member_img,
+				mir.role member_role,
 				case 
 					when m.member_code is not null then false
 					else false 
@@ -408,6 +399,7 @@ func (c *Contract) GetMemberItinWithGroupsByCode(db *pgxpool.Conn, ctx context.C
 				m.username member_username, 
 				mt.email member_email,
 				m.img member_img,
+				coalesce(mii.role, 'viewer') member_role,
 				case 
 					when m.member_code is not null then false
 					else false 
@@ -419,12 +411,13 @@ func (c *Contract) GetMemberItinWithGroupsByCode(db *pgxpool.Conn, ctx context.C
 			from member_temporaries mt
 			left join member_itins mi on mi.id = mt.member_itin_id 
 			left join members m on m.email = mt.email 
+			left join member_itin_invites mii on mii.member_itin_id = mt.member_itin_id and mii.email = mt.email
 		) groups_itin
 		group by groups_itin.itin_code
 	) mg on mg.itin_code = mi.itin_code
 	where mi.itin_code = $1 limit 1`
 
-	err := db.QueryRow(ctx, query, code).Scan(&m.ID, &m.ItinCode, &m.Title, &dest, &m.EstPrice, &startDate, &endDate, &m.Img, &m.Details, &m.CreatedDate, &m.UpdatedDate, &m.DeletedDate, &m.MemberEnt.Name, &m.MemberEnt.MemberCode, &cgCode, &m.GroupMembers)
+	err := db.QueryRow(ctx, query, code).Scan(&m.ID, &m.ItinCode, &m.Title, &dest, &m.EstPrice, &m.EstPriceCurrency, &m.EstPriceIDR, &startDate, &endDate, &m.Img, &m.Details, &m.CreatedDate, &m.UpdatedDate, &m.DeletedDate, &m.MemberEnt.Name, &m.MemberEnt.MemberCode, &cgCode, &m.GroupMembers)
 	if err != nil {
 		return m, err
 	}
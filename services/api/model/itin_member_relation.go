@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"database/sql"
+	"panorama/lib/psql"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -13,6 +14,7 @@ type MemberItinRelationEnt struct {
 	ID            int32
 	MemberItinID  int32
 	MemberID      int32
+	Role          string
 	CreatedDate   time.Time
 	DeletedDate   sql.NullTime
 	MemberItinEnt MemberItinEnt
@@ -27,9 +29,13 @@ func (c *Contract) AddMemberItinRelation(tx pgx.Tx, ctx context.Context, m Membe
 	var lastInsID int32
 	timeStamp := time.Now().In(time.UTC)
 
-	sql := `INSERT INTO member_itin_relations(member_itin_id, member_id, created_date) VALUES($1, $2, $3) RETURNING id`
+	if m.Role == "" {
+		m.Role = ITIN_ROLE_VIEWER
+	}
+
+	sql := `INSERT INTO member_itin_relations(member_itin_id, member_id, role, created_date) VALUES($1, $2, $3, $4) RETURNING id`
 
-	err := tx.QueryRow(ctx, sql, m.MemberItinID, m.MemberID, timeStamp).Scan(&lastInsID)
+	err := tx.QueryRow(ctx, sql, m.MemberItinID, m.MemberID, m.Role, timeStamp).Scan(&lastInsID)
 
 	m.ID = lastInsID
 
@@ -44,23 +50,35 @@ func (c *Contract) DeleteMemberItinRelation(tx pgx.Tx, ctx context.Context, memb
 	return err
 }
 
+// UpdateMemberItinRelationRole change role of the companion
+func (c *Contract) UpdateMemberItinRelationRole(tx pgx.Tx, ctx context.Context, id int32, role string) error {
+	sql := `UPDATE member_itin_relations SET role = $1 WHERE id = $2`
+	_, err := tx.Exec(ctx, sql, role, id)
+
+	return err
+}
+
 // GetMemberRelationByMemberID Get member itin relation by Member ID
 func (c *Contract) GetMemberItinRelationByMemberIDAndMemberItinID(db *pgxpool.Conn, ctx context.Context, memberID int32, memberItinID int32) (MemberItinRelationEnt, error) {
 	var m MemberItinRelationEnt
 
-	sqlM := `SELECT id, member_itin_id, member_id, created_date FROM member_itin_relations WHERE member_id = $1 AND member_itin_id = $2 AND deleted_date IS NULL`
+	sqlM := `SELECT id, member_itin_id, member_id, role, created_date FROM member_itin_relations WHERE member_id = $1 AND member_itin_id = $2 AND deleted_date IS NULL`
 
-	err := db.QueryRow(ctx, sqlM, memberID, memberItinID).Scan(&m.ID, &m.MemberItinID, &m.MemberID, &m.CreatedDate)
+	err := db.QueryRow(ctx, sqlM, memberID, memberItinID).Scan(&m.ID, &m.MemberItinID, &m.MemberID, &m.Role, &m.CreatedDate)
 
 	return m, err
 }
 
 // AddMemberItinRelationBatch add new member temporary batch
-func (c *Contract) AddMemberItinRelationBatch(ctx context.Context, tx pgx.Tx, arrTemp string) error {
-	var lastInsID int32
+func (c *Contract) AddMemberItinRelationBatch(ctx context.Context, tx pgx.Tx, rels []MemberItinRelationEnt) error {
+	now := time.Now().In(time.UTC)
+
+	var rows [][]interface{}
+	for _, r := range rels {
+		rows = append(rows, []interface{}{r.MemberItinID, r.MemberID, now})
+	}
 
-	sql := `INSERT INTO member_itin_relations(member_itin_id, member_id, created_date) VALUES ` + arrTemp + ` RETURNING id`
-	err := tx.QueryRow(ctx, sql).Scan(&lastInsID)
+	_, err := psql.InsertBatch(ctx, tx, "member_itin_relations", []string{"member_itin_id", "member_id", "created_date"}, rows)
 
 	return err
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"math"
+	"panorama/lib/psql"
 	"strings"
 	"time"
 
@@ -104,54 +105,33 @@ func (c *Contract) UpdateSugItin(tx pgx.Tx, ctx context.Context, sug ItinSugEnt,
 func (c *Contract) GetListItinSug(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]ItinSugEnt, error) {
 
 	list := []ItinSugEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 	var destination sql.NullString
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(name) like lower('%"+param["keyword"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["keyword"].(string), "name")
 	}
 
 	if len(param["nearby"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(destination) like lower('%"+param["nearby"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["nearby"].(string), "destination")
 	}
 
-	sql := `select itin_code,title, content, itin_suggestions.img, details, itin_suggestions.created_date, name, view, destination
-			from itin_suggestions 
-			join users us on us.id = itin_suggestions.created_by`
-
-	var q string = sql
-
 	if len(param["created_by"].(string)) > 0 {
-
-		var orWhere []string
-		orWhere = append(orWhere, " user_code = '"+param["created_by"].(string)+"'")
-		where = append(where, "("+strings.Join(orWhere, " AND ")+")")
-
+		w.Where("user_code = ?", param["created_by"])
 	}
 
 	if len(param["user_code"].(string)) > 0 {
-
-		var orWhere []string
-		orWhere = append(orWhere, " user_code = '"+param["user_code"].(string)+"'")
-		where = append(where, "("+strings.Join(orWhere, " AND ")+")")
-
+		w.Where("user_code = ?", param["user_code"])
 	}
 
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
+	sql := `select itin_code,title, content, itin_suggestions.img, details, itin_suggestions.created_date, name, view, destination
+			from itin_suggestions 
+			join users us on us.id = itin_suggestions.created_by`
+
+	var q string = sql + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + q + ` ) AS data`
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, q, w.Args...)
 		if err != nil {
 			return list, err
 		}
@@ -165,14 +145,9 @@ func (c *Contract) GetListItinSug(db *pgxpool.Conn, ctx context.Context, param m
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	if param["limit"].(int) == -1 {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
-	rows, err := db.Query(ctx, q, paramQuery...)
+	q += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"itin_suggestions.id"}, "itin_suggestions.id")
+	q += w.Page(param["offset"].(int), param["limit"].(int))
+	rows, err := db.Query(ctx, q, w.Args...)
 	if err != nil {
 		return list, err
 	}
@@ -0,0 +1,265 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"panorama/lib/psql/psqltest"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var hostileInputs = []string{
+	`' OR 1=1 --`,
+	`'; DROP TABLE members; --`,
+	`%' OR '1'='1`,
+	`1) OR (1=1`,
+	`\'; select pg_sleep(10); --`,
+	`%`,
+}
+
+// testDB pool of a fresh schema with all migrations applied, the schema is dropped after the test
+func testDB(t *testing.T) *pgxpool.Pool {
+	return psqltest.Connect(t)
+}
+
+// listParam params of the list functions, every filter is empty
+func listParam() map[string]interface{} {
+	param := map[string]interface{}{
+		"page":      1,
+		"limit":     10,
+		"offset":    0,
+		"count":     0,
+		"sort":      "desc",
+		"order":     "",
+		"is_paging": true,
+	}
+
+	for _, key := range []string{
+		"keyword", "member_code", "tc_code", "chat_status", "user_code", "created_by", "role", "nearby",
+		"order_type", "order_code", "stuff", "type", "trx_id", "call_type", "provider", "currency", "date",
+		"is_active", "code", "event_type", "actor_code", "actor_role", "channel", "entity_type", "entity_id",
+		"action", "group", "start_date", "end_date",
+	} {
+		param[key] = ""
+	}
+
+	return param
+}
+
+func TestListFiltersBindHostileInput(t *testing.T) {
+	pool := testDB(t)
+	ctx := context.Background()
+
+	db, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Release()
+
+	_, err = db.Exec(ctx, `insert into members(member_code, name, username, email, phone, password, created_date)
+		values('m-test-victim', 'Victim', 'victim', 'victim@example.com', '0811000000', 'x', now())`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(ctx, `insert into users(user_code, name, email, phone, password, role, is_active, created_date)
+		values('u-victim', 'Victim TC', 'tc@example.com', '0812000000', 'x', 'tc', true, now())`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Contract{}
+
+	// the filter would return the seeded member when the keyword is spliced as sql
+	if list, err := c.GetListMember(db, ctx, listParam()); err != nil || len(list) != 1 {
+		t.Fatalf("GetListMember() without filter = %d members, %v", len(list), err)
+	}
+
+	cases := []struct {
+		name    string
+		filters []string
+		list    func(param map[string]interface{}) (int, error)
+	}{
+		{"GetListMember", []string{"keyword"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListMember(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetUser", []string{"keyword", "role"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetUser(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListItinOrderMember", []string{"keyword", "member_code", "order_type", "order_code"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListItinOrderMember(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListStuff", []string{"stuff"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListStuff(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListNotification", []string{"keyword", "user_code"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListNotification(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetChatList", []string{"keyword", "member_code", "tc_code", "chat_status"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetChatList(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetChatListBackupV1", []string{"keyword", "member_code", "tc_code"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetChatListBackupV1(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetChatHistoryByGroupCode", []string{"code"}, func(p map[string]interface{}) (int, error) {
+			_, err := c.GetChatHistoryByGroupCode(db, ctx, p["code"].(string), p)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return 0, nil
+			}
+			return 1, err
+		}},
+		{"GetListItinMember", []string{"keyword", "member_code", "created_by"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListItinMember(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListItinSug", []string{"keyword", "nearby", "created_by", "user_code"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListItinSug(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListCallLogs", []string{"trx_id", "call_type", "provider"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListCallLogs(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListExchangeRate", []string{"currency"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListExchangeRate(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListVoucher", []string{"keyword"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListVoucher(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListLogActivityUser", []string{"keyword", "role", "code", "event_type"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListLogActivityUser(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListAuditLog", []string{"keyword", "actor_code", "actor_role", "channel", "entity_type", "entity_id", "action"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListAuditLog(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetListSetting", []string{"keyword", "group"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetListSetting(db, ctx, p)
+			return len(l), err
+		}},
+		{"GetTcRatingReport", []string{"tc_code"}, func(p map[string]interface{}) (int, error) {
+			l, err := c.GetTcRatingReport(db, ctx, p)
+			return len(l), err
+		}},
+	}
+
+	for _, tc := range cases {
+		for _, filter := range tc.filters {
+			for _, in := range hostileInputs {
+				param := listParam()
+				param[filter] = in
+				param["order"] = in
+
+				n, err := tc.list(param)
+				if err != nil {
+					t.Errorf("%s(%s=%q) error: %v", tc.name, filter, in, err)
+					continue
+				}
+				if n != 0 {
+					t.Errorf("%s(%s=%q) = %d rows, want 0", tc.name, filter, in, n)
+				}
+			}
+		}
+	}
+
+	// the tc id is never spliced, an invalid id is rejected before the query
+	for _, in := range hostileInputs {
+		param := listParam()
+		param["created_by"] = in
+		_, err := c.GetDashboardTc(db, ctx, param)
+		var pgErr *pgconn.PgError
+		if err == nil || errors.As(err, &pgErr) {
+			t.Errorf("GetDashboardTc(created_by=%q) = %v, want invalid tc id", in, err)
+		}
+	}
+
+	var members, users int
+	if err = db.QueryRow(ctx, "select (select count(*) from members), (select count(*) from users)").Scan(&members, &users); err != nil {
+		t.Fatalf("tables are not intact: %v", err)
+	}
+	if members != 1 || users != 1 {
+		t.Fatalf("tables are not intact: %d members, %d users", members, users)
+	}
+}
+
+func TestBatchInsertBindsValues(t *testing.T) {
+	pool := testDB(t)
+	ctx := context.Background()
+
+	db, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Release()
+
+	var memberID, chatGroupID int32
+	err = db.QueryRow(ctx, `insert into members(member_code, name, username, email, phone, password, created_date)
+		values('m-test-owner', 'Owner', 'owner', 'owner@example.com', '0811000001', 'x', now()) returning id`).Scan(&memberID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.QueryRow(ctx, `insert into chat_groups(chat_group_code, name, chat_group_type, created_by, status, created_date)
+		values('CG-test', 'Test', 'itinerary', $1, true, now()) returning id`, memberID).Scan(&chatGroupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Contract{}
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var temps []ChatMemberTemporary
+	for _, in := range hostileInputs {
+		temps = append(temps, ChatMemberTemporary{Email: in, ChatGroupID: chatGroupID})
+	}
+	if err = c.AddChatMemberTempBatch(ctx, tx, temps); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.AddChatGroupRelationBatch(ctx, tx, []ChatGroupRelationEnt{{MemberID: memberID, ChatGroupID: chatGroupID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := tx.Query(ctx, "select email from chat_member_temporaries where chat_group_id = $1 order by id", chatGroupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err = rows.Scan(&email); err != nil {
+			t.Fatal(err)
+		}
+		emails = append(emails, email)
+	}
+	rows.Close()
+
+	if len(emails) != len(hostileInputs) {
+		t.Fatalf("inserted %d emails, want %d", len(emails), len(hostileInputs))
+	}
+	for i, email := range emails {
+		if email != hostileInputs[i] {
+			t.Errorf("email %d = %q, want %q", i, email, hostileInputs[i])
+		}
+	}
+
+	var members int
+	if err = tx.QueryRow(ctx, "select count(*) from members").Scan(&members); err != nil || members != 1 {
+		t.Fatalf("members are not intact: %d, %v", members, err)
+	}
+}
@@ -3,6 +3,10 @@ package model
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"math"
+	"panorama/lib/psql"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -18,6 +22,8 @@ type LogActivityUserEnt struct {
 	Activity    string
 	EventType   string
 	CreatedDate sql.NullTime
+	UserCode    sql.NullString
+	Name        sql.NullString
 }
 
 // ActiveClientConsultan ...
@@ -66,6 +72,137 @@ func (c *Contract) GetListLogActivity(db *pgxpool.Conn, ctx context.Context, rol
 	return list, err
 }
 
+// GetListLogActivityUser activity logs of all users & members (admin)
+func (c *Contract) GetListLogActivityUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]LogActivityUserEnt, error) {
+	list := []LogActivityUserEnt{}
+
+	q, paramQuery := listLogActivityUserQuery(param)
+
+	{
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY l.id " + psql.Direction(param["sort"].(string))
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListLogActivityUser(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// CountListLogActivityUser total activity logs of the list filter
+func (c *Contract) CountListLogActivityUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listLogActivityUserQuery(param)
+
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
+
+// ExportListLogActivityUser all activity logs of the list filter, streamed into fn row by row
+func (c *Contract) ExportListLogActivityUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(LogActivityUserEnt) error) error {
+	q, paramQuery := listLogActivityUserQuery(param)
+
+	rows, err := db.Query(ctx, q+" ORDER BY l.id "+psql.Direction(param["sort"].(string)), paramQuery...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListLogActivityUser(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(a); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// listLogActivityUserQuery activity logs query of the list filter without order & pagination,
+// the user is taken from members for customer and from users for admin & tc
+func listLogActivityUserQuery(param map[string]interface{}) (string, []interface{}) {
+	var where []string
+	var paramQuery []interface{}
+
+	q := `select l.id, l.role, coalesce(u.user_code, m.member_code), coalesce(u.name, m.name),
+			l.title, l.activity, l.event_type, l.created_date
+		from log_activity_users l
+		left join users u on l.role <> 'customer' and u.id = l.user_id
+		left join members m on l.role = 'customer' and m.id = l.user_id`
+
+	if len(param["role"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["role"])
+		where = append(where, fmt.Sprintf("l.role = $%d", len(paramQuery)))
+	}
+
+	if len(param["code"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["code"])
+		where = append(where, fmt.Sprintf("coalesce(u.user_code, m.member_code) = $%d", len(paramQuery)))
+	}
+
+	if len(param["event_type"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["event_type"])
+		where = append(where, fmt.Sprintf("l.event_type = $%d", len(paramQuery)))
+	}
+
+	if len(param["keyword"].(string)) > 0 {
+		paramQuery = append(paramQuery, "%"+strings.ToLower(param["keyword"].(string))+"%")
+		where = append(where, fmt.Sprintf("(lower(l.title) like $%d OR lower(l.activity) like $%d)", len(paramQuery), len(paramQuery)))
+	}
+
+	if len(param["start_date"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["start_date"].(string)+" 00:00:00")
+		where = append(where, fmt.Sprintf("l.created_date >= $%d", len(paramQuery)))
+	}
+
+	if len(param["end_date"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["end_date"].(string)+" 23:59:59")
+		where = append(where, fmt.Sprintf("l.created_date <= $%d", len(paramQuery)))
+	}
+
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	return q, paramQuery
+}
+
+// scanListLogActivityUser ...
+func scanListLogActivityUser(rows pgx.Rows) (LogActivityUserEnt, error) {
+	var a LogActivityUserEnt
+	err := rows.Scan(&a.ID, &a.Role, &a.UserCode, &a.Name, &a.Title, &a.Activity, &a.EventType, &a.CreatedDate)
+
+	return a, err
+}
+
 // GetListLogActivity ...
 func (c *Contract) GetActiveClient(db *pgxpool.Conn, ctx context.Context, tcID int32) ([]ActiveClientConsultan, error) {
 	list := []ActiveClientConsultan{}
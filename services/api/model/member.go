@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"panorama/lib/psql"
 	"panorama/lib/utils"
-	"strings"
 	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
@@ -114,6 +114,10 @@ func (c *Contract) AddMember(tx pgx.Tx, ctx context.Context, m MemberEnt) (Membe
 
 func (c *Contract) GetMemberBy(db *pgxpool.Conn, ctx context.Context, field, username string) (MemberEnt, error) {
 	var m MemberEnt
+	if err := psql.Allowed(field, "id", "member_code", "username", "email", "phone"); err != nil {
+		return m, err
+	}
+
 	err := pgxscan.Get(ctx, db, &m,
 		fmt.Sprintf("select * from members where %s=$1 limit 1", field),
 		username,
@@ -149,7 +153,7 @@ func (c *Contract) GetMemberStatistikByCode(db *pgxpool.Conn, ctx context.Contex
 	from (
 		select 
 			member_code, members.name, members.img, l.last_active_date, total_visited,
-			COUNT(CASE WHEN o.order_status = 'C' THEN order_status END) as total_order
+			COUNT(CASE WHEN o.order_status in ('PD', 'IP', 'C') THEN order_status END) as total_order
 		from members 
 		left join member_itins mi on mi.created_by = members.id
 		left join log_visit_app l on l.user_id = members.id
@@ -217,42 +221,11 @@ func (c *Contract) GetListMember(db *pgxpool.Conn, ctx context.Context, param ma
 	// TODO join to visited customer next
 
 	list := []MemberEnt{}
-	var where []string
-	var paramQuery []interface{}
-
-	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-
-		orWhere = append(orWhere, "name like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "username like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "phone like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "member_code like '%"+param["keyword"].(string)+"%'")
 
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
-	}
-
-	sql := `
-		select id, member_code, name, username, email, phone, img, is_valid_email, 
-			   is_valid_phone, is_active, MAX(last_active_date), SUM(total_visited) 
-		from (
-			SELECT members.id, member_code, name, username, email, phone, img, is_valid_email, 
-			is_valid_phone, is_active, l.last_active_date, total_visited
-			from members
-			LEFT JOIN log_visit_app l on l.user_id = members.id
-			where l.role = 'customer' and is_active = true  
-			group by members.id, l.id
-		) a `
-
-	var q string = sql
-
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
+	q, paramQuery := listMemberQuery(param)
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM (` + q + ` group by id, member_code, name, username, email, phone, img, is_valid_email,is_valid_phone, is_active ) AS data`
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
 		if err != nil {
 			return list, err
 		}
@@ -266,12 +239,10 @@ func (c *Contract) GetListMember(db *pgxpool.Conn, ctx context.Context, param ma
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	if param["limit"].(int) == -1 {
-		q += " group by id, member_code, name, username, email, phone, img, is_valid_email,is_valid_phone, is_active ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += " group by id, member_code, name, username, email, phone, img, is_valid_email,is_valid_phone, is_active ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
+	q += " ORDER BY " + listMemberOrder(param)
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
 	}
 
 	rows, err := db.Query(ctx, q, paramQuery...)
@@ -282,8 +253,7 @@ func (c *Contract) GetListMember(db *pgxpool.Conn, ctx context.Context, param ma
 	defer rows.Close()
 
 	for rows.Next() {
-		var a MemberEnt
-		err = rows.Scan(&a.ID, &a.MemberCode, &a.Name, &a.Username, &a.Email, &a.Phone, &a.Img, &a.IsEmailValid, &a.IsPhoneValid, &a.IsActive, &a.MemberStatistik.LastActiveDate, &a.MemberStatistik.TotalVisited)
+		a, err := scanListMember(rows)
 		if err != nil {
 			return list, err
 		}
@@ -294,6 +264,72 @@ func (c *Contract) GetListMember(db *pgxpool.Conn, ctx context.Context, param ma
 	return list, err
 }
 
+// CountListMember total members of the list filter
+func (c *Contract) CountListMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listMemberQuery(param)
+
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
+
+// ExportListMember all members of the list filter, streamed into fn row by row
+func (c *Contract) ExportListMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(MemberEnt) error) error {
+	q, paramQuery := listMemberQuery(param)
+
+	rows, err := db.Query(ctx, q+" ORDER BY "+listMemberOrder(param), paramQuery...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListMember(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(a); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// listMemberQuery members query of the list filter without order & pagination
+func listMemberQuery(param map[string]interface{}) (string, []interface{}) {
+	var q psql.Query
+
+	if len(param["keyword"].(string)) > 0 {
+		q.Like(param["keyword"].(string), "name", "username", "phone", "member_code")
+	}
+
+	sql := `
+		select id, member_code, name, username, email, phone, img, is_valid_email, 
+			   is_valid_phone, is_active, MAX(last_active_date), SUM(total_visited) 
+		from (
+			SELECT members.id, member_code, name, username, email, phone, img, is_valid_email, 
+			is_valid_phone, is_active, l.last_active_date, total_visited
+			from members
+			LEFT JOIN log_visit_app l on l.user_id = members.id
+			where l.role = 'customer' and is_active = true  
+			group by members.id, l.id
+		) a `
+
+	return sql + q.WhereSQL() + " group by id, member_code, name, username, email, phone, img, is_valid_email,is_valid_phone, is_active", q.Args
+}
+
+// listMemberOrder whitelisted order of the members list
+func listMemberOrder(param map[string]interface{}) string {
+	return psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"id"}, "id")
+}
+
+// scanListMember ...
+func scanListMember(rows pgx.Rows) (MemberEnt, error) {
+	var a MemberEnt
+	err := rows.Scan(&a.ID, &a.MemberCode, &a.Name, &a.Username, &a.Email, &a.Phone, &a.Img, &a.IsEmailValid, &a.IsPhoneValid, &a.IsActive, &a.MemberStatistik.LastActiveDate, &a.MemberStatistik.TotalVisited)
+
+	return a, err
+}
+
 // UpdateMember ...
 func (c *Contract) UpdateMember(tx pgx.Tx, code string, m MemberEnt) (MemberEnt, error) {
 
@@ -103,9 +103,9 @@ func (c *Contract) GetMemberItinIdByCustID(db *pgxpool.Conn, ctx context.Context
 // UpdateTcIdOrder ...
 func (c *Contract) UpdateTcIdOrder(db *pgxpool.Conn, ctx context.Context, tx pgx.Tx, newTcID int32, chatGroupID int32) error {
 
-	sql := `UPDATE orders SET tc_id = $1 WHERE chat_id = $2 and order_status = $3 `
+	sql := `UPDATE orders SET tc_id = $1 WHERE chat_id = $2 and order_status in ($3, $4) `
 
-	stmt, err := tx.Query(ctx, sql, newTcID, chatGroupID, ORDER_STATUS_PENDING)
+	stmt, err := tx.Query(ctx, sql, newTcID, chatGroupID, ORDER_STATUS_PENDING, ORDER_STATUS_PARTIAL)
 
 	defer stmt.Close()
 
@@ -118,9 +118,9 @@ func (c *Contract) GetListChatGroupByTcID(db *pgxpool.Conn, ctx context.Context,
 	var chatGroupList []ChatGroupEnt
 	var memberItinID sql.NullInt32
 
-	sql := `select cg.id, cg.member_itin_id, cg.chat_group_code from orders o join chat_groups cg on cg.id = o.chat_id where o.tc_id = $1 and o.order_status = $2`
+	sql := `select cg.id, cg.member_itin_id, cg.chat_group_code from orders o join chat_groups cg on cg.id = o.chat_id where o.tc_id = $1 and o.order_status in ($2, $3)`
 
-	rows, err := db.Query(ctx, sql, tcID, ORDER_STATUS_PENDING)
+	rows, err := db.Query(ctx, sql, tcID, ORDER_STATUS_PENDING, ORDER_STATUS_PARTIAL)
 	if err != nil {
 		return chatGroupList, err
 	}
@@ -0,0 +1,289 @@
+package model
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"panorama/lib/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	ITIN_ROLE_OWNER  = "owner"
+	ITIN_ROLE_EDITOR = "editor"
+	ITIN_ROLE_VIEWER = "viewer"
+
+	ITIN_INVITE_PENDING  = "pending"
+	ITIN_INVITE_ACCEPTED = "accepted"
+	ITIN_INVITE_DECLINED = "declined"
+	ITIN_INVITE_EXPIRED  = "expired"
+
+	itinInviteExpiredDay = 7 // in days
+	itinInviteURL        = "https://panoramatest.page.link/test"
+)
+
+// MemberItinInviteEnt ...
+type MemberItinInviteEnt struct {
+	ID            int32
+	InviteCode    string
+	MemberItinID  int32
+	Email         string
+	MemberID      sql.NullInt32
+	Role          string
+	Status        string
+	InvitedBy     int32
+	ResendCount   int32
+	ExpiredDate   time.Time
+	RespondedDate sql.NullTime
+	CreatedDate   time.Time
+	UpdatedDate   sql.NullTime
+	MemberItin    MemberItinEnt
+	Member        MemberEnt
+	InvitedByName string
+}
+
+// CurrentStatus pending invite that pass the expired date is expired
+func (i MemberItinInviteEnt) CurrentStatus() string {
+	if i.Status == ITIN_INVITE_PENDING && time.Now().After(i.ExpiredDate) {
+		return ITIN_INVITE_EXPIRED
+	}
+
+	return i.Status
+}
+
+func (c *Contract) SetItinInviteCode() string {
+	rand.Seed(time.Now().UnixNano())
+	code, _ := utils.Generate(`INV-[a-z0-9]{10}`)
+	return code
+}
+
+// ItinInviteExpiredDate expired date of new or resend invite
+func (c *Contract) ItinInviteExpiredDate() time.Time {
+	day := c.Config.GetInt("app.itin_invite_expired_day")
+	if day <= 0 {
+		day = itinInviteExpiredDay
+	}
+
+	return time.Now().In(time.UTC).AddDate(0, 0, day)
+}
+
+func (c *Contract) signItinInvite(payload string) string {
+	mac := hmac.New(sha256.New, []byte(c.Config.GetString("app.key")))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignItinInviteToken signed token of invite link, contain invite code and expired date
+func (c *Contract) SignItinInviteToken(inviteCode string, expiredDate time.Time) string {
+	payload := inviteCode + "." + strconv.FormatInt(expiredDate.Unix(), 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload + "." + c.signItinInvite(payload)))
+}
+
+// ParseItinInviteToken validate signed token of invite link and return the invite code
+func (c *Contract) ParseItinInviteToken(token string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("Invalid invitation token")
+	}
+
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("Invalid invitation token")
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(c.signItinInvite(payload))) {
+		return "", fmt.Errorf("Invalid invitation token")
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("Invalid invitation token")
+	}
+	if time.Now().Unix() > exp {
+		return "", fmt.Errorf("Invitation has expired")
+	}
+
+	return parts[0], nil
+}
+
+// ItinInviteURL invite link sent to the invited email
+func (c *Contract) ItinInviteURL(i MemberItinInviteEnt) string {
+	base := c.Config.GetString("app.itin_invite_url")
+	if base == "" {
+		base = itinInviteURL
+	}
+
+	return base + "?" + url.Values{"token": {c.SignItinInviteToken(i.InviteCode, i.ExpiredDate)}}.Encode()
+}
+
+// AddMemberItinInvite add new invite, re-invite the same email reset the invite to pending with new invite code,
+// so the link of the previous invite can not be used anymore
+func (c *Contract) AddMemberItinInvite(tx pgx.Tx, ctx context.Context, i MemberItinInviteEnt) (MemberItinInviteEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `
+		INSERT INTO member_itin_invites(invite_code, member_itin_id, email, member_id, role, status, invited_by, expired_date, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (member_itin_id, email) DO UPDATE
+		SET invite_code = EXCLUDED.invite_code,
+			member_id = EXCLUDED.member_id,
+			role = EXCLUDED.role,
+			status = EXCLUDED.status,
+			invited_by = EXCLUDED.invited_by,
+			expired_date = EXCLUDED.expired_date,
+			responded_date = null,
+			updated_date = EXCLUDED.created_date
+		RETURNING id, invite_code`
+
+	err := tx.QueryRow(ctx, sql, i.InviteCode, i.MemberItinID, i.Email, i.MemberID, i.Role, ITIN_INVITE_PENDING, i.InvitedBy, i.ExpiredDate, timeStamp).Scan(&i.ID, &i.InviteCode)
+
+	i.Status = ITIN_INVITE_PENDING
+	i.CreatedDate = timeStamp
+
+	return i, err
+}
+
+const memberItinInviteSelect = `
+	select
+		mii.id, mii.invite_code, mii.member_itin_id, mii.email, mii.member_id, mii.role, mii.status,
+		mii.invited_by, mii.resend_count, mii.expired_date, mii.responded_date, mii.created_date, mii.updated_date,
+		mi.itin_code, mi.title, mi.created_by,
+		m.member_code, m.name, m.img,
+		ib.name invited_by_name
+	from member_itin_invites mii
+	join member_itins mi on mi.id = mii.member_itin_id
+	join members ib on ib.id = mii.invited_by
+	left join members m on m.id = mii.member_id `
+
+func scanMemberItinInvite(row pgx.Row) (MemberItinInviteEnt, error) {
+	var i MemberItinInviteEnt
+	var memberCode, memberName sql.NullString
+
+	err := row.Scan(&i.ID, &i.InviteCode, &i.MemberItinID, &i.Email, &i.MemberID, &i.Role, &i.Status,
+		&i.InvitedBy, &i.ResendCount, &i.ExpiredDate, &i.RespondedDate, &i.CreatedDate, &i.UpdatedDate,
+		&i.MemberItin.ItinCode, &i.MemberItin.Title, &i.MemberItin.CreatedBy,
+		&memberCode, &memberName, &i.Member.Img, &i.InvitedByName)
+	i.MemberItin.ID = i.MemberItinID
+	i.Member.ID = i.MemberID.Int32
+	i.Member.MemberCode = memberCode.String
+	i.Member.Name = memberName.String
+	i.Member.Email = i.Email
+
+	return i, err
+}
+
+// GetMemberItinInviteByCode get invite by invite code
+func (c *Contract) GetMemberItinInviteByCode(db *pgxpool.Conn, ctx context.Context, inviteCode string) (MemberItinInviteEnt, error) {
+	sql := memberItinInviteSelect + `where mii.invite_code = $1 limit 1`
+
+	return scanMemberItinInvite(db.QueryRow(ctx, sql, inviteCode))
+}
+
+// GetMemberItinInviteByEmail get invite of the email in member itin
+func (c *Contract) GetMemberItinInviteByEmail(db *pgxpool.Conn, ctx context.Context, itinID int32, email string) (MemberItinInviteEnt, error) {
+	sql := memberItinInviteSelect + `where mii.member_itin_id = $1 and mii.email = $2 limit 1`
+
+	return scanMemberItinInvite(db.QueryRow(ctx, sql, itinID, email))
+}
+
+// GetListMemberItinInviteByItinCode list of invite in member itin
+func (c *Contract) GetListMemberItinInviteByItinCode(db *pgxpool.Conn, ctx context.Context, itinCode string) ([]MemberItinInviteEnt, error) {
+	list := []MemberItinInviteEnt{}
+
+	sql := memberItinInviteSelect + `where mi.itin_code = $1 order by mii.created_date desc`
+
+	rows, err := db.Query(ctx, sql, itinCode)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		i, err := scanMemberItinInvite(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, i)
+	}
+
+	return list, rows.Err()
+}
+
+// RespondMemberItinInvite accept or decline the invite
+func (c *Contract) RespondMemberItinInvite(tx pgx.Tx, ctx context.Context, id, memberID int32, status string) error {
+	timeStamp := time.Now().In(time.UTC)
+
+	_, err := tx.Exec(ctx, `UPDATE member_itin_invites SET status = $1, member_id = $2, responded_date = $3, updated_date = $3 WHERE id = $4`,
+		status, memberID, timeStamp, id)
+
+	return err
+}
+
+// ResendMemberItinInvite extend the expired date of invite with new invite code and set the status to pending,
+// so the link of the previous invite can not be used anymore
+func (c *Contract) ResendMemberItinInvite(tx pgx.Tx, ctx context.Context, id int32, inviteCode string, expiredDate time.Time) error {
+	_, err := tx.Exec(ctx, `UPDATE member_itin_invites SET invite_code = $1, status = $2, expired_date = $3, resend_count = resend_count + 1, responded_date = null, updated_date = $4 WHERE id = $5`,
+		inviteCode, ITIN_INVITE_PENDING, expiredDate, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+// DeleteMemberItinInvite revoke the invite
+func (c *Contract) DeleteMemberItinInvite(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `delete from member_itin_invites where id = $1`, id)
+
+	return err
+}
+
+// LinkMemberItinInviteByEmail assign the registered member into the invites of the email
+func (c *Contract) LinkMemberItinInviteByEmail(tx pgx.Tx, ctx context.Context, email string, memberID int32) error {
+	_, err := tx.Exec(ctx, `UPDATE member_itin_invites SET member_id = $1, updated_date = $2 WHERE email = $3 and member_id is null`,
+		memberID, time.Now().In(time.UTC), email)
+
+	return err
+}
+
+// GetMemberItinRole role of member in member itin, empty when member is not a companion
+func (c *Contract) GetMemberItinRole(db *pgxpool.Conn, ctx context.Context, itinID, memberID int32) (string, error) {
+	var role string
+
+	sql := `
+		select $3::varchar from member_itins where id = $1 and created_by = $2
+		union all
+		select role from member_itin_relations where member_itin_id = $1 and member_id = $2 and deleted_date is null
+		limit 1`
+
+	err := db.QueryRow(ctx, sql, itinID, memberID, ITIN_ROLE_OWNER).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+
+	return role, err
+}
+
+// GetChatGroupByItinID chat group of member itin, zero id when the itin has no chat group
+func (c *Contract) GetChatGroupByItinID(db *pgxpool.Conn, ctx context.Context, itinID int32) (ChatGroupEnt, error) {
+	var cg ChatGroupEnt
+
+	err := db.QueryRow(ctx, `select id, chat_group_code from chat_groups where member_itin_id = $1 limit 1`, itinID).Scan(&cg.ID, &cg.ChatGroupCode)
+	if err == pgx.ErrNoRows {
+		return cg, nil
+	}
+
+	return cg, err
+}
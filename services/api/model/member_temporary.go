@@ -43,11 +43,12 @@ func (c *Contract) GetMemberTemporaryByEmailAndItinID(db *pgxpool.Conn, ctx cont
 	return m, err
 }
 
-// GetListMemberTemporaryByEmail Get member temporary list by email
+// GetListMemberTemporaryByEmail Get member temporary list by email, the temporary that has invite wait for the invite accepted
 func (c *Contract) GetListMemberTemporaryByEmail(db *pgxpool.Conn, ctx context.Context, email string) ([]MemberTemporaryEnt, error) {
 	var mList []MemberTemporaryEnt
 
-	query := `SELECT id, email, member_itin_id, created_date FROM member_temporaries WHERE email = $1`
+	query := `SELECT id, email, member_itin_id, created_date FROM member_temporaries mt WHERE email = $1
+		AND NOT EXISTS (SELECT 1 FROM member_itin_invites mii WHERE mii.member_itin_id = mt.member_itin_id AND mii.email = mt.email)`
 
 	rows, err := db.Query(ctx, query, email)
 	if err != nil {
@@ -67,10 +68,11 @@ func (c *Contract) GetListMemberTemporaryByEmail(db *pgxpool.Conn, ctx context.C
 	return mList, err
 }
 
-// DeleteMemberTempByEmail delete member temporary by email
+// DeleteMemberTempByEmail delete member temporary by email that has no invite
 func (c *Contract) DeleteMemberTempByEmail(ctx context.Context, tx pgx.Tx, email string) error {
 
-	sql := `delete from member_temporaries as mt where email = $1`
+	sql := `delete from member_temporaries as mt where email = $1
+		and not exists (select 1 from member_itin_invites mii where mii.member_itin_id = mt.member_itin_id and mii.email = mt.email)`
 
 	pgx, err := tx.Query(ctx, sql, email)
 
@@ -8,6 +8,7 @@ import (
 	"math"
 	"math/rand"
 	"panorama/lib/onesignal"
+	"panorama/lib/psql"
 	"panorama/lib/utils"
 	"strings"
 	"time"
@@ -30,6 +31,8 @@ const (
 	NOTIF_SUBJ_CHAT_INCOME           = "Chat Incoming"
 	NOTIF_SUBJ_CHAT_UNREAD           = "Chat Unread"
 	NOTIF_SUBJ_CHAT_ROOM_ASSIGNED    = "New Chat Room Assigned"
+	NOTIF_SUBJ_CALL_INCOMING         = "Incoming Call"
+	NOTIF_SUBJ_CALL_MISSED           = "Missed Call"
 	NOTIF_SUBJ_ORDER_INCOME          = "Payment Incoming"
 	NOTIF_SUBJ_ORDER_VERIF           = "Verified Payment"
 	NOTIF_SUBJ_ORDER_CANCEL          = "Cancelled Payment"
@@ -37,6 +40,9 @@ const (
 	NOTIF_SUBJ_ORDER_HISTORY         = "Payment History"
 	NOTIF_SUBJ_ORDER_CLIENT_COMPLETE = "Client Completed Payment"
 	NOTIF_SUBJ_ORDER_CLIENT_FAIL     = "Client Failed Payment"
+	NOTIF_SUBJ_ORDER_SHARE_REMINDER  = "Payment Share Reminder"
+	NOTIF_SUBJ_ORDER_INSTALLMENT_DUE = "Installment Due"
+	NOTIF_SUBJ_ORDER_STATUS          = "Order Status Changed"
 	NOTIF_SUBJ_MBITIN_PRE            = "Pre-trip"
 	NOTIF_SUBJ_MBITIN_BEGIN          = "Trip Begins"
 	NOTIF_SUBJ_SUGGITIN_NEW          = "New Suggested Itinerary"
@@ -79,6 +85,7 @@ type NotificationContent struct {
 	PaymentMethod string
 	TripName      string
 	StatusPayment string
+	OrderStatus   string
 	Day           int
 	Info          string
 	CustomerName  string
@@ -86,6 +93,8 @@ type NotificationContent struct {
 	AdminName     string
 	SugItinTitle  string
 	StuffName	  string
+	CallType      string
+	CallerName    string
 }
 
 func (c *Contract) SetNotificationCode() string {
@@ -163,8 +172,7 @@ func (c *Contract) ForceDeleteAllNotification(tx pgx.Tx, ctx context.Context, n
 // GetListNotification ...
 func (c *Contract) GetListNotification(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]NotificationEnt, error) {
 	listNotification := []NotificationEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 
 	query := `select distinct on(n.id)
 		n.code notif_code,
@@ -248,33 +256,18 @@ func (c *Contract) GetListNotification(db *pgxpool.Conn, ctx context.Context, pa
 	left join member_itins mi on mi.created_by = uapp.user_id and uapp.role = 'customer' and mi.deleted_date is null
 	left join itin_suggestions its on its.created_by = uapp.user_id and uapp.role != 'customer' and its.deleted_date is null `
 
-	var queryString string = query
-
 	if len(param["user_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "uapp.user_code = '"+param["user_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("uapp.user_code = ?", param["user_code"])
 	}
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(n.code) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(n.title) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(n.content) like lower('%"+param["keyword"].(string)+"%')")
-		orWhere = append(orWhere, "lower(n.subject) like lower('%"+param["keyword"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["keyword"].(string), "n.code", "n.title", "n.content", "n.subject")
 	}
 
-	if len(where) > 0 {
-		queryString += " WHERE " + strings.Join(where, " AND ")
-	}
+	var queryString string = query + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + queryString + ` ) AS data`
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, queryString, w.Args...)
 		if err != nil {
 			return listNotification, err
 		}
@@ -289,15 +282,10 @@ func (c *Contract) GetListNotification(db *pgxpool.Conn, ctx context.Context, pa
 		param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 	}
 
-	if param["limit"].(int) == -1 {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		queryString += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+	queryString += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"n.id"}, "n.id")
+	queryString += w.Page(param["offset"].(int), param["limit"].(int))
 
-	rows, err := db.Query(ctx, queryString, paramQuery...)
+	rows, err := db.Query(ctx, queryString, w.Args...)
 	if err != nil {
 		return listNotification, err
 	}
@@ -468,6 +456,20 @@ func (c *Contract) GetNotifChatRoomAssigned(userID int64, role, roomName string)
 	return c.SetNotifContent(userID, NOTIF_TYPE_CHAT, role, NOTIF_SUBJ_CHAT_ROOM_ASSIGNED, title, desc, "")
 }
 
+func (c *Contract) GetNotifCallIncoming(userID int64, role, callerName, roomName, callType string) NotificationEnt {
+	title := fmt.Sprintf("Incoming %s call from %s", callType, callerName)
+	desc := fmt.Sprintf(`%s is calling you in "%s"`, callerName, roomName)
+
+	return c.SetNotifContent(userID, NOTIF_TYPE_CHAT, role, NOTIF_SUBJ_CALL_INCOMING, title, desc, "")
+}
+
+func (c *Contract) GetNotifCallMissed(userID int64, role, callerName, roomName, callType string) NotificationEnt {
+	title := fmt.Sprintf("You missed a %s call from %s", callType, callerName)
+	desc := fmt.Sprintf(`Go to "%s" to call them back`, roomName)
+
+	return c.SetNotifContent(userID, NOTIF_TYPE_CHAT, role, NOTIF_SUBJ_CALL_MISSED, title, desc, "")
+}
+
 func (c *Contract) GetNotifChatClientCompletedPayment(userID int64, role, roomName, clientName, orderCode string) NotificationEnt {
 	title := fmt.Sprintf("%s assigne %s has completed the payment", roomName, clientName)
 	desc := fmt.Sprintf("%s has completed the payment for order ID %s", clientName, orderCode)
@@ -510,6 +512,27 @@ func (c *Contract) GetNotifPaymentFailed(userID int64, role, tripName, orderCode
 	return c.SetNotifContent(userID, NOTIF_TYPE_ORDER, role, NOTIF_SUBJ_ORDER_FAIL, title, desc, "")
 }
 
+func (c *Contract) GetNotifPaymentShareReminder(userID int64, role, tripName, orderCode, organizerName string) NotificationEnt {
+	title := fmt.Sprintf("Your share for %s (%s) is waiting", tripName, orderCode)
+	desc := fmt.Sprintf("%s has split the payment of %s, please pay your share immediately", organizerName, tripName)
+
+	return c.SetNotifContent(userID, NOTIF_TYPE_ORDER, role, NOTIF_SUBJ_ORDER_SHARE_REMINDER, title, desc, "")
+}
+
+func (c *Contract) GetNotifPaymentInstallmentDue(userID int64, role, tripName, orderCode, info string) NotificationEnt {
+	title := fmt.Sprintf("Installment of %s (%s) is due", tripName, orderCode)
+	desc := fmt.Sprintf("%s, please make payment immediately to keep your trip", info)
+
+	return c.SetNotifContent(userID, NOTIF_TYPE_ORDER, role, NOTIF_SUBJ_ORDER_INSTALLMENT_DUE, title, desc, "")
+}
+
+func (c *Contract) GetNotifOrderStatus(userID int64, role, tripName, orderCode, orderStatus string) NotificationEnt {
+	title := fmt.Sprintf("Order %s is %s", orderCode, strings.ToLower(orderStatus))
+	desc := fmt.Sprintf("Status of %s order is changed into %s", tripName, orderStatus)
+
+	return c.SetNotifContent(userID, NOTIF_TYPE_ORDER, role, NOTIF_SUBJ_ORDER_STATUS, title, desc, "")
+}
+
 func (c *Contract) GetNotifPaymentHistory(userID int64, role, tripName, statusPayment string) NotificationEnt {
 	title := "Payment History"
 	desc := fmt.Sprintf("%s status payment is %s", tripName, statusPayment)
@@ -638,6 +661,10 @@ func (c *Contract) SendNotifications(tx pgx.Tx, db *pgxpool.Conn, ctx context.Co
 			case NOTIF_SUBJ_CHAT_UNREAD:
 			case NOTIF_SUBJ_CHAT_ROOM_ASSIGNED:
 				notifContent = c.GetNotifChatRoomAssigned(p.UserID, p.Role, content.RoomName)
+			case NOTIF_SUBJ_CALL_INCOMING:
+				notifContent = c.GetNotifCallIncoming(p.UserID, p.Role, content.CallerName, content.RoomName, content.CallType)
+			case NOTIF_SUBJ_CALL_MISSED:
+				notifContent = c.GetNotifCallMissed(p.UserID, p.Role, content.CallerName, content.RoomName, content.CallType)
 			case NOTIF_SUBJ_ORDER_INCOME:
 				notifContent = c.GetNotifPaymentIncome(p.UserID, p.Role, content.TripName, content.OrderCode)
 			case NOTIF_SUBJ_ORDER_VERIF:
@@ -652,6 +679,12 @@ func (c *Contract) SendNotifications(tx pgx.Tx, db *pgxpool.Conn, ctx context.Co
 				notifContent = c.GetNotifChatClientCompletedPayment(p.UserID, p.Role, content.RoomName, content.ClientName, content.OrderCode)
 			case NOTIF_SUBJ_ORDER_CLIENT_FAIL:
 				notifContent = c.GetNotifChatClientFailedPayment(p.UserID, p.Role, content.RoomName, content.ClientName, content.OrderCode)
+			case NOTIF_SUBJ_ORDER_SHARE_REMINDER:
+				notifContent = c.GetNotifPaymentShareReminder(p.UserID, p.Role, content.TripName, content.OrderCode, content.CustomerName)
+			case NOTIF_SUBJ_ORDER_INSTALLMENT_DUE:
+				notifContent = c.GetNotifPaymentInstallmentDue(p.UserID, p.Role, content.TripName, content.OrderCode, content.Info)
+			case NOTIF_SUBJ_ORDER_STATUS:
+				notifContent = c.GetNotifOrderStatus(p.UserID, p.Role, content.TripName, content.OrderCode, content.OrderStatus)
 			case NOTIF_SUBJ_MBITIN_PRE:
 			case NOTIF_SUBJ_MBITIN_BEGIN:
 			case NOTIF_SUBJ_SUGGITIN_NEW:
@@ -684,7 +717,7 @@ func (c *Contract) SendNotifications(tx pgx.Tx, db *pgxpool.Conn, ctx context.Co
 
 		// Send notification - Send blast data notif into players
 		if len(listPlayerID) > 0 {
-			_, err := onesignal.New(c.App).PushNotification(notification.Title, notification.Content, listPlayerID)
+			_, err := onesignal.New(c.App).PushNotification(ctx, notification.Title, notification.Content, listPlayerID)
 			if err != nil {
 				return notifications, err
 			}
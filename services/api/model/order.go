@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"panorama/lib/psql"
 	"panorama/lib/utils"
-	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -15,11 +15,16 @@ import (
 )
 
 const (
-	ORDER_STATUS_COMPLETED = "C"
-	ORDER_STATUS_PENDING   = "P"
-	ORDER_STATUS_CANCEL    = "X"
-	ORDER_TYPE_REGULER     = "R"
-	ORDER_TYPE_CUSTOM      = "C"
+	ORDER_STATUS_DRAFT       = "D"
+	ORDER_STATUS_PENDING     = "P" // awaiting payment
+	ORDER_STATUS_PARTIAL     = "PP"
+	ORDER_STATUS_PAID        = "PD"
+	ORDER_STATUS_IN_PROGRESS = "IP"
+	ORDER_STATUS_COMPLETED   = "C"
+	ORDER_STATUS_CANCEL      = "X"
+	ORDER_STATUS_REFUNDED    = "R"
+	ORDER_TYPE_REGULER       = "R"
+	ORDER_TYPE_CUSTOM        = "C"
 )
 
 type OrderEnt struct {
@@ -38,11 +43,17 @@ type OrderEnt struct {
 	UserEnt                UserEnt
 	MemberEnt              MemberEnt
 	OrderPayment           OrderPaymentEnt
+	Installments           []OrderPaymentEnt
 	OrderStatusDescription string
 	Details                string
 	ChatID                 int32
 	Description            string
 	TotalPricePpn          int64
+	PpnRate                float64
+	PpnAmount              int64
+	RoundingAmount         int64
+	Items                  []OrderItemEnt
+	ExchangeRates          []ExchangeRateEnt
 }
 
 func (c *Contract) SetOrderCode() string {
@@ -66,13 +77,13 @@ func (c *Contract) AddOrder(tx pgx.Tx, ctx context.Context, o OrderEnt) (OrderEn
 	return o, err
 }
 
-// UpdateOrder update orders
+// UpdateOrder update orders, the status of order is changed by TransitionOrderStatus only
 func (c *Contract) UpdateOrderByCode(tx pgx.Tx, ctx context.Context, o OrderEnt) (OrderEnt, error) {
 	var ID int32
 
-	sql := `UPDATE orders SET paid_by=$1, order_status=$2, total_price=$3, tc_id=$4, order_type=$5, details=$6, title=$7 WHERE order_code=$8 RETURNING id`
+	sql := `UPDATE orders SET paid_by=$1, total_price=$2, tc_id=$3, order_type=$4, details=$5, title=$6 WHERE order_code=$7 RETURNING id, order_status`
 
-	err := tx.QueryRow(ctx, sql, o.PaidBy, o.OrderStatus, o.TotalPrice, o.TcID, o.OrderType, o.Details, o.Title, o.OrderCode).Scan(&ID)
+	err := tx.QueryRow(ctx, sql, o.PaidBy, o.TotalPrice, o.TcID, o.OrderType, o.Details, o.Title, o.OrderCode).Scan(&ID, &o.OrderStatus)
 
 	o.ID = ID
 
@@ -84,6 +95,7 @@ func (c *Contract) GetOrderByCode(db *pgxpool.Conn, ctx context.Context, code st
 	var o OrderEnt
 
 	sqlM := `select 
+		orders.id,
 		m.member_code, 
 		u.name, 
 		m.name, 
@@ -93,14 +105,18 @@ func (c *Contract) GetOrderByCode(db *pgxpool.Conn, ctx context.Context, code st
 		order_type, 
 		paid_by, 
 		total_price,
+		coalesce(total_price_ppn, 0),
+		coalesce(ppn_rate, 0),
+		coalesce(ppn_amount, 0),
+		coalesce(rounding_amount, 0),
 		orders.created_date 
 	from orders
 	join users u on u.id = orders.tc_id 
 	join members m on m.id = orders.paid_by
 	where order_code = $1 limit 1`
 
-	err := db.QueryRow(ctx, sqlM, code).Scan(&o.MemberEnt.MemberCode, &o.UserEnt.Name, &o.MemberEnt.Name, &o.Title, &o.OrderCode, &o.OrderStatus,
-		&o.OrderType, &o.PaidBy, &o.TotalPrice, &o.CreatedDate)
+	err := db.QueryRow(ctx, sqlM, code).Scan(&o.ID, &o.MemberEnt.MemberCode, &o.UserEnt.Name, &o.MemberEnt.Name, &o.Title, &o.OrderCode, &o.OrderStatus,
+		&o.OrderType, &o.PaidBy, &o.TotalPrice, &o.TotalPricePpn, &o.PpnRate, &o.PpnAmount, &o.RoundingAmount, &o.CreatedDate)
 
 	return o, err
 }
@@ -108,10 +124,81 @@ func (c *Contract) GetOrderByCode(db *pgxpool.Conn, ctx context.Context, code st
 // Get Order List
 func (c *Contract) GetListItinOrderMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]OrderEnt, error) {
 	list := []OrderEnt{}
-	var where []string
-	var paramQuery []interface{}
-	var title, description, memberImg, paymentStatus, detail sql.NullString
-	var totalPPN sql.NullInt64
+
+	q, paramQuery := listItinOrderMemberQuery(param)
+
+	{
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY " + listItinOrderMemberOrder(param)
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := scanListItinOrderMember(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, o)
+	}
+	return list, err
+}
+
+// CountListItinOrderMember total orders of the list filter
+func (c *Contract) CountListItinOrderMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listItinOrderMemberQuery(param)
+
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
+
+// ExportListItinOrderMember all orders of the list filter, streamed into fn row by row
+func (c *Contract) ExportListItinOrderMember(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(OrderEnt) error) error {
+	q, paramQuery := listItinOrderMemberQuery(param)
+
+	rows, err := db.Query(ctx, q+" ORDER BY "+listItinOrderMemberOrder(param), paramQuery...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		o, err := scanListItinOrderMember(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(o); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// listItinOrderMemberQuery orders query of the list filter without order & pagination
+func listItinOrderMemberQuery(param map[string]interface{}) (string, []interface{}) {
+	var q psql.Query
 
 	sql := `select
 		title,
@@ -127,92 +214,51 @@ func (c *Contract) GetListItinOrderMember(db *pgxpool.Conn, ctx context.Context,
 		o.created_date
 	from orders o
 	join members m on m.id = o.paid_by
-	left join order_payments op on o.id = op.order_id `
-
-	var q string = sql
+	left join order_payments op on o.id = op.order_id and op.installment_no = 0 `
 
 	if len(param["member_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " m.member_code = '"+param["member_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		q.Where("m.member_code = ?", param["member_code"])
 	}
 
 	if len(param["order_type"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " order_type = '"+param["order_type"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		q.Where("order_type = ?", param["order_type"])
 	}
 
 	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "lower(order_code) like lower('%"+param["keyword"].(string)+"%')")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		q.Like(param["keyword"].(string), "order_code")
 	}
 
 	if len(param["order_code"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " order_code = '"+param["order_code"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		q.Where("order_code = ?", param["order_code"])
 	}
 
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
-
-	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + q + ` ) AS data`
-
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
-		if err != nil {
-			return list, err
-		}
-		param["count"] = count
-	}
-
-	// Select Max Page
-	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
-		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
-	}
+	return sql + q.WhereSQL(), q.Args
+}
 
-	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+// listItinOrderMemberOrder whitelisted order of the orders list
+func listItinOrderMemberOrder(param map[string]interface{}) string {
+	return psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"o.id"}, "o.id")
+}
 
-	if param["limit"].(int) == -1 {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+// scanListItinOrderMember ...
+func scanListItinOrderMember(rows pgx.Rows) (OrderEnt, error) {
+	var o OrderEnt
+	var title, description, memberImg, paymentStatus, detail sql.NullString
+	var totalPPN sql.NullInt64
 
-	rows, err := db.Query(ctx, q, paramQuery...)
+	err := rows.Scan(&title, &o.MemberEnt.Name, &o.OrderCode, &description, &o.OrderType, &detail, &o.TotalPrice, &totalPPN, &memberImg, &paymentStatus, &o.CreatedDate)
 	if err != nil {
-		return list, err
+		return o, err
 	}
 
-	defer rows.Close()
+	o.Title = title.String
+	o.Details = detail.String
+	o.Description = description.String
+	o.TotalPricePpn = totalPPN.Int64
+	o.MemberEnt.Img = memberImg
+	o.OrderPayment.PaymentStatus = paymentStatus.String
 
-	for rows.Next() {
-		var o OrderEnt
-		err = rows.Scan(&title, &o.MemberEnt.Name, &o.OrderCode, &description, &o.OrderType, &detail, &o.TotalPrice, &totalPPN, &memberImg, &paymentStatus, &o.CreatedDate)
-		if err != nil {
-			return list, err
-		}
-
-		o.Title = title.String
-		o.Details = detail.String
-		o.Description = description.String
-		o.TotalPricePpn = totalPPN.Int64
-		o.MemberEnt.Img = memberImg
-		o.OrderPayment.PaymentStatus = paymentStatus.String
-
-		list = append(list, o)
-	}
-	return list, err
+	return o, nil
 }
 
 // Get Order by Code
@@ -268,6 +314,7 @@ func (c *Contract) GetListHistoryOrdByChatCode(db *pgxpool.Conn, ctx context.Con
 func (c *Contract) GetOrderByOrderCode(db *pgxpool.Conn, ctx context.Context, code string) (OrderEnt, error) {
 	var o OrderEnt
 	var tcID, memberID, itinID sql.NullInt32
+	var itinStartDate sql.NullTime
 	var itinCode, itinTitle, itinDestination, tcRole, tcCode, tcName, memberCode, memberName sql.NullString
 
 	sqlM := `select 
@@ -277,12 +324,14 @@ func (c *Contract) GetOrderByOrderCode(db *pgxpool.Conn, ctx context.Context, co
 		o.order_code, 
 		o.order_status, 
 		o.total_price, 
+		coalesce(o.total_price_ppn, 0),
 		o.tc_id, 
 		o.order_type, 
 		o.created_date,
 		mi.itin_code,
 		mi.title,
 		mi.destination,
+		mi.start_date,
 		u.id tc_id,
 		u.role tc_role,
 		u.user_code tc_code,
@@ -291,18 +340,19 @@ func (c *Contract) GetOrderByOrderCode(db *pgxpool.Conn, ctx context.Context, co
 		m.member_code,
 		m.name member_name
 	from orders o 
-	left join chat_groups cg on cg.member_itin_id = o.chat_id 
+	left join chat_groups cg on cg.id = o.chat_id 
 	left join member_itins mi on mi.id = cg.member_itin_id and mi.deleted_date is null
 	left join users u on u.id = o.tc_id and u.deleted_date is null
 	left join members m on m.id = o.paid_by and m.deleted_date is null
 	where o.order_code = $1`
 
-	err := db.QueryRow(ctx, sqlM, code).Scan(&o.ID, &itinID, &o.PaidBy, &o.OrderCode, &o.OrderStatus, &o.TotalPrice, &o.TcID, &o.OrderType, &o.CreatedDate, &itinCode, &itinTitle, &itinDestination, &tcID, &tcRole, &tcCode, &tcName, &memberID, &memberCode, &memberName)
+	err := db.QueryRow(ctx, sqlM, code).Scan(&o.ID, &itinID, &o.PaidBy, &o.OrderCode, &o.OrderStatus, &o.TotalPrice, &o.TotalPricePpn, &o.TcID, &o.OrderType, &o.CreatedDate, &itinCode, &itinTitle, &itinDestination, &itinStartDate, &tcID, &tcRole, &tcCode, &tcName, &memberID, &memberCode, &memberName)
 
 	o.MemberItinID = itinID.Int32
 	o.MemberItin.ItinCode = itinCode.String
 	o.MemberItin.Title = itinTitle.String
 	o.MemberItin.Destination = itinDestination.String
+	o.MemberItin.StartDate = itinStartDate.Time
 	o.UserEnt.ID = tcID.Int32
 	o.UserEnt.Role = tcRole.String
 	o.UserEnt.UserCode = tcCode.String
@@ -0,0 +1,328 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	installmentDepositDueDay = 1 // in days after the schedule is created
+	installmentReminderDay   = 3 // in days before due date
+)
+
+// InstallmentDepositDueDate due date of the first installment (deposit)
+func (c *Contract) InstallmentDepositDueDate() time.Time {
+	day := c.Config.GetInt("payment.deposit_due_day")
+	if day <= 0 {
+		day = installmentDepositDueDay
+	}
+
+	return time.Now().In(time.UTC).AddDate(0, 0, day)
+}
+
+// AddOrderInstallment add installment of order payment schedule
+func (c *Contract) AddOrderInstallment(tx pgx.Tx, ctx context.Context, oP OrderPaymentEnt) (OrderPaymentEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO order_payments(order_id, installment_no, title, amount, payment_status, due_date, created_date) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, oP.OrderID, oP.InstallmentNo, oP.Title, oP.Amount, PAYMENT_STATUS_PROCESS, oP.DueDate, timeStamp).Scan(&lastInsID)
+
+	oP.ID = lastInsID
+	oP.PaymentStatus = PAYMENT_STATUS_PROCESS
+	oP.CreatedDate = timeStamp
+
+	return oP, err
+}
+
+// DeleteOrderInstallmentByOrderID remove the installments before the schedule is created again
+func (c *Contract) DeleteOrderInstallmentByOrderID(tx pgx.Tx, ctx context.Context, orderID int32) error {
+	_, err := tx.Exec(ctx, `delete from order_payments where order_id = $1 and installment_no > 0`, orderID)
+
+	return err
+}
+
+const orderInstallmentSelect = `
+	select
+		op.id, op.order_id, o.order_code, op.installment_no, op.title, op.amount, op.payment_status,
+		op.payment_type, op.payment_url, op.payment_code, op.payloads, op.expired_date,
+		op.due_date, op.paid_date, op.reminded_date, op.created_date
+	from order_payments op
+	join orders o on o.id = op.order_id `
+
+func scanOrderInstallment(row pgx.Row) (OrderPaymentEnt, error) {
+	var oP OrderPaymentEnt
+	var title, paymentType, paymentURL, paymentCode sql.NullString
+	var expiredDate sql.NullTime
+
+	err := row.Scan(&oP.ID, &oP.OrderID, &oP.OrderCode, &oP.InstallmentNo, &title, &oP.Amount, &oP.PaymentStatus,
+		&paymentType, &paymentURL, &paymentCode, &oP.Payloads, &expiredDate,
+		&oP.DueDate, &oP.PaidDate, &oP.RemindedDate, &oP.CreatedDate)
+	oP.Title = title.String
+	oP.PaymentType = paymentType.String
+	oP.PaymentURL = paymentURL.String
+	oP.PaymentCode = paymentCode.String
+	oP.ExpiredDate = expiredDate.Time
+
+	return oP, err
+}
+
+// GetListOrderInstallmentByOrderID payment schedule of order
+func (c *Contract) GetListOrderInstallmentByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]OrderPaymentEnt, error) {
+	list := []OrderPaymentEnt{}
+
+	rows, err := db.Query(ctx, orderInstallmentSelect+`where op.order_id = $1 and op.installment_no > 0 order by op.installment_no`, orderID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		oP, err := scanOrderInstallment(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, oP)
+	}
+
+	return list, rows.Err()
+}
+
+// GetOrderInstallmentByPaymentCode installment of the midtrans transaction
+func (c *Contract) GetOrderInstallmentByPaymentCode(db *pgxpool.Conn, ctx context.Context, paymentCode string) (OrderPaymentEnt, error) {
+	sql := orderInstallmentSelect + `where op.payment_code = $1 and op.installment_no > 0 limit 1`
+
+	return scanOrderInstallment(db.QueryRow(ctx, sql, paymentCode))
+}
+
+// UpdateOrderInstallmentTransaction assign new midtrans transaction into the installment
+func (c *Contract) UpdateOrderInstallmentTransaction(tx pgx.Tx, ctx context.Context, id int32, paymentCode, paymentURL string) error {
+	sql := `UPDATE order_payments SET payment_code = $1, payment_url = $2, payment_status = $3, payment_type = null, expired_date = null WHERE id = $4 and payment_status != $5`
+
+	_, err := tx.Exec(ctx, sql, paymentCode, paymentURL, PAYMENT_STATUS_PROCESS, id, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+// UpdateOrderInstallmentStatus update status of installment from midtrans notification
+func (c *Contract) UpdateOrderInstallmentStatus(tx pgx.Tx, ctx context.Context, oP OrderPaymentEnt) error {
+	var expiredDate, paidDate sql.NullTime
+	if !oP.ExpiredDate.IsZero() {
+		expiredDate = sql.NullTime{Time: oP.ExpiredDate, Valid: true}
+	}
+	if oP.PaymentStatus == PAYMENT_STATUS_PAID {
+		paidDate = sql.NullTime{Time: time.Now().In(time.UTC), Valid: true}
+	}
+
+	sql := `UPDATE order_payments SET payment_status = $1, payment_type = $2, expired_date = $3, payloads = $4, paid_date = $5 WHERE id = $6 and payment_status != $7`
+
+	_, err := tx.Exec(ctx, sql, oP.PaymentStatus, oP.PaymentType, expiredDate, oP.Payloads, paidDate, oP.ID, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+// CancelOrderInstallment cancel the unpaid installments of order
+func (c *Contract) CancelOrderInstallment(tx pgx.Tx, ctx context.Context, orderID int32) error {
+	_, err := tx.Exec(ctx, `UPDATE order_payments SET payment_status = $1 WHERE order_id = $2 and payment_status != $3`, PAYMENT_STATUS_CANCEL, orderID, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+// CountUnpaidOrderInstallment total installment of order that not paid yet
+func (c *Contract) CountUnpaidOrderInstallment(tx pgx.Tx, ctx context.Context, orderID int32) (int32, error) {
+	var total int32
+
+	err := tx.QueryRow(ctx, `select count(id) from order_payments where order_id = $1 and installment_no > 0 and payment_status != $2`, orderID, PAYMENT_STATUS_PAID).Scan(&total)
+
+	return total, err
+}
+
+// GetListDueOrderInstallment unpaid installment of active order that due before the date
+func (c *Contract) GetListDueOrderInstallment(db *pgxpool.Conn, ctx context.Context, dueBefore time.Time) ([]OrderEnt, error) {
+	list := []OrderEnt{}
+	var tcCode, itinTitle, title sql.NullString
+
+	sql := `
+		select
+			op.id, op.order_id, op.installment_no, op.title, op.amount, op.payment_status, op.due_date, op.reminded_date,
+			o.order_code, o.order_status, m.member_code, m.name, u.user_code, mi.title
+		from order_payments op
+		join orders o on o.id = op.order_id
+		join members m on m.id = o.paid_by
+		left join users u on u.id = o.tc_id
+		left join chat_groups cg on cg.id = o.chat_id
+		left join member_itins mi on mi.id = cg.member_itin_id
+		where op.installment_no > 0 and op.payment_status != $1 and o.order_status in ($2, $3) and op.due_date <= $4
+		order by op.due_date, op.installment_no`
+
+	rows, err := db.Query(ctx, sql, PAYMENT_STATUS_PAID, ORDER_STATUS_PENDING, ORDER_STATUS_PARTIAL, dueBefore)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var o OrderEnt
+		err = rows.Scan(&o.OrderPayment.ID, &o.ID, &o.OrderPayment.InstallmentNo, &title, &o.OrderPayment.Amount, &o.OrderPayment.PaymentStatus,
+			&o.OrderPayment.DueDate, &o.OrderPayment.RemindedDate, &o.OrderCode, &o.OrderStatus, &o.MemberEnt.MemberCode, &o.MemberEnt.Name, &tcCode, &itinTitle)
+		if err != nil {
+			return list, err
+		}
+
+		o.OrderPayment.OrderID = o.ID
+		o.OrderPayment.OrderCode = o.OrderCode
+		o.OrderPayment.Title = title.String
+		o.UserEnt.UserCode = tcCode.String
+		o.MemberItin.Title = itinTitle.String
+
+		list = append(list, o)
+	}
+
+	return list, rows.Err()
+}
+
+// RemindOrderInstallments notify the payer of installment that almost or already due, once a day
+func (c *Contract) RemindOrderInstallments(db *pgxpool.Conn, ctx context.Context) (int, error) {
+	var total int
+
+	day := c.Config.GetInt("payment.installment_reminder_day")
+	if day <= 0 {
+		day = installmentReminderDay
+	}
+	list, err := c.GetListDueOrderInstallment(db, ctx, time.Now().In(time.UTC).AddDate(0, 0, day))
+	if err != nil {
+		return total, err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return total, err
+	}
+
+	for _, o := range list {
+		if o.OrderPayment.RemindedDate.Valid && time.Since(o.OrderPayment.RemindedDate.Time) < 24*time.Hour {
+			continue
+		}
+
+		players, err := c.GetListPlayerByUserCodeAndRole(db, ctx, o.MemberEnt.MemberCode, "customer")
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		_, err = c.SendNotifications(tx, db, ctx, players, NotificationContent{
+			Subject:   NOTIF_SUBJ_ORDER_INSTALLMENT_DUE,
+			TripName:  o.MemberItin.Title,
+			OrderCode: o.OrderCode,
+			Info:      fmt.Sprintf("%s is due on %s", o.OrderPayment.Title, o.OrderPayment.DueDate.Time.Format("Jan 02, 2006")),
+		})
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE order_payments SET reminded_date = $1 WHERE id = $2`, time.Now().In(time.UTC), o.OrderPayment.ID)
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		total++
+	}
+
+	return total, tx.Commit(ctx)
+}
+
+// CancelOverdueOrderInstallments cancel the order that has installment passed the due date and grace period
+func (c *Contract) CancelOverdueOrderInstallments(db *pgxpool.Conn, ctx context.Context) (int, error) {
+	var total int
+
+	// grace period after due date, default the order is cancelled right after the due date
+	day := c.Config.GetInt("payment.installment_grace_day")
+	if day < 0 {
+		day = 0
+	}
+	list, err := c.GetListDueOrderInstallment(db, ctx, time.Now().In(time.UTC).AddDate(0, 0, -day))
+	if err != nil {
+		return total, err
+	}
+
+	userPlayers, err := c.GetListPlayerByUserCodeAndRole(db, ctx, "", "")
+	if err != nil {
+		return total, err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return total, err
+	}
+
+	cancelled := map[int32]bool{}
+	for _, o := range list {
+		if cancelled[o.ID] {
+			continue
+		}
+		cancelled[o.ID] = true
+
+		// the installment that is being paid is cancelled on the next run when it is expired
+		pending, err := c.HasPendingPayment(db, ctx, o.ID)
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		if pending {
+			continue
+		}
+
+		order, err := c.GetOrderByOrderCode(db, ctx, o.OrderCode)
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+
+		// the paid deposit of partially paid order is refunded after the fee of cancellation policy
+		transition, _, _, err := c.CancelOrder(tx, db, ctx, order, OrderStatusActor{Type: ORDER_ACTOR_SYSTEM, Name: "scheduler"},
+			fmt.Sprintf("%s is not paid after the due date", o.OrderPayment.Title))
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		if transition.History.ID == 0 {
+			continue
+		}
+
+		players, err := c.GetListPlayerByUserCodeAndRole(db, ctx, o.MemberEnt.MemberCode, "customer")
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		_, err = c.SendNotifications(tx, db, ctx, players, NotificationContent{
+			Subject:       NOTIF_SUBJ_ORDER_CANCEL,
+			TripName:      o.MemberItin.Title,
+			OrderCode:     o.OrderCode,
+			PaymentMethod: o.OrderPayment.Title,
+		})
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+
+		_, err = c.SendNotifications(tx, db, ctx, userPlayers, NotificationContent{
+			Subject:       NOTIF_SUBJ_ORDER_HISTORY,
+			TripName:      o.MemberItin.Title,
+			StatusPayment: PAYMENT_STATUS_CANCEL_DESC,
+		})
+		if err != nil {
+			tx.Rollback(ctx)
+			return total, err
+		}
+		total++
+	}
+
+	return total, tx.Commit(ctx)
+}
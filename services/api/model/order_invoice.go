@@ -0,0 +1,418 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"panorama/lib/pdf"
+	"panorama/lib/upload"
+	"panorama/lib/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	INVOICE_DOC_INVOICE = "invoice"
+	INVOICE_DOC_RECEIPT = "receipt"
+
+	companySettingGroup = "company"
+)
+
+var invoiceDocPrefix = map[string]string{
+	INVOICE_DOC_INVOICE: "INV",
+	INVOICE_DOC_RECEIPT: "RCP",
+}
+
+// OrderInvoiceEnt numbered tax invoice or payment receipt of paid order
+type OrderInvoiceEnt struct {
+	ID          int32
+	OrderID     int32
+	OrderCode   string
+	DocType     string
+	DocNo       string
+	Period      string
+	SeqNo       int32
+	Amount      int64
+	PpnAmount   int64
+	PaymentType string
+	FilePath    string
+	IssuedDate  time.Time
+	EmailedDate sql.NullTime
+	CreatedDate time.Time
+}
+
+// CompanyInfo company of the invoice issuer, loaded from settings
+type CompanyInfo struct {
+	Name    string
+	Address string
+	Npwp    string
+	Phone   string
+	Email   string
+}
+
+// OrderInvoiceItem line item of invoice that already formatted
+type OrderInvoiceItem struct {
+	No        int
+	Name      string
+	Quantity  int32
+	UnitPrice string
+	Subtotal  string
+}
+
+// OrderInvoiceData data of invoice & receipt template
+type OrderInvoiceData struct {
+	Company        CompanyInfo
+	DocNo          string
+	InvoiceNo      string
+	IssuedDate     string
+	OrderCode      string
+	OrderTitle     string
+	CustomerName   string
+	CustomerEmail  string
+	CustomerPhone  string
+	Items          []OrderInvoiceItem
+	Subtotal       string
+	PpnRate        string
+	PpnAmount      string
+	RoundingAmount string
+	Total          string
+	PaymentMethod  string
+}
+
+// FormatRupiah format amount into rupiah with thousand separator
+func FormatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	s := fmt.Sprintf("%d", amount)
+	var parts []string
+	for len(s) > 3 {
+		parts = append([]string{s[len(s)-3:]}, parts...)
+		s = s[:len(s)-3]
+	}
+	parts = append([]string{s}, parts...)
+
+	return sign + "Rp " + strings.Join(parts, ".")
+}
+
+// GetCompanyInfo company info of invoice from settings
+func (c *Contract) GetCompanyInfo(db *pgxpool.Conn, ctx context.Context) (CompanyInfo, error) {
+	var info CompanyInfo
+
+	fields := map[string]*string{
+		"name":    &info.Name,
+		"address": &info.Address,
+		"npwp":    &info.Npwp,
+		"phone":   &info.Phone,
+		"email":   &info.Email,
+	}
+	for key, field := range fields {
+		value, err := c.GetSettingValue(db, ctx, companySettingGroup, key)
+		if err != nil {
+			return info, err
+		}
+		*field = value
+	}
+
+	return info, nil
+}
+
+// nextInvoiceNo lock and increase the counter of the month, the counter is rolled back
+// together with the transaction so the number is sequential without gap
+func (c *Contract) nextInvoiceNo(tx pgx.Tx, ctx context.Context, docType string, issued time.Time) (string, int32, string, error) {
+	var seq int32
+	period := issued.In(time.Local).Format("200601")
+
+	sql := `INSERT INTO invoice_sequences(doc_type, period, last_no) VALUES($1, $2, 1)
+		ON CONFLICT (doc_type, period) DO UPDATE SET last_no = invoice_sequences.last_no + 1
+		RETURNING last_no`
+
+	err := tx.QueryRow(ctx, sql, docType, period).Scan(&seq)
+
+	return period, seq, fmt.Sprintf("%s/%s/%05d", invoiceDocPrefix[docType], period, seq), err
+}
+
+// IssueOrderInvoices number the invoice and receipt of the paid order, must be called
+// in the same transaction that complete the order. Issued document is not numbered again
+func (c *Contract) IssueOrderInvoices(tx pgx.Tx, ctx context.Context, orderID int32) ([]OrderInvoiceEnt, error) {
+	list := []OrderInvoiceEnt{}
+	timeStamp := time.Now().In(time.UTC)
+
+	var amount, ppnAmount int64
+	var orderStatus string
+	err := tx.QueryRow(ctx, `select coalesce(nullif(total_price_ppn, 0), total_price), coalesce(ppn_amount, 0), order_status from orders where id = $1`, orderID).Scan(&amount, &ppnAmount, &orderStatus)
+	if err != nil {
+		return list, err
+	}
+
+	// the cancelled or unpaid order is never invoiced
+	if !IsOrderPaid(orderStatus) {
+		return list, fmt.Errorf("order %d with status %s is not paid", orderID, orderStatus)
+	}
+
+	// payment method of the last paid payment, installment or share
+	var paymentType sql.NullString
+	err = tx.QueryRow(ctx, `select payment_type from (
+			select payment_type, coalesce(paid_date, created_date) paid_date from order_payments
+			where order_id = $1 and payment_status = $2 and coalesce(payment_type, '') != ''
+			union all
+			select payment_type, paid_date from order_payment_shares
+			where order_id = $1 and payment_status = $2 and coalesce(payment_type, '') != ''
+		) p order by paid_date desc nulls last limit 1`, orderID, PAYMENT_STATUS_PAID).Scan(&paymentType)
+	if err != nil && err != pgx.ErrNoRows {
+		return list, err
+	}
+
+	for _, docType := range []string{INVOICE_DOC_INVOICE, INVOICE_DOC_RECEIPT} {
+		var exist int32
+		err = tx.QueryRow(ctx, `select count(id) from order_invoices where order_id = $1 and doc_type = $2`, orderID, docType).Scan(&exist)
+		if err != nil {
+			return list, err
+		}
+		if exist > 0 {
+			continue
+		}
+
+		inv := OrderInvoiceEnt{
+			OrderID:     orderID,
+			DocType:     docType,
+			Amount:      amount,
+			PpnAmount:   ppnAmount,
+			PaymentType: paymentType.String,
+			IssuedDate:  timeStamp,
+			CreatedDate: timeStamp,
+		}
+		inv.Period, inv.SeqNo, inv.DocNo, err = c.nextInvoiceNo(tx, ctx, docType, timeStamp)
+		if err != nil {
+			return list, err
+		}
+
+		err = tx.QueryRow(ctx, `INSERT INTO order_invoices(order_id, doc_type, doc_no, period, seq_no, amount, ppn_amount, payment_type, issued_date, created_date)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+			inv.OrderID, inv.DocType, inv.DocNo, inv.Period, inv.SeqNo, inv.Amount, inv.PpnAmount, inv.PaymentType, inv.IssuedDate, inv.CreatedDate).Scan(&inv.ID)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, inv)
+	}
+
+	return list, nil
+}
+
+// GetListOrderInvoiceByOrderID invoice and receipt of order
+func (c *Contract) GetListOrderInvoiceByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]OrderInvoiceEnt, error) {
+	list := []OrderInvoiceEnt{}
+
+	rows, err := db.Query(ctx, `select oi.id, oi.order_id, o.order_code, oi.doc_type, oi.doc_no, oi.period, oi.seq_no, oi.amount, oi.ppn_amount,
+			oi.payment_type, oi.file_path, oi.issued_date, oi.emailed_date, oi.created_date
+		from order_invoices oi
+		join orders o on o.id = oi.order_id
+		where oi.order_id = $1 order by oi.id`, orderID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var inv OrderInvoiceEnt
+		var paymentType, filePath sql.NullString
+
+		err = rows.Scan(&inv.ID, &inv.OrderID, &inv.OrderCode, &inv.DocType, &inv.DocNo, &inv.Period, &inv.SeqNo, &inv.Amount, &inv.PpnAmount,
+			&paymentType, &filePath, &inv.IssuedDate, &inv.EmailedDate, &inv.CreatedDate)
+		if err != nil {
+			return list, err
+		}
+		inv.PaymentType = paymentType.String
+		inv.FilePath = filePath.String
+
+		list = append(list, inv)
+	}
+
+	return list, rows.Err()
+}
+
+// UpdateOrderInvoiceFile save the s3 path of the pdf
+func (c *Contract) UpdateOrderInvoiceFile(db *pgxpool.Conn, ctx context.Context, id int32, filePath string) error {
+	_, err := db.Exec(ctx, `UPDATE order_invoices SET file_path = $1, updated_date = $2 WHERE id = $3`, filePath, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+// UpdateOrderInvoiceEmailed save the date of invoice is emailed to the payer
+func (c *Contract) UpdateOrderInvoiceEmailed(db *pgxpool.Conn, ctx context.Context, id int32) error {
+	_, err := db.Exec(ctx, `UPDATE order_invoices SET emailed_date = $1 WHERE id = $2`, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+// GetOrderInvoiceData data of invoice template
+func (c *Contract) GetOrderInvoiceData(db *pgxpool.Conn, ctx context.Context, inv OrderInvoiceEnt) (OrderInvoiceData, error) {
+	var d OrderInvoiceData
+	var subtotal, ppnAmount, roundingAmount, total int64
+	var ppnRate float64
+	var email, phone sql.NullString
+
+	err := db.QueryRow(ctx, `select o.order_code, o.title, m.name, m.email, m.phone,
+			o.total_price, coalesce(o.ppn_rate, 0), coalesce(o.ppn_amount, 0), coalesce(o.rounding_amount, 0), coalesce(nullif(o.total_price_ppn, 0), o.total_price)
+		from orders o
+		join members m on m.id = o.paid_by
+		where o.id = $1`, inv.OrderID).Scan(&d.OrderCode, &d.OrderTitle, &d.CustomerName, &email, &phone,
+		&subtotal, &ppnRate, &ppnAmount, &roundingAmount, &total)
+	if err != nil {
+		return d, err
+	}
+	d.CustomerEmail = email.String
+	d.CustomerPhone = phone.String
+
+	d.Company, err = c.GetCompanyInfo(db, ctx)
+	if err != nil {
+		return d, err
+	}
+
+	items, err := c.GetListOrderItemByOrderID(db, ctx, inv.OrderID)
+	if err != nil {
+		return d, err
+	}
+	for i, a := range items {
+		// foreign price is shown with the locked rate, the amount of invoice is in rupiah
+		name := a.Name
+		if a.Currency != BASE_CURRENCY {
+			unit := Money{Amount: a.SourceUnitPrice, Currency: a.Currency, MinorUnit: a.ExchangeRate.MinorUnit}
+			name = fmt.Sprintf("%s (%s @ %s)", a.Name, unit.String(), strconv.FormatFloat(a.ExchangeRate.Rate, 'f', -1, 64))
+		}
+
+		d.Items = append(d.Items, OrderInvoiceItem{
+			No:        i + 1,
+			Name:      name,
+			Quantity:  a.Quantity,
+			UnitPrice: FormatRupiah(a.UnitPrice),
+			Subtotal:  FormatRupiah(a.Subtotal),
+		})
+	}
+
+	// receipt refer to the tax invoice of the same order
+	d.InvoiceNo = inv.DocNo
+	if inv.DocType == INVOICE_DOC_RECEIPT {
+		db.QueryRow(ctx, `select doc_no from order_invoices where order_id = $1 and doc_type = $2`, inv.OrderID, INVOICE_DOC_INVOICE).Scan(&d.InvoiceNo)
+	}
+
+	d.DocNo = inv.DocNo
+	d.IssuedDate = inv.IssuedDate.In(time.Local).Format("02 January 2006")
+	d.Subtotal = FormatRupiah(subtotal)
+	d.PpnRate = strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", ppnRate), "0"), ".")
+	d.PpnAmount = FormatRupiah(ppnAmount)
+	d.RoundingAmount = FormatRupiah(roundingAmount)
+	d.Total = FormatRupiah(total)
+	d.PaymentMethod = strings.ToUpper(strings.ReplaceAll(inv.PaymentType, "_", " "))
+
+	return d, nil
+}
+
+// RenderOrderInvoice render pdf of invoice or receipt from the template of resource path
+func (c *Contract) RenderOrderInvoice(db *pgxpool.Conn, ctx context.Context, inv OrderInvoiceEnt) ([]byte, error) {
+	data, err := c.GetOrderInvoiceData(db, ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := fmt.Sprintf("%s/order_%s.txt", c.Config.GetString("resource_path"), inv.DocType)
+	text, err := utils.ParseTpl(fn, data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := pdf.New(inv.DocNo)
+	doc.Write(text)
+
+	return doc.Bytes(), nil
+}
+
+// Filename file name of the pdf
+func (inv OrderInvoiceEnt) Filename() string {
+	return strings.ReplaceAll(inv.DocNo, "/", "-") + ".pdf"
+}
+
+// StoreOrderInvoice render the pdf then store it into the storage as private object, the pdf is only
+// downloaded with the presigned url of the invoice endpoint
+func (c *Contract) StoreOrderInvoice(db *pgxpool.Conn, ctx context.Context, inv OrderInvoiceEnt) (OrderInvoiceEnt, []byte, error) {
+	file, err := c.RenderOrderInvoice(db, ctx, inv)
+	if err != nil {
+		return inv, file, err
+	}
+
+	path := fmt.Sprintf("%s/invoices/%s", c.Config.GetString("aws.s3.filepath"), inv.Filename())
+	err = c.Storage.Put(path, file, upload.ContentTypePDF, true)
+	if err != nil {
+		return inv, file, err
+	}
+
+	err = c.UpdateOrderInvoiceFile(db, ctx, inv.ID, path)
+	inv.FilePath = path
+
+	return inv, file, err
+}
+
+// PublishOrderInvoices store the pdf of issued invoice & receipt of order then email them to the payer.
+// Called after the transaction is committed, the failed document is stored again on download
+func (c *Contract) PublishOrderInvoices(ctx context.Context, orderID int32) {
+	log := c.Log.FromContext(ctx).WithField("order_id", orderID)
+	db, err := c.DB.Acquire(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to publish the invoices")
+		return
+	}
+	defer db.Release()
+
+	invoices, err := c.GetListOrderInvoiceByOrderID(db, ctx, orderID)
+	if err != nil {
+		log.WithError(err).Error("Failed to publish the invoices")
+		return
+	}
+
+	var data OrderInvoiceData
+	var attachments []MailAttachment
+	var emailed []int32
+	for _, inv := range invoices {
+		if inv.EmailedDate.Valid {
+			continue
+		}
+
+		inv, file, err := c.StoreOrderInvoice(db, ctx, inv)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to store the invoice %d", inv.ID)
+		}
+		if len(file) == 0 {
+			continue
+		}
+
+		if inv.DocType == INVOICE_DOC_INVOICE || len(data.DocNo) == 0 {
+			data, _ = c.GetOrderInvoiceData(db, ctx, inv)
+		}
+		attachments = append(attachments, MailAttachment{Filename: inv.Filename(), Mime: upload.ContentTypePDF, Data: file})
+		emailed = append(emailed, inv.ID)
+	}
+
+	if len(attachments) == 0 || !utils.IsEmail(data.CustomerEmail) {
+		return
+	}
+
+	err = c.sendDataMailAttachments(ctx, "order_invoice", fmt.Sprintf("Invoice %s - %s", data.InvoiceNo, data.OrderTitle), data.CustomerEmail, data, attachments)
+	if err != nil {
+		log.WithError(err).Error("Failed to email the invoices")
+		return
+	}
+
+	for _, id := range emailed {
+		if err = c.UpdateOrderInvoiceEmailed(db, ctx, id); err != nil {
+			log.WithError(err).Errorf("Failed to update the emailed date of the invoice %d", id)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	ORDER_ITEM_ITINERARY   = "itinerary"
+	ORDER_ITEM_STUFF       = "stuff"
+	ORDER_ITEM_SERVICE_FEE = "service_fee"
+	ORDER_ITEM_DISCOUNT    = "discount"
+	ORDER_ITEM_VOUCHER     = "voucher"
+
+	ROUNDING_NEAREST = "nearest"
+	ROUNDING_UP      = "up"
+	ROUNDING_DOWN    = "down"
+
+	orderSettingGroup   = "order"
+	defaultPpnRate      = 11 // in percent
+	defaultRoundingUnit = 1
+)
+
+// OrderItemEnt line item of order
+type OrderItemEnt struct {
+	ID          int32
+	OrderID     int32
+	ItemType    string
+	RefCode     string
+	Name        string
+	Description string
+	Quantity    int32
+	UnitPrice   int64
+	Subtotal    int64
+	IsTaxable   bool
+	CreatedDate time.Time
+
+	// price in the source currency, unit price and subtotal above are in rupiah
+	Currency        string
+	SourceUnitPrice int64
+	ExchangeRate    ExchangeRateEnt
+}
+
+// SourceSubtotal subtotal in the source currency
+func (i OrderItemEnt) SourceSubtotal() Money {
+	amount := int64(i.Quantity) * i.SourceUnitPrice
+	if i.Subtotal < 0 {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: i.Currency, MinorUnit: i.ExchangeRate.MinorUnit}
+}
+
+// OrderPricing ppn rate and rounding rule of order total, loaded from settings
+type OrderPricing struct {
+	PpnRate      float64 // in percent
+	RoundingUnit int64
+	RoundingMode string
+}
+
+// OrderTotal server computed total of order items
+type OrderTotal struct {
+	Subtotal       int64
+	TaxableAmount  int64
+	PpnAmount      int64
+	RoundingAmount int64
+	Total          int64
+}
+
+// GetOrderPricing pricing rule of order from settings, fallback to the default rule
+func (c *Contract) GetOrderPricing(db *pgxpool.Conn, ctx context.Context) (OrderPricing, error) {
+	p := OrderPricing{
+		PpnRate:      defaultPpnRate,
+		RoundingUnit: defaultRoundingUnit,
+		RoundingMode: ROUNDING_NEAREST,
+	}
+
+	rate, err := c.GetSettingValue(db, ctx, orderSettingGroup, "ppn_rate")
+	if err != nil {
+		return p, err
+	}
+	if v, err := strconv.ParseFloat(rate, 64); err == nil && v >= 0 {
+		p.PpnRate = v
+	}
+
+	unit, err := c.GetSettingValue(db, ctx, orderSettingGroup, "rounding_unit")
+	if err != nil {
+		return p, err
+	}
+	if v, err := strconv.ParseInt(unit, 10, 64); err == nil && v > 0 {
+		p.RoundingUnit = v
+	}
+
+	mode, err := c.GetSettingValue(db, ctx, orderSettingGroup, "rounding_mode")
+	if err != nil {
+		return p, err
+	}
+	if mode == ROUNDING_UP || mode == ROUNDING_DOWN || mode == ROUNDING_NEAREST {
+		p.RoundingMode = mode
+	}
+
+	return p, nil
+}
+
+// Round round the amount into the rounding unit
+func (p OrderPricing) Round(amount int64) int64 {
+	if p.RoundingUnit <= 1 {
+		return amount
+	}
+
+	rest := amount % p.RoundingUnit
+	if rest == 0 {
+		return amount
+	}
+
+	switch p.RoundingMode {
+	case ROUNDING_UP:
+		return amount - rest + p.RoundingUnit
+	case ROUNDING_DOWN:
+		return amount - rest
+	default:
+		if rest*2 >= p.RoundingUnit {
+			return amount - rest + p.RoundingUnit
+		}
+		return amount - rest
+	}
+}
+
+// Calculate subtotal of items, ppn of taxable items (half up) and the rounded total.
+// Subtotal of discount item is negative, taxable discount is deducted before ppn
+func (p OrderPricing) Calculate(items []OrderItemEnt) OrderTotal {
+	var t OrderTotal
+
+	for _, i := range items {
+		t.Subtotal += i.Subtotal
+		if i.IsTaxable {
+			t.TaxableAmount += i.Subtotal
+		}
+	}
+	if t.TaxableAmount < 0 {
+		t.TaxableAmount = 0
+	}
+
+	rateBps := int64(math.Round(p.PpnRate * 100))
+	t.PpnAmount = (t.TaxableAmount*rateBps + 5000) / 10000
+
+	t.Total = p.Round(t.Subtotal + t.PpnAmount)
+	t.RoundingAmount = t.Total - (t.Subtotal + t.PpnAmount)
+
+	return t
+}
+
+var nonDigit = regexp.MustCompile(`[^0-9]`)
+
+// GetStuffPrice active stuff of catalog with price in the currency of stuff, the price of stuff is saved as text
+func (c *Contract) GetStuffPrice(db *pgxpool.Conn, ctx context.Context, code string) (StuffEnt, Money, error) {
+	var s StuffEnt
+	var cur CurrencyEnt
+
+	err := db.QueryRow(ctx, `select s.id, s.code_stuff, s.name_stuff, s.description, s.price, cu.currency_code, cu.minor_unit
+		from stuff s
+		join currencies cu on cu.currency_code = s.currency_code
+		where s.code_stuff = $1 and s.is_active = true and s.deleted_date is null limit 1`, code).
+		Scan(&s.ID, &s.Code, &s.Name, &s.Description, &s.Price, &cur.Code, &cur.MinorUnit)
+	if err != nil {
+		return s, Money{}, err
+	}
+	s.CurrencyCode = cur.Code
+
+	price, _ := ParseMoney(s.Price, cur)
+
+	return s, price, nil
+}
+
+// AddOrderItems replace line items of order
+func (c *Contract) AddOrderItems(tx pgx.Tx, ctx context.Context, orderID int32, items []OrderItemEnt) ([]OrderItemEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	_, err := tx.Exec(ctx, `delete from order_items where order_id = $1`, orderID)
+	if err != nil {
+		return items, err
+	}
+
+	sql := `INSERT INTO order_items(order_id, item_type, ref_code, name, description, quantity, unit_price, subtotal, is_taxable, created_date, currency_code, source_unit_price, exchange_rate)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
+
+	for k, i := range items {
+		if len(i.Currency) == 0 || i.Currency == BASE_CURRENCY {
+			i.Currency = BASE_CURRENCY
+			i.SourceUnitPrice = i.UnitPrice
+			i.ExchangeRate = ExchangeRateEnt{CurrencyCode: BASE_CURRENCY, Rate: 1}
+			items[k] = i
+		}
+
+		err = tx.QueryRow(ctx, sql, orderID, i.ItemType, i.RefCode, i.Name, i.Description, i.Quantity, i.UnitPrice, i.Subtotal, i.IsTaxable, timeStamp,
+			i.Currency, i.SourceUnitPrice, i.ExchangeRate.Rate).Scan(&items[k].ID)
+		if err != nil {
+			return items, err
+		}
+		items[k].OrderID = orderID
+		items[k].CreatedDate = timeStamp
+	}
+
+	return items, nil
+}
+
+// GetListOrderItemByOrderID line items of order
+func (c *Contract) GetListOrderItemByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]OrderItemEnt, error) {
+	list := []OrderItemEnt{}
+
+	rows, err := db.Query(ctx, `select oi.id, oi.order_id, oi.item_type, oi.ref_code, oi.name, oi.description, oi.quantity, oi.unit_price, oi.subtotal, oi.is_taxable, oi.created_date,
+		oi.currency_code, coalesce(oi.source_unit_price, oi.unit_price), coalesce(oi.exchange_rate, 1), cu.minor_unit
+		from order_items oi
+		join currencies cu on cu.currency_code = oi.currency_code
+		where oi.order_id = $1 order by oi.id`, orderID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var i OrderItemEnt
+		var refCode, description sql.NullString
+
+		err = rows.Scan(&i.ID, &i.OrderID, &i.ItemType, &refCode, &i.Name, &description, &i.Quantity, &i.UnitPrice, &i.Subtotal, &i.IsTaxable, &i.CreatedDate,
+			&i.Currency, &i.SourceUnitPrice, &i.ExchangeRate.Rate, &i.ExchangeRate.MinorUnit)
+		if err != nil {
+			return list, err
+		}
+		i.ExchangeRate.CurrencyCode = i.Currency
+		i.RefCode = refCode.String
+		i.Description = description.String
+
+		list = append(list, i)
+	}
+
+	return list, rows.Err()
+}
+
+// UpdateOrderPricing save the computed total of order
+func (c *Contract) UpdateOrderPricing(tx pgx.Tx, ctx context.Context, orderID int32, p OrderPricing, t OrderTotal) error {
+	sql := `UPDATE orders SET total_price = $1, total_price_ppn = $2, ppn_rate = $3, ppn_amount = $4, rounding_amount = $5 WHERE id = $6`
+
+	_, err := tx.Exec(ctx, sql, t.Subtotal, t.Total, p.PpnRate, t.PpnAmount, t.RoundingAmount, orderID)
+
+	return err
+}
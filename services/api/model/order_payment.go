@@ -3,6 +3,9 @@ package model
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"math/rand"
+	"panorama/lib/utils"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -17,6 +20,7 @@ const (
 	PAYMENT_STATUS_PROCESS_METHOD_DESC = "Waiting For Payment Method"
 	PAYMENT_STATUS_PAID_DESC           = "Completed"
 	PAYMENT_STATUS_CANCEL_DESC         = "Cancel"
+	PAYMENT_STATUS_PARTIAL_DESC        = "Partially Paid"
 )
 
 type OrderPaymentEnt struct {
@@ -30,6 +34,24 @@ type OrderPaymentEnt struct {
 	PaymentURL    string
 	Payloads      map[string]interface{}
 	OrderCode     string
+	InstallmentNo int32 // 0 is the full payment or summary of installments
+	Title         string
+	PaymentCode   string
+	DueDate       sql.NullTime
+	PaidDate      sql.NullTime
+	RemindedDate  sql.NullTime
+}
+
+// IsPending payment has midtrans transaction that still waiting to be paid
+func (oP OrderPaymentEnt) IsPending() bool {
+	return oP.PaymentStatus == PAYMENT_STATUS_PROCESS && len(oP.PaymentType) > 0 && oP.ExpiredDate.After(time.Now())
+}
+
+// SetPaymentTransactionCode midtrans order id of partial payment, renewed on each payment request
+func (c *Contract) SetPaymentTransactionCode(prefix string) string {
+	rand.Seed(time.Now().UnixNano())
+	code, _ := utils.Generate(`[a-z0-9]{4}`)
+	return fmt.Sprintf("%s-%s", prefix, code)
 }
 
 // AddOrderPayment add new order payments
@@ -52,7 +74,7 @@ func (c *Contract) UpdateOrderPayment(tx pgx.Tx, ctx context.Context, oP OrderPa
 	var ID int32
 	var paramQuery []interface{}
 
-	sql := `UPDATE order_payments SET order_id=$1, payment_type=$2, amount=$3, payment_status=$4, expired_date=$5, payment_url=$6, payloads=$7 WHERE order_id=$8 RETURNING id`
+	sql := `UPDATE order_payments SET order_id=$1, payment_type=$2, amount=$3, payment_status=$4, expired_date=$5, payment_url=$6, payloads=$7 WHERE order_id=$8 AND installment_no=0 RETURNING id`
 
 	if oP.PaymentType == "" && oP.ExpiredDate.IsZero() && oP.Payloads == nil {
 		paramQuery = append(paramQuery, oP.OrderID, nil, oP.Amount, oP.PaymentStatus, nil, oP.PaymentURL, nil, orderID)
@@ -69,6 +91,13 @@ func (c *Contract) UpdateOrderPayment(tx pgx.Tx, ctx context.Context, oP OrderPa
 	return oP, err
 }
 
+// UpdateOrderPaymentStatus update status of order payments
+func (c *Contract) UpdateOrderPaymentStatus(tx pgx.Tx, ctx context.Context, orderID int32, status string) error {
+	_, err := tx.Exec(ctx, `UPDATE order_payments SET payment_status=$1 WHERE order_id=$2 AND installment_no=0`, status, orderID)
+
+	return err
+}
+
 // GetPaymentOrderByOrderID Get Order payment by Order ID
 func (c *Contract) GetPaymentOrderByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) (OrderPaymentEnt, error) {
 	var oP OrderPaymentEnt
@@ -76,7 +105,7 @@ func (c *Contract) GetPaymentOrderByOrderID(db *pgxpool.Conn, ctx context.Contex
 	var orderNullID sql.NullInt32
 	var expiredDate sql.NullTime
 
-	sqlM := `SELECT id, order_id, payment_type, amount, payment_status, expired_date, created_date, payment_url, payloads FROM order_payments WHERE order_id = $1`
+	sqlM := `SELECT id, order_id, payment_type, amount, payment_status, expired_date, created_date, payment_url, payloads FROM order_payments WHERE order_id = $1 AND installment_no = 0`
 
 	err := db.QueryRow(ctx, sqlM, orderID).Scan(&oP.ID, &orderNullID, &paymentType, &oP.Amount, &oP.PaymentStatus, &expiredDate, &oP.CreatedDate, &paymentURL, &oP.Payloads)
 
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"panorama/lib/utils"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	ORDER_SPLIT_EQUAL  = "equal"
+	ORDER_SPLIT_CUSTOM = "custom"
+
+	orderShareReminderHour = 24 // in hours
+)
+
+// OrderPaymentShareEnt share of order payment that paid by a companion
+type OrderPaymentShareEnt struct {
+	ID              int32
+	ShareCode       string
+	OrderID         int32
+	OrderCode       string
+	MemberID        int32
+	Amount          int64
+	PaymentStatus   string
+	PaymentType     string
+	PaymentURL      string
+	TransactionCode string
+	CoveredBy       sql.NullInt32
+	Payloads        map[string]interface{}
+	ExpiredDate     sql.NullTime
+	PaidDate        sql.NullTime
+	RemindedDate    sql.NullTime
+	CreatedDate     time.Time
+	Member          MemberEnt
+	CoveredByCode   string
+	CoveredByName   string
+}
+
+// IsPending share has midtrans payment that still waiting to be paid
+func (s OrderPaymentShareEnt) IsPending() bool {
+	return s.PaymentStatus == PAYMENT_STATUS_PROCESS && len(s.PaymentType) > 0 && s.ExpiredDate.Valid && s.ExpiredDate.Time.After(time.Now())
+}
+
+func (c *Contract) SetOrderShareCode() string {
+	rand.Seed(time.Now().UnixNano())
+	code, _ := utils.Generate(`[a-z0-9]{8}`)
+	return fmt.Sprintf("SHR-%s-%s", time.Now().In(time.Local).Format("060102"), code)
+}
+
+// OrderShareReminderInterval minimum interval between reminder of unpaid share
+func (c *Contract) OrderShareReminderInterval() time.Duration {
+	hour := c.Config.GetInt("payment.share_reminder_hour")
+	if hour <= 0 {
+		hour = orderShareReminderHour
+	}
+
+	return time.Duration(hour) * time.Hour
+}
+
+// AddOrderPaymentShare add new share of order payment
+func (c *Contract) AddOrderPaymentShare(tx pgx.Tx, ctx context.Context, s OrderPaymentShareEnt) (OrderPaymentShareEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO order_payment_shares(share_code, order_id, member_id, amount, payment_status, created_date) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, s.ShareCode, s.OrderID, s.MemberID, s.Amount, PAYMENT_STATUS_PROCESS, timeStamp).Scan(&lastInsID)
+
+	s.ID = lastInsID
+	s.PaymentStatus = PAYMENT_STATUS_PROCESS
+	s.CreatedDate = timeStamp
+
+	return s, err
+}
+
+// DeleteOrderPaymentShareByOrderID remove all share of order before the order split again
+func (c *Contract) DeleteOrderPaymentShareByOrderID(tx pgx.Tx, ctx context.Context, orderID int32) error {
+	_, err := tx.Exec(ctx, `delete from order_payment_shares where order_id = $1`, orderID)
+
+	return err
+}
+
+// CancelOrderPaymentShare cancel the unpaid shares of order
+func (c *Contract) CancelOrderPaymentShare(tx pgx.Tx, ctx context.Context, orderID int32) error {
+	_, err := tx.Exec(ctx, `UPDATE order_payment_shares SET payment_status = $1 WHERE order_id = $2 and payment_status != $3`, PAYMENT_STATUS_CANCEL, orderID, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+const orderPaymentShareSelect = `
+	select
+		ops.id, ops.share_code, ops.order_id, o.order_code, ops.member_id, ops.amount, ops.payment_status,
+		ops.payment_type, ops.payment_url, ops.transaction_code, ops.covered_by, ops.payloads,
+		ops.expired_date, ops.paid_date, ops.reminded_date, ops.created_date,
+		m.member_code, m.name, m.email, m.img,
+		cb.member_code covered_by_code, cb.name covered_by_name
+	from order_payment_shares ops
+	join orders o on o.id = ops.order_id
+	join members m on m.id = ops.member_id
+	left join members cb on cb.id = ops.covered_by `
+
+func scanOrderPaymentShare(row pgx.Row) (OrderPaymentShareEnt, error) {
+	var s OrderPaymentShareEnt
+	var paymentType, paymentURL, transactionCode, coveredByCode, coveredByName sql.NullString
+
+	err := row.Scan(&s.ID, &s.ShareCode, &s.OrderID, &s.OrderCode, &s.MemberID, &s.Amount, &s.PaymentStatus,
+		&paymentType, &paymentURL, &transactionCode, &s.CoveredBy, &s.Payloads,
+		&s.ExpiredDate, &s.PaidDate, &s.RemindedDate, &s.CreatedDate,
+		&s.Member.MemberCode, &s.Member.Name, &s.Member.Email, &s.Member.Img,
+		&coveredByCode, &coveredByName)
+	s.PaymentType = paymentType.String
+	s.PaymentURL = paymentURL.String
+	s.TransactionCode = transactionCode.String
+	s.CoveredByCode = coveredByCode.String
+	s.CoveredByName = coveredByName.String
+	s.Member.ID = s.MemberID
+
+	return s, err
+}
+
+func (c *Contract) getListOrderPaymentShare(db *pgxpool.Conn, ctx context.Context, sql string, args ...interface{}) ([]OrderPaymentShareEnt, error) {
+	list := []OrderPaymentShareEnt{}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		s, err := scanOrderPaymentShare(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, s)
+	}
+
+	return list, rows.Err()
+}
+
+// GetListOrderPaymentShareByOrderID list share of order
+func (c *Contract) GetListOrderPaymentShareByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]OrderPaymentShareEnt, error) {
+	return c.getListOrderPaymentShare(db, ctx, orderPaymentShareSelect+`where ops.order_id = $1 order by ops.id`, orderID)
+}
+
+// GetListOrderPaymentShareByTransactionCode list share paid by the midtrans transaction
+func (c *Contract) GetListOrderPaymentShareByTransactionCode(db *pgxpool.Conn, ctx context.Context, transactionCode string) ([]OrderPaymentShareEnt, error) {
+	return c.getListOrderPaymentShare(db, ctx, orderPaymentShareSelect+`where ops.transaction_code = $1 order by ops.id`, transactionCode)
+}
+
+// GetOrderPaymentShareByMemberID share of the member in order
+func (c *Contract) GetOrderPaymentShareByMemberID(db *pgxpool.Conn, ctx context.Context, orderID, memberID int32) (OrderPaymentShareEnt, error) {
+	sql := orderPaymentShareSelect + `where ops.order_id = $1 and ops.member_id = $2 limit 1`
+
+	return scanOrderPaymentShare(db.QueryRow(ctx, sql, orderID, memberID))
+}
+
+// UpdateOrderPaymentShareTransaction assign new midtrans transaction into the shares
+func (c *Contract) UpdateOrderPaymentShareTransaction(tx pgx.Tx, ctx context.Context, ids []int32, transactionCode, paymentURL string, coveredBy sql.NullInt32) error {
+	sql := `UPDATE order_payment_shares
+		SET transaction_code = $1, payment_url = $2, covered_by = $3, payment_status = $4, payment_type = null, expired_date = null, updated_date = $5
+		WHERE id = any($6) and payment_status != $7`
+
+	_, err := tx.Exec(ctx, sql, transactionCode, paymentURL, coveredBy, PAYMENT_STATUS_PROCESS, time.Now().In(time.UTC), ids, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+// UpdateOrderPaymentShareStatus update status of shares from midtrans notification
+func (c *Contract) UpdateOrderPaymentShareStatus(tx pgx.Tx, ctx context.Context, transactionCode string, s OrderPaymentShareEnt) error {
+	timeStamp := time.Now().In(time.UTC)
+
+	var paidDate sql.NullTime
+	if s.PaymentStatus == PAYMENT_STATUS_PAID {
+		paidDate = sql.NullTime{Time: timeStamp, Valid: true}
+	}
+
+	sql := `UPDATE order_payment_shares
+		SET payment_status = $1, payment_type = $2, expired_date = $3, payloads = $4, paid_date = $5, updated_date = $6
+		WHERE transaction_code = $7 and payment_status != $8`
+
+	_, err := tx.Exec(ctx, sql, s.PaymentStatus, s.PaymentType, s.ExpiredDate, s.Payloads, paidDate, timeStamp, transactionCode, PAYMENT_STATUS_PAID)
+
+	return err
+}
+
+// UpdateOrderPaymentShareReminded save the last reminder date of share
+func (c *Contract) UpdateOrderPaymentShareReminded(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `UPDATE order_payment_shares SET reminded_date = $1 WHERE id = $2`, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+// CountUnpaidOrderPaymentShare total share of order that not paid yet
+func (c *Contract) CountUnpaidOrderPaymentShare(tx pgx.Tx, ctx context.Context, orderID int32) (int32, error) {
+	var total int32
+
+	err := tx.QueryRow(ctx, `select count(id) from order_payment_shares where order_id = $1 and payment_status != $2`, orderID, PAYMENT_STATUS_PAID).Scan(&total)
+
+	return total, err
+}
@@ -0,0 +1,270 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	ORDER_ACTOR_USER   = "user"
+	ORDER_ACTOR_MEMBER = "member"
+	ORDER_ACTOR_SYSTEM = "system"
+)
+
+// orderTransitions allowed next status of each order status
+var orderTransitions = map[string][]string{
+	ORDER_STATUS_DRAFT:       {ORDER_STATUS_PENDING, ORDER_STATUS_CANCEL},
+	ORDER_STATUS_PENDING:     {ORDER_STATUS_PARTIAL, ORDER_STATUS_PAID, ORDER_STATUS_CANCEL},
+	ORDER_STATUS_PARTIAL:     {ORDER_STATUS_PAID, ORDER_STATUS_CANCEL, ORDER_STATUS_REFUNDED},
+	ORDER_STATUS_PAID:        {ORDER_STATUS_IN_PROGRESS, ORDER_STATUS_COMPLETED, ORDER_STATUS_CANCEL, ORDER_STATUS_REFUNDED},
+	ORDER_STATUS_IN_PROGRESS: {ORDER_STATUS_COMPLETED, ORDER_STATUS_REFUNDED},
+	ORDER_STATUS_COMPLETED:   {ORDER_STATUS_REFUNDED},
+	ORDER_STATUS_CANCEL:      {ORDER_STATUS_PENDING, ORDER_STATUS_REFUNDED}, // reopen when the failed payment is renewed
+	ORDER_STATUS_REFUNDED:    {},
+}
+
+// orderActorDeniedTransitions transitions of the lifecycle that the actor can not make, the paid order
+// is cancelled only by the user or the member so a late failed notification does not cancel it
+var orderActorDeniedTransitions = map[string]map[string][]string{
+	ORDER_ACTOR_SYSTEM: {
+		ORDER_STATUS_PAID: {ORDER_STATUS_CANCEL},
+	},
+}
+
+var orderStatusDesc = map[string]string{
+	ORDER_STATUS_DRAFT:       "Draft",
+	ORDER_STATUS_PENDING:     "Waiting For Payment",
+	ORDER_STATUS_PARTIAL:     "Partially Paid",
+	ORDER_STATUS_PAID:        "Paid",
+	ORDER_STATUS_IN_PROGRESS: "In Progress",
+	ORDER_STATUS_COMPLETED:   "Completed",
+	ORDER_STATUS_CANCEL:      "Cancel",
+	ORDER_STATUS_REFUNDED:    "Refunded",
+}
+
+// OrderStatusDesc description of order status
+func OrderStatusDesc(status string) string {
+	if desc, ok := orderStatusDesc[status]; ok {
+		return desc
+	}
+
+	return status
+}
+
+// CanTransitionOrder whether the order can be moved by the actor from the status into the next status
+func CanTransitionOrder(actorType, from, to string) bool {
+	for _, s := range orderActorDeniedTransitions[actorType][from] {
+		if s == to {
+			return false
+		}
+	}
+
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsOrderPaid status of order that is already fully paid
+func IsOrderPaid(status string) bool {
+	return status == ORDER_STATUS_PAID || status == ORDER_STATUS_IN_PROGRESS || status == ORDER_STATUS_COMPLETED
+}
+
+// OrderTransitionError status change that is not allowed by the order lifecycle
+type OrderTransitionError struct {
+	From string
+	To   string
+}
+
+func (e OrderTransitionError) Error() string {
+	return fmt.Sprintf("Order status can not be changed from %s to %s.", OrderStatusDesc(e.From), OrderStatusDesc(e.To))
+}
+
+// OrderStatusActor user, member or system (webhook, scheduler) that change the status of order
+type OrderStatusActor struct {
+	Type string
+	ID   sql.NullInt32
+	Name string
+}
+
+// OrderStatusHistoryEnt ...
+type OrderStatusHistoryEnt struct {
+	ID          int32
+	OrderID     int32
+	FromStatus  sql.NullString
+	ToStatus    string
+	ActorType   string
+	ActorID     sql.NullInt32
+	ActorName   string
+	Reason      sql.NullString
+	CreatedDate time.Time
+}
+
+// OrderTransition status change of order, passed into the hooks
+type OrderTransition struct {
+	Order   OrderEnt
+	From    string
+	To      string
+	Actor   OrderStatusActor
+	History OrderStatusHistoryEnt
+}
+
+// OrderStatusHook run in the same transaction after the order is moved into the status
+type OrderStatusHook func(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, c *Contract, t OrderTransition) error
+
+var orderStatusHooks = map[string][]OrderStatusHook{}
+
+// OnOrderStatus register hook of the order status
+func OnOrderStatus(status string, hook OrderStatusHook) {
+	orderStatusHooks[status] = append(orderStatusHooks[status], hook)
+}
+
+func init() {
+	for _, s := range []string{ORDER_STATUS_PENDING, ORDER_STATUS_IN_PROGRESS, ORDER_STATUS_COMPLETED, ORDER_STATUS_CANCEL, ORDER_STATUS_REFUNDED} {
+		OnOrderStatus(s, notifyOrderStatus)
+	}
+}
+
+// notifyOrderStatus notify the member and tc of order, the payment result (paid, partially paid and
+// failed payment) and the cancellation by customer are already notified by their own flow
+func notifyOrderStatus(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, c *Contract, t OrderTransition) error {
+	if t.To == ORDER_STATUS_CANCEL && t.Actor.Type != ORDER_ACTOR_USER {
+		return nil
+	}
+
+	content := NotificationContent{
+		Subject:     NOTIF_SUBJ_ORDER_STATUS,
+		TripName:    t.Order.Title,
+		OrderCode:   t.Order.OrderCode,
+		OrderStatus: OrderStatusDesc(t.To),
+	}
+
+	memberPlayers, err := c.GetListPlayerByUserCodeAndRole(db, ctx, t.Order.MemberEnt.MemberCode, "customer")
+	if err != nil {
+		return err
+	}
+	if _, err = c.SendNotifications(tx, db, ctx, memberPlayers, content); err != nil {
+		return err
+	}
+
+	if len(t.Order.UserEnt.UserCode) == 0 {
+		return nil
+	}
+	tcPlayers, err := c.GetListPlayerByUserCodeAndRole(db, ctx, t.Order.UserEnt.UserCode, "tc")
+	if err != nil {
+		return err
+	}
+	_, err = c.SendNotifications(tx, db, ctx, tcPlayers, content)
+
+	return err
+}
+
+// AddOrderStatusHistory record status of order, the from status is null when the order is created
+func (c *Contract) AddOrderStatusHistory(tx pgx.Tx, ctx context.Context, h OrderStatusHistoryEnt) (OrderStatusHistoryEnt, error) {
+	h.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO order_status_history(order_id, from_status, to_status, actor_type, actor_id, actor_name, reason, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, h.OrderID, h.FromStatus, h.ToStatus, h.ActorType, h.ActorID, h.ActorName, h.Reason, h.CreatedDate).Scan(&h.ID)
+
+	return h, err
+}
+
+// TransitionOrderStatus move the order into the status when it is allowed by the lifecycle, record the
+// history and run the hooks of the status. Nothing is changed when the order is already in the status
+func (c *Contract) TransitionOrderStatus(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, orderID int32, to string, actor OrderStatusActor, reason string) (OrderTransition, error) {
+	t := OrderTransition{To: to, Actor: actor}
+	var title, memberCode, tcCode sql.NullString
+
+	// lock the order so the concurrent notifications are applied one by one
+	err := tx.QueryRow(ctx, `select o.id, o.order_code, o.order_status, o.title, m.member_code, u.user_code
+		from orders o
+		left join members m on m.id = o.paid_by
+		left join users u on u.id = o.tc_id
+		where o.id = $1 for update of o`, orderID).
+		Scan(&t.Order.ID, &t.Order.OrderCode, &t.From, &title, &memberCode, &tcCode)
+	if err != nil {
+		return t, err
+	}
+	t.Order.Title = title.String
+	t.Order.MemberEnt.MemberCode = memberCode.String
+	t.Order.UserEnt.UserCode = tcCode.String
+
+	if t.From == to {
+		t.Order.OrderStatus = to
+		return t, nil
+	}
+	if !CanTransitionOrder(actor.Type, t.From, to) {
+		return t, OrderTransitionError{From: t.From, To: to}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE orders SET order_status=$1 WHERE id=$2`, to, orderID)
+	if err != nil {
+		return t, err
+	}
+	t.Order.OrderStatus = to
+
+	t.History, err = c.AddOrderStatusHistory(tx, ctx, OrderStatusHistoryEnt{
+		OrderID:    orderID,
+		FromStatus: sql.NullString{String: t.From, Valid: true},
+		ToStatus:   to,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		Reason:     sql.NullString{String: reason, Valid: len(reason) > 0},
+	})
+	if err != nil {
+		return t, err
+	}
+
+	for _, hook := range orderStatusHooks[to] {
+		if err = hook(tx, db, ctx, c, t); err != nil {
+			return t, err
+		}
+	}
+
+	return t, nil
+}
+
+// GetLastOrderStatusHistory the latest status change of order
+func (c *Contract) GetLastOrderStatusHistory(db *pgxpool.Conn, ctx context.Context, orderID int32) (OrderStatusHistoryEnt, error) {
+	var h OrderStatusHistoryEnt
+
+	err := db.QueryRow(ctx, `select id, order_id, from_status, to_status, actor_type, actor_id, actor_name, reason, created_date
+		from order_status_history where order_id = $1 order by created_date desc, id desc limit 1`, orderID).
+		Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &h.ActorType, &h.ActorID, &h.ActorName, &h.Reason, &h.CreatedDate)
+
+	return h, err
+}
+
+// GetListOrderStatusHistory status history of order, the oldest first
+func (c *Contract) GetListOrderStatusHistory(db *pgxpool.Conn, ctx context.Context, orderID int32) ([]OrderStatusHistoryEnt, error) {
+	list := []OrderStatusHistoryEnt{}
+
+	rows, err := db.Query(ctx, `select id, order_id, from_status, to_status, actor_type, actor_id, actor_name, reason, created_date
+		from order_status_history where order_id = $1 order by created_date, id`, orderID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var h OrderStatusHistoryEnt
+		err = rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &h.ActorType, &h.ActorID, &h.ActorName, &h.Reason, &h.CreatedDate)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, h)
+	}
+
+	return list, rows.Err()
+}
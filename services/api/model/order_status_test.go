@@ -0,0 +1,18 @@
+package model
+
+import "testing"
+
+func TestCanTransitionOrder(t *testing.T) {
+	if CanTransitionOrder(ORDER_ACTOR_SYSTEM, ORDER_STATUS_PAID, ORDER_STATUS_CANCEL) {
+		t.Fatal("paid order is cancelled by the system")
+	}
+	if !CanTransitionOrder(ORDER_ACTOR_USER, ORDER_STATUS_PAID, ORDER_STATUS_CANCEL) {
+		t.Fatal("paid order is not cancelled by the user")
+	}
+	if !CanTransitionOrder(ORDER_ACTOR_SYSTEM, ORDER_STATUS_PENDING, ORDER_STATUS_CANCEL) {
+		t.Fatal("pending order is not cancelled by the system")
+	}
+	if CanTransitionOrder(ORDER_ACTOR_MEMBER, ORDER_STATUS_REFUNDED, ORDER_STATUS_PAID) {
+		t.Fatal("refunded order is moved into paid")
+	}
+}
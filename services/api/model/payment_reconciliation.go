@@ -0,0 +1,579 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	RECON_RESULT_MATCHED         = "matched"
+	RECON_RESULT_PENDING_LOCAL   = "pending_local"   // settled in midtrans but not paid here
+	RECON_RESULT_AMOUNT_MISMATCH = "amount_mismatch" // settled amount is different with the payment amount
+	RECON_RESULT_UNKNOWN_ORDER   = "unknown_order"   // midtrans order id does not match any payment
+	RECON_RESULT_MISSING         = "missing"         // paid here but not settled in midtrans
+	RECON_RESULT_SKIPPED         = "skipped"         // row of midtrans that is not settled (e.g. refund)
+)
+
+// midtransTimezone transaction time of midtrans export is in WIB
+var midtransTimezone = time.FixedZone("WIB", 7*60*60)
+
+// SettlementRow transaction of midtrans settlement export
+type SettlementRow struct {
+	Line              int
+	OrderID           string
+	TransactionID     string
+	PaymentType       string
+	TransactionStatus string
+	TransactionTime   sql.NullTime
+	Amount            int64
+}
+
+// IsSettled money of the transaction is settled by midtrans
+func (s SettlementRow) IsSettled() bool {
+	return IsMidtransSettled(s.TransactionStatus)
+}
+
+// IsMidtransSettled transaction status of midtrans that the money is settled
+func IsMidtransSettled(status string) bool {
+	return status == "settlement" || status == "capture"
+}
+
+// MidtransPaymentEnt local payment (order payment, installment or shares) of midtrans order id
+type MidtransPaymentEnt struct {
+	MidtransOrderID string
+	Source          string
+	OrderCode       string
+	Amount          int64
+	PaymentStatus   string
+	PaidDate        sql.NullTime
+}
+
+// PaymentReconciliationEnt result of settlement file that matched with the local payments
+type PaymentReconciliationEnt struct {
+	ID             int32
+	FileName       string
+	PeriodStart    sql.NullTime
+	PeriodEnd      sql.NullTime
+	TotalRows      int32
+	MatchedRows    int32
+	MismatchedRows int32
+	SettledAmount  int64
+	CreatedBy      sql.NullInt32
+	CreatedDate    time.Time
+	Items          []PaymentReconciliationItemEnt
+}
+
+// PaymentReconciliationItemEnt row of reconciliation
+type PaymentReconciliationItemEnt struct {
+	ID               int32
+	ReconciliationID int32
+	MidtransOrderID  string
+	OrderCode        sql.NullString
+	PaymentSource    sql.NullString
+	TransactionID    sql.NullString
+	PaymentType      sql.NullString
+	TransactionTime  sql.NullTime
+	MidtransStatus   sql.NullString
+	MidtransAmount   sql.NullInt64
+	LocalStatus      sql.NullString
+	LocalAmount      sql.NullInt64
+	Result           string
+	Note             sql.NullString
+	CheckedDate      sql.NullTime
+}
+
+// ParseMidtransAmount parse amount of midtrans (e.g. 150000.00) into rupiah
+func ParseMidtransAmount(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %s", s)
+	}
+
+	return int64(math.Round(value)), nil
+}
+
+func parseMidtransTime(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), midtransTimezone); err == nil {
+			return t.In(time.UTC), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %s", s)
+}
+
+// settlementColumns accepted header of settlement export, the header is matched case insensitive
+var settlementColumns = map[string][]string{
+	"order_id":           {"order_id"},
+	"amount":             {"gross_amount", "amount"},
+	"transaction_id":     {"transaction_id"},
+	"payment_type":       {"payment_type"},
+	"transaction_status": {"transaction_status", "status"},
+	"transaction_time":   {"transaction_time", "settlement_time"},
+}
+
+// ParseSettlementFile read midtrans settlement export (csv), order id and gross amount column is required
+func ParseSettlementFile(r io.Reader) ([]SettlementRow, error) {
+	rows := []SettlementRow{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return rows, fmt.Errorf("Settlement file is empty.")
+	}
+	if err != nil {
+		return rows, err
+	}
+
+	index := map[string]int{}
+	for i, h := range header {
+		h = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))), " ", "_")
+		for col, names := range settlementColumns {
+			for _, name := range names {
+				if _, ok := index[col]; !ok && h == name {
+					index[col] = i
+				}
+			}
+		}
+	}
+	if _, ok := index["order_id"]; !ok {
+		return rows, fmt.Errorf("Column order_id is required.")
+	}
+	if _, ok := index["amount"]; !ok {
+		return rows, fmt.Errorf("Column gross_amount is required.")
+	}
+
+	value := func(record []string, col string) string {
+		i, ok := index[col]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+
+		// empty row is skipped
+		if len(value(record, "order_id")) == 0 {
+			continue
+		}
+
+		row := SettlementRow{
+			Line:              line,
+			OrderID:           value(record, "order_id"),
+			TransactionID:     value(record, "transaction_id"),
+			PaymentType:       value(record, "payment_type"),
+			TransactionStatus: strings.ToLower(value(record, "transaction_status")),
+		}
+		// settlement report only has the settled transaction
+		if len(row.TransactionStatus) == 0 {
+			row.TransactionStatus = "settlement"
+		}
+
+		row.Amount, err = ParseMidtransAmount(value(record, "amount"))
+		if err != nil {
+			return rows, fmt.Errorf("Line %d: %s", line, err.Error())
+		}
+
+		if t := value(record, "transaction_time"); len(t) > 0 {
+			transactionTime, err := parseMidtransTime(t)
+			if err != nil {
+				return rows, fmt.Errorf("Line %d: %s", line, err.Error())
+			}
+			row.TransactionTime = sql.NullTime{Time: transactionTime, Valid: true}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return rows, fmt.Errorf("Settlement file is empty.")
+	}
+
+	return rows, nil
+}
+
+// Reconcile compare the transaction of midtrans with the local payment, the payment without source is not
+// found and the item without midtrans status is paid here but not found in midtrans
+func (i *PaymentReconciliationItemEnt) Reconcile(p MidtransPaymentEnt) {
+	if len(p.Source) > 0 {
+		i.OrderCode = sql.NullString{String: p.OrderCode, Valid: true}
+		i.PaymentSource = sql.NullString{String: p.Source, Valid: true}
+		i.LocalStatus = sql.NullString{String: p.PaymentStatus, Valid: true}
+		i.LocalAmount = sql.NullInt64{Int64: p.Amount, Valid: true}
+	}
+	i.Note = sql.NullString{}
+
+	switch {
+	case !i.MidtransStatus.Valid:
+		i.Result = RECON_RESULT_MISSING
+		i.Note = sql.NullString{String: "Paid here but not settled in midtrans.", Valid: true}
+	case !IsMidtransSettled(i.MidtransStatus.String):
+		i.Result = RECON_RESULT_SKIPPED
+	case len(p.Source) == 0:
+		i.Result = RECON_RESULT_UNKNOWN_ORDER
+	case p.PaymentStatus != PAYMENT_STATUS_PAID:
+		i.Result = RECON_RESULT_PENDING_LOCAL
+		i.Note = sql.NullString{String: fmt.Sprintf("Payment is %s here.", p.PaymentStatus), Valid: true}
+	case i.MidtransAmount.Int64 != p.Amount:
+		i.Result = RECON_RESULT_AMOUNT_MISMATCH
+		i.Note = sql.NullString{String: fmt.Sprintf("Difference %s.", FormatRupiah(i.MidtransAmount.Int64-p.Amount)), Valid: true}
+	default:
+		i.Result = RECON_RESULT_MATCHED
+	}
+}
+
+// IsMismatch row that should be checked by finance
+func (i PaymentReconciliationItemEnt) IsMismatch() bool {
+	return i.Result != RECON_RESULT_MATCHED && i.Result != RECON_RESULT_SKIPPED
+}
+
+const midtransPaymentSelect = `
+	select * from (
+		select op.payment_code midtrans_order_id, 'installment' source, o.order_code, op.amount, op.payment_status, op.paid_date
+		from order_payments op join orders o on o.id = op.order_id
+		where op.installment_no > 0 and op.payment_code is not null
+		union all
+		select o.order_code, 'order', o.order_code, op.amount, op.payment_status, coalesce(op.paid_date, op.created_date)
+		from order_payments op join orders o on o.id = op.order_id
+		where op.installment_no = 0 and coalesce(op.payment_type, '') != ''
+		union all
+		select ops.transaction_code, 'share', o.order_code, sum(ops.amount), min(ops.payment_status), max(ops.paid_date)
+		from order_payment_shares ops join orders o on o.id = ops.order_id
+		where ops.transaction_code is not null
+		group by ops.transaction_code, o.order_code
+	) mp `
+
+// GetPaymentByMidtransOrderID local payment of midtrans order id, the order code is used by the order payment,
+// the payment code by the installment and the transaction code by the shares
+func (c *Contract) GetPaymentByMidtransOrderID(db *pgxpool.Conn, ctx context.Context, orderID string) (MidtransPaymentEnt, error) {
+	var p MidtransPaymentEnt
+
+	err := db.QueryRow(ctx, midtransPaymentSelect+`where mp.midtrans_order_id = $1 limit 1`, orderID).
+		Scan(&p.MidtransOrderID, &p.Source, &p.OrderCode, &p.Amount, &p.PaymentStatus, &p.PaidDate)
+
+	return p, err
+}
+
+// GetListPaidMidtransPayment local payment that paid in the period
+func (c *Contract) GetListPaidMidtransPayment(db *pgxpool.Conn, ctx context.Context, start, end time.Time) ([]MidtransPaymentEnt, error) {
+	list := []MidtransPaymentEnt{}
+
+	rows, err := db.Query(ctx, midtransPaymentSelect+`where mp.payment_status = $1 and mp.paid_date between $2 and $3 order by mp.paid_date`, PAYMENT_STATUS_PAID, start, end)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var p MidtransPaymentEnt
+		if err = rows.Scan(&p.MidtransOrderID, &p.Source, &p.OrderCode, &p.Amount, &p.PaymentStatus, &p.PaidDate); err != nil {
+			return list, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// ReconcileSettlement match rows of settlement file with the local payments, the payment that paid in the
+// period of file but not settled is flagged as missing
+func (c *Contract) ReconcileSettlement(db *pgxpool.Conn, ctx context.Context, rec PaymentReconciliationEnt, rows []SettlementRow) (PaymentReconciliationEnt, error) {
+	rec.Items = []PaymentReconciliationItemEnt{}
+	settledOrderIDs := map[string]bool{}
+
+	for _, row := range rows {
+		item := PaymentReconciliationItemEnt{
+			MidtransOrderID: row.OrderID,
+			TransactionID:   sql.NullString{String: row.TransactionID, Valid: len(row.TransactionID) > 0},
+			PaymentType:     sql.NullString{String: row.PaymentType, Valid: len(row.PaymentType) > 0},
+			TransactionTime: row.TransactionTime,
+			MidtransStatus:  sql.NullString{String: row.TransactionStatus, Valid: true},
+			MidtransAmount:  sql.NullInt64{Int64: row.Amount, Valid: true},
+		}
+
+		p, err := c.GetPaymentByMidtransOrderID(db, ctx, row.OrderID)
+		if err != nil && err != pgx.ErrNoRows {
+			return rec, err
+		}
+		item.Reconcile(p)
+
+		if row.IsSettled() {
+			settledOrderIDs[row.OrderID] = true
+			rec.SettledAmount += row.Amount
+
+			if row.TransactionTime.Valid {
+				if !rec.PeriodStart.Valid || row.TransactionTime.Time.Before(rec.PeriodStart.Time) {
+					rec.PeriodStart = row.TransactionTime
+				}
+				if !rec.PeriodEnd.Valid || row.TransactionTime.Time.After(rec.PeriodEnd.Time) {
+					rec.PeriodEnd = row.TransactionTime
+				}
+			}
+		}
+
+		rec.Items = append(rec.Items, item)
+	}
+
+	if rec.PeriodStart.Valid {
+		paid, err := c.GetListPaidMidtransPayment(db, ctx, rec.PeriodStart.Time, rec.PeriodEnd.Time)
+		if err != nil {
+			return rec, err
+		}
+
+		for _, p := range paid {
+			if settledOrderIDs[p.MidtransOrderID] {
+				continue
+			}
+
+			item := PaymentReconciliationItemEnt{MidtransOrderID: p.MidtransOrderID}
+			item.Reconcile(p)
+			rec.Items = append(rec.Items, item)
+		}
+	}
+
+	rec.TotalRows = int32(len(rows))
+	for _, item := range rec.Items {
+		if item.Result == RECON_RESULT_MATCHED {
+			rec.MatchedRows++
+		} else if item.IsMismatch() {
+			rec.MismatchedRows++
+		}
+	}
+
+	return rec, nil
+}
+
+// AddPaymentReconciliation save reconciliation with the items
+func (c *Contract) AddPaymentReconciliation(tx pgx.Tx, ctx context.Context, rec PaymentReconciliationEnt) (PaymentReconciliationEnt, error) {
+	rec.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO payment_reconciliations(file_name, period_start, period_end, total_rows, matched_rows, mismatched_rows, settled_amount, created_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, rec.FileName, rec.PeriodStart, rec.PeriodEnd, rec.TotalRows, rec.MatchedRows, rec.MismatchedRows, rec.SettledAmount, rec.CreatedBy, rec.CreatedDate).Scan(&rec.ID)
+	if err != nil {
+		return rec, err
+	}
+
+	sqlItem := `INSERT INTO payment_reconciliation_items(reconciliation_id, midtrans_order_id, order_code, payment_source, transaction_id, payment_type, transaction_time,
+			midtrans_status, midtrans_amount, local_status, local_amount, result, note)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`
+
+	for i, item := range rec.Items {
+		item.ReconciliationID = rec.ID
+		err = tx.QueryRow(ctx, sqlItem, item.ReconciliationID, item.MidtransOrderID, item.OrderCode, item.PaymentSource, item.TransactionID, item.PaymentType, item.TransactionTime,
+			item.MidtransStatus, item.MidtransAmount, item.LocalStatus, item.LocalAmount, item.Result, item.Note).Scan(&item.ID)
+		if err != nil {
+			return rec, err
+		}
+		rec.Items[i] = item
+	}
+
+	return rec, nil
+}
+
+// RecheckPaymentReconciliationItem reconcile the item again with the status of midtrans and the current local payment
+func (c *Contract) RecheckPaymentReconciliationItem(db *pgxpool.Conn, tx pgx.Tx, ctx context.Context, item PaymentReconciliationItemEnt, midtransStatus string, midtransAmount int64) (PaymentReconciliationItemEnt, error) {
+	p, err := c.GetPaymentByMidtransOrderID(db, ctx, item.MidtransOrderID)
+	if err != nil && err != pgx.ErrNoRows {
+		return item, err
+	}
+
+	item.MidtransStatus = sql.NullString{String: midtransStatus, Valid: true}
+	item.MidtransAmount = sql.NullInt64{Int64: midtransAmount, Valid: true}
+	item.Reconcile(p)
+
+	return c.UpdatePaymentReconciliationItem(tx, ctx, item)
+}
+
+// UpdatePaymentReconciliationItem save the re-checked item and recount the summary of reconciliation
+func (c *Contract) UpdatePaymentReconciliationItem(tx pgx.Tx, ctx context.Context, item PaymentReconciliationItemEnt) (PaymentReconciliationItemEnt, error) {
+	item.CheckedDate = sql.NullTime{Time: time.Now().In(time.UTC), Valid: true}
+
+	q := `UPDATE payment_reconciliation_items SET order_code = $1, payment_source = $2, midtrans_status = $3, midtrans_amount = $4,
+			local_status = $5, local_amount = $6, result = $7, note = $8, checked_date = $9
+		WHERE id = $10`
+
+	_, err := tx.Exec(ctx, q, item.OrderCode, item.PaymentSource, item.MidtransStatus, item.MidtransAmount,
+		item.LocalStatus, item.LocalAmount, item.Result, item.Note, item.CheckedDate, item.ID)
+	if err != nil {
+		return item, err
+	}
+
+	q = `UPDATE payment_reconciliations SET
+			matched_rows = (select count(id) from payment_reconciliation_items where reconciliation_id = $1 and result = $2),
+			mismatched_rows = (select count(id) from payment_reconciliation_items where reconciliation_id = $1 and result not in ($2, $3))
+		WHERE id = $1`
+
+	_, err = tx.Exec(ctx, q, item.ReconciliationID, RECON_RESULT_MATCHED, RECON_RESULT_SKIPPED)
+
+	return item, err
+}
+
+const paymentReconciliationSelect = `
+	select id, file_name, period_start, period_end, total_rows, matched_rows, mismatched_rows, settled_amount, created_by, created_date
+	from payment_reconciliations `
+
+func scanPaymentReconciliation(row pgx.Row) (PaymentReconciliationEnt, error) {
+	var rec PaymentReconciliationEnt
+
+	err := row.Scan(&rec.ID, &rec.FileName, &rec.PeriodStart, &rec.PeriodEnd, &rec.TotalRows, &rec.MatchedRows, &rec.MismatchedRows, &rec.SettledAmount, &rec.CreatedBy, &rec.CreatedDate)
+
+	return rec, err
+}
+
+// GetPaymentReconciliationByID reconciliation without the items
+func (c *Contract) GetPaymentReconciliationByID(db *pgxpool.Conn, ctx context.Context, id int32) (PaymentReconciliationEnt, error) {
+	return scanPaymentReconciliation(db.QueryRow(ctx, paymentReconciliationSelect+`where id = $1`, id))
+}
+
+// GetListPaymentReconciliation list reconciliation, the newest first
+func (c *Contract) GetListPaymentReconciliation(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]PaymentReconciliationEnt, error) {
+	list := []PaymentReconciliationEnt{}
+
+	{
+		var count int
+		err := db.QueryRow(ctx, `SELECT COUNT(*) FROM payment_reconciliations`).Scan(&count)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q := paymentReconciliationSelect + `ORDER BY id desc`
+	var paramQuery []interface{}
+	if param["limit"].(int) != -1 {
+		q += ` offset $1 limit $2`
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		rec, err := scanPaymentReconciliation(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, rec)
+	}
+
+	return list, rows.Err()
+}
+
+const paymentReconciliationItemSelect = `
+	select id, reconciliation_id, midtrans_order_id, order_code, payment_source, transaction_id, payment_type, transaction_time,
+		midtrans_status, midtrans_amount, local_status, local_amount, result, note, checked_date
+	from payment_reconciliation_items `
+
+func scanPaymentReconciliationItem(row pgx.Row) (PaymentReconciliationItemEnt, error) {
+	var i PaymentReconciliationItemEnt
+
+	err := row.Scan(&i.ID, &i.ReconciliationID, &i.MidtransOrderID, &i.OrderCode, &i.PaymentSource, &i.TransactionID, &i.PaymentType, &i.TransactionTime,
+		&i.MidtransStatus, &i.MidtransAmount, &i.LocalStatus, &i.LocalAmount, &i.Result, &i.Note, &i.CheckedDate)
+
+	return i, err
+}
+
+// GetPaymentReconciliationItemByID ...
+func (c *Contract) GetPaymentReconciliationItemByID(db *pgxpool.Conn, ctx context.Context, reconciliationID, id int32) (PaymentReconciliationItemEnt, error) {
+	return scanPaymentReconciliationItem(db.QueryRow(ctx, paymentReconciliationItemSelect+`where reconciliation_id = $1 and id = $2`, reconciliationID, id))
+}
+
+// GetListPaymentReconciliationItem items of reconciliation filtered by the result, the mismatch only
+// when the result is "mismatch"
+func (c *Contract) GetListPaymentReconciliationItem(db *pgxpool.Conn, ctx context.Context, reconciliationID int32, result string) ([]PaymentReconciliationItemEnt, error) {
+	list := []PaymentReconciliationItemEnt{}
+	where := `where reconciliation_id = $1`
+	paramQuery := []interface{}{reconciliationID}
+
+	if result == "mismatch" {
+		where += ` and result not in ($2, $3)`
+		paramQuery = append(paramQuery, RECON_RESULT_MATCHED, RECON_RESULT_SKIPPED)
+	} else if len(result) > 0 {
+		where += ` and result = $2`
+		paramQuery = append(paramQuery, result)
+	}
+
+	rows, err := db.Query(ctx, paymentReconciliationItemSelect+where+` order by id`, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		i, err := scanPaymentReconciliationItem(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, i)
+	}
+
+	return list, rows.Err()
+}
+
+// WritePaymentReconciliationReport write the items of reconciliation as csv
+func WritePaymentReconciliationReport(w io.Writer, items []PaymentReconciliationItemEnt) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"midtrans_order_id", "order_code", "payment_source", "transaction_id", "payment_type", "transaction_time",
+		"midtrans_status", "midtrans_amount", "local_status", "local_amount", "result", "note", "checked_date"})
+	if err != nil {
+		return err
+	}
+
+	formatTime := func(t sql.NullTime) string {
+		if !t.Valid {
+			return ""
+		}
+		return t.Time.In(midtransTimezone).Format("2006-01-02 15:04:05")
+	}
+	formatAmount := func(a sql.NullInt64) string {
+		if !a.Valid {
+			return ""
+		}
+		return strconv.FormatInt(a.Int64, 10)
+	}
+
+	for _, i := range items {
+		err = writer.Write([]string{i.MidtransOrderID, i.OrderCode.String, i.PaymentSource.String, i.TransactionID.String, i.PaymentType.String, formatTime(i.TransactionTime),
+			i.MidtransStatus.String, formatAmount(i.MidtransAmount), i.LocalStatus.String, formatAmount(i.LocalAmount), i.Result, i.Note.String, formatTime(i.CheckedDate)})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -25,7 +25,7 @@ func (c *Contract) AddDevice(tx pgx.Tx, ctx context.Context, p DeviceListEnt) (D
 	var lastInsID int32
 
 	if len(p.PlayerID) <= 0 {
-		playerID, err := onesignal.New(c.App).AddDevice(int(p.DeviceType))
+		playerID, err := onesignal.New(c.App).AddDevice(ctx, int(p.DeviceType))
 		if err != nil {
 			return p, err
 		}
@@ -159,7 +159,7 @@ func (c *Contract) AddPlayer(tx pgx.Tx, db *pgxpool.Conn, ctx context.Context, u
 	xPlayerID = device.PlayerID
 
 	// Check device by player id onesignal
-	playerDevice, err := onesignal.New(c.App).GetPlayerDevice(xPlayerID)
+	playerDevice, err := onesignal.New(c.App).GetPlayerDevice(ctx, xPlayerID)
 	if err != nil {
 		return device, err
 	}
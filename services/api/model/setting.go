@@ -4,13 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"panorama/lib/settings"
 	"panorama/lib/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const (
+	SETTING_JSON_ARR = "json_arr"
+	SETTING_JSON_OBJ = "json_obj"
+	SETTING_BOOL     = "bool"
+	SETTING_STR      = "str"
+	SETTING_INT      = "int"
+	SETTING_FLOAT    = "float"
+)
+
 // SettingEnt ...
 type SettingEnt struct {
 	ID           int32
@@ -21,15 +34,71 @@ type SettingEnt struct {
 	ContentType  string
 	ContentValue string
 	IsActive     bool
+	IsPublic     bool
 	CreatedDate  time.Time
-	UpdatedDate  time.Time
+	UpdatedDate  *time.Time
 }
 
 var setType = []string{
-	"json_arr",
-	"json_obj",
-	"bool",
-	"str",
+	SETTING_JSON_ARR,
+	SETTING_JSON_OBJ,
+	SETTING_BOOL,
+	SETTING_STR,
+	SETTING_INT,
+	SETTING_FLOAT,
+}
+
+// ValidateSettingValue the content value should match the content type
+func ValidateSettingValue(contentType, value string) error {
+	if !utils.Contains(setType, contentType) {
+		return fmt.Errorf("Content type %s is not supported, use %s.", contentType, strings.Join(setType, ", "))
+	}
+
+	var err error
+	switch contentType {
+	case SETTING_JSON_ARR:
+		var v []interface{}
+		err = json.Unmarshal([]byte(value), &v)
+	case SETTING_JSON_OBJ:
+		var v map[string]interface{}
+		err = json.Unmarshal([]byte(value), &v)
+	case SETTING_BOOL:
+		_, err = strconv.ParseBool(value)
+	case SETTING_INT:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case SETTING_FLOAT:
+		_, err = strconv.ParseFloat(value, 64)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Content value is not a valid %s.", contentType)
+	}
+
+	return nil
+}
+
+// Value typed content value of the setting, the content value is returned as is when it is invalid
+func (s SettingEnt) Value() interface{} {
+	var err error
+	var v interface{}
+	switch s.ContentType {
+	case SETTING_JSON_ARR, SETTING_JSON_OBJ:
+		err = json.Unmarshal([]byte(s.ContentValue), &v)
+	case SETTING_BOOL:
+		v, err = strconv.ParseBool(s.ContentValue)
+	case SETTING_INT:
+		v, err = strconv.ParseInt(s.ContentValue, 10, 64)
+	case SETTING_FLOAT:
+		v, err = strconv.ParseFloat(s.ContentValue, 64)
+	default:
+		v = s.ContentValue
+	}
+
+	if err != nil {
+		return s.ContentValue
+	}
+
+	return v
 }
 
 // GetByGroup ...
@@ -37,7 +106,7 @@ func (c *Contract) GetByGroup(db *pgxpool.Conn, ctx context.Context, gr string)
 	var s []SettingEnt
 	var err error
 
-	err = pgxscan.Select(ctx, db, &s, `select * from settings where set_group=$1`, gr)
+	err = pgxscan.Select(ctx, db, &s, `select * from settings where set_group=$1 order by set_order, id`, gr)
 
 	return s, err
 }
@@ -52,6 +121,73 @@ func (c *Contract) GetByGroupAndKey(db *pgxpool.Conn, ctx context.Context, gr, k
 	return s, err
 }
 
+// GetSettingByID ...
+func (c *Contract) GetSettingByID(db *pgxpool.Conn, ctx context.Context, id int32) (SettingEnt, error) {
+	var s SettingEnt
+
+	err := pgxscan.Get(ctx, db, &s, `select * from settings where id=$1`, id)
+
+	return s, err
+}
+
+// GetListSetting settings of all groups (admin)
+func (c *Contract) GetListSetting(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]SettingEnt, error) {
+	list := []SettingEnt{}
+	var where []string
+	var paramQuery []interface{}
+
+	q := `select * from settings`
+
+	if len(param["group"].(string)) > 0 {
+		paramQuery = append(paramQuery, param["group"])
+		where = append(where, fmt.Sprintf("set_group = $%d", len(paramQuery)))
+	}
+
+	if len(param["keyword"].(string)) > 0 {
+		paramQuery = append(paramQuery, "%"+strings.ToLower(param["keyword"].(string))+"%")
+		where = append(where, fmt.Sprintf("(lower(set_key) like $%d OR lower(set_label) like $%d)", len(paramQuery), len(paramQuery)))
+	}
+
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	{
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY set_group, set_order, id"
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+	}
+
+	err := pgxscan.Select(ctx, db, &list, q, paramQuery...)
+
+	return list, err
+}
+
+// GetPublicSettings active settings of the group that readable without login
+func (c *Contract) GetPublicSettings(db *pgxpool.Conn, ctx context.Context, group string) ([]SettingEnt, error) {
+	list := []SettingEnt{}
+
+	err := pgxscan.Select(ctx, db, &list, `select * from settings where set_group=$1 and is_active = true and is_public = true
+		order by set_order, id`, group)
+
+	return list, err
+}
+
 // ToArray decode to array
 func (s SettingEnt) ToArray() []map[string]interface{} {
 	var data []map[string]interface{}
@@ -61,39 +197,160 @@ func (s SettingEnt) ToArray() []map[string]interface{} {
 }
 
 // AddSetting
-func (c *Contract) AddSetting(db *pgxpool.Conn, ctx context.Context, s SettingEnt) error {
-	if !utils.Contains(setType, s.ContentType) {
-		return fmt.Errorf("%s", "wrong content type")
+func (c *Contract) AddSetting(db *pgxpool.Conn, ctx context.Context, s SettingEnt) (SettingEnt, error) {
+	if err := ValidateSettingValue(s.ContentType, s.ContentValue); err != nil {
+		return s, err
 	}
 
-	sql := `insert into settings(set_group, set_key, set_label, set_order, content_type, content_value, is_active, created_date)
-	values($1, $2, $3, $4, $5, $6, $7, $8)`
+	s.CreatedDate = time.Now().In(time.UTC)
 
-	_, err := db.Exec(context.Background(), sql,
-		s.SetGroup, s.SetKey, s.SetLabel,
+	sql := `insert into settings(set_group, set_key, set_label, set_order, content_type, content_value, is_active, is_public, created_date)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+
+	err := db.QueryRow(ctx, sql,
+		s.SetGroup, s.SetKey, s.SetLabel, s.SetOrder,
 		s.ContentType, s.ContentValue,
-		s.IsActive, time.Now().In(time.UTC),
-	)
+		s.IsActive, s.IsPublic, s.CreatedDate,
+	).Scan(&s.ID)
+	if err != nil {
+		return s, err
+	}
 
-	return err
+	c.ForgetSettingGroup(ctx, s.SetGroup)
+
+	return s, nil
 }
 
 // UpdateSetting ...
-func (c *Contract) UpdateSetting(db *pgxpool.Conn, ctx context.Context, id int32, s SettingEnt) error {
-	sql := `update settings set set_label=$1, set_order=$2, content_type=$3, content_value=$4, is_active=$5, updated_date=$6
-	where id=$`
-	_, err := db.Exec(context.Background(), sql,
-		s.SetLabel, s.SetOrder, s.ContentType, s.ContentValue,
-		s.IsActive, time.Now().In(time.UTC),
+func (c *Contract) UpdateSetting(db *pgxpool.Conn, ctx context.Context, id int32, s SettingEnt) (SettingEnt, error) {
+	if err := ValidateSettingValue(s.ContentType, s.ContentValue); err != nil {
+		return s, err
+	}
+
+	old, err := c.GetSettingByID(db, ctx, id)
+	if err != nil {
+		return s, err
+	}
+
+	now := time.Now().In(time.UTC)
+	s.ID = id
+	s.CreatedDate = old.CreatedDate
+	s.UpdatedDate = &now
+
+	sql := `update settings set set_group=$1, set_key=$2, set_label=$3, set_order=$4, content_type=$5, content_value=$6,
+		is_active=$7, is_public=$8, updated_date=$9
+	where id=$10`
+	_, err = db.Exec(ctx, sql,
+		s.SetGroup, s.SetKey, s.SetLabel, s.SetOrder, s.ContentType, s.ContentValue,
+		s.IsActive, s.IsPublic, now, id,
 	)
+	if err != nil {
+		return s, err
+	}
+
+	c.ForgetSettingGroup(ctx, old.SetGroup, s.SetGroup)
+
+	return s, nil
+}
+
+// DeleteSetting ...
+func (c *Contract) DeleteSetting(db *pgxpool.Conn, ctx context.Context, id int32) error {
+	s, err := c.GetSettingByID(db, ctx, id)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if _, err = db.Exec(ctx, "delete from settings where id=$1", id); err != nil {
+		return err
+	}
+
+	c.ForgetSettingGroup(ctx, s.SetGroup)
+
+	return nil
 }
 
 // ActivationSetting ...
 func (c *Contract) ActivationSetting(db *pgxpool.Conn, ctx context.Context, id int32, isActive bool) error {
-	bVal := utils.Bint(isActive)
-	_, err := db.Exec(context.Background(), "update settings set is_active=$1 where id=$2", bVal, id)
+	s, err := c.GetSettingByID(db, ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(ctx, "update settings set is_active=$1, updated_date=$2 where id=$3", isActive, time.Now().In(time.UTC), id); err != nil {
+		return err
+	}
+
+	c.ForgetSettingGroup(ctx, s.SetGroup)
+
+	return nil
+}
+
+// ForgetSettingGroup remove the cached settings of the groups, the next read is loaded from the database
+func (c *Contract) ForgetSettingGroup(ctx context.Context, groups ...string) {
+	settings.Forget(ctx, c.Log, c.RedisCache, groups...)
+}
+
+// getSettingGroup active content values of the group by key, cached in redis until the group is updated
+func (c *Contract) getSettingGroup(db *pgxpool.Conn, ctx context.Context, group string) (map[string]string, error) {
+	return settings.Group(ctx, c.Log, db, c.RedisCache, settings.TTL(c.Config.GetInt("settings.cache_ttl")), group)
+}
+
+// GetSettingValue value of the active setting, empty when setting is not exist
+func (c *Contract) GetSettingValue(db *pgxpool.Conn, ctx context.Context, group, key string) (string, error) {
+	values, err := c.getSettingGroup(db, ctx, group)
+	if err != nil {
+		return "", err
+	}
+
+	return values[key], nil
+}
+
+// SettingString value of the active setting, def when the setting is not exist or can not be read
+func (c *Contract) SettingString(db *pgxpool.Conn, ctx context.Context, group, key, def string) string {
+	v, err := c.GetSettingValue(db, ctx, group, key)
+	if err != nil || len(v) == 0 {
+		return def
+	}
+
+	return v
+}
+
+// SettingInt ...
+func (c *Contract) SettingInt(db *pgxpool.Conn, ctx context.Context, group, key string, def int) int {
+	v, err := strconv.Atoi(c.SettingString(db, ctx, group, key, ""))
+	if err != nil {
+		return def
+	}
+
+	return v
+}
+
+// SettingFloat ...
+func (c *Contract) SettingFloat(db *pgxpool.Conn, ctx context.Context, group, key string, def float64) float64 {
+	v, err := strconv.ParseFloat(c.SettingString(db, ctx, group, key, ""), 64)
+	if err != nil {
+		return def
+	}
+
+	return v
+}
+
+// SettingBool ...
+func (c *Contract) SettingBool(db *pgxpool.Conn, ctx context.Context, group, key string, def bool) bool {
+	v, err := strconv.ParseBool(c.SettingString(db, ctx, group, key, ""))
+	if err != nil {
+		return def
+	}
+
+	return v
+}
+
+// SettingJSON decode the json_arr / json_obj setting into v, v is untouched when the setting is not exist
+func (c *Contract) SettingJSON(db *pgxpool.Conn, ctx context.Context, group, key string, v interface{}) error {
+	value, err := c.GetSettingValue(db, ctx, group, key)
+	if err != nil || len(value) == 0 {
+		return err
+	}
 
-	return err
+	return json.Unmarshal([]byte(value), v)
 }
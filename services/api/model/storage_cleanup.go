@@ -0,0 +1,159 @@
+package model
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"panorama/lib/storage"
+	"panorama/lib/upload"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// referencedFileSelect values that reference the stored files, the text value (json details, chat messages and
+// settings) may contain the paths or the urls anywhere in it
+const referencedFileSelect = `
+	select img, false from users where img is not null
+	union select img, false from members where img is not null
+	union select img, false from member_itins where img is not null
+	union select img, false from itin_suggestions where img is not null
+	union select image, false from stuff where image is not null
+	union select file_path, false from order_invoices where file_path is not null
+	union select file_path, false from trip_documents where deleted_date is null
+	union select details, true from orders where details is not null
+	union select details::text, true from member_itins
+	union select details::text, true from member_itin_changes
+	union select details::text, true from itin_suggestions
+	union select messages, true from chat_messages
+	union select content_value, true from settings`
+
+// auditedFileFolders folders under aws.s3.filepath whose every referencing column is in referencedFileSelect,
+// the orphan files are only deleted from these folders
+var auditedFileFolders = []string{"avatars", "itin-covers", "stuffs", "invoices", "documents"}
+
+// fileRefToken candidate of the path or url in the text value
+var fileRefToken = regexp.MustCompile(`[^\s"'<>()\[\]{},\\]+`)
+
+// storageKey the stored path without the leading slash
+func storageKey(path string) string {
+	return strings.TrimPrefix(strings.TrimSpace(path), "/")
+}
+
+// AuditedFilePrefixes prefixes of the stored files that can be cleaned
+func (c *Contract) AuditedFilePrefixes() []string {
+	prefixes := []string{}
+	for _, f := range auditedFileFolders {
+		prefixes = append(prefixes, storageKey(c.Config.GetString("aws.s3.filepath")+"/"+f))
+	}
+
+	return prefixes
+}
+
+// IsAuditedFilePrefix the prefix is one of the audited prefixes or under it
+func (c *Contract) IsAuditedFilePrefix(prefix string) bool {
+	prefix = strings.TrimSuffix(storageKey(prefix), "/")
+	for _, p := range c.AuditedFilePrefixes() {
+		if prefix == p || strings.HasPrefix(prefix, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileRefKey key of the referenced path or the url built by the storage, the url of others is not stored by us
+func (c *Contract) fileRefKey(ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if len(ref) == 0 {
+		return "", false
+	}
+
+	if strings.Contains(ref, "://") {
+		if key, ok := c.Storage.Key(ref); ok {
+			return storageKey(key), true
+		}
+		if base := c.Config.GetString("aws.s3.public_url"); len(base) > 0 && strings.HasPrefix(ref, base) {
+			return storageKey(strings.SplitN(strings.TrimPrefix(ref, base), "?", 2)[0]), true
+		}
+
+		return "", false
+	}
+
+	return storageKey(ref), true
+}
+
+// addFileRef mark the referenced key and its renditions
+func (c *Contract) addFileRef(paths map[string]bool, ref string) {
+	key, ok := c.fileRefKey(ref)
+	if !ok {
+		return
+	}
+
+	paths[key] = true
+	for _, p := range upload.RenditionPaths(key) {
+		paths[storageKey(p)] = true
+	}
+}
+
+// GetReferencedFilePaths paths of the stored files that still referenced by the data (stored as the path or
+// the url of the storage), including the renditions of the images
+func (c *Contract) GetReferencedFilePaths(db *pgxpool.Conn, ctx context.Context) (map[string]bool, error) {
+	paths := map[string]bool{}
+
+	rows, err := db.Query(ctx, referencedFileSelect)
+	if err != nil {
+		return paths, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			value  string
+			isText bool
+		)
+		if err = rows.Scan(&value, &isText); err != nil {
+			return paths, err
+		}
+
+		if !isText {
+			c.addFileRef(paths, value)
+			continue
+		}
+
+		for _, t := range fileRefToken.FindAllString(strings.ReplaceAll(value, `\/`, "/"), -1) {
+			c.addFileRef(paths, t)
+		}
+	}
+
+	return paths, rows.Err()
+}
+
+// GetOrphanFiles stored files under the prefixes that no longer referenced, the file newer than minAge is skipped
+// because the uploaded file is saved into the data after the upload
+func (c *Contract) GetOrphanFiles(db *pgxpool.Conn, ctx context.Context, prefixes []string, minAge time.Duration) ([]storage.Object, error) {
+	orphans := []storage.Object{}
+
+	paths, err := c.GetReferencedFilePaths(db, ctx)
+	if err != nil {
+		return orphans, err
+	}
+
+	threshold := time.Now().Add(-minAge)
+	for _, prefix := range prefixes {
+		objects, err := c.Storage.List(storageKey(prefix))
+		if err != nil {
+			return orphans, err
+		}
+
+		for _, o := range objects {
+			if paths[storageKey(o.Key)] || o.LastModified.After(threshold) {
+				continue
+			}
+			orphans = append(orphans, o)
+		}
+	}
+
+	return orphans, nil
+}
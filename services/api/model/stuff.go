@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"panorama/lib/psql"
 	"panorama/lib/utils"
-	"strings"
 	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
@@ -23,6 +23,8 @@ type StuffEnt struct {
 	Image            sql.NullString
 	Description      string
 	Price            string
+	CurrencyCode     string
+	PriceIDR         sql.NullInt64 // price in rupiah with the latest rate, null when the rate is not available
 	Type             int32
 	IsActive         bool
 	CreatedDate      time.Time
@@ -30,6 +32,31 @@ type StuffEnt struct {
 	DeletedDate      sql.NullTime
 }
 
+// stuffRateJoin currency and the latest rate of the stuff price
+const stuffRateJoin = ` left join currencies cu on cu.currency_code = s.currency_code
+	left join lateral (
+		select er.rate from exchange_rates er
+		where er.currency_code = s.currency_code and er.effective_date <= now()::date
+		order by er.effective_date desc limit 1
+	) er on true`
+
+// setPriceIDR convert the text price of stuff into rupiah
+func (s *StuffEnt) setPriceIDR(minorUnit int32, rate sql.NullFloat64) {
+	cur := CurrencyEnt{Code: s.CurrencyCode, MinorUnit: minorUnit}
+	if s.CurrencyCode == BASE_CURRENCY {
+		rate = sql.NullFloat64{Float64: 1, Valid: true}
+	}
+
+	price, err := ParseMoney(s.Price, cur)
+	if err != nil || !rate.Valid {
+		s.PriceIDR = sql.NullInt64{}
+		return
+	}
+
+	e := ExchangeRateEnt{CurrencyCode: cur.Code, MinorUnit: cur.MinorUnit, Rate: rate.Float64}
+	s.PriceIDR = sql.NullInt64{Int64: e.ToIDR(price.Amount), Valid: true}
+}
+
 func (c *Contract) SetStuffCode() string {
 	rand.Seed(time.Now().UnixNano())
 	code, _ := utils.Generate(`[a-z0-9]{6}`)
@@ -39,9 +66,12 @@ func (c *Contract) SetStuffCode() string {
 func (c *Contract) AddStuff(db *pgxpool.Conn, ctx context.Context, s StuffEnt) (StuffEnt, error) {
 	var lastInsID int32
 	s.Code = c.SetStuffCode()
-	err := db.QueryRow(ctx, `insert into stuff (code_stuff, name_stuff, image, description , price, type, created_date) 
-		values($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
-		s.Code, s.Name, s.Image, s.Description, s.Price, s.Type, time.Now().In(time.UTC),
+	if len(s.CurrencyCode) == 0 {
+		s.CurrencyCode = BASE_CURRENCY
+	}
+	err := db.QueryRow(ctx, `insert into stuff (code_stuff, name_stuff, image, description , price, type, created_date, currency_code) 
+		values($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		s.Code, s.Name, s.Image, s.Description, s.Price, s.Type, time.Now().In(time.UTC), s.CurrencyCode,
 	).Scan(&lastInsID)
 	
 	s.ID = lastInsID
@@ -53,36 +83,22 @@ func (c *Contract) AddStuff(db *pgxpool.Conn, ctx context.Context, s StuffEnt) (
 func (c *Contract) GetListStuff(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]StuffEnt, error) {
 
 	list := []StuffEnt{}
-	var where []string
-	var paramQuery []interface{}
+	var w psql.Query
 
 	if len(param["stuff"].(string)) > 0 {
-		var orWhere []string
-
-		orWhere = append(orWhere, "s.name like '%"+param["stuff"].(string)+"%'")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
+		w.Like(param["stuff"].(string), "s.name_stuff")
 	}
 
 	if len(param["type"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, " s.type = '"+param["type"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
+		w.Where("s.type = ?", param["type"])
 	}
 
-	sql := `select id, code, name, image, description, price, type, created_date from stuff s`
+	query := `select s.id, s.code_stuff, s.name_stuff, s.image, s.description, s.price, s.type, s.created_date, s.currency_code, cu.minor_unit, er.rate from stuff s` + stuffRateJoin
 
-	var q string = sql
-
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
+	var q string = query + w.WhereSQL()
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM (` + q + `) AS data`
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, q, w.Args...)
 		if err != nil {
 			return list, err
 		}
@@ -96,15 +112,10 @@ func (c *Contract) GetListStuff(db *pgxpool.Conn, ctx context.Context, param map
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	if param["limit"].(int) == -1 {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += " ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
-	}
+	q += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"s.id"}, "s.id")
+	q += w.Page(param["offset"].(int), param["limit"].(int))
 
-	rows, err := db.Query(ctx, q, paramQuery...)
+	rows, err := db.Query(ctx, q, w.Args...)
 	if err != nil {
 		return list, err
 	}
@@ -113,10 +124,13 @@ func (c *Contract) GetListStuff(db *pgxpool.Conn, ctx context.Context, param map
 
 	for rows.Next() {
 		var a StuffEnt
-		err = rows.Scan(&a.ID, &a.Code, &a.Name, &a.Image, &a.Description, &a.Price, &a.Type, &a.CreatedDate)
+		var minorUnit int32
+		var rate sql.NullFloat64
+		err = rows.Scan(&a.ID, &a.Code, &a.Name, &a.Image, &a.Description, &a.Price, &a.Type, &a.CreatedDate, &a.CurrencyCode, &minorUnit, &rate)
 		if err != nil {
 			return list, err
 		}
+		a.setPriceIDR(minorUnit, rate)
 
 		list = append(list, a)
 	}
@@ -126,11 +140,14 @@ func (c *Contract) GetListStuff(db *pgxpool.Conn, ctx context.Context, param map
 
 func (c *Contract) GetStuffCode(db *pgxpool.Conn, ctx context.Context, code string) (StuffEnt, error) {
 	var s StuffEnt
-	sql := `select
-				code, name, image, description, price
-			from stuff
-			where code = $1 limit 1`
-	err := db.QueryRow(ctx, sql, code).Scan(&s.Code, &s.Name, &s.Image, &s.Description, &s.Price)
+	var minorUnit int32
+	var rate sql.NullFloat64
+	q := `select
+				s.code_stuff, s.name_stuff, s.image, s.description, s.price, s.currency_code, cu.minor_unit, er.rate
+			from stuff s` + stuffRateJoin + `
+			where s.code_stuff = $1 limit 1`
+	err := db.QueryRow(ctx, q, code).Scan(&s.Code, &s.Name, &s.Image, &s.Description, &s.Price, &s.CurrencyCode, &minorUnit, &rate)
+	s.setPriceIDR(minorUnit, rate)
 
 	return s, err
 }
@@ -146,9 +163,13 @@ func (c *Contract) UpdateStuff(tx pgx.Tx, ctx context.Context, code string, s St
 
 	var ID int32
 
-	sql := `update stuff set name=$1, image=$2, description=$3, price=$4, type=$5, is_active=$6, updated_date=$7 where code=$8 RETURNING id`
+	if len(s.CurrencyCode) == 0 {
+		s.CurrencyCode = BASE_CURRENCY
+	}
+
+	sql := `update stuff set name=$1, image=$2, description=$3, price=$4, type=$5, is_active=$6, updated_date=$7, currency_code=$8 where code=$9 RETURNING id`
 
-	err := tx.QueryRow(ctx, sql, s.Name, s.Image.String, s.Description, s.Price, s.Type, s.IsActive, time.Now().In(time.UTC), code).Scan(&ID)
+	err := tx.QueryRow(ctx, sql, s.Name, s.Image.String, s.Description, s.Price, s.Type, s.IsActive, time.Now().In(time.UTC), s.CurrencyCode, code).Scan(&ID)
 
 	s.ID = ID
 
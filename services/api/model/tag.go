@@ -2,8 +2,7 @@ package model
 
 import (
 	"context"
-	"strconv"
-	"strings"
+	"panorama/lib/psql"
 
 	"github.com/georgysavva/scany/pgxscan"
 	"github.com/jackc/pgx/v4"
@@ -43,16 +42,12 @@ func (c *Contract) AddMutiTag(tx pgx.Tx, ctx context.Context, t []string) []int3
 
 // AddMultiSugItinTags ..
 func (c *Contract) AddMultiSugItinTags(tx pgx.Tx, ctx context.Context, sug int32, tags []int32) error {
-	sql := "insert into itin_suggestion_tags (itin_sug_id, tag_id) values "
-
-	var arrStr []string
+	var rows [][]interface{}
 	for _, v := range tags {
-		arrStr = append(arrStr, "("+strconv.Itoa(int(sug))+","+strconv.Itoa(int(v))+")")
+		rows = append(rows, []interface{}{sug, v})
 	}
 
-	sql = sql + strings.Join(arrStr, ",")
-
-	_, err := tx.Exec(context.Background(), sql)
+	_, err := psql.InsertBatch(ctx, tx, "itin_suggestion_tags", []string{"itin_sug_id", "tag_id"}, rows)
 
 	return err
 }
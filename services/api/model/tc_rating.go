@@ -0,0 +1,145 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TcRatingEnt ...
+type TcRatingEnt struct {
+	ID          int32
+	ChatGroupID int32
+	TcID        int32
+	MemberID    int32
+	Rating      int32
+	Comment     string
+	CreatedDate time.Time
+	User        UserEnt
+	Member      MemberEnt
+}
+
+// TcRatingReportEnt ...
+type TcRatingReportEnt struct {
+	User        UserEnt
+	TotalRating int32
+	AvgRating   float64
+	TotalStar   map[string]int32
+}
+
+// AddTcRating save customer rating for tc after the chat session
+func (c *Contract) AddTcRating(tx pgx.Tx, ctx context.Context, tr TcRatingEnt) (TcRatingEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO tc_ratings(chat_group_id, tc_id, member_id, rating, comment, created_date) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, tr.ChatGroupID, tr.TcID, tr.MemberID, tr.Rating, tr.Comment, timeStamp).Scan(&lastInsID)
+
+	tr.ID = lastInsID
+	tr.CreatedDate = timeStamp
+
+	return tr, err
+}
+
+// GetTcRatingReport aggregate rating per tc in the given period, the last month by default
+func (c *Contract) GetTcRatingReport(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]TcRatingReportEnt, error) {
+	list := []TcRatingReportEnt{}
+	var paramQuery []interface{}
+
+	startDate := fmt.Sprintf("%v", time.Now().AddDate(0, -1, 0).Format("2006-01-02")) + " 00:00:00"
+	endDate := fmt.Sprintf("%v", time.Now().Format("2006-01-02")) + " 23:59:59"
+
+	// the single bound is applied, the period without start date is one month before the end date
+	if len(param["end_date"].(string)) > 0 {
+		endDate = fmt.Sprintf("%v %s", param["end_date"], "23:59:59")
+		if end, err := time.Parse("2006-01-02", param["end_date"].(string)); err == nil {
+			startDate = end.AddDate(0, -1, 0).Format("2006-01-02") + " 00:00:00"
+		}
+	}
+	if len(param["start_date"].(string)) > 0 {
+		startDate = fmt.Sprintf("%v %s", param["start_date"], "00:00:00")
+	}
+	paramQuery = append(paramQuery, startDate, endDate)
+
+	sql := `
+		select
+			u.user_code, u.name, u.img,
+			count(tr.id) total_rating,
+			coalesce(avg(tr.rating), 0)::float8 avg_rating,
+			count(case when tr.rating = 1 then tr.id end) star_1,
+			count(case when tr.rating = 2 then tr.id end) star_2,
+			count(case when tr.rating = 3 then tr.id end) star_3,
+			count(case when tr.rating = 4 then tr.id end) star_4,
+			count(case when tr.rating = 5 then tr.id end) star_5
+		from users u
+		left join tc_ratings tr on tr.tc_id = u.id and tr.created_date between $1 and $2
+		where u.role = 'tc' and u.is_active = true`
+
+	if len(param["tc_code"].(string)) > 0 {
+		sql += ` and u.user_code = $3`
+		paramQuery = append(paramQuery, param["tc_code"])
+	}
+
+	sql += ` group by u.id order by avg_rating desc, total_rating desc`
+
+	rows, err := db.Query(ctx, sql, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a TcRatingReportEnt
+		var s1, s2, s3, s4, s5 int32
+		err = rows.Scan(&a.User.UserCode, &a.User.Name, &a.User.Img, &a.TotalRating, &a.AvgRating, &s1, &s2, &s3, &s4, &s5)
+		if err != nil {
+			return list, err
+		}
+
+		a.TotalStar = map[string]int32{"1": s1, "2": s2, "3": s3, "4": s4, "5": s5}
+
+		list = append(list, a)
+	}
+
+	return list, err
+}
+
+// GetListTcRatingByTcCode latest ratings of tc
+func (c *Contract) GetListTcRatingByTcCode(db *pgxpool.Conn, ctx context.Context, code string, limit int) ([]TcRatingEnt, error) {
+	list := []TcRatingEnt{}
+	var comment sql.NullString
+
+	sql := `
+		select
+			tr.id, tr.rating, tr.comment, tr.created_date,
+			m.member_code, m.name
+		from tc_ratings tr
+		join users u on u.id = tr.tc_id
+		join members m on m.id = tr.member_id
+		where u.user_code = $1
+		order by tr.created_date desc limit $2`
+
+	rows, err := db.Query(ctx, sql, code, limit)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var a TcRatingEnt
+		err = rows.Scan(&a.ID, &a.Rating, &comment, &a.CreatedDate, &a.Member.MemberCode, &a.Member.Name)
+		if err != nil {
+			return list, err
+		}
+		a.Comment = comment.String
+
+		list = append(list, a)
+	}
+
+	return list, err
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"panorama/lib/utils"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	DOCUMENT_TYPE_PASSPORT  = "passport"
+	DOCUMENT_TYPE_VISA      = "visa"
+	DOCUMENT_TYPE_TICKET    = "ticket"
+	DOCUMENT_TYPE_INSURANCE = "insurance"
+	DOCUMENT_TYPE_OTHER     = "other"
+
+	DOCUMENT_ACTOR_MEMBER = "member"
+	DOCUMENT_ACTOR_USER   = "user"
+
+	DOCUMENT_ACTION_UPLOAD   = "upload"
+	DOCUMENT_ACTION_DOWNLOAD = "download"
+	DOCUMENT_ACTION_DELETE   = "delete"
+
+	passportValidMonth = 6 // passport should be valid at least 6 months after the trip start
+)
+
+// TripDocumentEnt travel document of member itin, the file is a private s3 object
+type TripDocumentEnt struct {
+	ID             int32
+	DocumentCode   string
+	MemberItinID   int32
+	MemberID       sql.NullInt32
+	MemberCode     string
+	MemberName     string
+	DocumentType   string
+	Title          string
+	DocumentNumber sql.NullString
+	ExpiryDate     sql.NullTime
+	FilePath       string
+	FileName       string
+	FileMime       string
+	FileSize       int64
+	UploadedByType string
+	UploadedBy     int32
+	CreatedDate    time.Time
+}
+
+// TripDocumentAccessLogEnt access of document
+type TripDocumentAccessLogEnt struct {
+	ID          int32
+	DocumentID  int32
+	ActorType   string
+	ActorID     int32
+	ActorName   string
+	Action      string
+	IPAddress   sql.NullString
+	UserAgent   sql.NullString
+	CreatedDate time.Time
+}
+
+// SetTripDocumentCode ...
+func (c *Contract) SetTripDocumentCode() string {
+	rand.Seed(time.Now().UnixNano())
+	code, _ := utils.Generate(`[a-z0-9]{8}`)
+	return fmt.Sprintf("DOC-%s", code)
+}
+
+// PassportValidMonth months of passport validity that required after the trip start
+func (c *Contract) PassportValidMonth() int {
+	if month := c.Config.GetInt("document.passport_valid_month"); month > 0 {
+		return month
+	}
+
+	return passportValidMonth
+}
+
+// ExpiryWarning warning of passport that expired before the trip start or not valid long enough after the trip start
+func (d TripDocumentEnt) ExpiryWarning(startDate time.Time, validMonth int) string {
+	if d.DocumentType != DOCUMENT_TYPE_PASSPORT || !d.ExpiryDate.Valid || startDate.IsZero() {
+		return ""
+	}
+
+	if d.ExpiryDate.Time.Before(startDate) {
+		return "Passport expires before the trip start."
+	}
+	if d.ExpiryDate.Time.Before(startDate.AddDate(0, validMonth, 0)) {
+		return fmt.Sprintf("Passport expires within %d months of the trip start.", validMonth)
+	}
+
+	return ""
+}
+
+// GetMemberItinTcID tc that assigned into the chat group of member itin, zero when no tc is assigned
+func (c *Contract) GetMemberItinTcID(db *pgxpool.Conn, ctx context.Context, itinID int32) (int32, error) {
+	var tcID int32
+
+	err := db.QueryRow(ctx, `select tc_id from chat_groups where member_itin_id = $1 and tc_id is not null limit 1`, itinID).Scan(&tcID)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+
+	return tcID, err
+}
+
+// AddTripDocument ...
+func (c *Contract) AddTripDocument(tx pgx.Tx, ctx context.Context, d TripDocumentEnt) (TripDocumentEnt, error) {
+	d.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO trip_documents(document_code, member_itin_id, member_id, document_type, title, document_number, expiry_date,
+			file_path, file_name, file_mime, file_size, uploaded_by_type, uploaded_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, d.DocumentCode, d.MemberItinID, d.MemberID, d.DocumentType, d.Title, d.DocumentNumber, d.ExpiryDate,
+		d.FilePath, d.FileName, d.FileMime, d.FileSize, d.UploadedByType, d.UploadedBy, d.CreatedDate).Scan(&d.ID)
+
+	return d, err
+}
+
+// DeleteTripDocument soft delete document, the access log is kept
+func (c *Contract) DeleteTripDocument(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `UPDATE trip_documents SET deleted_date = $1 WHERE id = $2`, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+const tripDocumentSelect = `
+	select td.id, td.document_code, td.member_itin_id, td.member_id, m.member_code, m.name, td.document_type, td.title, td.document_number,
+		td.expiry_date, td.file_path, td.file_name, td.file_mime, td.file_size, td.uploaded_by_type, td.uploaded_by, td.created_date
+	from trip_documents td
+	left join members m on m.id = td.member_id `
+
+func scanTripDocument(row pgx.Row) (TripDocumentEnt, error) {
+	var d TripDocumentEnt
+	var memberCode, memberName sql.NullString
+
+	err := row.Scan(&d.ID, &d.DocumentCode, &d.MemberItinID, &d.MemberID, &memberCode, &memberName, &d.DocumentType, &d.Title, &d.DocumentNumber,
+		&d.ExpiryDate, &d.FilePath, &d.FileName, &d.FileMime, &d.FileSize, &d.UploadedByType, &d.UploadedBy, &d.CreatedDate)
+	d.MemberCode = memberCode.String
+	d.MemberName = memberName.String
+
+	return d, err
+}
+
+// GetTripDocumentByCode document of member itin that not deleted
+func (c *Contract) GetTripDocumentByCode(db *pgxpool.Conn, ctx context.Context, itinID int32, code string) (TripDocumentEnt, error) {
+	return scanTripDocument(db.QueryRow(ctx, tripDocumentSelect+`where td.member_itin_id = $1 and td.document_code = $2 and td.deleted_date is null`, itinID, code))
+}
+
+// GetListTripDocument documents of member itin filtered by the document type
+func (c *Contract) GetListTripDocument(db *pgxpool.Conn, ctx context.Context, itinID int32, documentType string) ([]TripDocumentEnt, error) {
+	list := []TripDocumentEnt{}
+	where := `where td.member_itin_id = $1 and td.deleted_date is null`
+	paramQuery := []interface{}{itinID}
+
+	if len(documentType) > 0 {
+		where += ` and td.document_type = $2`
+		paramQuery = append(paramQuery, documentType)
+	}
+
+	rows, err := db.Query(ctx, tripDocumentSelect+where+` order by td.id desc`, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		d, err := scanTripDocument(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, d)
+	}
+
+	return list, rows.Err()
+}
+
+// AddTripDocumentAccessLog record access of document
+func (c *Contract) AddTripDocumentAccessLog(tx pgx.Tx, ctx context.Context, l TripDocumentAccessLogEnt) (TripDocumentAccessLogEnt, error) {
+	l.CreatedDate = time.Now().In(time.UTC)
+
+	sql := `INSERT INTO trip_document_access_logs(document_id, actor_type, actor_id, actor_name, action, ip_address, user_agent, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, l.DocumentID, l.ActorType, l.ActorID, l.ActorName, l.Action, l.IPAddress, l.UserAgent, l.CreatedDate).Scan(&l.ID)
+
+	return l, err
+}
+
+// GetListTripDocumentAccessLog access log of document, the newest first
+func (c *Contract) GetListTripDocumentAccessLog(db *pgxpool.Conn, ctx context.Context, documentID int32) ([]TripDocumentAccessLogEnt, error) {
+	list := []TripDocumentAccessLogEnt{}
+
+	rows, err := db.Query(ctx, `select id, document_id, actor_type, actor_id, actor_name, action, ip_address, user_agent, created_date
+		from trip_document_access_logs where document_id = $1 order by created_date desc, id desc`, documentID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var l TripDocumentAccessLogEnt
+		err = rows.Scan(&l.ID, &l.DocumentID, &l.ActorType, &l.ActorID, &l.ActorName, &l.Action, &l.IPAddress, &l.UserAgent, &l.CreatedDate)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, l)
+	}
+
+	return list, rows.Err()
+}
@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"panorama/lib/psql"
 	"panorama/lib/utils"
-	"strings"
 	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
@@ -36,6 +36,9 @@ type UserEnt struct {
 	TotalItinSugView      sql.NullInt32
 	TotalItinSug          sql.NullInt32
 	ActiveClientConsultan []ActiveClientConsultan
+	AvgRating             sql.NullFloat64
+	TotalRating           sql.NullInt32
+	TcRatings             []TcRatingEnt
 }
 
 func (c *Contract) createUserCode(role string) string {
@@ -56,54 +59,11 @@ func (c *Contract) createUserCode(role string) string {
 func (c *Contract) GetUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]UserEnt, error) {
 
 	list := []UserEnt{}
-	var where []string
-	var paramQuery []interface{}
 
-	if len(param["keyword"].(string)) > 0 {
-		var orWhere []string
-		orWhere = append(orWhere, "name like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "role like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "phone like '%"+param["keyword"].(string)+"%'")
-		orWhere = append(orWhere, "email like '%"+param["keyword"].(string)+"%'")
-
-		where = append(where, "("+strings.Join(orWhere, " OR ")+")")
-	}
-
-	sql := `
-		select role, user_code, name,img, phone, email, count(distinct(total_client)), MAX(last_active_date) 
-			from (
-				select 
-					u.id, l.role, u.user_code,
-					u.name, u.img, u.phone, u.email,
-					paid_by as total_client, 
-					l.last_active_date
-				from users as u
-				left join orders o on o.tc_id = u.id
-				join log_visit_app l on l.user_id = u.id
-				where is_active = true 
-				group by paid_by, u.id, l.id 
-			) a `
-
-	var q string = sql
-
-	if len(param["role"].(string)) > 0 {
-
-		var orWhere []string
-		orWhere = append(orWhere, " role = '"+param["role"].(string)+"'")
-
-		where = append(where, strings.Join(orWhere, " AND "))
-
-	}
-
-	if len(where) > 0 {
-		q += " WHERE " + strings.Join(where, " AND ")
-	}
+	q, paramQuery := listUserQuery(param)
 
 	{
-		var count int
-		newQcount := `SELECT COUNT(*) FROM ( ` + q + ` group by id, role, name, img, phone, user_code, email ) AS data`
-
-		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		count, err := c.countListQuery(db, ctx, q, paramQuery...)
 		if err != nil {
 			return list, err
 		}
@@ -117,12 +77,10 @@ func (c *Contract) GetUser(db *pgxpool.Conn, ctx context.Context, param map[stri
 
 	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
 
-	if param["limit"].(int) == -1 {
-		q += " group by id, role, name, img, phone, email, user_code ORDER BY " + param["order"].(string) + " " + param["sort"].(string)
-	} else {
-		q += " group by id, role, name, img, phone, email, user_code ORDER BY " + param["order"].(string) + " " + param["sort"].(string) + " offset $1 limit $2 "
-		paramQuery = append(paramQuery, param["offset"])
-		paramQuery = append(paramQuery, param["limit"])
+	q += " ORDER BY " + listUserOrder(param)
+	if param["limit"].(int) != -1 {
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)+1, len(paramQuery)+2)
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
 	}
 
 	rows, err := db.Query(ctx, q, paramQuery...)
@@ -132,8 +90,7 @@ func (c *Contract) GetUser(db *pgxpool.Conn, ctx context.Context, param map[stri
 
 	defer rows.Close()
 	for rows.Next() {
-		var a UserEnt
-		err = rows.Scan(&a.Role, &a.UserCode, &a.Name, &a.Img, &a.Phone, &a.Email, &a.TotalClient, &a.LastVisit)
+		a, err := scanListUser(rows)
 		if err != nil {
 			return list, err
 		}
@@ -143,6 +100,81 @@ func (c *Contract) GetUser(db *pgxpool.Conn, ctx context.Context, param map[stri
 	return list, err
 }
 
+// CountUser total users of the list filter
+func (c *Contract) CountUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) (int, error) {
+	q, paramQuery := listUserQuery(param)
+
+	return c.countListQuery(db, ctx, q, paramQuery...)
+}
+
+// ExportUser all users of the list filter, streamed into fn row by row
+func (c *Contract) ExportUser(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}, fn func(UserEnt) error) error {
+	q, paramQuery := listUserQuery(param)
+
+	rows, err := db.Query(ctx, q+" ORDER BY "+listUserOrder(param), paramQuery...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		a, err := scanListUser(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(a); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// listUserQuery users query of the list filter without order & pagination
+func listUserQuery(param map[string]interface{}) (string, []interface{}) {
+	var q psql.Query
+
+	if len(param["keyword"].(string)) > 0 {
+		q.Like(param["keyword"].(string), "name", "role", "phone", "email")
+	}
+
+	sql := `
+		select role, user_code, name,img, phone, email, count(distinct(total_client)), MAX(last_active_date), MAX(avg_rating), MAX(total_rating) 
+			from (
+				select 
+					u.id, l.role, u.user_code,
+					u.name, u.img, u.phone, u.email,
+					paid_by as total_client, 
+					l.last_active_date,
+					(select avg(tr.rating)::float8 from tc_ratings tr where tr.tc_id = u.id) as avg_rating,
+					(select count(tr.id) from tc_ratings tr where tr.tc_id = u.id) as total_rating
+				from users as u
+				left join orders o on o.tc_id = u.id
+				join log_visit_app l on l.user_id = u.id
+				where is_active = true 
+				group by paid_by, u.id, l.id 
+			) a `
+
+	if len(param["role"].(string)) > 0 {
+		q.Where("role = ?", param["role"])
+	}
+
+	return sql + q.WhereSQL() + " group by id, role, name, img, phone, email, user_code", q.Args
+}
+
+// listUserOrder whitelisted order of the users list
+func listUserOrder(param map[string]interface{}) string {
+	return psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"id", "name"}, "name")
+}
+
+// scanListUser ...
+func scanListUser(rows pgx.Rows) (UserEnt, error) {
+	var a UserEnt
+	err := rows.Scan(&a.Role, &a.UserCode, &a.Name, &a.Img, &a.Phone, &a.Email, &a.TotalClient, &a.LastVisit, &a.AvgRating, &a.TotalRating)
+
+	return a, err
+}
+
 // GetUserByCode ...
 func (c *Contract) GetUserByCode(db *pgxpool.Conn, ctx context.Context, code string) (UserEnt, error) {
 	var u UserEnt
@@ -161,7 +193,9 @@ func (c *Contract) GetDetailTcByCode(db *pgxpool.Conn, ctx context.Context, code
 				count(distinct(paid_by)) as total_client, 
 				count(o.id) as  total_ord,
 				count(case when o.order_type = 'C' then o.order_type end) as total_cust_ord,
-				l.last_active_date
+				l.last_active_date,
+				(select avg(tr.rating)::float8 from tc_ratings tr where tr.tc_id = u.id) as avg_rating,
+				(select count(tr.id) from tc_ratings tr where tr.tc_id = u.id) as total_rating
 			from users as u
 			left join orders o on o.tc_id = u.id
 			left join log_visit_app l on l.user_id = u.id
@@ -170,7 +204,7 @@ func (c *Contract) GetDetailTcByCode(db *pgxpool.Conn, ctx context.Context, code
 
 	err := db.QueryRow(ctx, sql, code).Scan(
 		&u.ID, &u.UserCode, &u.Role, &u.Name, &u.Phone, &u.Img, &u.Phone, &u.TotalClient,
-		&u.TotalOrd, &u.TotalCustomOrder, &u.LastVisit)
+		&u.TotalOrd, &u.TotalCustomOrder, &u.LastVisit, &u.AvgRating, &u.TotalRating)
 
 	return u, err
 }
@@ -269,6 +303,10 @@ func (c *Contract) UpdateEmail(db *pgxpool.Conn, ctx context.Context, id int32,
 
 func (c *Contract) GetUserBy(db *pgxpool.Conn, ctx context.Context, field, username string) (UserEnt, error) {
 	var u UserEnt
+	if err := psql.Allowed(field, "id", "user_code", "email", "phone"); err != nil {
+		return u, err
+	}
+
 	err := pgxscan.Get(ctx, db, &u,
 		fmt.Sprintf("select * from users where %s=$1 limit 1", field),
 		username,
@@ -0,0 +1,342 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"panorama/lib/psql"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	VOUCHER_TYPE_PERCENT = "percent"
+	VOUCHER_TYPE_FIXED   = "fixed"
+)
+
+// VoucherEnt promo code of order that managed by admin
+type VoucherEnt struct {
+	ID               int32
+	VoucherCode      string
+	Title            string
+	Description      string
+	DiscountType     string
+	DiscountValue    int64 // percent for percent voucher, amount for fixed voucher
+	MaxDiscount      sql.NullInt64
+	MinSpend         int64
+	StartDate        time.Time
+	EndDate          time.Time
+	UsageLimit       sql.NullInt32
+	UsageLimitMember sql.NullInt32
+	UsedCount        int32
+	Destinations     []string
+	OrderTypes       []string
+	IsActive         bool
+	CreatedBy        int32
+	CreatedDate      time.Time
+	UpdatedDate      sql.NullTime
+}
+
+// VoucherRedemptionEnt voucher that applied into an order
+type VoucherRedemptionEnt struct {
+	ID             int32
+	VoucherID      int32
+	OrderID        int32
+	OrderCode      string
+	MemberID       int32
+	MemberCode     string
+	MemberName     string
+	DiscountAmount int64
+	CreatedDate    time.Time
+}
+
+// Discount amount of discount from the order amount before tax
+func (v VoucherEnt) Discount(amount int64) int64 {
+	discount := v.DiscountValue
+	if v.DiscountType == VOUCHER_TYPE_PERCENT {
+		discount = amount * v.DiscountValue / 100
+	}
+
+	if v.MaxDiscount.Valid && v.MaxDiscount.Int64 > 0 && discount > v.MaxDiscount.Int64 {
+		discount = v.MaxDiscount.Int64
+	}
+	if discount > amount {
+		discount = amount
+	}
+
+	return discount
+}
+
+// IsAvailable voucher is active and in the validity window
+func (v VoucherEnt) IsAvailable(now time.Time) bool {
+	return v.IsActive && !now.Before(v.StartDate) && !now.After(v.EndDate)
+}
+
+// IsApplicable voucher can be used for the destination and type of order
+func (v VoucherEnt) IsApplicable(destination, orderType string) bool {
+	if len(v.OrderTypes) > 0 {
+		var match bool
+		for _, t := range v.OrderTypes {
+			if t == orderType {
+				match = true
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+
+	if len(v.Destinations) > 0 {
+		for _, d := range v.Destinations {
+			if strings.EqualFold(strings.TrimSpace(d), strings.TrimSpace(destination)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// AddVoucher add new voucher
+func (c *Contract) AddVoucher(tx pgx.Tx, ctx context.Context, v VoucherEnt) (VoucherEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO vouchers(voucher_code, title, description, discount_type, discount_value, max_discount, min_spend, start_date, end_date,
+			usage_limit, usage_limit_member, destinations, order_types, is_active, created_by, created_date)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, v.VoucherCode, v.Title, v.Description, v.DiscountType, v.DiscountValue, v.MaxDiscount, v.MinSpend, v.StartDate, v.EndDate,
+		v.UsageLimit, v.UsageLimitMember, v.Destinations, v.OrderTypes, v.IsActive, v.CreatedBy, timeStamp).Scan(&lastInsID)
+
+	v.ID = lastInsID
+	v.CreatedDate = timeStamp
+
+	return v, err
+}
+
+// UpdateVoucher update voucher, used count is only changed by redemption
+func (c *Contract) UpdateVoucher(tx pgx.Tx, ctx context.Context, v VoucherEnt) (VoucherEnt, error) {
+	timeStamp := time.Now().In(time.UTC)
+
+	q := `UPDATE vouchers SET title = $1, description = $2, discount_type = $3, discount_value = $4, max_discount = $5, min_spend = $6,
+			start_date = $7, end_date = $8, usage_limit = $9, usage_limit_member = $10, destinations = $11, order_types = $12, is_active = $13, updated_date = $14
+		WHERE id = $15`
+
+	_, err := tx.Exec(ctx, q, v.Title, v.Description, v.DiscountType, v.DiscountValue, v.MaxDiscount, v.MinSpend,
+		v.StartDate, v.EndDate, v.UsageLimit, v.UsageLimitMember, v.Destinations, v.OrderTypes, v.IsActive, timeStamp, v.ID)
+
+	v.UpdatedDate = sql.NullTime{Time: timeStamp, Valid: true}
+
+	return v, err
+}
+
+// DeleteVoucher soft delete voucher, redemption of voucher is kept for the report
+func (c *Contract) DeleteVoucher(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `UPDATE vouchers SET is_active = false, deleted_date = $1 WHERE id = $2`, time.Now().In(time.UTC), id)
+
+	return err
+}
+
+const voucherSelect = `
+	select
+		v.id, v.voucher_code, v.title, v.description, v.discount_type, v.discount_value, v.max_discount, v.min_spend,
+		v.start_date, v.end_date, v.usage_limit, v.usage_limit_member, v.used_count, v.destinations, v.order_types,
+		v.is_active, v.created_by, v.created_date, v.updated_date
+	from vouchers v `
+
+func scanVoucher(row pgx.Row) (VoucherEnt, error) {
+	var v VoucherEnt
+	var description sql.NullString
+
+	err := row.Scan(&v.ID, &v.VoucherCode, &v.Title, &description, &v.DiscountType, &v.DiscountValue, &v.MaxDiscount, &v.MinSpend,
+		&v.StartDate, &v.EndDate, &v.UsageLimit, &v.UsageLimitMember, &v.UsedCount, &v.Destinations, &v.OrderTypes,
+		&v.IsActive, &v.CreatedBy, &v.CreatedDate, &v.UpdatedDate)
+	v.Description = description.String
+
+	return v, err
+}
+
+// GetVoucherByCode voucher that not deleted by the code, code of voucher is case insensitive
+func (c *Contract) GetVoucherByCode(db *pgxpool.Conn, ctx context.Context, code string) (VoucherEnt, error) {
+	return scanVoucher(db.QueryRow(ctx, voucherSelect+`where upper(v.voucher_code) = upper($1) and v.deleted_date is null limit 1`, code))
+}
+
+// GetVoucherByID voucher by the id
+func (c *Contract) GetVoucherByID(db *pgxpool.Conn, ctx context.Context, id int32) (VoucherEnt, error) {
+	return scanVoucher(db.QueryRow(ctx, voucherSelect+`where v.id = $1 limit 1`, id))
+}
+
+// GetListVoucher list voucher that not deleted
+func (c *Contract) GetListVoucher(db *pgxpool.Conn, ctx context.Context, param map[string]interface{}) ([]VoucherEnt, error) {
+	list := []VoucherEnt{}
+	where := []string{"v.deleted_date is null"}
+	var paramQuery []interface{}
+
+	if len(param["keyword"].(string)) > 0 {
+		paramQuery = append(paramQuery, "%"+param["keyword"].(string)+"%")
+		where = append(where, "(v.voucher_code ilike $1 or v.title ilike $1)")
+	}
+
+	if len(param["is_active"].(string)) > 0 {
+		if param["is_active"].(string) == "true" {
+			where = append(where, "v.is_active = true and now() between v.start_date and v.end_date")
+		} else {
+			where = append(where, "(v.is_active = false or now() not between v.start_date and v.end_date)")
+		}
+	}
+
+	q := voucherSelect + " WHERE " + strings.Join(where, " AND ")
+
+	{
+		var count int
+		newQcount := `SELECT COUNT(*) FROM (` + q + `) AS data`
+		err := db.QueryRow(ctx, newQcount, paramQuery...).Scan(&count)
+		if err != nil {
+			return list, err
+		}
+		param["count"] = count
+	}
+
+	// Select Max Page
+	if param["count"].(int) > param["limit"].(int) && param["page"].(int) > int(param["count"].(int)/param["limit"].(int)) {
+		param["page"] = int(math.Ceil(float64(param["count"].(int)) / float64(param["limit"].(int))))
+	}
+
+	param["offset"] = (param["page"].(int) - 1) * param["limit"].(int)
+
+	q += " ORDER BY " + psql.OrderBy(param["order"].(string), param["sort"].(string), []string{"v.id", "v.start_date", "v.end_date", "v.used_count"}, "v.id")
+	if param["limit"].(int) != -1 {
+		paramQuery = append(paramQuery, param["offset"], param["limit"])
+		q += fmt.Sprintf(" offset $%d limit $%d", len(paramQuery)-1, len(paramQuery))
+	}
+
+	rows, err := db.Query(ctx, q, paramQuery...)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, v)
+	}
+
+	return list, rows.Err()
+}
+
+// RedeemVoucher count the redemption of voucher, the row lock of the update keep the global cap
+// under concurrent checkouts. pgx.ErrNoRows is returned when the usage limit is reached
+func (c *Contract) RedeemVoucher(tx pgx.Tx, ctx context.Context, id int32) (int32, error) {
+	var usedCount int32
+
+	sql := `UPDATE vouchers SET used_count = used_count + 1
+		WHERE id = $1 and (usage_limit is null or used_count < usage_limit) RETURNING used_count`
+
+	err := tx.QueryRow(ctx, sql, id).Scan(&usedCount)
+
+	return usedCount, err
+}
+
+// ReleaseVoucher uncount the redemption of voucher when it removed from order
+func (c *Contract) ReleaseVoucher(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `UPDATE vouchers SET used_count = greatest(used_count - 1, 0) WHERE id = $1`, id)
+
+	return err
+}
+
+// CountVoucherRedemptionByMember total redemption of voucher by the member, must be called after RedeemVoucher
+// so the redemption of concurrent checkout is already committed
+func (c *Contract) CountVoucherRedemptionByMember(tx pgx.Tx, ctx context.Context, voucherID, memberID int32) (int32, error) {
+	var total int32
+
+	err := tx.QueryRow(ctx, `select count(id) from voucher_redemptions where voucher_id = $1 and member_id = $2`, voucherID, memberID).Scan(&total)
+
+	return total, err
+}
+
+// AddVoucherRedemption save voucher that applied into order
+func (c *Contract) AddVoucherRedemption(tx pgx.Tx, ctx context.Context, r VoucherRedemptionEnt) (VoucherRedemptionEnt, error) {
+	var lastInsID int32
+	timeStamp := time.Now().In(time.UTC)
+
+	sql := `INSERT INTO voucher_redemptions(voucher_id, order_id, member_id, discount_amount, created_date) VALUES($1, $2, $3, $4, $5) RETURNING id`
+
+	err := tx.QueryRow(ctx, sql, r.VoucherID, r.OrderID, r.MemberID, r.DiscountAmount, timeStamp).Scan(&lastInsID)
+
+	r.ID = lastInsID
+	r.CreatedDate = timeStamp
+
+	return r, err
+}
+
+// DeleteVoucherRedemption remove voucher from order
+func (c *Contract) DeleteVoucherRedemption(tx pgx.Tx, ctx context.Context, id int32) error {
+	_, err := tx.Exec(ctx, `delete from voucher_redemptions where id = $1`, id)
+
+	return err
+}
+
+// ReleaseOrderVoucher remove the applied voucher of order and uncount the redemption
+func (c *Contract) ReleaseOrderVoucher(tx pgx.Tx, ctx context.Context, redemption VoucherRedemptionEnt) error {
+	if redemption.ID == 0 {
+		return nil
+	}
+
+	err := c.DeleteVoucherRedemption(tx, ctx, redemption.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.ReleaseVoucher(tx, ctx, redemption.VoucherID)
+}
+
+const voucherRedemptionSelect = `
+	select vr.id, vr.voucher_id, vr.order_id, o.order_code, vr.member_id, m.member_code, m.name, vr.discount_amount, vr.created_date
+	from voucher_redemptions vr
+	join orders o on o.id = vr.order_id
+	join members m on m.id = vr.member_id `
+
+func scanVoucherRedemption(row pgx.Row) (VoucherRedemptionEnt, error) {
+	var r VoucherRedemptionEnt
+
+	err := row.Scan(&r.ID, &r.VoucherID, &r.OrderID, &r.OrderCode, &r.MemberID, &r.MemberCode, &r.MemberName, &r.DiscountAmount, &r.CreatedDate)
+
+	return r, err
+}
+
+// GetVoucherRedemptionByOrderID voucher that applied into order
+func (c *Contract) GetVoucherRedemptionByOrderID(db *pgxpool.Conn, ctx context.Context, orderID int32) (VoucherRedemptionEnt, error) {
+	return scanVoucherRedemption(db.QueryRow(ctx, voucherRedemptionSelect+`where vr.order_id = $1 limit 1`, orderID))
+}
+
+// GetListVoucherRedemption redemption history of voucher
+func (c *Contract) GetListVoucherRedemption(db *pgxpool.Conn, ctx context.Context, voucherID int32) ([]VoucherRedemptionEnt, error) {
+	list := []VoucherRedemptionEnt{}
+
+	rows, err := db.Query(ctx, voucherRedemptionSelect+`where vr.voucher_id = $1 order by vr.id desc`, voucherID)
+	if err != nil {
+		return list, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanVoucherRedemption(rows)
+		if err != nil {
+			return list, err
+		}
+
+		list = append(list, r)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"panorama/lib/payment"
+	"panorama/lib/psql"
+	"panorama/services/api/model"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// ReconcileFlags ...
+	ReconcileFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file",
+			Usage:    "Midtrans settlement export (csv)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "out",
+			Usage: "Write the mismatched rows into the csv report",
+		},
+		&cli.BoolFlag{
+			Name:  "recheck",
+			Usage: "Re-check the mismatched rows with the midtrans status api",
+		},
+	}
+)
+
+// ReconcileSettlement match midtrans settlement export with the paid payments and save the reconciliation
+func (app Boot) ReconcileSettlement(c *cli.Context) error {
+	file, err := os.Open(c.String("file"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := model.ParseSettlementFile(file)
+	if err != nil {
+		return err
+	}
+
+	db, err := psql.Connect(app.Config.GetString("db.psql_dsn"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.App.DB = db
+
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	m := model.Contract{App: app.App}
+
+	rec, err := m.ReconcileSettlement(conn, ctx, model.PaymentReconciliationEnt{FileName: filepath.Base(c.String("file"))}, rows)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	rec, err = m.AddPaymentReconciliation(tx, ctx, rec)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	if c.Bool("recheck") {
+		paymentService := payment.New(app.App)
+		for i, item := range rec.Items {
+			if !item.IsMismatch() {
+				continue
+			}
+
+			status, err := paymentService.GetMidtransTransactionStatus(ctx, item.MidtransOrderID)
+			if err != nil {
+				log.Printf("Reconcile -> %s: %s", item.MidtransOrderID, err.Error())
+				continue
+			}
+
+			rec.Items[i], err = m.RecheckPaymentReconciliationItem(conn, tx, ctx, item, status.TransactionStatus, status.GrossAmount)
+			if err != nil {
+				tx.Rollback(ctx)
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	mismatched := []model.PaymentReconciliationItemEnt{}
+	for _, item := range rec.Items {
+		if item.IsMismatch() {
+			mismatched = append(mismatched, item)
+			log.Printf("Reconcile -> %s %s: %s", item.MidtransOrderID, item.Result, item.Note.String)
+		}
+	}
+
+	if out := c.String("out"); len(out) > 0 {
+		report, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer report.Close()
+
+		if err = model.WritePaymentReconciliationReport(report, mismatched); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Reconciliation %d -> %d rows, %d matched, %d mismatched\n", rec.ID, rec.TotalRows, rec.MatchedRows, len(mismatched))
+
+	return nil
+}
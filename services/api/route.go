@@ -9,6 +9,10 @@ import (
 
 // RegisterRoutes all routes for the apps
 func RegisterRoutes(r *chi.Mux, app *bootstrap.App) {
+	// probes of the deployment, the metrics are served on the internal host (metrics.host)
+	r.Get("/healthz", app.HealthzAction)
+	r.Get("/readyz", app.ReadyzAction)
+
 	r.Route("/v1", func(r chi.Router) {
 		r.Get("/ping", app.PingAction)
 
@@ -33,8 +37,25 @@ func RegisterSubsRoute(r chi.Router, app *bootstrap.App) {
 		r.Post("/midtrans/notification", h.AddMidtransNotificationAct)
 	})
 
+	// files of local storage (development & tests)
+	r.Get("/files/*", h.ServeFileAct)
+
+	r.Route("/settings", func(r chi.Router) {
+		r.Get("/public/{group}", h.GetPublicSettingAct)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.VerifyJwtToken)
+			r.Get("/", h.GetListSettingAct)
+			r.Get("/{id}", h.GetSettingAct)
+			r.Post("/", h.AddSettingAct)
+			r.Put("/{id}", h.UpdateSettingAct)
+			r.Delete("/{id}", h.DeleteSettingAct)
+		})
+	})
+
 	r.Route("/call-logs", func(r chi.Router) {
 		r.Get("/", h.GetLogsList)
+		r.With(app.VerifyJwtToken).Get("/export", h.GetLogsList)
 		r.Post("/citcall/{type}", h.AddCitcallLogs)
 	})
 
@@ -45,12 +66,21 @@ func RegisterSubsRoute(r chi.Router, app *bootstrap.App) {
 
 		r.Route("/chats", func(r chi.Router) {
 			r.Get("/", h.GetChatListAct)
-			r.Post("/", h.ChatAct)
 			r.Post("/room", h.CreateChatGroup)
 			r.Put("/invite-tc", h.InviteTcToGroupChat)
 			r.Post("/message", h.ChatMessage)
 			r.Get("/{code}", h.GetHistoryChatByCode)
 			r.Put("/{code}/leave-session", h.LeaveSessionChatAct)
+			r.Get("/{code}/handovers", h.GetChatTcHandoverAct)
+			r.Post("/{code}/rating", h.AddTcRatingAct)
+			r.Route("/{code}/calls", func(r chi.Router) {
+				r.Get("/", h.GetCallHistoryAct)
+				r.Post("/", h.StartCallAct)
+				r.Post("/{callCode}/join", h.JoinCallAct)
+				r.Post("/{callCode}/token", h.RenewCallTokenAct)
+				r.Post("/{callCode}/leave", h.LeaveCallAct)
+				r.Post("/{callCode}/end", h.EndCallAct)
+			})
 			r.Put("/is-read", h.UpdateIsReadMessages)
 		})
 
@@ -68,6 +98,16 @@ func RegisterSubsRoute(r chi.Router, app *bootstrap.App) {
 			r.Get("/{code}", h.GetMemberItinAct)
 			r.Put("/{code}", h.UpdateMemberItinAct)
 			r.Delete("/{code}", h.DelMemberItinAct)
+			r.Post("/invites/accept", h.AcceptMemberItinInviteAct)
+			r.Post("/invites/decline", h.DeclineMemberItinInviteAct)
+			r.Get("/{code}/invites", h.GetMemberItinInvitesAct)
+			r.Post("/{code}/invites/{inviteCode}/resend", h.ResendMemberItinInviteAct)
+			r.Delete("/{code}/invites/{inviteCode}", h.RevokeMemberItinInviteAct)
+			r.Get("/{code}/documents", h.GetListTripDocumentAct)
+			r.Post("/{code}/documents", h.AddTripDocumentAct)
+			r.Get("/{code}/documents/{documentCode}", h.GetTripDocumentAct)
+			r.Delete("/{code}/documents/{documentCode}", h.DeleteTripDocumentAct)
+			r.Get("/{code}/documents/{documentCode}/logs", h.GetTripDocumentLogsAct)
 		})
 
 		r.Route("/users", func(r chi.Router) {
@@ -99,6 +139,21 @@ func RegisterSubsRoute(r chi.Router, app *bootstrap.App) {
 			r.Post("/", h.AddOrderAct)
 			r.Put("/{code}", h.UpdateOrderAct)
 			r.Post("/payment", h.PostPaymentAct)
+			r.Get("/{code}/shares", h.GetOrderSharesAct)
+			r.Post("/{code}/shares", h.SplitOrderAct)
+			r.Post("/{code}/shares/pay", h.PayOrderShareAct)
+			r.Post("/{code}/shares/cover", h.CoverOrderSharesAct)
+			r.Post("/{code}/shares/remind", h.RemindOrderSharesAct)
+			r.Get("/{code}/installments", h.GetOrderInstallmentsAct)
+			r.Post("/{code}/installments", h.AddOrderInstallmentsAct)
+			r.Post("/{code}/installments/pay", h.PayOrderInstallmentAct)
+			r.Post("/{code}/apply-voucher", h.ApplyOrderVoucherAct)
+			r.Delete("/{code}/voucher", h.RemoveOrderVoucherAct)
+			r.Get("/{code}/invoice", h.GetOrderInvoiceAct)
+			r.Get("/{code}/history", h.GetOrderHistoryAct)
+			r.Put("/{code}/status", h.UpdateOrderStatusAct)
+			r.Get("/{code}/cancellation", h.GetOrderCancellationAct)
+			r.Post("/{code}/cancel", h.CancelOrderAct)
 		})
 
 		// create push notification
@@ -111,10 +166,65 @@ func RegisterSubsRoute(r chi.Router, app *bootstrap.App) {
 			r.Delete("/", h.DeleteAllNotificationAct)
 		})
 
+		r.Route("/ratings", func(r chi.Router) {
+			r.Get("/report", h.GetTcRatingReportAct)
+		})
+
+		r.Route("/vouchers", func(r chi.Router) {
+			r.Get("/", h.GetListVoucherAct)
+			r.Post("/", h.AddVoucherAct)
+			r.Get("/{code}", h.GetVoucherAct)
+			r.Put("/{code}", h.UpdateVoucherAct)
+			r.Delete("/{code}", h.DeleteVoucherAct)
+			r.Get("/{code}/redemptions", h.GetVoucherRedemptionsAct)
+		})
+
+		r.Route("/cancellation-policies", func(r chi.Router) {
+			r.Get("/", h.GetListCancellationPolicyAct)
+			r.Post("/", h.AddCancellationPolicyAct)
+			r.Put("/{id}", h.UpdateCancellationPolicyAct)
+			r.Delete("/{id}", h.DeleteCancellationPolicyAct)
+		})
+
+		r.Route("/reconciliations", func(r chi.Router) {
+			r.Get("/", h.GetListPaymentReconciliationAct)
+			r.Post("/import", h.ImportPaymentReconciliationAct)
+			r.Get("/{id}", h.GetPaymentReconciliationAct)
+			r.Post("/{id}/items/{itemID}/recheck", h.RecheckPaymentReconciliationItemAct)
+		})
+
+		r.Get("/currencies", h.GetListCurrencyAct)
+
+		r.Route("/exchange-rates", func(r chi.Router) {
+			r.Get("/", h.GetListExchangeRateAct)
+			r.Post("/", h.AddExchangeRateAct)
+			r.Post("/import", h.ImportExchangeRateAct)
+			r.Delete("/{id}", h.DeleteExchangeRateAct)
+		})
+
 		r.Route("/dashboard", func(r chi.Router) {
 			r.Get("/", h.GetDashboardAct)
 		})
 
+		r.Route("/activity-logs", func(r chi.Router) {
+			r.Get("/", h.GetListLogActivityAct)
+		})
+
+		r.Route("/audit-logs", func(r chi.Router) {
+			r.Get("/", h.GetListAuditLogAct)
+			r.Get("/{id}", h.GetAuditLogAct)
+		})
+
+		r.Route("/analytics", func(r chi.Router) {
+			r.Get("/revenue", h.GetAnalyticsRevenueAct)
+			r.Get("/orders", h.GetAnalyticsOrdersAct)
+			r.Get("/funnel", h.GetAnalyticsFunnelAct)
+			r.Get("/suggestions", h.GetAnalyticsSuggestionsAct)
+			r.Get("/destinations", h.GetAnalyticsDestinationsAct)
+			r.Get("/tc-performance", h.GetAnalyticsTcPerformanceAct)
+			r.Post("/refresh", h.RefreshAnalyticsAct)
+		})
+
 		r.Route("/stuff", func(r chi.Router) {
 			r.Post("/", h.AddStuffAct)
 			r.Get("/", h.GetListStuffAct)
@@ -9,11 +9,11 @@ import (
 	"os"
 	"os/signal"
 	"panorama/bootstrap"
+	"panorama/lib/metrics"
 	"panorama/lib/psql"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/valve"
 	"github.com/urfave/cli/v2"
@@ -46,6 +46,15 @@ func (app Boot) Start(c *cli.Context) error {
 	}
 	app.App.DB = db
 
+	// refuse to boot on the schema behind the migrations of the binary
+	if err = app.checkSchema(db); err != nil {
+		return err
+	}
+
+	if err = metrics.RegisterDBPool(db); err != nil {
+		return err
+	}
+
 	host := c.String("host")
 	if len(host) == 0 {
 		host = app.Config.GetString("app.host")
@@ -59,45 +68,24 @@ func (app Boot) Start(c *cli.Context) error {
 	baseCtx := valv.Context()
 
 	// start new app
-	r := chi.NewRouter()
-	cr := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{
-			"Accept",
-			"Authorization",
-			"Content-Type",
-			"X-CSRF-Token",
-			"X-SIGNATURE",
-			"X-TIMESTAMPT",
-			"X-CHANNEL",
-			"X-PLAYER",
-			"Access-Control-Allow-Headers",
-			"X-Requested-With",
-			"application/json",
-			"Cache-Control",
-			"Token",
-			"X-Token",
-		},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	})
-	r.Use(cr.Handler)
-	if app.Debug {
-		r.Use(middleware.Logger)
-	}
-	r.Use(app.Recoverer)
-	r.Use(app.NotfoundMiddleware)
-	// r.Use(app.HeaderCheckerMiddleware)
-
-	RegisterRoutes(r, app.App)
+	r := app.Router()
 
 	// handle grace full shutdown
 	srv := http.Server{Addr: host, Handler: r}
 	srv.BaseContext = func(_ net.Listener) context.Context {
 		return baseCtx
 	}
+
+	// prometheus scraping on the internal host, it is not served by the public router
+	metricsSrv := app.metricsServer()
+	if metricsSrv != nil {
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics host [%v] is stopped: %s", metricsSrv.Addr, err.Error())
+			}
+		}()
+	}
+
 	sng := make(chan os.Signal, 1)
 	signal.Notify(sng, os.Interrupt)
 
@@ -116,6 +104,9 @@ func (app Boot) Start(c *cli.Context) error {
 			if err != nil {
 				log.Println("Can't shutdown this server until all process are done!")
 			}
+			if metricsSrv != nil {
+				metricsSrv.Shutdown(ctx)
+			}
 			select {
 			case <-time.After(21 * time.Second):
 				fmt.Println("not all connections done")
@@ -127,3 +118,59 @@ func (app Boot) Start(c *cli.Context) error {
 
 	return srv.ListenAndServe()
 }
+
+// Router routes of the api with the middlewares, shared by the http host and the tests
+func (app Boot) Router() *chi.Mux {
+	r := chi.NewRouter()
+	cr := cors.New(cors.Options{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Accept",
+			"Authorization",
+			"Content-Type",
+			"X-CSRF-Token",
+			"X-SIGNATURE",
+			"X-TIMESTAMPT",
+			"X-CHANNEL",
+			"X-PLAYER",
+			"X-App-Version",
+			"X-Platform",
+			"Access-Control-Allow-Headers",
+			"X-Requested-With",
+			"application/json",
+			"Cache-Control",
+			"Token",
+			"X-Token",
+		},
+		ExposedHeaders:   []string{"Link", "X-App-Update"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	})
+	r.Use(cr.Handler)
+	r.Use(app.MetricsMiddleware)
+	r.Use(app.RequestLogger)
+	r.Use(app.Recoverer)
+	r.Use(app.NotfoundMiddleware)
+	r.Use(app.AppControlMiddleware)
+	r.Use(app.AuditMiddleware)
+	// r.Use(app.HeaderCheckerMiddleware)
+
+	RegisterRoutes(r, app.App)
+
+	return r
+}
+
+// metricsServer http host of the prometheus metrics on metrics.host (e.g. 127.0.0.1:9100), it must be
+// reachable by the internal network only. Nil when metrics.host is not set, the metrics are not served
+func (app Boot) metricsServer() *http.Server {
+	host := app.Config.GetString("metrics.host")
+	if len(host) == 0 {
+		return nil
+	}
+
+	r := chi.NewRouter()
+	r.Handle("/metrics", metrics.Handler())
+
+	return &http.Server{Addr: host, Handler: r}
+}
@@ -0,0 +1,13 @@
+-- fixtures of the end-to-end tests, the password of the users is secret123
+insert into users(user_code, name, email, phone, password, role, is_active, created_date) values
+	('u-e2e-admin', 'E2E Admin', 'admin@e2e.test', '+6281100000001', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'admin', true, now()),
+	('u-e2e-tc', 'E2E Consultant', 'tc@e2e.test', '+6281100000002', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'tc', true, now()),
+	('u-e2e-tc2', 'E2E Second Consultant', 'tc2@e2e.test', '+6281100000003', '$2a$10$IvQ0zqdhQcTEfAu.2qclCe2gr5BCowWFK2P57oICtadYZn.0mdPhW', 'tc', true, now());
+
+-- the tc is online today, so the tc is assigned by the invite
+insert into log_visit_app(user_id, role, total_visited, last_active_date)
+	select id, role, 1, now() from users where user_code = 'u-e2e-tc';
+
+-- catalog stuff priced by the order, the price of stuff is saved as text
+insert into stuff(code_stuff, name_stuff, description, price, type, is_active, created_date) values
+	('STF-E2E-001', 'Snorkeling Gear', 'Mask, snorkel & fins', '250000', 0, true, now());